@@ -0,0 +1,138 @@
+package cmd
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/jedib0t/go-pretty/v6/table"
+	"github.com/spacemeshos/go-spacemesh/genvm/core"
+	"github.com/spf13/cobra"
+
+	"github.com/spacemeshos/smcli/common"
+	"github.com/spacemeshos/smcli/wallet"
+)
+
+// addressCmd represents the address command.
+var addressCmd = &cobra.Command{
+	Use:   "address",
+	Short: "Address-related utilities",
+}
+
+// validateAddressCmd represents the validate command.
+var validateAddressCmd = &cobra.Command{
+	Use:   "validate [address] [--network name] [--hrp prefix]",
+	Short: "Check that an address is valid for the selected network",
+	Args:  cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		if _, err := wallet.ValidateAddress(args[0], hrp); err != nil {
+			fatalf(common.ErrBadInput, "invalid address: %v\n", err)
+		}
+		fmt.Println("OK")
+	},
+}
+
+// pubkeyEncoding is the encoding of a public key given on the command line, detected if empty.
+var pubkeyEncoding string
+
+// pubkeyAddressCmd prints the address of the wallet account with a given public key.
+var pubkeyAddressCmd = &cobra.Command{
+	Use:   "from-pubkey [pubkey] [--encoding hex|base64] [--network name] [--hrp prefix]",
+	Short: "Print the address of the wallet account with the given public key, without needing a wallet file",
+	Long: `Compute the address of the single-signature wallet account controlled by a raw ed25519 public key,
+e.g., one shown by a block explorer or printed by wallet pubkeys, and print it for the selected network. The key
+must be 32 bytes, hex-encoded (with or without a 0x prefix) or base64-encoded: the encoding is detected unless
+set using --encoding.`,
+	Args: cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		pubkey, err := wallet.ParsePublicKey(args[0], pubkeyEncoding)
+		checkErr(err)
+		fmt.Println(wallet.PubkeyToAddress(pubkey, hrp))
+	},
+}
+
+var (
+	// multisigRequired is the number of signatures required by a multisig account.
+	multisigRequired uint8
+	// rawKeyOrder keeps the public keys of a multisig account in the order given instead of sorting them.
+	rawKeyOrder bool
+)
+
+// multisigAddressCmd prints the address and spawn arguments of a multisig account.
+var multisigAddressCmd = &cobra.Command{
+	Use:   "multisig [pubkey]... --required k [--raw-order] [--output table|json]",
+	Short: "Print the address and spawn arguments of a multisig account for its participants to verify",
+	Long: `Compute the address of a multisig account requiring k signatures from the given hex-encoded
+public keys, and print it along with its participants and a fingerprint of its spawn arguments. Each participant
+should run this command independently and compare the results before anyone funds the account.
+
+The multisig template is sensitive to the order of the public keys, so they're sorted in canonical order,
+ascending byte order, before computing the address: the same keys in any order give the same address and
+fingerprint. Participants are listed in that order, which is also the order to use when signing. Add
+--raw-order to keep the keys in the order given instead, e.g., for an account spawned elsewhere without sorting
+them: every participant must then list them in the same order.`,
+	Args: cobra.MinimumNArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		checkOutputFormat()
+		pubkeys := parsePublicKeys(args)
+		var opts []wallet.WalletOpt
+		if rawKeyOrder {
+			opts = append(opts, wallet.WithRawKeyOrder())
+		}
+		spawn, err := wallet.DescribeMultiSigSpawn(multisigRequired, pubkeys, opts...)
+		checkErr(err)
+
+		keys := make([]string, len(spawn.PublicKeys))
+		for i, pubkey := range spawn.PublicKeys {
+			keys[i] = hex.EncodeToString(pubkey[:])
+		}
+		if outputFormat == outputJSON {
+			enc := json.NewEncoder(os.Stdout)
+			enc.SetIndent("", "  ")
+			checkErr(enc.Encode(struct {
+				Address     string   `json:"address"`
+				Required    uint8    `json:"required"`
+				PublicKeys  []string `json:"publicKeys"`
+				Fingerprint string   `json:"fingerprint"`
+			}{wallet.AddressString(spawn.Principal, hrp), spawn.Required, keys, spawn.Fingerprint}))
+			return
+		}
+		t := table.NewWriter()
+		t.SetOutputMirror(os.Stdout)
+		t.AppendRow(table.Row{"address", wallet.AddressString(spawn.Principal, hrp)})
+		t.AppendRow(table.Row{"required signatures", fmt.Sprintf("%d of %d", spawn.Required, len(keys))})
+		for i, key := range keys {
+			t.AppendRow(table.Row{fmt.Sprintf("public key %d", i), key})
+		}
+		t.AppendRow(table.Row{"fingerprint", spawn.Fingerprint})
+		t.Render()
+	},
+}
+
+// parsePublicKeys parses the hex-encoded public keys of the participants in a multisig account.
+func parsePublicKeys(args []string) []core.PublicKey {
+	pubkeys := make([]core.PublicKey, len(args))
+	for i, arg := range args {
+		key, err := hex.DecodeString(strings.TrimPrefix(arg, "0x"))
+		if err != nil || len(key) != len(pubkeys[i]) {
+			fatalf(common.ErrBadInput, "Invalid public key %q, must be %d hex-encoded bytes\n", arg, len(pubkeys[i]))
+		}
+		copy(pubkeys[i][:], key)
+	}
+	return pubkeys
+}
+
+func init() {
+	rootCmd.AddCommand(addressCmd)
+	addressCmd.AddCommand(validateAddressCmd)
+	addressCmd.AddCommand(multisigAddressCmd)
+	addressCmd.AddCommand(pubkeyAddressCmd)
+	pubkeyAddressCmd.Flags().StringVar(&pubkeyEncoding, "encoding", "",
+		"Encoding of the public key, hex or base64 (default detected)")
+	multisigAddressCmd.Flags().Uint8VarP(&multisigRequired, "required", "k", 1, "Number of required signatures")
+	multisigAddressCmd.Flags().BoolVar(&rawKeyOrder, "raw-order", false,
+		"Keep the public keys in the order given instead of sorting them")
+	multisigAddressCmd.Flags().StringVarP(&outputFormat, "output", "o", outputTable, "Output format, table or json")
+}
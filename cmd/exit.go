@@ -0,0 +1,55 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"log"
+	"os"
+
+	"github.com/spacemeshos/smcli/common"
+)
+
+// Commands exit with the exit code of the category of the error that stopped them, see common.ExitCode. Errors
+// returned to cobra, e.g., when parsing flags, are bad input unless they belong to another category.
+
+// checkErr prints err and exits with its exit code if it isn't nil, like cobra.CheckErr.
+func checkErr(err error) {
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "Error:", err)
+		os.Exit(common.ExitCode(err))
+	}
+}
+
+// checkWriteErr is checkErr for errors writing files, e.g., a new wallet file. A missing directory there is a
+// failure rather than a missing input file, so it exits with ExitFailure instead of ExitBadInput, unless err was
+// put in the bad input category explicitly.
+func checkWriteErr(err error) {
+	if errors.Is(err, fs.ErrNotExist) && common.ExitCode(err) == common.ExitBadInput &&
+		!errors.Is(err, common.ErrBadInput) {
+		fmt.Fprintln(os.Stderr, "Error:", err)
+		os.Exit(common.ExitFailure)
+	}
+	checkErr(err)
+}
+
+// fatalf logs a message, like log.Fatalf, and exits with the exit code of category, one of the common category
+// errors.
+func fatalf(category error, format string, v ...interface{}) {
+	log.Printf(format, v...)
+	os.Exit(common.ExitCode(category))
+}
+
+// fatalln logs a message, like log.Fatalln, and exits with the exit code of category.
+func fatalln(category error, v ...interface{}) {
+	log.Println(v...)
+	os.Exit(common.ExitCode(category))
+}
+
+// exitCode returns the exit code for an error returned by the root command.
+func exitCode(err error) int {
+	if code := common.ExitCode(err); code != common.ExitFailure {
+		return code
+	}
+	return common.ExitBadInput
+}
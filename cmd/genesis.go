@@ -4,15 +4,15 @@ import (
 	"crypto/ed25519"
 	"encoding/hex"
 	"fmt"
-	"log"
 
 	"github.com/spacemeshos/economics/constants"
 	"github.com/spacemeshos/go-spacemesh/common/types"
 	"github.com/spacemeshos/go-spacemesh/genvm/core"
-	"github.com/spacemeshos/go-spacemesh/genvm/templates/multisig"
 	"github.com/spacemeshos/go-spacemesh/genvm/templates/vault"
-	"github.com/spacemeshos/go-spacemesh/genvm/templates/vesting"
 	"github.com/spf13/cobra"
+
+	"github.com/spacemeshos/smcli/common"
+	"github.com/spacemeshos/smcli/wallet"
 )
 
 // genesisCmd represents the wallet command.
@@ -41,7 +41,7 @@ var verifyCmd = &cobra.Command{
 			}
 			keyBytes, err := hex.DecodeString(keyStr)
 			if err != nil || len(keyBytes) != ed25519.PublicKeySize {
-				log.Fatalln("Error: key is unreadable")
+				fatalln(common.ErrBadInput, "Error: key is unreadable")
 			}
 			key := [ed25519.PublicKeySize]byte{}
 			copy(key[:], keyBytes)
@@ -49,44 +49,37 @@ var verifyCmd = &cobra.Command{
 			fmt.Printf("[enter next key or just press enter to end] > ")
 		}
 		if len(keys) == 0 {
-			log.Fatalln("Error: must enter at least one key")
+			fatalln(common.ErrBadInput, "Error: must enter at least one key")
 		}
 
 		// next collect multisig params
-		m := uint8(1)
+		required := 1
 		if len(keys) > 1 {
 			fmt.Printf("Enter number of required signatures (between 1 and %d): ", len(keys))
-			_, err := fmt.Scanln(&m)
-			cobra.CheckErr(err)
+			_, err := fmt.Scanln(&required)
+			checkErr(err)
 		}
+		m, err := wallet.MultiSigThreshold(required, len(keys))
+		checkErr(err)
 
 		// finally, collect amount
 		var amount uint64
 		fmt.Printf("Enter vault balance (denominated in SMH): ")
 		_, err = fmt.Scanln(&amount)
-		cobra.CheckErr(err)
+		checkErr(err)
 		amount *= constants.OneSmesh
 
 		// calculate keys
-		vestingArgs := &multisig.SpawnArguments{
-			Required:   m,
-			PublicKeys: keys,
-		}
-		if int(vestingArgs.Required) > len(vestingArgs.PublicKeys) {
-			log.Fatalf("requires more signatures (%d) than public keys (%d) in the wallet\n",
-				vestingArgs.Required,
-				len(vestingArgs.PublicKeys),
-			)
-		}
-		vestingAddress := core.ComputePrincipal(vesting.TemplateAddress, vestingArgs)
-		vaultArgs := &vault.SpawnArguments{
+		vestingAddress, _, err := wallet.SpawnVesting(m, keys)
+		checkErr(err)
+		vaultAddress, _, err := wallet.SpawnVault(&vault.SpawnArguments{
 			Owner:               vestingAddress,
 			TotalAmount:         amount,
 			InitialUnlockAmount: amount / 4,
 			VestingStart:        types.LayerID(constants.VestStart),
 			VestingEnd:          types.LayerID(constants.VestEnd),
-		}
-		vaultAddress := core.ComputePrincipal(vault.TemplateAddress, vaultArgs)
+		})
+		checkErr(err)
 
 		// output addresses
 		fmt.Printf("Vesting address: %s\nVault address: %s\n",
@@ -0,0 +1,57 @@
+package cmd
+
+import (
+	"io"
+	"os"
+	"strings"
+
+	"github.com/hashicorp/go-secure-stdlib/password"
+	"golang.org/x/term"
+
+	"github.com/spacemeshos/smcli/wallet"
+)
+
+// readInput reads a line of user input without echoing it. If stdin isn't a terminal, e.g., when input is piped
+// in by a script, it reads the next line of stdin instead, one byte at a time so that later prompts get the
+// following lines. The line ending is not included.
+func readInput() (string, error) {
+	if term.IsTerminal(int(os.Stdin.Fd())) {
+		return password.Read(os.Stdin)
+	}
+	var line []byte
+	var buf [1]byte
+	for {
+		n, err := os.Stdin.Read(buf[:])
+		if n == 1 && buf[0] != '\n' {
+			line = append(line, buf[0])
+		}
+		if n == 1 && buf[0] == '\n' || err == io.EOF {
+			break
+		}
+		if err != nil {
+			return "", err
+		}
+	}
+	return strings.TrimSuffix(string(line), "\r"), nil
+}
+
+// pickAccount lets the user choose one of the accounts of w using a menu if stdin is a terminal, or else by
+// entering its index or label on the next line of stdin.
+func pickAccount(w *wallet.Wallet) wallet.AccountPublicKey {
+	keys, err := w.PublicKeys(hrp)
+	checkErr(err)
+	picker := wallet.NewAccountPicker(keys)
+	fd := int(os.Stdin.Fd())
+	if !term.IsTerminal(fd) {
+		acct, err := picker.Prompt(os.Stdin, promptOutput())
+		checkErr(err)
+		return acct
+	}
+	state, err := term.MakeRaw(fd)
+	checkErr(err)
+	acct, err := picker.Pick(os.Stdin, promptOutput())
+	// restore the terminal before exiting on an error
+	checkErr(term.Restore(fd, state))
+	checkErr(err)
+	return acct
+}
@@ -0,0 +1,105 @@
+package cmd
+
+import (
+	"bytes"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"os"
+
+	"github.com/spf13/cobra"
+
+	"github.com/spacemeshos/smcli/common"
+	"github.com/spacemeshos/smcli/wallet"
+)
+
+// signKeyTx makes sign-with-key sign a transaction built using tx build instead of a message.
+var signKeyTx bool
+
+// signWithKeyCmd signs a message or a transaction using a standalone key file.
+var signWithKeyCmd = &cobra.Command{
+	Use: "sign-with-key [key file] [message] [--encoding hex|base64] | " +
+		"sign-with-key [key file] --tx [unsigned transaction file] [signed transaction file]",
+	Short: "Sign a message or a transaction using a key file instead of a wallet",
+	Long: `Decrypt a standalone key file, written by import-key --key-file, and sign using its key.
+
+By default the message given on the command line is signed like by sign-message and the signature is printed,
+encoded using --encoding, to be checked using verify-message. With --tx, an unsigned transaction built using
+tx build is signed instead, like by tx sign: what it does is printed first and has to be confirmed, and the
+signed transaction is written hex-encoded to a new file, to be submitted using tx broadcast. The key must be
+the one of the principal of the transaction.`,
+	Args: func(cmd *cobra.Command, args []string) error {
+		if signKeyTx {
+			return cobra.ExactArgs(3)(cmd, args)
+		}
+		return cobra.ExactArgs(2)(cmd, args)
+	},
+	Run: func(cmd *cobra.Command, args []string) {
+		var txn *wallet.OfflineTxn
+		encode := signatureEncoder()
+		if signKeyTx {
+			f, err := os.Open(args[1])
+			checkErr(err)
+			txn, err = wallet.ReadOfflineTxn(f)
+			f.Close()
+			checkErr(err)
+		}
+
+		kp := openKeyFile(args[0])
+		defer kp.Wipe()
+		if !signKeyTx {
+			sig, err := kp.SignMessage([]byte(args[1]))
+			checkErr(err)
+			fmt.Println(encode(sig))
+			return
+		}
+
+		d, err := txn.Decode()
+		checkErr(err)
+		if principal := wallet.PubkeyToPrincipal(kp.Public); principal != d.Principal {
+			fatalf(common.ErrBadInput, "The key in %s doesn't send this transaction, its principal is %s, not %s\n",
+				args[0], wallet.AddressString(d.Principal, hrp), wallet.AddressString(principal, hrp))
+		}
+		printOfflineTxn(txn)
+		if !confirmed("Sign this transaction? [y/N]: ") {
+			fatalln(common.ErrBadInput, "Not signed")
+		}
+		signed, err := wallet.SignTxn(kp, txn.GenesisID, txn.Unsigned)
+		checkErr(err)
+		out, err := os.OpenFile(args[2], os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0o644)
+		checkErr(err)
+		defer out.Close()
+		_, err = fmt.Fprintln(out, hex.EncodeToString(signed))
+		checkErr(err)
+		fmt.Printf("Signed, saved to %s.\n", args[2])
+	},
+}
+
+// openKeyFile reads the password, see readWalletPassword, and decrypts a key file, telling a wrong password
+// apart from a file that isn't a valid key file.
+func openKeyFile(fn string) *wallet.EDKeyPair {
+	data, err := os.ReadFile(fn)
+	checkErr(err)
+	if !wallet.IsKeyFile(data) {
+		fatalf(common.ErrBadInput, "%s is not a key file, use import-key --key-file to create one\n", fn)
+	}
+	password := readWalletPassword(os.Stderr, "Enter key file password: ")
+	wk := wallet.NewKey(wallet.WithPasswordOnly([]byte(password)))
+	kp, err := wk.OpenKeyFile(bytes.NewReader(data))
+	switch {
+	case errors.Is(err, wallet.ErrWrongPassword):
+		fatalf(err, "Wrong password for the key file %s, or the file was modified\n", fn)
+	case errors.Is(err, wallet.ErrMalformedKeyFile):
+		fatalf(err, "%s is not a valid key file: %v\n", fn, err)
+	}
+	checkErr(err)
+	return kp
+}
+
+func init() {
+	walletCmd.AddCommand(signWithKeyCmd)
+	signWithKeyCmd.Flags().BoolVar(&signKeyTx, "tx", false,
+		"Sign an unsigned transaction file built using tx build instead of a message")
+	signWithKeyCmd.Flags().StringVar(&signatureEncoding, "encoding", wallet.PubkeyEncodingHex,
+		"Signature encoding, hex or base64")
+}
@@ -0,0 +1,118 @@
+package cmd
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+
+	"github.com/jedib0t/go-pretty/v6/table"
+	"github.com/spf13/cobra"
+
+	"github.com/spacemeshos/smcli/common"
+	"github.com/spacemeshos/smcli/node"
+)
+
+// nodeCmd represents the node command.
+var nodeCmd = &cobra.Command{
+	Use:   "node",
+	Short: "Query the node used for network operations",
+}
+
+// nodeStatusCmd prints the status of the node.
+var nodeStatusCmd = &cobra.Command{
+	Use:   "status [--output table|json]",
+	Short: "Print the sync status, current layer, peer count, and version of the node",
+	Long: `Query the node set using --endpoint for its sync status, the current (top) layer of the network and the
+last layers it has synced and verified, the number of peers it's connected to, and its version. Add
+--output json to print the status as a JSON object. Exits with an error if the node can't be reached.`,
+	Args: cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		checkOutputFormat()
+		client := newNodeClient()
+		defer client.Close()
+		st, err := client.Status(cmd.Context())
+		if errors.Is(err, node.ErrNodeUnavailable) {
+			fatalf(common.ErrNetwork,
+				"Can't reach the node at %s, check that it's running and that --endpoint is correct: %v\n",
+				client.Endpoint(), err)
+		}
+		checkErr(err)
+
+		if outputFormat == outputJSON {
+			enc := json.NewEncoder(os.Stdout)
+			enc.SetIndent("", "  ")
+			checkErr(enc.Encode(st))
+			return
+		}
+		t := table.NewWriter()
+		t.SetOutputMirror(os.Stdout)
+		t.AppendRows([]table.Row{
+			{"endpoint", client.Endpoint()},
+			{"version", st.Version},
+			{"synced", st.Synced},
+			{"top layer", st.TopLayer},
+			{"synced layer", st.SyncedLayer},
+			{"verified layer", st.VerifiedLayer},
+			{"connected peers", st.ConnectedPeers},
+		})
+		t.Render()
+	},
+}
+
+// nodePeersCmd prints the peers of the node.
+var nodePeersCmd = &cobra.Command{
+	Use:   "peers [--output table|json]",
+	Short: "Print the peer ID of the node and the number of peers it's connected to",
+	Long: `Query the node set using --endpoint for its own peer ID and the number of peers it's connected to. The
+peer ID is read using the debug API service, which is disabled on nodes by default: if so, only the number of
+peers is printed. The individual peers aren't listed: version 1.16 of the node API, which this version of smcli
+uses, has no call that lists them, so their IDs, addresses, and the direction of their connections can't be
+printed. Add --output json to print the result as a JSON object.`,
+	Args: cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		checkOutputFormat()
+		client := newNodeClient()
+		defer client.Close()
+		st, err := client.Status(cmd.Context())
+		if errors.Is(err, node.ErrNodeUnavailable) {
+			fatalf(common.ErrNetwork,
+				"Can't reach the node at %s, check that it's running and that --endpoint is correct: %v\n",
+				client.Endpoint(), err)
+		}
+		checkErr(err)
+		id, err := client.PeerID(cmd.Context())
+		if errors.Is(err, node.ErrServiceDisabled) {
+			fmt.Fprintf(os.Stderr, "Note: the debug API service is disabled on the node at %s, so its peer ID "+
+				"is unknown\n", client.Endpoint())
+		} else {
+			checkErr(err)
+		}
+
+		if outputFormat == outputJSON {
+			enc := json.NewEncoder(os.Stdout)
+			enc.SetIndent("", "  ")
+			checkErr(enc.Encode(struct {
+				PeerID         string `json:"peerId,omitempty"`
+				ConnectedPeers uint64 `json:"connectedPeers"`
+			}{id, st.ConnectedPeers}))
+			return
+		}
+		t := table.NewWriter()
+		t.SetOutputMirror(os.Stdout)
+		t.AppendRows([]table.Row{
+			{"endpoint", client.Endpoint()},
+			{"peer id", id},
+			{"connected peers", st.ConnectedPeers},
+		})
+		t.Render()
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(nodeCmd)
+	nodeCmd.AddCommand(nodeStatusCmd)
+	nodeCmd.AddCommand(nodePeersCmd)
+	nodeStatusCmd.Flags().StringVarP(&outputFormat, "output", "o", outputTable, "Output format, table or json")
+	nodePeersCmd.Flags().StringVarP(&outputFormat, "output", "o", outputTable, "Output format, table or json")
+}
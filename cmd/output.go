@@ -0,0 +1,63 @@
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"os"
+
+	"golang.org/x/term"
+
+	"github.com/spacemeshos/smcli/common"
+)
+
+// Values of the --output flag.
+const (
+	outputTable = "table"
+	outputJSON  = "json"
+)
+
+var (
+	// outputFormat selects between human-readable table output and machine-readable JSON output.
+	outputFormat string
+
+	// printQR draws QR codes of addresses, and of new or printed mnemonics, for scanning from a phone.
+	printQR bool
+)
+
+// checkOutputFormat exits if --output is set to an unknown format.
+func checkOutputFormat() {
+	if outputFormat != outputTable && outputFormat != outputJSON {
+		fatalf(common.ErrBadInput, "unknown output format %q, must be %q or %q\n", outputFormat, outputTable, outputJSON)
+	}
+}
+
+// promptOutput returns where interactive prompts are written. With JSON output they go to stderr so that
+// stdout only contains the JSON document.
+func promptOutput() io.Writer {
+	if outputFormat == outputJSON {
+		return os.Stderr
+	}
+	return os.Stdout
+}
+
+// printQRCode prints a QR code of content below a title.
+func printQRCode(title, content string) {
+	qr, err := common.QRCode(content)
+	checkErr(err)
+	fmt.Printf("\n%s\n%s", title, qr)
+}
+
+// printMnemonicQRCode prints a QR code of a mnemonic after warning that anyone who sees it can take the funds.
+func printMnemonicQRCode(m string) {
+	fmt.Fprintln(os.Stderr, "Warning: anyone who sees or photographs this QR code can restore the wallet and take "+
+		"its funds. Only scan it into a device you trust to back up the mnemonic.")
+	printQRCode("Mnemonic:", m)
+}
+
+// clearScreen clears the terminal, including its scrollback, e.g., once a mnemonic no longer needs to be shown.
+// It does nothing if stdout isn't a terminal.
+func clearScreen() {
+	if term.IsTerminal(int(os.Stdout.Fd())) {
+		fmt.Print("\033[H\033[2J\033[3J")
+	}
+}
@@ -1,16 +1,106 @@
 package cmd
 
 import (
+	"context"
 	"fmt"
 	"os"
+	"reflect"
+	"time"
 
+	"github.com/spacemeshos/go-spacemesh/common/types"
 	"github.com/spf13/cobra"
 	"github.com/spf13/viper"
+	"golang.org/x/exp/slog"
 
 	"github.com/spacemeshos/smcli/common"
+	"github.com/spacemeshos/smcli/node"
+	"github.com/spacemeshos/smcli/wallet"
 )
 
-var cfgFile string
+// Environment variables that set the endpoint and the token if --endpoint and --token aren't set.
+const (
+	envEndpoint = "SMCLI_ENDPOINT"
+	envToken    = "SMCLI_TOKEN"
+)
+
+// envPassphrase is the environment variable that sets the BIP-39 passphrase instead of prompting for it, unless
+// --passphrase-file is set.
+const envPassphrase = "SMCLI_PASSPHRASE"
+
+// envWalletPassword is the environment variable that sets the wallet password instead of prompting for it. It's
+// insecure, for CI only: other processes of the same user can read the environment.
+const envWalletPassword = "SMCLI_WALLET_PASSWORD"
+
+// envDefaultAccounts is the environment variable that sets the number of accounts of a new wallet if none is
+// given, overriding the config file's default-accounts.
+const envDefaultAccounts = "SMCLI_DEFAULT_ACCOUNTS"
+
+// Config file keys. The config file sets the default endpoint, and can list named endpoints, either as an
+// address or with the settings of the connection, e.g.:
+//
+//	endpoint: testnet
+//	endpoints:
+//	  local:
+//	    address: localhost:9092
+//	    insecure: true
+//	  testnet:
+//	    address: testnet-node.example.com:443
+//	    ca-cert: /etc/ssl/testnet-ca.pem
+//	    token: secret
+//	  lan:
+//	    address: 192.168.1.10:9092
+//	    insecure: true
+//
+// It can also set the number of accounts of a new wallet if none is given, e.g., default-accounts: 1.
+const (
+	configEndpoint        = "endpoint"
+	configEndpoints       = "endpoints"
+	configDefaultAccounts = "default-accounts"
+)
+
+var (
+	cfgFile string
+
+	// network is the name of the network that addresses are rendered for.
+	network string
+
+	// hrp is the human-readable network identifier used in Spacemesh network addresses. It's resolved from
+	// the network unless set explicitly.
+	hrp string
+
+	// genesisIDHex is the hex-encoded genesis ID of the target network, and genesisID its parsed value.
+	genesisIDHex string
+	genesisID    types.Hash20
+
+	// allowGenesisMismatch allows using a wallet with a network other than the one it was created for.
+	allowGenesisMismatch bool
+
+	// endpoint is the address of the gRPC API of the node used for network operations, or the name of an
+	// endpoint listed in the config file. nodeEndpoint is resolved from it, envEndpoint, or the config file.
+	endpoint     string
+	nodeEndpoint node.Endpoint
+
+	// insecureNode, caCertFile, and token override the connection settings of the endpoint.
+	insecureNode bool
+	caCertFile   string
+	token        string
+
+	// timeout is the timeout of a single request to the node.
+	timeout time.Duration
+
+	// retries and retryBackoff set how read-only requests to the node are retried if it's unavailable.
+	retries      int
+	retryBackoff time.Duration
+
+	// verbose and logLevel set the level of the messages logged to stderr. Secrets are never logged.
+	verbose  bool
+	logLevel string
+	// debug is the deprecated old name of verbose, which hides it from the help.
+	debug bool
+
+	// quiet hides the progress of long operations, which is otherwise drawn on stderr if it's a terminal.
+	quiet bool
+)
 
 // rootCmd represents the base command when called without any subcommands.
 var rootCmd = &cobra.Command{
@@ -24,6 +114,23 @@ This tool provides an ergonomic set of tools for managing a wallet.`,
 	// Run: func(cmd *cobra.Command, args []string) {
 	// 	fmt.Println("Hello world!")
 	// },
+	PersistentPreRunE: func(cmd *cobra.Command, args []string) (err error) {
+		if err = setupLogging(); err != nil {
+			return
+		}
+		setupProgress()
+		if hrp, err = common.ResolveHRP(network, hrp); err != nil {
+			return
+		}
+		types.SetNetworkHRP(hrp)
+		if err = resolveEndpoint(cmd); err != nil {
+			return
+		}
+		if genesisIDHex != "" {
+			genesisID, err = wallet.ParseGenesisID(genesisIDHex)
+		}
+		return
+	},
 }
 
 // Execute adds all child commands to the root command and sets flags appropriately.
@@ -31,7 +138,7 @@ This tool provides an ergonomic set of tools for managing a wallet.`,
 func Execute() {
 	err := rootCmd.Execute()
 	if err != nil {
-		os.Exit(1)
+		os.Exit(exitCode(err))
 	}
 }
 
@@ -42,7 +149,39 @@ func init() {
 	// Cobra supports persistent flags, which, if defined here,
 	// will be common for your application.
 
-	rootCmd.PersistentFlags().StringVar(&cfgFile, "config", "", "config file (default is $HOME/.smcli.yaml)")
+	rootCmd.PersistentFlags().StringVar(&cfgFile, "config", "", "config file (default is $HOME/.spacemesh/config.yaml)")
+	rootCmd.PersistentFlags().StringVar(&network, "network", common.NetworkMainnet,
+		"Network used to render and parse addresses (mainnet or testnet)")
+	rootCmd.PersistentFlags().StringVar(&hrp, "hrp", "",
+		"Set human-readable address prefix (overrides --network)")
+	rootCmd.PersistentFlags().StringVar(&genesisIDHex, "genesis-id", "",
+		"Hex-encoded genesis ID of the target network")
+	rootCmd.PersistentFlags().BoolVar(&allowGenesisMismatch, "allow-genesis-mismatch", false,
+		"Allow using a wallet with a network other than the one it was created for")
+	rootCmd.PersistentFlags().StringVar(&endpoint, "endpoint", "",
+		"Address of the gRPC API of the node used for network operations (host:port), or the name of an endpoint "+
+			"listed in the config file (default $"+envEndpoint+", else the config file's endpoint, else "+
+			node.DefaultEndpoint+")")
+	rootCmd.PersistentFlags().BoolVar(&insecureNode, "insecure", false,
+		"Connect to the node without TLS, e.g., to a local node that doesn't serve it (any token is sent in plaintext)")
+	rootCmd.PersistentFlags().StringVar(&caCertFile, "ca-cert", "",
+		"PEM file of the CA certificates used to verify the node's TLS certificate (default the system's)")
+	rootCmd.PersistentFlags().StringVar(&token, "token", "",
+		"Token sent to the node in the authorization header of each request (default $"+envToken+")")
+	rootCmd.PersistentFlags().DurationVar(&timeout, "timeout", node.DefaultTimeout,
+		"Timeout of a single request to the node")
+	rootCmd.PersistentFlags().IntVar(&retries, "retries", node.DefaultRetries,
+		"Number of times a read-only request is retried if the node is unavailable (transactions are never resent)")
+	rootCmd.PersistentFlags().DurationVar(&retryBackoff, "retry-backoff", node.DefaultRetryBackoff,
+		"Delay before retrying a request to the node, doubled after every retry")
+	rootCmd.PersistentFlags().BoolVarP(&verbose, "verbose", "v", false,
+		"Log debug messages to stderr, the same as --log-level debug")
+	rootCmd.PersistentFlags().BoolVarP(&debug, "debug", "d", false, "Log debug messages, the same as --verbose")
+	checkErr(rootCmd.PersistentFlags().MarkDeprecated("debug", "use --verbose instead"))
+	rootCmd.PersistentFlags().StringVar(&logLevel, "log-level", common.LogLevelWarn,
+		"Level of the messages logged to stderr: debug, info, warn, or error")
+	rootCmd.PersistentFlags().BoolVarP(&quiet, "quiet", "q", false,
+		"Don't show the progress of long operations, such as deriving many accounts")
 
 	// Cobra also supports local flags, which will only run
 	// when this action is called directly.
@@ -62,9 +201,90 @@ func initConfig() {
 		viper.SetConfigName(common.ConfigFileName())
 	}
 
-	viper.AutomaticEnv() // read in environment variables that match
+	viper.SetEnvPrefix("smcli")
+	viper.AutomaticEnv() // read in environment variables that match, e.g., SMCLI_ENDPOINT
 	// If a config file is found, read it in.
 	if err := viper.ReadInConfig(); err == nil {
 		fmt.Fprintln(os.Stderr, "Using config file:", viper.ConfigFileUsed())
 	}
 }
+
+// setupLogging sets the level of the logger from --log-level, or to debug if --verbose or --debug is set.
+func setupLogging() error {
+	level, err := common.ParseLogLevel(logLevel)
+	if err != nil {
+		return err
+	}
+	if verbose || debug {
+		level = slog.LevelDebug
+	}
+	common.SetLogger(common.NewLogger(os.Stderr, level))
+	return nil
+}
+
+// setupProgress draws the progress of long operations, such as deriving accounts or the wallet key, on stderr
+// unless --quiet is set, stderr isn't a terminal, or the output is JSON.
+func setupProgress() {
+	common.SetProgress(common.NewProgressMeter(os.Stderr, quiet || outputFormat == outputJSON))
+}
+
+// resolveEndpoint sets nodeEndpoint from --endpoint, envEndpoint, or the config file, and applies the connection
+// settings set using flags or envToken.
+func resolveEndpoint(cmd *cobra.Command) error {
+	named := map[string]node.Endpoint{}
+	err := viper.UnmarshalKey(configEndpoints, &named, viper.DecodeHook(
+		// an endpoint given as a string is only an address
+		func(from, to reflect.Type, data interface{}) (interface{}, error) {
+			if from.Kind() == reflect.String && to == reflect.TypeOf(node.Endpoint{}) {
+				return node.Endpoint{Address: data.(string)}, nil
+			}
+			return data, nil
+		}))
+	if err != nil {
+		return fmt.Errorf("invalid %s in config file: %w", configEndpoints, err)
+	}
+	nodeEndpoint, err = node.ResolveEndpoint(endpoint, os.Getenv(envEndpoint), viper.GetString(configEndpoint),
+		named)
+	if err != nil {
+		return err
+	}
+	if cmd.Flags().Changed("insecure") {
+		nodeEndpoint.Insecure = &insecureNode
+	}
+	if caCertFile != "" {
+		nodeEndpoint.CACert = caCertFile
+	}
+	if token != "" {
+		nodeEndpoint.Token = token
+	} else if envValue := os.Getenv(envToken); envValue != "" {
+		nodeEndpoint.Token = envValue
+	}
+	return nil
+}
+
+// newNodeClient returns a client for the node at --endpoint.
+func newNodeClient() *node.Client {
+	opts, err := nodeEndpoint.ClientOpts()
+	checkErr(err)
+	opts = append(opts, node.WithTimeout(timeout), node.WithRetries(retries, retryBackoff), node.WithHRP(hrp))
+	client, err := node.NewClient(nodeEndpoint.Address, opts...)
+	checkErr(err)
+	return client
+}
+
+// checkWalletNetwork refuses to continue if the wallet was created for a network other than the target network,
+// unless --allow-genesis-mismatch is set. It must be called before any operation that talks to a network. The
+// target network is the one set using --genesis-id, which must match the node's, or else the node's.
+func checkWalletNetwork(ctx context.Context, w *wallet.Wallet, client *node.Client) {
+	nodeGenesisID, err := client.GenesisID(ctx)
+	checkErr(err)
+	common.Logger().Debug("checking wallet network", "endpoint", client.Endpoint(),
+		"nodeGenesisID", fmt.Sprintf("%x", nodeGenesisID[:]), "walletGenesisID", w.Meta.GenesisID)
+	if genesisID == (types.Hash20{}) {
+		genesisID = nodeGenesisID
+	} else if genesisID != nodeGenesisID {
+		fatalf(common.ErrBadInput, "the node at %s is on a different network (genesis ID %x) than --genesis-id\n",
+			client.Endpoint(), nodeGenesisID[:])
+	}
+	checkErr(w.CheckGenesisID(genesisID, allowGenesisMismatch))
+}
@@ -0,0 +1,160 @@
+package cmd
+
+import (
+	"encoding/hex"
+	"fmt"
+	"os"
+
+	"github.com/jedib0t/go-pretty/v6/table"
+	"github.com/spf13/cobra"
+
+	"github.com/spacemeshos/smcli/common"
+	"github.com/spacemeshos/smcli/wallet"
+)
+
+// rotateTo, if set, is the wallet file that rotate sweeps the funds to instead of a new one.
+var rotateTo string
+
+// rotateCmd moves the funds of a wallet to a new one.
+var rotateCmd = &cobra.Command{
+	Use: "rotate [wallet file] [--to wallet file | --file path [--force] | --as name] [--gas-price n] [--words n] " +
+		"[--kdf name] [--no-verify] [--qr]",
+	Short: "Move all funds of a wallet to a new wallet with a fresh mnemonic",
+	Long: `Rotate to a new mnemonic, e.g., if the mnemonic of a wallet may have been compromised: create a new wallet,
+then move the balance of every account of the old wallet to the account with the same index in the new one.
+
+The new wallet has as many accounts as the old one and a freshly generated mnemonic, which is shown and checked
+like for create (add --no-verify to skip the check, --qr to also draw it as a QR code, and --words to choose its
+length). It has no BIP-39 passphrase. It's saved, like by create, before anything is sent: to a new file in
+~/.spacemesh unless --file or --as is given. Add --to instead to sweep to an existing wallet, e.g., one created
+using create with a passphrase or a Ledger device, or to finish an earlier rotation.
+
+The node set using --endpoint is asked for the balance of each account, counting pending transactions, and its
+next nonce. Each funded account sends its whole balance, less the maximum fee, in a single spend at --gas-price,
+spawning itself first if it hasn't been spawned. Accounts whose balance doesn't cover the fee are left as they
+are. A summary of the transfers is printed and has to be confirmed before they're signed and broadcast. Since
+the actual fee can be lower than the maximum, a little dust may be left behind. The old wallet can't be a Ledger
+wallet yet, since the Ledger transport of this version of smcli can only read public keys and not sign.`,
+	Args: cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		if rotateTo != "" && (newWalletFile != "" || newWalletName != "") {
+			fatalln(common.ErrBadInput, "--to can't be combined with --file or --as")
+		}
+		if rotateTo == "" {
+			checkNewWalletFile()
+		}
+
+		w, _ := openWallet(walletFile(args[0]))
+		defer w.Wipe()
+		checkCanSign(w)
+		client := newNodeClient()
+		defer client.Close()
+		checkWalletNetwork(cmd.Context(), w, client)
+
+		var to *wallet.Wallet
+		if rotateTo != "" {
+			to, _ = openWallet(walletFile(rotateTo))
+			checkErr(to.CheckGenesisID(genesisID, allowGenesisMismatch))
+		} else {
+			pubkeys, err := w.PublicKeys(hrp)
+			checkErr(err)
+			n := 0
+			for _, pubkey := range pubkeys {
+				if int(pubkey.Index) >= n {
+					n = int(pubkey.Index) + 1
+				}
+			}
+			to, err = wallet.NewMultiWalletRandomMnemonic(
+				n,
+				wallet.WithWordCount(mnemonicWords),
+				wallet.WithGenesisID(genesisID),
+				wallet.WithDisplayName(w.Meta.DisplayName),
+				wallet.WithAccountsLater(true),
+			)
+			checkErr(err)
+			showNewMnemonic(to.Mnemonic())
+			saveNewWallet(to)
+		}
+		defer to.Wipe()
+
+		plan, err := wallet.PlanSweep(client.Checker(cmd.Context()), w, to, gasPrice)
+		exitIfNodeUnavailable(client, err, "check that it's running and that --endpoint is correct")
+		checkErr(err)
+		printSweepPlan(plan)
+		if len(plan.Transfers) == 0 {
+			fmt.Println("Nothing to send.")
+			return
+		}
+		if !confirmed("Sign and broadcast these transactions? [y/N]: ") {
+			fatalln(common.ErrBadInput, "Not sent")
+		}
+
+		// sign everything first, so that nothing is sent if signing fails
+		var signed [][]byte
+		for _, t := range plan.Transfers {
+			for _, tx := range t.Txns {
+				s, _, err := w.SignOfflineTxn(&wallet.OfflineTxn{GenesisID: genesisID, Unsigned: tx},
+					wallet.WithLedgerDevice(ledgerDevice))
+				checkErr(err)
+				signed = append(signed, s)
+			}
+		}
+		for _, tx := range signed {
+			id, err := client.BroadcastTransaction(cmd.Context(), tx)
+			if err != nil {
+				fatalf(err, "Error broadcasting a transaction, run rotate again with --to to "+
+					"send the rest once its status is known (see tx status): %v\n", err)
+			}
+			fmt.Printf("Transaction submitted, ID: %s\n", hex.EncodeToString(id[:]))
+		}
+	},
+}
+
+// printSweepPlan prints the transfers of a sweep, and the accounts it leaves behind.
+func printSweepPlan(plan *wallet.SweepPlan) {
+	t := table.NewWriter()
+	t.SetOutputMirror(os.Stdout)
+	t.AppendHeader(table.Row{"index", "from", "to", "amount", "max fee", "transactions"})
+	for _, tr := range plan.Transfers {
+		txns := "spend"
+		if len(tr.Txns) > 1 {
+			txns = "spawn, spend"
+		}
+		t.AppendRow(table.Row{
+			tr.Index, wallet.AddressString(tr.From, hrp), wallet.AddressString(tr.To, hrp),
+			formatAmount(tr.Amount), fmt.Sprintf("%d smidge", tr.Fee), txns,
+		})
+	}
+	for _, tr := range plan.Dust {
+		t.AppendRow(table.Row{
+			tr.Index, wallet.AddressString(tr.From, hrp), "left behind", formatAmount(tr.Balance),
+			fmt.Sprintf("%d smidge", tr.Fee), "none, the balance doesn't cover the fee",
+		})
+	}
+	amount, fee := plan.Total()
+	t.AppendFooter(table.Row{"", "", "total", formatAmount(amount), fmt.Sprintf("%d smidge", fee), ""})
+	t.Render()
+}
+
+func init() {
+	walletCmd.AddCommand(rotateCmd)
+	rotateCmd.Flags().StringVar(&rotateTo, "to", "", "Existing wallet file to send the funds to instead of a new one")
+	rotateCmd.Flags().Uint64Var(&gasPrice, "gas-price", wallet.DefaultGasPrice, "Gas price in smidge per unit of gas")
+	rotateCmd.Flags().IntVar(&mnemonicWords, "words", wallet.DefaultMnemonicWords,
+		"Number of words in the new mnemonic (12, 15, 18, 21, or 24)")
+	rotateCmd.Flags().BoolVar(&noVerify, "no-verify", false,
+		"Don't ask for words of the new mnemonic to check that it was written down")
+	rotateCmd.Flags().BoolVar(&printQR, "qr", false, "Also draw the new mnemonic as a QR code")
+	rotateCmd.Flags().StringVar(&kdf, "kdf", wallet.KDFPbkdf2,
+		"Key derivation function used to encrypt the new wallet file (PBKDF2, scrypt, or argon2id)")
+	rotateCmd.Flags().BoolVar(&allowWeakPassword, "allow-weak-password", false,
+		"Skip the password strength check (not recommended)")
+	rotateCmd.Flags().StringVar(&ledgerDevice, "ledger-device", "",
+		"ID of the Ledger device to use if more than one is connected (see ledger-devices)")
+	rotateCmd.Flags().StringVar(&newWalletFile, "file", "", "Path of the new wallet file (default a new file in "+
+		common.DotDirectory()+")")
+	rotateCmd.Flags().BoolVar(&forceOverwrite, "force", false,
+		"Replace the new wallet file if it already exists, after backing it up")
+	rotateCmd.Flags().StringVar(&newWalletName, "as", "",
+		"Save the new wallet in "+common.WalletsDirectory()+" under this short name instead of --file")
+}
@@ -0,0 +1,649 @@
+package cmd
+
+import (
+	"bytes"
+	"context"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"math"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/jedib0t/go-pretty/v6/table"
+	"github.com/spacemeshos/go-spacemesh/common/types"
+	"github.com/spacemeshos/go-spacemesh/genvm/core"
+	"github.com/spacemeshos/go-spacemesh/genvm/templates/multisig"
+	"github.com/spf13/cobra"
+
+	"github.com/spacemeshos/smcli/common"
+	"github.com/spacemeshos/smcli/node"
+	"github.com/spacemeshos/smcli/wallet"
+)
+
+var (
+	// gasPrice is the gas price of a transaction, in smidge per unit of gas.
+	gasPrice uint64
+
+	// txFrom, txTo, txAmount, and txNonce describe the spend built by tx build. txAmount is in SMH or smidge,
+	// see wallet.ParseAmount.
+	txFrom   string
+	txTo     string
+	txAmount string
+	txNonce  uint64
+	// txSpawn is the spawn mode of tx build, one of the wallet.Spawn constants, and txPubkey the hex-encoded
+	// public key of the sending account, needed to spawn it.
+	txSpawn  string
+	txPubkey string
+
+	// txMultisig is the label of the multisig account, stored in the wallet file, that sends the transaction
+	// signed by tx sign or aggregated by tx aggregate, see wallet multisig.
+	txMultisig string
+
+	// watchTx makes tx status poll the node until the transaction is final, every watchInterval.
+	watchTx       bool
+	watchInterval time.Duration
+)
+
+// txCmd represents the tx command.
+var txCmd = &cobra.Command{
+	Use:   "tx",
+	Short: "Transaction-related utilities",
+}
+
+// broadcastTxCmd submits a signed transaction to a node.
+var broadcastTxCmd = &cobra.Command{
+	Use:   "broadcast [transaction file]",
+	Short: "Submit a signed transaction to a node",
+	Long: `Submit a signed transaction to the node set using --endpoint, which adds it to its mempool and
+gossips it to the network. The file contains the transaction either hex-encoded or as raw bytes. If the node
+rejects the transaction its reason is printed as is.`,
+	Args: cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		tx, err := readTxFile(args[0])
+		checkErr(err)
+
+		client := newNodeClient()
+		defer client.Close()
+		id, err := client.BroadcastTransaction(cmd.Context(), tx)
+		checkErr(err)
+		fmt.Printf("Transaction submitted, ID: %s\n", hex.EncodeToString(id[:]))
+	},
+}
+
+// estimateFeeCmd prints the maximum fee of a spend transaction.
+var estimateFeeCmd = &cobra.Command{
+	Use:   "estimate-fee [unsigned transaction file] [--gas-price n]",
+	Short: "Estimate the fee of a spend transaction",
+	Long: `Print the maximum fee of a single-sig spend transaction at the gas price set using --gas-price.
+The fee depends on the size of the transaction: if no unsigned transaction file is given, the estimate is for
+the largest possible spend. The node API doesn't currently recommend a gas price.`,
+	Args: cobra.MaximumNArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		var unsigned []byte
+		var err error
+		if len(args) > 0 {
+			unsigned, err = readTxFile(args[0])
+		} else {
+			unsigned, err = wallet.GenerateTxnData(wallet.TxnData{
+				Principal: types.Address{1},
+				Recipient: types.Address{1},
+				Amount:    math.MaxUint64,
+				Nonce:     math.MaxUint64 - 1,
+				GasPrice:  math.MaxUint64,
+			})
+		}
+		checkErr(err)
+		est, err := wallet.EstimateTxnFee(unsigned, gasPrice)
+		checkErr(err)
+		fmt.Printf("max gas: %d\n", est.Gas)
+		fmt.Printf("estimated fee: %d smidge\n", est.Fee)
+	},
+}
+
+// decodeTxCmd prints the contents of a transaction.
+var decodeTxCmd = &cobra.Command{
+	Use:   "decode [transaction file]",
+	Short: "Print what a transaction does",
+	Long: `Decode a signed or unsigned transaction and print its principal, method, and arguments, e.g.,
+to review a transaction produced elsewhere before signing or submitting it. The file contains the transaction
+either hex-encoded or as raw bytes. Spend transactions don't include the template of the principal.`,
+	Args: cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		tx, err := readTxFile(args[0])
+		checkErr(err)
+		d, err := wallet.DecodeTransaction(tx)
+		checkErr(err)
+
+		t := table.NewWriter()
+		t.SetOutputMirror(os.Stdout)
+		t.AppendRow(table.Row{"principal", wallet.AddressString(d.Principal, hrp)})
+		t.AppendRow(table.Row{"method", fmt.Sprintf("%s (%d)", d.MethodName, d.Method)})
+		if d.Template != nil {
+			name := d.TemplateName
+			if name == "" {
+				name = "unknown"
+			}
+			t.AppendRow(table.Row{"template", fmt.Sprintf("%s (%s)", name, wallet.AddressString(*d.Template, hrp))})
+		}
+		t.AppendRow(table.Row{"nonce", d.Nonce})
+		t.AppendRow(table.Row{"gas price", d.GasPrice})
+		switch d.MethodName {
+		case wallet.MethodNameSpawn:
+			t.AppendRow(table.Row{"self spawn", d.SelfSpawn})
+			if d.VaultArgs != nil {
+				t.AppendRow(table.Row{"owner", wallet.AddressString(d.VaultArgs.Owner, hrp)})
+				t.AppendRow(table.Row{"total amount", formatAmount(d.VaultArgs.TotalAmount)})
+				t.AppendRow(table.Row{"initial unlock", formatAmount(d.VaultArgs.InitialUnlockAmount)})
+				t.AppendRow(table.Row{"vesting start", d.VaultArgs.VestingStart})
+				t.AppendRow(table.Row{"vesting end", d.VaultArgs.VestingEnd})
+			} else {
+				t.AppendRow(table.Row{"required signatures", d.Required})
+				for i, pubkey := range d.PublicKeys {
+					t.AppendRow(table.Row{fmt.Sprintf("public key %d", i), hex.EncodeToString(pubkey[:])})
+				}
+			}
+		case wallet.MethodNameDrainVault:
+			t.AppendRow(table.Row{"vault", wallet.AddressString(d.Vault, hrp)})
+			fallthrough
+		case wallet.MethodNameSpend:
+			t.AppendRow(table.Row{"recipient", wallet.AddressString(d.Recipient, hrp)})
+			t.AppendRow(table.Row{"amount", formatAmount(d.Amount)})
+		}
+		switch {
+		case len(d.Signature) > 0:
+			t.AppendRow(table.Row{"signature", hex.EncodeToString(d.Signature)})
+		case len(d.Parts) > 0:
+			for _, part := range d.Parts {
+				t.AppendRow(table.Row{fmt.Sprintf("signature %d", part.Ref), hex.EncodeToString(part.Sig[:])})
+			}
+		default:
+			t.AppendRow(table.Row{"signature", "none (unsigned)"})
+		}
+		t.Render()
+	},
+}
+
+// buildTxCmd writes an unsigned spend transaction to a file, to be signed offline, preceded by the spawn of the
+// sending account if needed.
+var buildTxCmd = &cobra.Command{
+	Use: "build [wallet file, with --pick] [unsigned transaction file] --from address|--pick --to address " +
+		"--amount amount [--spawn auto|only|never] [--pubkey key] [--nonce n] [--gas-price n]",
+	Short: "Build an unsigned spend transaction to sign on another machine",
+	Long: `Build an unsigned transaction that spends --amount from the single-sig wallet account --from to --to,
+and write it to a new file along with the genesis ID of the network. The amount is written in SMH or smidge,
+e.g., "1.5 SMH" or "1500000000 smidge"; a number alone is in smidge. Unless --genesis-id is set, the
+genesis ID is read from the node set using --endpoint. Take the file to the machine that holds the wallet, e.g.,
+one that's kept offline, and sign it there using tx sign; then submit the signed transaction using tx broadcast.
+
+The recipient must be a valid address on the network set using --network or --hrp, with a valid checksum. If it's
+the zero address, whose funds can never be spent, or --from itself, a warning is printed and the spend is only
+built once confirmed.
+
+A new account must spawn itself before it can spend. By default (--spawn auto) the node is asked whether --from
+has been spawned, and for its next nonce (counting its pending transactions). If it hasn't been spawned, a
+transaction that spawns it is written first, to a second file named after the first with -spawn added (e.g.,
+tx-spawn.json for tx.json): sign and broadcast it before the spend. Spawning needs the public key of the
+account, set using --pubkey (see wallet pubkeys). Use --spawn only to build the spawn alone, or --spawn never to
+build the spend alone. Setting --nonce implies --spawn never; set both --genesis-id and --nonce, or use --spawn
+only with --genesis-id, to build offline.
+
+A spend sent by a multisig account is built the same way once the multisig account has been spawned, with its
+address as --from; its participants co-sign it using tx sign --multisig.
+
+Add --pick and a wallet file before the transaction file instead of --from to choose the sending account from a
+menu of the accounts of the wallet, as for wallet sign-message; its public key is then used as --pubkey.`,
+	Args: func(cmd *cobra.Command, args []string) error {
+		if pickSigner {
+			return cobra.ExactArgs(2)(cmd, args)
+		}
+		return cobra.ExactArgs(1)(cmd, args)
+	},
+	Run: func(cmd *cobra.Command, args []string) {
+		switch {
+		case pickSigner && cmd.Flags().Changed("from"):
+			fatalln(common.ErrBadInput, "Set either --from or --pick, not both")
+		case pickSigner:
+			w, _ := openWallet(walletFile(args[0]))
+			acct := pickAccount(w)
+			w.Wipe()
+			txFrom = acct.Address
+			if txPubkey == "" {
+				txPubkey = acct.Hex
+			}
+		case txFrom == "":
+			fatalln(common.ErrBadInput, "Set --from to the address of the sending account, or --pick to choose it")
+		}
+		fn := args[len(args)-1]
+		from, err := wallet.ValidateAddress(txFrom, hrp)
+		checkErr(err)
+		var pubkey core.PublicKey
+		if txPubkey != "" {
+			key, err := hex.DecodeString(strings.TrimPrefix(txPubkey, "0x"))
+			if err != nil || len(key) != len(pubkey) {
+				fatalf(common.ErrBadInput, "Invalid public key %q, must be %d hex-encoded bytes\n", txPubkey, len(pubkey))
+			}
+			copy(pubkey[:], key)
+		}
+		mode := txSpawn
+		if cmd.Flags().Changed("nonce") {
+			if cmd.Flags().Changed("spawn") && mode == wallet.SpawnAuto {
+				fatalln(common.ErrBadInput, "--nonce can't be used with --spawn auto, the nonce is read from the node")
+			}
+			if mode == wallet.SpawnAuto {
+				mode = wallet.SpawnNever
+			}
+		}
+		data := wallet.TxnData{Principal: from, Nonce: txNonce, GasPrice: gasPrice}
+		switch mode {
+		case wallet.SpawnOnly:
+			if pubkey == (core.PublicKey{}) {
+				fatalln(common.ErrBadInput, "Set --pubkey to the public key of the account to spawn it")
+			}
+		case wallet.SpawnAuto, wallet.SpawnNever:
+			if txTo == "" || txAmount == "" {
+				fatalln(common.ErrBadInput, "Set --to and --amount to the recipient and the amount to send")
+			}
+			data.Recipient, err = wallet.ValidateAddress(txTo, hrp)
+			checkErr(err)
+			if warnRecipient(from, data.Recipient) && !confirmed("Build the spend anyway? [y/N]: ") {
+				fatalln(common.ErrBadInput, "Not built")
+			}
+			data.Amount, err = wallet.ParseAmount(txAmount)
+			checkErr(err)
+		default:
+			fatalf(common.ErrBadInput, "Invalid --spawn %q, must be %s, %s, or %s\n",
+				mode, wallet.SpawnAuto, wallet.SpawnOnly, wallet.SpawnNever)
+		}
+
+		id := genesisID
+		var client *node.Client
+		var checker wallet.SpawnChecker
+		hint := "set --genesis-id and --nonce to build offline"
+		fetchNonce := mode == wallet.SpawnNever && !cmd.Flags().Changed("nonce")
+		if id == (types.Hash20{}) || mode == wallet.SpawnAuto || fetchNonce {
+			client = newNodeClient()
+			defer client.Close()
+			if id == (types.Hash20{}) {
+				id, err = client.GenesisID(cmd.Context())
+				exitIfNodeUnavailable(client, err, hint)
+				checkErr(err)
+			}
+			if fetchNonce {
+				data.Nonce, err = client.NextNonce(cmd.Context(), from)
+				exitIfNodeUnavailable(client, err, hint)
+				if errors.Is(err, node.ErrNotSpawned) {
+					fatalf(common.ErrBadInput, "%s hasn't been spawned yet, so it can't send funds: its first transaction must "+
+						"spawn it. Use --spawn auto to spawn it first, or set --nonce to build the spend anyway.\n",
+						txFrom)
+				}
+				checkErr(err)
+				fmt.Printf("Using nonce %d, the next nonce of %s according to %s.\n", data.Nonce, txFrom,
+					client.Endpoint())
+			}
+			checker = client.Checker(cmd.Context())
+		}
+		txs, err := wallet.TransferTxns(checker, data, pubkey, mode)
+		exitIfNodeUnavailable(client, err, hint)
+		if errors.Is(err, wallet.ErrPublicKeyNeeded) {
+			fatalf(common.ErrBadInput, "%s hasn't been spawned yet, so its first transaction must spawn it: set "+
+				"--pubkey to its public key to build the spawn (see wallet pubkeys), or --spawn never to build the "+
+				"spend anyway.\n", txFrom)
+		}
+		checkErr(err)
+
+		files := []string{fn}
+		if len(txs) > 1 {
+			ext := filepath.Ext(fn)
+			files = []string{strings.TrimSuffix(fn, ext) + "-spawn" + ext, fn}
+		}
+		// either all the files are written or none are: the files created so far are removed if one fails
+		outs := make([]*os.File, 0, len(files))
+		removeOuts := func(err error) {
+			for _, out := range outs {
+				out.Close()
+				os.Remove(out.Name())
+			}
+			checkErr(err)
+		}
+		for _, fn := range files {
+			out, err := os.OpenFile(fn, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0o644)
+			if err != nil {
+				removeOuts(err)
+			}
+			outs = append(outs, out)
+		}
+		txns := make([]*wallet.OfflineTxn, len(txs))
+		for i, tx := range txs {
+			txns[i] = &wallet.OfflineTxn{GenesisID: id, Unsigned: tx}
+			if err := txns[i].Export(outs[i]); err != nil {
+				removeOuts(err)
+			}
+		}
+		for _, out := range outs {
+			if err := out.Close(); err != nil {
+				removeOuts(err)
+			}
+		}
+		for i, txn := range txns {
+			printOfflineTxn(txn)
+			fmt.Printf("Unsigned transaction saved to %s.\n", files[i])
+		}
+		if len(txs) > 1 {
+			fmt.Printf("%s hasn't been spawned yet: sign and broadcast %s before %s.\n", txFrom, files[0], files[1])
+		}
+	},
+}
+
+// signTxCmd signs a transaction built by tx build.
+var signTxCmd = &cobra.Command{
+	Use:   "sign [wallet file] [unsigned transaction file] [signed transaction file] [--pick] [--multisig label]",
+	Short: "Sign a transaction built using tx build",
+	Long: `Sign an unsigned transaction built using tx build with the account of the wallet file that sends it.
+This doesn't need network access. What the transaction does is printed first and has to be confirmed. The
+signed transaction is written hex-encoded to a new file, to be submitted using tx broadcast. Ledger wallets
+can't sign yet: the Ledger transport of this version of smcli can only read public keys.
+
+To co-sign a spend sent by a multisig account stored in the wallet file (see wallet multisig), set --multisig
+to its label. The account of the wallet that takes part in it signs, and its partial signature is written
+hex-encoded to the file instead. Once enough participants have signed, as required by the threshold of the
+multisig account, combine their partial signatures using tx aggregate.
+
+Add --pick to choose the signing account from a menu of the accounts of the wallet, as for wallet
+sign-message, instead of using the one that sends the transaction; it's only signed if they're the same.`,
+	Args: cobra.ExactArgs(3),
+	Run: func(cmd *cobra.Command, args []string) {
+		f, err := os.Open(args[1])
+		checkErr(err)
+		txn, err := wallet.ReadOfflineTxn(f)
+		f.Close()
+		checkErr(err)
+
+		w, _ := openWallet(walletFile(args[0]))
+		defer w.Wipe()
+		if txMultisig != "" {
+			if pickSigner {
+				fatalln(common.ErrBadInput, "--pick can't be used with --multisig, the account that takes part in the "+
+					"multisig account signs")
+			}
+			signMultiSigTxn(w, txn, args[2])
+			return
+		}
+		checkCanSign(w)
+		checkErr(w.CheckGenesisID(txn.GenesisID, allowGenesisMismatch))
+		d, err := txn.Decode()
+		checkErr(err)
+		principals, err := w.Principals()
+		checkErr(err)
+		found := false
+		for _, principal := range principals {
+			found = found || principal == d.Principal
+		}
+		if !found {
+			fatalf(common.ErrBadInput, "No account in %s sends this transaction, its principal is %s\n",
+				args[0], wallet.AddressString(d.Principal, hrp))
+		}
+		if pickSigner {
+			if acct := pickAccount(w); acct.Address != wallet.AddressString(d.Principal, hrp) {
+				fatalf(common.ErrBadInput, "Account %d (%s) doesn't send this transaction, its principal is %s\n",
+					acct.Index, acct.Address, wallet.AddressString(d.Principal, hrp))
+			}
+		}
+		printOfflineTxn(txn)
+		if d.Method != core.MethodSpawn {
+			warnRecipient(d.Principal, d.Recipient)
+		}
+		if !confirmed("Sign this transaction? [y/N]: ") {
+			fatalln(common.ErrBadInput, "Not signed")
+		}
+
+		signed, index, err := w.SignOfflineTxn(txn, wallet.WithLedgerDevice(ledgerDevice))
+		checkErr(err)
+		out, err := os.OpenFile(args[2], os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0o644)
+		checkErr(err)
+		defer out.Close()
+		_, err = fmt.Fprintln(out, hex.EncodeToString(signed))
+		checkErr(err)
+		fmt.Printf("Signed using account %d, saved to %s.\n", index, args[2])
+	},
+}
+
+// signMultiSigTxn co-signs an offline transaction sent by the multisig account of the wallet labeled txMultisig,
+// and writes the partial signature to the file named fn.
+func signMultiSigTxn(w *wallet.Wallet, txn *wallet.OfflineTxn, fn string) {
+	if w.IsLedger() {
+		fatalln(common.ErrBadInput, "Ledger wallets can't co-sign multisig spends yet")
+	}
+	checkErr(w.CheckGenesisID(txn.GenesisID, allowGenesisMismatch))
+	_, spawn, err := w.MultiSig(txMultisig)
+	checkErr(err)
+	d, err := txn.Decode()
+	checkErr(err)
+	printOfflineTxn(txn)
+	warnRecipient(d.Principal, d.Recipient)
+	if !confirmed(fmt.Sprintf("Co-sign this transaction for %s (%d-of-%d)? [y/N]: ",
+		txMultisig, spawn.Required, len(spawn.PublicKeys))) {
+		fatalln(common.ErrBadInput, "Not signed")
+	}
+
+	part, kp, err := w.SignMultiSigTxn(txMultisig, txn)
+	checkErr(err)
+	out, err := os.OpenFile(fn, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0o644)
+	checkErr(err)
+	defer out.Close()
+	_, err = fmt.Fprintln(out, wallet.EncodeMultiSigPart(part))
+	checkErr(err)
+	fmt.Printf("Signed as participant %d using %s, partial signature saved to %s.\n", part.Ref,
+		hex.EncodeToString(kp.Public), fn)
+	fmt.Printf("Collect %d partial signatures and combine them using tx aggregate.\n", spawn.Required)
+}
+
+// aggregateTxCmd combines the partial signatures of the participants of a multisig account.
+var aggregateTxCmd = &cobra.Command{
+	Use: "aggregate [wallet file] [unsigned transaction file] [signed transaction file] [partial signature file]... " +
+		"--multisig label",
+	Short: "Combine the partial signatures of a multisig spend",
+	Long: `Combine the partial signatures written by tx sign --multisig into a signed transaction, sent by the
+multisig account stored in the wallet file under --multisig (see wallet multisig). As many partial signatures
+as required by its threshold are needed, each from a different participant; they're checked against the
+transaction and ordered as the multisig template expects. This doesn't need network access or the keys of the
+participants. The signed transaction is written hex-encoded to a new file, to be submitted using tx broadcast.`,
+	Args: cobra.MinimumNArgs(4),
+	Run: func(cmd *cobra.Command, args []string) {
+		f, err := os.Open(args[1])
+		checkErr(err)
+		txn, err := wallet.ReadOfflineTxn(f)
+		f.Close()
+		checkErr(err)
+
+		parts := make([]multisig.Part, 0, len(args)-3)
+		for _, fn := range args[3:] {
+			data, err := os.ReadFile(fn)
+			checkErr(err)
+			part, err := wallet.ParseMultiSigPart(string(data))
+			if err != nil {
+				fatalf(common.ErrBadInput, "Error reading %s: %v\n", fn, err)
+			}
+			parts = append(parts, part)
+		}
+
+		w, _ := openWallet(walletFile(args[0]))
+		defer w.Wipe()
+		checkErr(w.CheckGenesisID(txn.GenesisID, allowGenesisMismatch))
+		signed, err := w.AggregateMultiSigTxn(txMultisig, txn, parts)
+		checkErr(err)
+		out, err := os.OpenFile(args[2], os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0o644)
+		checkErr(err)
+		defer out.Close()
+		_, err = fmt.Fprintln(out, hex.EncodeToString(signed))
+		checkErr(err)
+		fmt.Printf("Combined %d partial signatures, saved to %s.\n", len(parts), args[2])
+	},
+}
+
+// warnRecipient prints a warning if the recipient of a spend sent by principal is probably a mistake, see
+// wallet.CheckRecipient, and reports whether it did.
+func warnRecipient(principal, recipient core.Address) bool {
+	err := wallet.CheckRecipient(principal, recipient)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: %v: %s\n", err, wallet.AddressString(recipient, hrp))
+	}
+	return err != nil
+}
+
+// confirmed asks a yes or no question and reports whether the answer was yes.
+func confirmed(question string) bool {
+	fmt.Print(question)
+	answer, err := readInput()
+	fmt.Println()
+	checkErr(err)
+	answer = strings.ToLower(strings.TrimSpace(answer))
+	return answer == "y" || answer == "yes"
+}
+
+// formatAmount writes an amount of smidge in SMH, followed by the exact number of smidge so that it can be
+// checked before signing.
+func formatAmount(smidge uint64) string {
+	return fmt.Sprintf("%s (%d smidge)", wallet.FormatAmount(smidge), smidge)
+}
+
+// exitIfNodeUnavailable exits with an explanation if err is due to the node being unreachable, adding hint. client
+// may be nil if no node was queried.
+func exitIfNodeUnavailable(client *node.Client, err error, hint string) {
+	if errors.Is(err, node.ErrNodeUnavailable) {
+		fatalf(common.ErrNetwork, "Can't reach the node at %s, %s: %v\n", client.Endpoint(), hint, err)
+	}
+}
+
+// printOfflineTxn prints what an offline transaction (a spend or a self spawn) does, and its maximum fee.
+func printOfflineTxn(txn *wallet.OfflineTxn) {
+	d, err := txn.Decode()
+	checkErr(err)
+	est, err := wallet.EstimateTxnFee(txn.Unsigned, d.GasPrice)
+	checkErr(err)
+	t := table.NewWriter()
+	t.SetOutputMirror(os.Stdout)
+	t.AppendRows([]table.Row{
+		{"genesis id", hex.EncodeToString(txn.GenesisID[:])},
+		{"method", d.MethodName},
+		{"principal", wallet.AddressString(d.Principal, hrp)},
+	})
+	if d.Method == core.MethodSpawn {
+		t.AppendRow(table.Row{"public key", hex.EncodeToString(d.PublicKeys[0][:])})
+	} else {
+		t.AppendRow(table.Row{"recipient", wallet.AddressString(d.Recipient, hrp)})
+		t.AppendRow(table.Row{"amount", formatAmount(d.Amount)})
+	}
+	t.AppendRows([]table.Row{
+		{"nonce", d.Nonce},
+		{"gas price", d.GasPrice},
+		{"max fee", fmt.Sprintf("%d smidge", est.Fee)},
+	})
+	t.Render()
+}
+
+// txStatusCmd prints the state of a transaction.
+var txStatusCmd = &cobra.Command{
+	Use:   "status [transaction ID] [--watch]",
+	Short: "Print whether a transaction is pending, included, applied, or rejected",
+	Long: `Query the node set using --endpoint for the state of a transaction, given its hex-encoded ID as printed
+by broadcast. A transaction is pending while it's in the mempool, then included in a block, then applied or
+failed once it's executed in a layer (a failed transaction still pays its fee). The node may also reject it,
+e.g., if it conflicts with another transaction. Add --watch to poll the node every --interval and print each
+change, until the transaction is applied, failed, or rejected, or until you press Ctrl-C.`,
+	Args: cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		idBytes, err := hex.DecodeString(strings.TrimPrefix(args[0], "0x"))
+		var id types.TransactionID
+		if err != nil || len(idBytes) != len(id) {
+			fatalf(common.ErrBadInput, "Invalid transaction ID %q, must be %d hex-encoded bytes\n", args[0], len(id))
+		}
+		copy(id[:], idBytes)
+		if watchTx && watchInterval <= 0 {
+			fatalln(common.ErrBadInput, "Interval must be positive")
+		}
+
+		client := newNodeClient()
+		defer client.Close()
+		var st *node.TxStatus
+		if watchTx {
+			// stop watching on Ctrl-C, leaving the transaction as it is
+			ctx, stop := signal.NotifyContext(cmd.Context(), os.Interrupt)
+			defer stop()
+			st, err = client.WatchTransaction(ctx, id, watchInterval, func(st *node.TxStatus) {
+				fmt.Printf("%s Transaction %s\n", time.Now().Format("15:04:05"), st)
+			})
+			if errors.Is(err, context.Canceled) {
+				fmt.Println("Stopped watching the transaction.")
+				return
+			}
+		} else {
+			st, err = client.TransactionStatus(cmd.Context(), id)
+		}
+		if errors.Is(err, node.ErrNodeUnavailable) {
+			fatalf(common.ErrNetwork,
+				"Can't reach the node at %s, check that it's running and that --endpoint is correct: %v\n",
+				client.Endpoint(), err)
+		}
+		checkErr(err)
+		if !watchTx {
+			fmt.Printf("Transaction %s\n", st)
+		}
+	},
+}
+
+// readTxFile reads a transaction that's either hex-encoded or raw bytes, or an unsigned transaction written by
+// tx build.
+func readTxFile(fn string) ([]byte, error) {
+	data, err := os.ReadFile(fn)
+	if err != nil {
+		return nil, err
+	}
+	if txn, err := wallet.ReadOfflineTxn(bytes.NewReader(data)); err == nil {
+		return txn.Unsigned, nil
+	}
+	if decoded, err := hex.DecodeString(string(bytes.TrimSpace(data))); err == nil {
+		return decoded, nil
+	}
+	return data, nil
+}
+
+func init() {
+	rootCmd.AddCommand(txCmd)
+	txCmd.AddCommand(broadcastTxCmd)
+	txCmd.AddCommand(estimateFeeCmd)
+	txCmd.AddCommand(decodeTxCmd)
+	txCmd.AddCommand(txStatusCmd)
+	txCmd.AddCommand(buildTxCmd)
+	txCmd.AddCommand(signTxCmd)
+	txCmd.AddCommand(aggregateTxCmd)
+	estimateFeeCmd.Flags().Uint64Var(&gasPrice, "gas-price", wallet.DefaultGasPrice,
+		"Gas price in smidge per unit of gas")
+	buildTxCmd.Flags().StringVar(&txFrom, "from", "", "Address of the sending account")
+	buildTxCmd.Flags().StringVar(&txTo, "to", "", "Address of the recipient")
+	buildTxCmd.Flags().StringVar(&txAmount, "amount", "", `Amount to send, e.g., "1.5 SMH" or "1500 smidge"`)
+	buildTxCmd.Flags().Uint64Var(&txNonce, "nonce", 0, "Next nonce of the sending account (default read from the node)")
+	buildTxCmd.Flags().Uint64Var(&gasPrice, "gas-price", wallet.DefaultGasPrice,
+		"Gas price in smidge per unit of gas")
+	buildTxCmd.Flags().StringVar(&txSpawn, "spawn", wallet.SpawnAuto,
+		"Whether to spawn the sending account: auto (if needed), only (without the spend), or never")
+	buildTxCmd.Flags().StringVar(&txPubkey, "pubkey", "", "Hex-encoded public key of the sending account, to spawn it")
+	buildTxCmd.Flags().BoolVar(&pickSigner, "pick", false,
+		"Choose the sending account from a menu of the accounts of a wallet file instead of setting --from")
+	signTxCmd.Flags().StringVar(&ledgerDevice, "ledger-device", "",
+		"ID of the Ledger device to use if more than one is connected (see ledger-devices)")
+	signTxCmd.Flags().BoolVar(&pickSigner, "pick", false,
+		"Choose the signing account from a menu, which must be the account that sends the transaction")
+	signTxCmd.Flags().StringVar(&txMultisig, "multisig", "",
+		"Label of the multisig account that sends the transaction, to co-sign it (see wallet multisig)")
+	aggregateTxCmd.Flags().StringVar(&txMultisig, "multisig", "",
+		"Label of the multisig account that sends the transaction (see wallet multisig)")
+	checkErr(aggregateTxCmd.MarkFlagRequired("multisig"))
+	txStatusCmd.Flags().BoolVarP(&watchTx, "watch", "w", false,
+		"Poll the node until the transaction is applied, failed, or rejected")
+	txStatusCmd.Flags().DurationVar(&watchInterval, "interval", 10*time.Second,
+		"Time between polls when using --watch")
+}
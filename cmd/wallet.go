@@ -1,28 +1,31 @@
 package cmd
 
 import (
+	"bytes"
+	"context"
+	"crypto/ed25519"
+	"encoding/base64"
 	"encoding/hex"
+	"encoding/json"
 	"errors"
 	"fmt"
-	"log"
+	"io"
 	"os"
 	"strconv"
 	"strings"
+	"time"
 
 	"github.com/btcsuite/btcutil/base58"
-	"github.com/hashicorp/go-secure-stdlib/password"
 	"github.com/jedib0t/go-pretty/v6/table"
-	"github.com/spacemeshos/go-spacemesh/common/types"
 	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+	"golang.org/x/term"
 
 	"github.com/spacemeshos/smcli/common"
 	"github.com/spacemeshos/smcli/wallet"
 )
 
 var (
-	// debug indicates that the program is in debug mode.
-	debug bool
-
 	// printPrivate indicates that private keys should be printed.
 	printPrivate bool
 
@@ -35,11 +38,113 @@ var (
 	// printParent indicates that the parent key should be printed.
 	printParent bool
 
+	// infoDecrypt makes wallet info decrypt an encrypted wallet to count its accounts.
+	infoDecrypt bool
+
 	// useLedger indicates that the Ledger device should be used.
 	useLedger bool
 
-	// hrp is the human-readable network identifier used in Spacemesh network addresses.
-	hrp string
+	// ledgerDevice is the ID of the Ledger device to use when more than one is connected.
+	ledgerDevice string
+
+	// mnemonicWords is the number of words in a newly generated mnemonic.
+	mnemonicWords int
+
+	// mnemonicFile is a file to read the mnemonic from instead of prompting for it, or "-" for stdin.
+	mnemonicFile string
+
+	// passphraseFile is a file to read the BIP-39 passphrase from instead of prompting for it.
+	passphraseFile string
+
+	// mnemonicLanguage selects the BIP-39 wordlist used to generate or check a mnemonic.
+	mnemonicLanguage string
+
+	// kdf is the key derivation function used to encrypt a new wallet file.
+	kdf string
+
+	// dryRun indicates that a new wallet should only be derived and its addresses printed, without writing it.
+	dryRun bool
+
+	// rollsKind, if set, is the kind of rolls (dice or coin) used to generate a new mnemonic by hand.
+	rollsKind string
+
+	// scanAccounts indicates that a new wallet should contain the accounts found in use on the network.
+	scanAccounts bool
+
+	// allowWeakPassword disables the password strength check for new wallet passwords.
+	allowWeakPassword bool
+
+	// argon2Time, argon2Memory, and argon2Threads override the default argon2id cost parameters.
+	argon2Time    uint32
+	argon2Memory  uint32
+	argon2Threads uint8
+
+	// walletName is the display name of a new wallet.
+	walletName string
+
+	// importKeyFile, if set, is the key file that import-key saves the key to instead of a wallet file.
+	importKeyFile string
+
+	// accountsLater allows creating a wallet without any accounts.
+	accountsLater bool
+
+	// pbkdf2Iterations is the PBKDF2 iteration count used to encrypt a new wallet file.
+	pbkdf2Iterations int
+
+	// rekeyIterations, if set, is the minimum PBKDF2 iteration count of wallet files: files using fewer are
+	// re-encrypted when they're opened.
+	rekeyIterations int
+
+	// gapLimit is the number of accounts past the stored ones that find-account derives, or the number of
+	// consecutive unused accounts after which create --scan stops.
+	gapLimit int
+
+	// signatureEncoding is the encoding of message signatures, hex or base64.
+	signatureEncoding string
+
+	// understandSeedRisk confirms that the user understands the risk of exporting the seed of a wallet.
+	understandSeedRisk bool
+
+	// addressStart, addressCount, addressFormat, and addressPubkeys select the addresses written by
+	// export-addresses.
+	addressStart   int
+	addressCount   int
+	addressFormat  string
+	addressPubkeys bool
+
+	// shareThreshold and shareCount configure a Shamir backup of the mnemonic.
+	shareThreshold int
+	shareCount     int
+
+	// noVerify skips asking for words of a newly generated mnemonic to make sure that it was written down.
+	noVerify bool
+
+	// qrAccounts, if set, are the indices of the accounts for which read draws QR codes (default all).
+	qrAccounts []int
+
+	// encryptHardware encrypts the file of a new Ledger wallet, which is otherwise saved without a password.
+	encryptHardware bool
+
+	// newWalletFile, if set, is the path of a new wallet file instead of one in the wallet directory, and
+	// forceOverwrite allows replacing an existing file there (after backing it up).
+	newWalletFile  string
+	forceOverwrite bool
+
+	// noStoreMnemonic saves a new wallet without its mnemonic, only with the keys of its accounts.
+	noStoreMnemonic bool
+
+	// seedOnly restores a new wallet from its BIP-39 seed rather than its mnemonic.
+	seedOnly bool
+
+	// pickSigner chooses the signing account from a menu rather than by index.
+	pickSigner bool
+
+	// pathTemplate, if set, is the HD path template of the accounts of a new wallet.
+	pathTemplate string
+
+	// newWalletName, if set, is the short name under which a new wallet file is saved in the managed wallet
+	// directory instead (see walletFile).
+	newWalletName string
 )
 
 // walletCmd represents the wallet command.
@@ -56,22 +161,120 @@ to quickly create a Cobra application.`,
 
 // createCmd represents the create command.
 var createCmd = &cobra.Command{
-	Use:   "create [--ledger] [numaccounts]",
+	Use:   "create [numaccounts]",
 	Short: "Generate a new wallet file from a BIP-39-compatible mnemonic or Ledger device",
-	Long: `Create a new wallet file containing one or more accounts using a BIP-39-compatible mnemonic
+	Long: fmt.Sprintf(`Create a new wallet file containing one or more accounts using a BIP-39-compatible mnemonic
 or a Ledger hardware wallet. If using a mnemonic you can choose to use an existing mnemonic or generate
-a new, random mnemonic.
+a new, random mnemonic. Add --words to choose the length of a newly generated mnemonic (default 24).
+Add --language to generate or import a mnemonic using a wordlist other than English.
+
+Add --rolls dice or --rolls coin to generate the new mnemonic from dice rolls or coin flips that you enter,
+rather than using the system's random number generator. Use a real, fair die or coin: you'll be asked for
+enough rolls to provide the full entropy of the mnemonic (100 dice rolls or 256 coin flips for 24 words).
+
+Once a newly generated mnemonic has been shown, the screen is cleared and you're asked to enter a few of its
+words, picked at random, to make sure that you wrote it down correctly. Add --no-verify to skip this, e.g., for
+scripting. Add --qr to also draw a newly generated mnemonic as a QR code, e.g., to back it up to a trusted
+device. Anyone who sees the QR code can restore the wallet.
+
+Add --mnemonic-file to import the mnemonic from a file rather than typing it, e.g., for scripting. The file must
+contain only the mnemonic, optionally followed by a newline. Use --mnemonic-file - to read it from the first line
+of stdin; the remaining prompts then read the following lines.
+
+The optional BIP-39 passphrase is prompted for too, unless it's read from the file given using --passphrase-file
+(which may end with a newline) or from $SMCLI_PASSPHRASE, for automated restores. It's never accepted on the
+command line, where other users could see it in the process list. The other commands that derive keys from the
+mnemonic, such as add-accounts, accept the same sources.
 
 Add --ledger to instead read the public key from a Ledger device. If using a Ledger device please make
-sure the device is connected, unlocked, and the Spacemesh app is open.`,
+sure the device is connected, unlocked, and the Spacemesh app is open. This version of smcli can't check which app
+is open or its version yet, since the SDK it uses can't read them: a warning is printed instead, and a wrong or
+outdated app is only noticed if reading the public key fails. The private keys stay on the device, so
+the wallet file only contains public keys and isn't encrypted: it opens without a password. Signing using the
+device isn't supported yet by this version of smcli. Add --encrypt to encrypt it anyway, to keep its addresses private.
+
+Add --kdf scrypt or --kdf argon2id to encrypt the wallet file using scrypt or argon2id rather than
+the default PBKDF2. The PBKDF2 iteration count can be raised using --pbkdf2-iterations. The argon2id cost
+can be tuned with --argon2-time, --argon2-memory, and --argon2-threads.
+
+Add --dry-run to only print the addresses that an existing mnemonic (or Ledger device) produces, without
+encrypting or writing anything, e.g., to check that you're importing the right mnemonic.
+
+The wallet contains one account unless numaccounts is given, or a different default is set using
+$SMCLI_DEFAULT_ACCOUNTS or default-accounts in the config file (at most %d). To create a wallet without any
+accounts, e.g., to add them later using add-accounts, pass 0 and --accounts-later.
+
+Add --scan instead of numaccounts to restore as many accounts as an existing mnemonic (or Ledger device) was
+used with. The node set using --endpoint is queried for each account in turn until --gap-limit consecutive
+accounts (default 20) have never been used, and the wallet contains the accounts up to the last used one. If
+none were used it contains one account, or none with --accounts-later.
+
+Add --seed-only to restore a wallet from its hex-encoded 64-byte BIP-39 seed, e.g., as printed by export-seed,
+if you only have the seed and not the mnemonic. The seed already includes the BIP-39 passphrase, so none is asked
+for. The mnemonic can't be recovered from the seed: the wallet file stores the seed instead, so accounts can still
+be added, but the wallet can't be exported to Smapp or split into mnemonic shares.
+
+Add --no-store-mnemonic to keep the mnemonic out of the wallet file, which then only holds the keys of the
+accounts created now. They can still sign, but no more accounts can be added and the seed can't be exported:
+the mnemonic, which you must keep backed up, is then the only way to restore the wallet or to add accounts.
+
+Add --path-template to derive the accounts at other HD paths than the default m/44'/540'/0'/0'/{index}', e.g.,
+m/44'/540'/{index}' to restore the accounts of another wallet application. The path must start with m/44'/540',
+the only purpose and coin type that keys can be derived for, and end with {index}', the account index, every
+segment must be hardened, and it can have at most five segments.
+The template is stored in the wallet file and used for accounts added later too. Ledger wallets always use the
+default.
+
+Add --name to give the wallet a display name other than "Main Wallet". It can be changed later using rename.
+
+The wallet file is written to a new file in ~/.spacemesh unless --file is given. An existing file is never
+overwritten unless --force is added, which first renames it to a backup (the file name followed by .bak- and the
+time).
+
+Add --as to save the wallet in the managed wallet directory, ~/.spacemesh/wallets, under a short name instead.
+Other commands then accept the short name in place of the path of the wallet file; see wallet list.
+
+Add --genesis-id to record the network the wallet is for. Network operations then refuse to use the wallet with
+any other network unless --allow-genesis-mismatch is given.
+
+The wallet password must be at least 12 characters long and mix letters, digits, and symbols, or be a
+passphrase of at least 20 characters. Add --allow-weak-password to skip this check. It's prompted for unless
+$SMCLI_WALLET_PASSWORD is set, which is insecure and only meant for CI; the other commands that open wallet
+files read it too.`, common.MaxAccountsPerWallet),
 	Args: cobra.MaximumNArgs(1),
 	Run: func(cmd *cobra.Command, args []string) {
+		if !dryRun {
+			checkNewWalletFile()
+		}
+		if noStoreMnemonic && useLedger {
+			fatalln(common.ErrBadInput,
+				"--no-store-mnemonic can't be used with --ledger, the mnemonic never leaves the device")
+		}
+		if pathTemplate != "" && useLedger {
+			fatalln(common.ErrBadInput, "--path-template can't be used with --ledger")
+		}
+		if seedOnly && (useLedger || mnemonicFile != "" || rollsKind != "") {
+			fatalln(common.ErrBadInput, "--seed-only can't be combined with --ledger, --mnemonic-file, or --rolls")
+		}
+
 		// get the number of accounts to create
-		n := 1
-		if len(args) > 0 {
-			tmpN, err := strconv.ParseInt(args[0], 10, 16)
-			cobra.CheckErr(err)
-			n = int(tmpN)
+		n := 0
+		if !scanAccounts {
+			var arg string
+			if len(args) > 0 {
+				arg = args[0]
+			}
+			var err error
+			n, err = wallet.ResolveAccountCount(arg, os.Getenv(envDefaultAccounts),
+				viper.GetString(configDefaultAccounts))
+			checkErr(err)
+		}
+		if scanAccounts {
+			if len(args) > 0 {
+				fatalln(common.ErrBadInput, "--scan can't be combined with a number of accounts")
+			}
+		} else if n == 0 && !accountsLater {
+			fatalln(common.ErrBadInput, "Creating a wallet without accounts requires --accounts-later")
 		}
 
 		var w *wallet.Wallet
@@ -79,97 +282,170 @@ sure the device is connected, unlocked, and the Spacemesh app is open.`,
 
 		// Short-circuit and check for a ledger device
 		if useLedger {
-			w, err = wallet.NewMultiWalletFromLedger(n)
-			cobra.CheckErr(err)
-			fmt.Println("Note that, when using a hardware wallet, the wallet file I'm about to produce won't " +
-				"contain any private keys or mnemonics, but you may still choose to encrypt it to protect privacy.")
+			w, err = wallet.NewMultiWalletFromLedger(
+				n,
+				wallet.WithLedgerDevice(ledgerDevice),
+				wallet.WithGenesisID(genesisID),
+				wallet.WithDisplayName(walletName),
+				wallet.WithAccountsLater(accountsLater || scanAccounts),
+			)
+			checkErr(err)
+			if encryptHardware {
+				fmt.Println("Note that, when using a hardware wallet, the wallet file I'm about to produce won't " +
+					"contain any private keys or mnemonics, but it's encrypted to protect your privacy.")
+			}
+		} else if seedOnly {
+			fmt.Print("Enter the hex-encoded BIP-39 seed: ")
+			text, err := readInput()
+			fmt.Println()
+			checkErr(err)
+			seed, err := wallet.ParseSeed(text)
+			checkErr(err)
+			w, err = wallet.NewMultiWalletFromSeed(
+				seed,
+				n,
+				wallet.WithGenesisID(genesisID),
+				wallet.WithDisplayName(walletName),
+				wallet.WithAccountsLater(accountsLater || scanAccounts),
+				wallet.WithPathTemplate(pathTemplate),
+			)
+			for i := range seed {
+				seed[i] = 0
+			}
+			checkErr(err)
 		} else {
 			// get or generate the mnemonic
-			fmt.Print("Enter a BIP-39-compatible mnemonic (or leave blank to generate a new one): ")
-			text, err := password.Read(os.Stdin)
-			fmt.Println()
-			cobra.CheckErr(err)
-			fmt.Println("Note: This application does not yet support BIP-39-compatible optional passwords. Support will be added soon.")
-
-			// It's critical that we trim whitespace, including CRLF. Otherwise it will get included in the mnemonic.
-			text = strings.TrimSpace(text)
-
-			if text == "" {
-				w, err = wallet.NewMultiWalletRandomMnemonic(n)
-				cobra.CheckErr(err)
-				fmt.Println("\nThis is your mnemonic (seed phrase). Write it down and store it safely. It is the ONLY way to restore your wallet.")
-				fmt.Println("Neither Spacemesh nor anyone else can help you restore your wallet without this mnemonic.")
-				fmt.Println("\n***********************************\nSAVE THIS MNEMONIC IN A SAFE PLACE!\n***********************************")
+			var text string
+			switch mnemonicFile {
+			case "":
+				fmt.Print("Enter a BIP-39-compatible mnemonic (or leave blank to generate a new one): ")
+				text, err = readInput()
 				fmt.Println()
-				fmt.Println(w.Mnemonic())
-				fmt.Println("\nPress enter when you have securely saved your mnemonic.")
-				_, _ = fmt.Scanln()
+				checkErr(err)
+
+				// It's critical that we trim whitespace, including CRLF. Otherwise it will get included in the mnemonic.
+				text = strings.TrimSpace(text)
+			case "-":
+				text, err = wallet.ReadMnemonic(os.Stdin, wallet.WithLanguage(mnemonicLanguage))
+				checkErr(err)
+			default:
+				if fi, err := os.Stat(mnemonicFile); err == nil && fi.Mode().Perm()&0o077 != 0 {
+					fmt.Fprintf(os.Stderr, "Warning: %s is readable by other users\n", mnemonicFile)
+				}
+				text, err = wallet.ReadMnemonicFile(mnemonicFile, wallet.WithLanguage(mnemonicLanguage))
+				checkErr(err)
+			}
+
+			// get the optional BIP-39 passphrase. note that we do NOT trim it: whitespace is significant here.
+			passphrase := readPassphrase("Enter an optional BIP-39 passphrase (or leave blank for none): ")
+			if passphrase != "" {
+				fmt.Println("Note: the passphrase is NOT stored in the wallet file. You will need both the mnemonic " +
+					"and the passphrase to restore this wallet.")
+			}
+
+			if text == "" && dryRun {
+				fatalln(common.ErrBadInput, "--dry-run requires an existing mnemonic")
+			}
+			if text == "" && scanAccounts {
+				fatalln(common.ErrBadInput, "--scan requires an existing mnemonic")
+			}
+			if text != "" && rollsKind != "" {
+				fatalln(common.ErrBadInput, "--rolls can only be used to generate a new mnemonic")
+			}
+			if text == "" && rollsKind != "" {
+				m := mnemonicFromRolls()
+				w, err = wallet.NewMultiWalletFromMnemonic(
+					m,
+					n,
+					wallet.WithPassphrase(passphrase),
+					wallet.WithLanguage(mnemonicLanguage),
+					wallet.WithGenesisID(genesisID),
+					wallet.WithDisplayName(walletName),
+					wallet.WithAccountsLater(accountsLater || scanAccounts),
+					wallet.WithPathTemplate(pathTemplate),
+				)
+				checkErr(err)
+				showNewMnemonic(m)
+			} else if text == "" {
+				w, err = wallet.NewMultiWalletRandomMnemonic(
+					n,
+					wallet.WithPassphrase(passphrase),
+					wallet.WithWordCount(mnemonicWords),
+					wallet.WithLanguage(mnemonicLanguage),
+					wallet.WithGenesisID(genesisID),
+					wallet.WithDisplayName(walletName),
+					wallet.WithAccountsLater(accountsLater || scanAccounts),
+					wallet.WithPathTemplate(pathTemplate),
+				)
+				checkErr(err)
+				showNewMnemonic(w.Mnemonic())
 			} else {
 				// try to use as a mnemonic
-				w, err = wallet.NewMultiWalletFromMnemonic(text, n)
-				cobra.CheckErr(err)
+				w, err = wallet.NewMultiWalletFromMnemonic(
+					text,
+					n,
+					wallet.WithPassphrase(passphrase),
+					wallet.WithLanguage(mnemonicLanguage),
+					wallet.WithGenesisID(genesisID),
+					wallet.WithDisplayName(walletName),
+					wallet.WithAccountsLater(accountsLater || scanAccounts),
+					wallet.WithPathTemplate(pathTemplate),
+				)
+				checkErr(err)
 			}
 		}
 
-		fmt.Print("Enter a secure password used to encrypt the wallet file (optional but strongly recommended): ")
-		password, err := password.Read(os.Stdin)
-		fmt.Println()
-		cobra.CheckErr(err)
-		wk := wallet.NewKey(wallet.WithRandomSalt(), wallet.WithPbkdf2Password([]byte(password)))
-		err = os.MkdirAll(common.DotDirectory(), 0o700)
-		cobra.CheckErr(err)
-
-		// Make sure we're not overwriting an existing wallet (this should not happen)
-		walletFn := common.WalletFile()
-		_, err = os.Stat(walletFn)
-		switch {
-		case errors.Is(err, os.ErrNotExist):
-			// all fine
-		case err == nil:
-			log.Fatalln("Wallet file already exists")
-		default:
-			log.Fatalf("Error opening %s: %v\n", walletFn, err)
+		defer w.Wipe()
+
+		if scanAccounts {
+			scanWalletAccounts(cmd.Context(), w)
 		}
 
-		// Now open for writing
-		f2, err := os.OpenFile(walletFn, os.O_WRONLY|os.O_CREATE, 0o600)
-		cobra.CheckErr(err)
-		defer f2.Close()
-		cobra.CheckErr(wk.Export(f2, w))
+		if w.Meta.GenesisID == "" {
+			fmt.Println("Note: no --genesis-id was given, so the wallet won't be bound to a network.")
+		}
 
-		fmt.Printf("Wallet saved to %s. BACK UP THIS FILE NOW!\n", walletFn)
+		if dryRun {
+			t := table.NewWriter()
+			t.SetOutputMirror(os.Stdout)
+			t.AppendHeader(table.Row{"index", "address"})
+			for i, address := range w.Addresses(hrp) {
+				t.AppendRow(table.Row{i, address})
+			}
+			t.Render()
+			fmt.Println("Dry run: no wallet file was written.")
+			return
+		}
+
+		if noStoreMnemonic {
+			w.ForgetMnemonic()
+			fmt.Println("The mnemonic won't be stored in the wallet file: keep your backup of it, it's needed to " +
+				"add accounts and to restore the wallet.")
+		}
+		saveNewWallet(w)
 	},
 }
 
 // readCmd reads an existing wallet file.
 var readCmd = &cobra.Command{
-	Use:   "read [wallet file] [--full/-f] [--private/-p] [--base58]",
+	Use:   "read [wallet file] [--full/-f] [--private/-p] [--base58] [--qr [--qr-account n]...]",
 	Short: "Reads an existing wallet file",
 	Long: `This command can be used to verify whether an existing wallet file can be
 successfully read and decrypted, whether the password to open the file is correct, etc.
 It prints the accounts from the wallet file. By default it does not print private keys.
 Add --private to print private keys. Add --full to print full keys. Add --base58 to print
 keys in base58 format rather than hexadecimal. Add --parent to print parent key (and not
-only child keys).`,
+only child keys). Add --qr to also draw the address of each account as a QR code, e.g., to
+scan a deposit address using a phone, and --qr-account to only draw those of some accounts
+(by index). Combined with --private, --qr also draws the mnemonic: only scan it into a device
+you trust.`,
 	Args: cobra.ExactArgs(1),
 	Run: func(cmd *cobra.Command, args []string) {
-		walletFn := args[0]
+		walletFn := walletFile(args[0])
 
 		// make sure the file exists
-		f, err := os.Open(walletFn)
-		cobra.CheckErr(err)
-		defer f.Close()
-
-		// get the password
-		fmt.Print("Enter wallet password: ")
-		password, err := password.Read(os.Stdin)
-		fmt.Println()
-		cobra.CheckErr(err)
-
-		// attempt to read it
-		wk := wallet.NewKey(wallet.WithPasswordOnly([]byte(password)))
-		w, err := wk.Open(f, debug)
-		cobra.CheckErr(err)
+		w, _ := openWallet(walletFn)
+		defer w.Wipe()
 
 		widthEnforcer := func(col string, maxLen int) string {
 			if len(col) <= maxLen {
@@ -185,8 +461,10 @@ only child keys).`,
 		t.SetOutputMirror(os.Stdout)
 		t.SetTitle("Wallet Contents")
 		caption := ""
-		if printPrivate {
+		if printPrivate && w.Mnemonic() != "" {
 			caption = fmt.Sprintf("Mnemonic: %s", w.Mnemonic())
+		} else if printPrivate {
+			caption = "Mnemonic: not stored in the wallet file"
 		}
 		if !printFull {
 			if printPrivate {
@@ -207,6 +485,7 @@ only child keys).`,
 				"privkey",
 				"path",
 				"name",
+				"label",
 				"created",
 			})
 			t.SetColumnConfigs([]table.ColumnConfig{
@@ -219,6 +498,7 @@ only child keys).`,
 				"pubkey",
 				"path",
 				"name",
+				"label",
 				"created",
 			})
 			t.SetColumnConfigs([]table.ColumnConfig{
@@ -250,6 +530,7 @@ only child keys).`,
 						privKeyEncoder(master.Private),
 						master.Path.String(),
 						master.DisplayName,
+						master.Label,
 						master.Created,
 					})
 				} else {
@@ -258,6 +539,7 @@ only child keys).`,
 						encoder(master.Public),
 						master.Path.String(),
 						master.DisplayName,
+						master.Label,
 						master.Created,
 					})
 				}
@@ -273,6 +555,7 @@ only child keys).`,
 					privKeyEncoder(a.Private),
 					a.Path.String(),
 					a.DisplayName,
+					a.Label,
 					a.Created,
 				})
 			} else {
@@ -281,23 +564,1605 @@ only child keys).`,
 					encoder(a.Public),
 					a.Path.String(),
 					a.DisplayName,
+					a.Label,
 					a.Created,
 				})
 			}
 		}
 		t.Render()
+
+		if printQR {
+			for _, i := range qrAccounts {
+				if i < 0 || i >= len(w.Secrets.Accounts) {
+					fatalf(common.ErrBadInput, "Invalid account index %d, the wallet has %d accounts\n", i, len(w.Secrets.Accounts))
+				}
+			}
+			for i, a := range w.Secrets.Accounts {
+				if len(qrAccounts) > 0 && !containsInt(qrAccounts, i) {
+					continue
+				}
+				address := wallet.PubkeyToAddress(a.Public, hrp)
+				printQRCode(fmt.Sprintf("Account %d: %s", i, address), address)
+			}
+			if printPrivate && w.Mnemonic() != "" {
+				printMnemonicQRCode(w.Mnemonic())
+			}
+		}
+	},
+}
+
+// containsInt reports whether v is one of values.
+func containsInt(values []int, v int) bool {
+	for _, value := range values {
+		if value == v {
+			return true
+		}
+	}
+	return false
+}
+
+// validateMnemonicCmd checks a mnemonic without creating a wallet.
+var validateMnemonicCmd = &cobra.Command{
+	Use:   "validate-mnemonic",
+	Short: "Check a BIP-39 mnemonic for typos and other problems",
+	Long: `Check a BIP-39 mnemonic without creating a wallet. Reports the number of words, any words
+that are not in the wordlist, whitespace problems, characters that aren't NFKD-normalized (such as non-breaking
+spaces), and whether the checksum is valid. Add --language
+to check against a wordlist other than English.`,
+	Args: cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		fmt.Print("Enter a BIP-39 mnemonic: ")
+		text, err := readInput()
+		fmt.Println()
+		checkErr(err)
+		// only strip the line ending; any other whitespace is reported
+		text = strings.TrimRight(text, "\r\n")
+
+		d, err := wallet.DiagnoseMnemonic(text, wallet.WithLanguage(mnemonicLanguage))
+		checkErr(err)
+		fmt.Printf("Word count: %d", d.WordCount)
+		if !d.ValidWordCount {
+			fmt.Print(" (invalid, must be 12, 15, 18, 21, or 24)")
+		}
+		fmt.Println()
+		if len(d.UnknownWords) > 0 {
+			fmt.Printf("Not in wordlist: %s\n", strings.Join(d.UnknownWords, ", "))
+		}
+		if d.WhitespaceViolation {
+			fmt.Println("Whitespace: words must be separated by single spaces (or ideographic spaces for Japanese), " +
+				"without leading or trailing spaces")
+		}
+		if d.NotNormalized {
+			fmt.Println("Normalization: not NFKD-normalized, e.g., contains non-breaking spaces or composed accents; " +
+				"smcli normalizes it, but other wallets may derive a different seed")
+		}
+		switch {
+		case !d.ValidWordCount || len(d.UnknownWords) > 0:
+			fmt.Println("Checksum: not checked, fix the words first")
+		case d.ValidChecksum:
+			fmt.Println("Checksum: OK")
+		default:
+			fmt.Println("Checksum: bad, all words are valid but one is wrong or they are out of order")
+		}
+		if !d.Valid() {
+			fatalln(common.ErrBadInput, "Mnemonic is invalid")
+		}
+		fmt.Println("Mnemonic is valid.")
+	},
+}
+
+// pubkeysCmd prints the public keys of the accounts in a wallet file.
+var pubkeysCmd = &cobra.Command{
+	Use:   "pubkeys [wallet file] [--output table|json]",
+	Short: "Print the public keys and addresses of the accounts in a wallet file",
+	Long: `Print the raw ed25519 public key of each account in a wallet file, in hex and base64,
+along with its address. Private keys are never printed.
+
+Add --output json to print a JSON array instead, with one object per account of the form
+{"index": 0, "path": "m/44'/540'/0'/0'/0'", "pubkeyHex": "...", "address": "sm1..."}, plus
+"label" for labeled accounts. The password prompt is then written to stderr.`,
+	Args: cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		checkOutputFormat()
+		w, _ := openWallet(walletFile(args[0]))
+		defer w.Wipe()
+
+		if outputFormat == outputJSON {
+			checkErr(w.WriteAccountsJSON(os.Stdout, hrp))
+			return
+		}
+		keys, err := w.PublicKeys(hrp)
+		checkErr(err)
+		t := table.NewWriter()
+		t.SetOutputMirror(os.Stdout)
+		t.AppendHeader(table.Row{"index", "pubkey (hex)", "pubkey (base64)", "address", "label"})
+		for _, key := range keys {
+			t.AppendRow(table.Row{key.Index, key.Hex, key.Base64, key.Address, key.Label})
+		}
+		t.Render()
+	},
+}
+
+// walletInfoCmd prints the metadata of a wallet file without decrypting it.
+var walletInfoCmd = &cobra.Command{
+	Use:   "info [wallet file] [--decrypt] [--output table|json]",
+	Short: "Print the name, creation time, and other metadata of a wallet file",
+	Long: `Print what can be read from a wallet file without its password, to identify it at a glance: its type,
+display name, creation time and age, genesis ID, format version, and for encrypted wallets and key files the
+cipher and key derivation function used to encrypt it. Hardware and watch-only wallet files also list the
+number of accounts. The accounts of an encrypted wallet are encrypted too: add --decrypt to enter the
+password and also count them.
+
+Add --output json to print a JSON object instead, with the fields "type", "version", "displayName",
+"created" (an RFC 3339 timestamp), "genesisID", "accounts", "device", "cipher", "kdf", and "kdfParams",
+leaving out those that don't apply to the file.`,
+	Args: cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		checkOutputFormat()
+		walletFn := walletFile(args[0])
+		data, err := os.ReadFile(walletFn)
+		checkErr(err)
+		info, err := wallet.ReadWalletInfo(bytes.NewReader(data))
+		if err != nil {
+			fatalf(common.ErrBadInput, "Error reading %s: %v\n", walletFn, err)
+		}
+		if infoDecrypt && info.Type == wallet.EncryptedType {
+			w, _ := openWallet(walletFn)
+			n := len(w.Secrets.Accounts)
+			w.Wipe()
+			info.Accounts = &n
+		}
+
+		if outputFormat == outputJSON {
+			enc := json.NewEncoder(os.Stdout)
+			enc.SetIndent("", "  ")
+			checkErr(enc.Encode(info))
+			return
+		}
+		accounts := "unknown (encrypted, use --decrypt)"
+		if info.Accounts != nil {
+			accounts = strconv.Itoa(*info.Accounts)
+		}
+		genesisID := info.GenesisID
+		if genesisID == "" {
+			genesisID = "(not set)"
+		}
+		t := table.NewWriter()
+		t.SetOutputMirror(os.Stdout)
+		t.SetTitle("Wallet Info")
+		t.AppendRow(table.Row{"type", info.Type})
+		if info.Device != "" {
+			t.AppendRow(table.Row{"device", info.Device})
+		}
+		if info.Type != wallet.KeyFileType {
+			t.AppendRow(table.Row{"display name", info.DisplayName})
+		}
+		created := info.Created
+		if at, err := common.ParseTimeString(info.Created); err == nil {
+			created += " (" + common.FormatAge(time.Since(at)) + ")"
+		}
+		t.AppendRow(table.Row{"created", created})
+		if info.Type != wallet.KeyFileType {
+			t.AppendRow(table.Row{"genesis ID", genesisID})
+		}
+		t.AppendRow(table.Row{"version", info.Version})
+		t.AppendRow(table.Row{"accounts", accounts})
+		if info.Cipher != "" {
+			t.AppendRow(table.Row{"cipher", info.Cipher})
+			kdf := info.KDF
+			if info.KDFParams != "" {
+				kdf += " (" + info.KDFParams + ")"
+			}
+			t.AppendRow(table.Row{"kdf", kdf})
+		}
+		t.Render()
+	},
+}
+
+// balanceCmd prints the balance and nonce of every account in a wallet file.
+var balanceCmd = &cobra.Command{
+	Use:   "balance [wallet file]",
+	Short: "Print the balance and nonce of each account in a wallet file",
+	Long: `Query a node for the current balance and nonce of each account in a wallet file. The
+node is set using --endpoint. Balances are denominated in SMH; the projected balance and nonce include
+pending transactions.`,
+	Args: cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		w, _ := openWallet(walletFile(args[0]))
+		defer w.Wipe()
+
+		principals, err := w.Principals()
+		checkErr(err)
+
+		client := newNodeClient()
+		defer client.Close()
+		checkWalletNetwork(cmd.Context(), w, client)
+
+		t := table.NewWriter()
+		t.SetOutputMirror(os.Stdout)
+		t.AppendHeader(table.Row{"index", "address", "balance", "nonce", "projected balance", "projected nonce"})
+		for i, addr := range principals {
+			state, err := client.QueryAccount(cmd.Context(), addr)
+			checkErr(err)
+			t.AppendRow(table.Row{
+				i, wallet.AddressString(addr, hrp),
+				wallet.FormatAmount(state.Balance), state.Nonce, wallet.FormatAmount(state.ProjectedBalance),
+				state.ProjectedNonce,
+			})
+		}
+		t.Render()
+	},
+}
+
+// exportWatchOnlyCmd writes the public part of a wallet file to a separate, unencrypted file.
+var exportWatchOnlyCmd = &cobra.Command{
+	Use:   "export-watch-only [wallet file] [output file]",
+	Short: "Export the public keys of a wallet file for watch-only use",
+	Long: `Write the public keys, derivation paths, and metadata of the accounts in a wallet file to a separate,
+unencrypted JSON file. The output can be used to enumerate addresses and monitor balances but contains no
+mnemonic or private keys, so it can't be used to sign transactions. Accounts added to the wallet later are not
+included: export again after adding accounts.`,
+	Args: cobra.ExactArgs(2),
+	Run: func(cmd *cobra.Command, args []string) {
+		w, _ := openWallet(walletFile(args[0]))
+		defer w.Wipe()
+
+		wo := w.WatchOnly()
+		out, err := os.OpenFile(args[1], os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0o644)
+		checkErr(err)
+		err = wo.Export(out)
+		if closeErr := out.Close(); err == nil {
+			err = closeErr
+		}
+		if err != nil {
+			os.Remove(args[1])
+		}
+		checkErr(err)
+
+		t := table.NewWriter()
+		t.SetOutputMirror(os.Stdout)
+		t.AppendHeader(table.Row{"index", "address"})
+		for i, address := range wo.Addresses(hrp) {
+			t.AppendRow(table.Row{i, address})
+		}
+		t.Render()
+		fmt.Printf("Watch-only wallet saved to %s.\n", args[1])
+	},
+}
+
+// exportAddressesCmd writes a list of addresses of a wallet file to a separate file.
+var exportAddressesCmd = &cobra.Command{
+	Use:   "export-addresses [wallet file] [output file] [--start n] [--count k] [--format csv|jsonl] [--pubkeys]",
+	Short: "Write a list of addresses of a wallet file to a CSV or JSONL file",
+	Long: fmt.Sprintf(`Derive --count accounts of a wallet file starting at account index --start, and write their
+indices and addresses (and public keys with --pubkeys) to a new file, in order, either as CSV with a header
+line or as JSON lines, e.g., to hand out deposit addresses. The accounts aren't added to the wallet file and
+private keys aren't written anywhere; add the accounts using add-accounts before spending from them. Indices
+are limited to the %d accounts a wallet file can contain. If the wallet was created with a BIP-39 passphrase
+you'll need to enter it again.`, common.MaxAccountsPerWallet),
+	Args: cobra.ExactArgs(2),
+	Run: func(cmd *cobra.Command, args []string) {
+		w, _ := openWallet(walletFile(args[0]))
+		defer w.Wipe()
+		opts := []wallet.WalletOpt{wallet.WithLedgerDevice(ledgerDevice)}
+		if needsPassphrase(w) {
+			opts = append(opts, wallet.WithPassphrase(readPassphrase(existingPassphrasePrompt)))
+		}
+
+		out, err := os.OpenFile(args[1], os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0o644)
+		checkErr(err)
+		err = w.WriteAddresses(out, addressStart, addressCount, hrp, strings.ToLower(addressFormat),
+			addressPubkeys, opts...)
+		if closeErr := out.Close(); err == nil {
+			err = closeErr
+		}
+		if err != nil {
+			os.Remove(args[1])
+		}
+		checkErr(err)
+		fmt.Printf("Wrote %d address(es) to %s.\n", addressCount, args[1])
+	},
+}
+
+// exportSeedCmd prints the BIP39 seed of a wallet file.
+var exportSeedCmd = &cobra.Command{
+	Use:   "export-seed [wallet file] --i-understand-this-is-dangerous",
+	Short: "Print the raw BIP39 seed of a wallet file (dangerous)",
+	Long: `Print the hex-encoded BIP39 seed that the keys of a wallet file are derived from, i.e., the result of
+bip39.NewSeed(mnemonic, passphrase), e.g., to reproduce the derivation using another library. ANYONE WHO SEES
+THE SEED CONTROLS ALL OF THE ACCOUNTS OF THE WALLET, and unlike the mnemonic it's not meant to be written down.
+The --i-understand-this-is-dangerous flag is required, and the seed is only printed after confirming once more.
+If the wallet was created with a BIP-39 passphrase you'll need to enter it again. Wallets backed by a Ledger
+device, and wallets saved without their mnemonic, have no seed that can be exported.`,
+	Args: cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		if !understandSeedRisk {
+			fatalln(common.ErrBadInput, "Exporting the seed gives anyone who sees it control of all accounts of the wallet: "+
+				"set --i-understand-this-is-dangerous to continue")
+		}
+		w, _ := openWallet(walletFile(args[0]))
+		defer w.Wipe()
+		if w.IsImported() {
+			fatalln(common.ErrBadInput, "Wallets imported from a private key have no seed that can be exported")
+		}
+		if w.IsLedger() {
+			fatalln(common.ErrBadInput, "Wallets backed by a Ledger device have no seed that can be exported")
+		}
+
+		var opts []wallet.WalletOpt
+		if needsPassphrase(w) {
+			opts = append(opts, wallet.WithPassphrase(readPassphrase(existingPassphrasePrompt)))
+		}
+		seed, err := w.Seed(opts...)
+		if errors.Is(err, wallet.ErrNoMnemonic) {
+			fatalln(common.ErrBadInput, "The wallet was saved without its mnemonic and has no seed that can be exported: "+
+				"restore it from its mnemonic first")
+		}
+		checkErr(err)
+		defer func() {
+			for i := range seed {
+				seed[i] = 0
+			}
+		}()
+
+		fmt.Println("The seed gives full control of all accounts of this wallet. Make sure nobody can see your screen,")
+		fmt.Println("and that your terminal isn't being recorded or logged.")
+		fmt.Print("Type \"show seed\" to print it: ")
+		confirm, err := readInput()
+		fmt.Println()
+		checkErr(err)
+		if strings.TrimSpace(confirm) != "show seed" {
+			fatalln(common.ErrBadInput, "Not confirmed, the seed was not printed")
+		}
+		fmt.Println(hex.EncodeToString(seed))
+	},
+}
+
+// splitMnemonicCmd splits the mnemonic of a wallet file into Shamir shares.
+var splitMnemonicCmd = &cobra.Command{
+	Use:   "split-mnemonic [wallet file] [--threshold m] [--shares n]",
+	Short: "Back up the mnemonic of a wallet file as shares, any m of n of which can restore it",
+	Long: `Split the mnemonic stored in a wallet file into n shares using Shamir's Secret Sharing. Any m of
+the shares (the threshold) can rebuild the mnemonic using combine-shares, but fewer than m reveal nothing about it.
+Each share is a list of English words, and each share includes a checksum so that typos are detected.
+Store each share in a different place. A BIP-39 passphrase, if any, is not part of the shares.`,
+	Args: cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		w, _ := openWallet(walletFile(args[0]))
+		defer w.Wipe()
+		if w.Mnemonic() == "" {
+			fatalln(common.ErrBadInput,
+				"Wallet file does not contain a mnemonic, e.g., because it was created using a Ledger device")
+		}
+
+		shares, err := wallet.SplitMnemonic(w.Mnemonic(), shareThreshold, shareCount)
+		checkErr(err)
+		fmt.Printf("Any %d of these %d shares can restore the mnemonic. Write each one down and store them "+
+			"in different places.\n\n", shareThreshold, shareCount)
+		for i, share := range shares {
+			fmt.Printf("Share %d of %d:\n%s\n\n", i+1, shareCount, share)
+		}
+	},
+}
+
+// combineSharesCmd rebuilds a mnemonic from Shamir shares.
+var combineSharesCmd = &cobra.Command{
+	Use:   "combine-shares",
+	Short: "Restore a mnemonic from the shares produced by split-mnemonic",
+	Long: `Rebuild a mnemonic from the shares produced by split-mnemonic. Enter one share per prompt until
+the threshold is reached. The mnemonic is printed so that it can be imported using wallet create.
+Add --language to print the mnemonic using a wordlist other than English.`,
+	Args: cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		var shares []string
+		for {
+			fmt.Printf("Enter share %d (or leave blank when done): ", len(shares)+1)
+			share, err := readInput()
+			fmt.Println()
+			checkErr(err)
+			share = strings.TrimSpace(share)
+			if share == "" {
+				break
+			}
+			shares = append(shares, share)
+		}
+
+		m, err := wallet.CombineShares(shares, wallet.WithLanguage(mnemonicLanguage))
+		checkErr(err)
+		fmt.Println("\nThis is your mnemonic (seed phrase):")
+		fmt.Println()
+		fmt.Println(m)
+	},
+}
+
+// addAccountsCmd derives additional accounts in an existing wallet file.
+var addAccountsCmd = &cobra.Command{
+	Use:   "add-accounts [wallet file] [numaccounts]",
+	Short: "Add accounts to an existing wallet file",
+	Long: `Derive one or more additional accounts from the mnemonic stored in an existing wallet file,
+continuing the sequence from the accounts it already contains, and save them to the file.
+If the wallet was created with a BIP-39 passphrase you'll need to enter it again.`,
+	Args: cobra.RangeArgs(1, 2),
+	Run: func(cmd *cobra.Command, args []string) {
+		walletFn := walletFile(args[0])
+		n := 1
+		if len(args) > 1 {
+			tmpN, err := strconv.Atoi(args[1])
+			checkErr(common.WithCategory(common.ErrBadInput, err))
+			n = tmpN
+		}
+
+		w, wk := openWallet(walletFn)
+		defer w.Wipe()
+
+		// a ledger wallet doesn't need a passphrase
+		opts := []wallet.WalletOpt{wallet.WithLedgerDevice(ledgerDevice)}
+		if needsPassphrase(w) {
+			opts = append(opts, wallet.WithPassphrase(readPassphrase(existingPassphrasePrompt)))
+		}
+		checkErr(w.AddAccounts(n, opts...))
+
+		saveWallet(walletFn, wk, w)
+
+		fmt.Printf("Added %d account(s) to %s, which now contains %d account(s).\n",
+			n, walletFn, len(w.Secrets.Accounts))
+	},
+}
+
+// findAccountCmd finds the account in a wallet file that controls an address.
+var findAccountCmd = &cobra.Command{
+	Use:   "find-account [wallet file] [address] [--gap-limit n]",
+	Short: "Find the account in a wallet file that controls an address",
+	Long: `Check whether an account in a wallet file controls an address, e.g., one seen in a block explorer,
+and print its account index. If none of the accounts stored in the file do, up to --gap-limit accounts
+following them are derived and checked too (but not added to the file: use add-accounts for that).
+If the wallet was created with a BIP-39 passphrase you'll need to enter it again to derive them.`,
+	Args: cobra.ExactArgs(2),
+	Run: func(cmd *cobra.Command, args []string) {
+		addr, err := wallet.ValidateAddress(args[1], hrp)
+		checkErr(err)
+
+		w, _ := openWallet(walletFile(args[0]))
+		defer w.Wipe()
+
+		index, err := w.FindAccount(addr)
+		if errors.Is(err, wallet.ErrAccountNotFound) && gapLimit > 0 {
+			opts := []wallet.WalletOpt{wallet.WithGapLimit(gapLimit), wallet.WithLedgerDevice(ledgerDevice)}
+			if needsPassphrase(w) {
+				opts = append(opts, wallet.WithPassphrase(readPassphrase(existingPassphrasePrompt)))
+			}
+			index, err = w.FindAccount(addr, opts...)
+		}
+		if errors.Is(err, wallet.ErrAccountNotFound) {
+			fatalf(common.ErrBadInput, "Not found: no account in %s controls %s\n", args[0], args[1])
+		}
+		checkErr(err)
+
+		if _, err := w.Account(index); err != nil {
+			fmt.Printf("%s is controlled by account %d, which is not stored in the wallet file.\n", args[1], index)
+			return
+		}
+		fmt.Printf("%s is controlled by account %d.\n", args[1], index)
+	},
+}
+
+// verifyWalletCmd checks that the accounts of a wallet file match the ones derived from its mnemonic.
+var verifyWalletCmd = &cobra.Command{
+	Use:   "verify [wallet file] [--passphrase-file file] [--ledger-device id]",
+	Short: "Check that the accounts of a wallet file are the ones its mnemonic derives",
+	Long: `Decrypt a wallet file, re-derive each of its accounts from the stored mnemonic, and check that the stored
+path and keys of the account match, e.g., to make sure that a backup isn't corrupted or hasn't been tampered with.
+Every mismatch is printed along with the account index, and the command fails if there are any. If the wallet
+was created with a BIP-39 passphrase you'll need to enter it again. The accounts of a Ledger wallet are read
+from the device instead.`,
+	Args: cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		w, _ := openWallet(walletFile(args[0]))
+		defer w.Wipe()
+
+		opts := []wallet.WalletOpt{wallet.WithLedgerDevice(ledgerDevice)}
+		if needsPassphrase(w) {
+			opts = append(opts, wallet.WithPassphrase(readPassphrase(existingPassphrasePrompt)))
+		}
+		mismatches, err := w.Verify(opts...)
+		checkErr(err)
+		for _, m := range mismatches {
+			fmt.Printf("Account %d (position %d in the file): %s\n", m.Index, m.Position, m.Problem)
+		}
+		if len(mismatches) > 0 {
+			fatalf(common.ErrBadInput, "%s has %d account(s) that don't match its mnemonic\n", args[0], len(mismatches))
+		}
+		fmt.Printf("All %d account(s) of %s match its mnemonic.\n", len(w.Secrets.Accounts), args[0])
+	},
+}
+
+// repairWalletCmd re-derives the accounts of a wallet file whose accounts are corrupted.
+var repairWalletCmd = &cobra.Command{
+	Use:   "repair [wallet file] [numaccounts] [--scan [--gap-limit n]] [--passphrase-file file] [--ledger-device id]",
+	Short: "Restore the accounts of a wallet file from its mnemonic if they're corrupted",
+	Long: `Replace the accounts stored in a wallet file with the ones derived from its mnemonic, e.g., when verify reports
+that they don't match but the mnemonic is intact. The wallet gets as many accounts as it contained, or numaccounts if
+given. Add --scan to also restore the accounts used on the network following them, querying the node at --endpoint,
+up to --gap-limit consecutive unused accounts. Account labels are kept where the account index can still be read.
+The accounts are printed and the file is only rewritten once confirmed, after backing it up (the file name followed
+by .bak- and the time). If the wallet was created with a BIP-39 passphrase you'll need to enter it again.`,
+	Args: cobra.RangeArgs(1, 2),
+	Run: func(cmd *cobra.Command, args []string) {
+		walletFn := walletFile(args[0])
+		w, wk := openWallet(walletFn)
+		defer w.Wipe()
+		n := len(w.Secrets.Accounts)
+		if len(args) > 1 {
+			tmpN, err := strconv.Atoi(args[1])
+			checkErr(common.WithCategory(common.ErrBadInput, err))
+			n = tmpN
+		}
+
+		opts := []wallet.WalletOpt{wallet.WithLedgerDevice(ledgerDevice)}
+		if needsPassphrase(w) {
+			opts = append(opts, wallet.WithPassphrase(readPassphrase(existingPassphrasePrompt)))
+		}
+		mismatches, err := w.Verify(opts...)
+		checkErr(err)
+		for _, m := range mismatches {
+			fmt.Printf("Account %d (position %d in the file): %s\n", m.Index, m.Position, m.Problem)
+		}
+		checkErr(w.RepairAccounts(n, opts...))
+		if scanAccounts {
+			client := newNodeClient()
+			defer client.Close()
+			checkWalletNetwork(cmd.Context(), w, client)
+			fmt.Printf("Scanning %s for used accounts...\n", client.Endpoint())
+			found, err := w.ScanAccounts(client.Checker(cmd.Context()), append(opts, wallet.WithGapLimit(gapLimit))...)
+			checkErr(err)
+			fmt.Printf("Found %d more used account(s).\n", found)
+		}
+
+		t := table.NewWriter()
+		t.SetOutputMirror(os.Stdout)
+		t.SetTitle("Repaired Accounts")
+		t.AppendHeader(table.Row{"index", "address", "label"})
+		keys, err := w.PublicKeys(hrp)
+		checkErr(err)
+		for _, key := range keys {
+			t.AppendRow(table.Row{key.Index, key.Address, key.Label})
+		}
+		t.Render()
+		fmt.Printf("Rewrite the accounts of %s? [y/N]: ", walletFn)
+		confirm, err := readInput()
+		fmt.Println()
+		checkErr(err)
+		if answer := strings.ToLower(strings.TrimSpace(confirm)); answer != "y" && answer != "yes" {
+			fatalln(common.ErrBadInput, "Not repaired, the wallet file was not changed")
+		}
+
+		data, err := os.ReadFile(walletFn)
+		checkErr(err)
+		backup := common.BackupFileName(walletFn)
+		checkErr(os.WriteFile(backup, data, 0o600))
+		saveWallet(walletFn, wk, w)
+		fmt.Printf("Repaired %s, which now contains %d account(s). The previous file was backed up to %s.\n",
+			walletFn, len(w.Secrets.Accounts), backup)
+	},
+}
+
+// signMessageCmd signs an arbitrary message with the key of an account.
+var signMessageCmd = &cobra.Command{
+	Use:   "sign-message [wallet file] [account index | --pick] [message] [--encoding hex|base64]",
+	Short: "Sign a message to prove ownership of an account",
+	Long: `Sign an arbitrary message, such as a challenge sent by a dApp, with the key of an account in a wallet file
+and print the signature, encoded using --encoding. The message is signed with a prefix so that the signature
+can never be used as the signature of a transaction. Ledger wallets can't sign yet: the Ledger transport of
+this version of smcli can only read public keys.
+The signature can be checked using verify-message and the public key printed by pubkeys.
+
+Add --pick instead of the account index to choose the account from a menu of the accounts of the wallet, with
+their labels and addresses. If stdin isn't a terminal, the accounts are listed and the index or label of one
+of them is read from the next line of stdin instead.`,
+	Args: func(cmd *cobra.Command, args []string) error {
+		if pickSigner {
+			return cobra.ExactArgs(2)(cmd, args)
+		}
+		return cobra.ExactArgs(3)(cmd, args)
+	},
+	Run: func(cmd *cobra.Command, args []string) {
+		var index uint64
+		if !pickSigner {
+			var err error
+			index, err = strconv.ParseUint(args[1], 10, 31)
+			checkErr(common.WithCategory(common.ErrBadInput, err))
+		}
+		encode := signatureEncoder()
+
+		w, _ := openWallet(walletFile(args[0]))
+		defer w.Wipe()
+		checkCanSign(w)
+		if pickSigner {
+			index = uint64(pickAccount(w).Index)
+		}
+
+		sig, err := w.SignMessage(int(index), []byte(args[len(args)-1]), wallet.WithLedgerDevice(ledgerDevice))
+		checkErr(err)
+		fmt.Println(encode(sig))
+	},
+}
+
+// verifyMessageCmd checks a signature produced by sign-message.
+var verifyMessageCmd = &cobra.Command{
+	Use:   "verify-message [public key] [message] [signature] [--encoding hex|base64] [--pubkey-encoding hex|base64]",
+	Short: "Verify a message signature produced by sign-message",
+	Long: `Check that a signature, encoded using --encoding, was produced by sign-message over the message using the
+key with the given public key, as printed by pubkeys. The address of the key is printed if it does. As for
+address from-pubkey, the key is hex-encoded (with or without a 0x prefix) or base64-encoded: the encoding is
+detected unless set using --pubkey-encoding.`,
+	Args: cobra.ExactArgs(3),
+	Run: func(cmd *cobra.Command, args []string) {
+		pubkey, err := wallet.ParsePublicKey(args[0], pubkeyEncoding)
+		if err != nil {
+			fatalf(common.ErrBadInput, "Invalid public key %q, must be %d hex- or base64-encoded bytes\n",
+				args[0], ed25519.PublicKeySize)
+		}
+		sig, err := wallet.ParseSignature(args[2], signatureEncoding)
+		checkErr(err)
+
+		if !wallet.VerifyMessage(pubkey, []byte(args[1]), sig) {
+			fatalln(wallet.ErrInvalidSignature, "Invalid signature")
+		}
+		fmt.Printf("Valid signature by %s\n", wallet.PubkeyToAddress(pubkey, hrp))
+	},
+}
+
+// signatureEncoder returns the function that encodes signatures using --encoding.
+func signatureEncoder() func([]byte) string {
+	switch signatureEncoding {
+	case wallet.PubkeyEncodingHex:
+		return hex.EncodeToString
+	case wallet.PubkeyEncodingBase64:
+		return base64.StdEncoding.EncodeToString
+	}
+	fatalf(common.ErrBadInput, "Unsupported signature encoding %s\n", signatureEncoding)
+	return nil
+}
+
+// labelCmd sets the label of an account in a wallet file.
+var labelCmd = &cobra.Command{
+	Use:   "label [wallet file] [account index] [label]",
+	Short: "Set or change the label of an account in a wallet file",
+	Long: `Give an account in a wallet file a label such as "savings" to tell it apart from the others.
+The account index is the one printed by the pubkeys command. An existing label is replaced, and an
+empty label ("") removes it. Labels are stored encrypted in the wallet file along with the account.`,
+	Args: cobra.ExactArgs(3),
+	Run: func(cmd *cobra.Command, args []string) {
+		walletFn := walletFile(args[0])
+		index, err := strconv.ParseUint(args[1], 10, 31)
+		checkErr(common.WithCategory(common.ErrBadInput, err))
+
+		w, wk := openWallet(walletFn)
+		defer w.Wipe()
+
+		checkErr(w.SetAccountLabel(uint32(index), args[2]))
+
+		saveWallet(walletFn, wk, w)
+
+		if args[2] == "" {
+			fmt.Printf("Removed the label of account %d.\n", index)
+		} else {
+			fmt.Printf("Account %d is now labeled %q.\n", index, args[2])
+		}
+	},
+}
+
+// multisigWalletCmd groups the commands that manage the multisig accounts stored in a wallet file.
+var multisigWalletCmd = &cobra.Command{
+	Use:   "multisig",
+	Short: "Manage the multisig accounts stored in a wallet file",
+	Long: `Store the threshold and participants of multisig accounts that the wallet takes part in, so that they can
+be referred to by label instead of listing the public keys again. They're stored encrypted in the wallet file
+along with the accounts, except in the file of a Ledger wallet, which isn't encrypted. Spends sent by a stored
+multisig account are co-signed using tx sign --multisig and combined using tx aggregate --multisig.`,
+}
+
+// addMultisigCmd stores a multisig account in a wallet file.
+var addMultisigCmd = &cobra.Command{
+	Use:   "add [wallet file] [label] [pubkey]... --required k [--raw-order]",
+	Short: "Store a multisig account in a wallet file under a label",
+	Long: `Store the multisig account requiring k signatures from the given hex-encoded public keys in a wallet file
+under a label such as "treasury". As for address multisig, the keys are sorted in canonical order unless
+--raw-order is added, and they're stored in that order along with the address of the account, which is computed
+again and checked whenever the wallet is opened. The label must be unique within the wallet, and the same
+account can't be stored twice.`,
+	Args: cobra.MinimumNArgs(3),
+	Run: func(cmd *cobra.Command, args []string) {
+		walletFn := walletFile(args[0])
+		pubkeys := parsePublicKeys(args[2:])
+		var opts []wallet.WalletOpt
+		if rawKeyOrder {
+			opts = append(opts, wallet.WithRawKeyOrder())
+		}
+
+		w, wk := openWallet(walletFn)
+		defer w.Wipe()
+		m, err := w.AddMultiSig(args[1], multisigRequired, pubkeys, opts...)
+		checkErr(err)
+		spawn, err := m.Spawn()
+		checkErr(err)
+
+		saveWallet(walletFn, wk, w)
+		fmt.Printf("Stored the %d-of-%d multisig account %s as %q.\n", spawn.Required, len(spawn.PublicKeys),
+			wallet.AddressString(spawn.Principal, hrp), m.Label)
+	},
+}
+
+// listMultisigsCmd prints the multisig accounts stored in a wallet file.
+var listMultisigsCmd = &cobra.Command{
+	Use:   "list [wallet file]",
+	Short: "List the multisig accounts stored in a wallet file",
+	Long: `Print the label, address, threshold, and participants of each multisig account stored in a wallet file,
+along with the fingerprint of its spawn arguments to compare with the other participants (see address
+multisig). Participants are listed in spawn order, which is also the order to use when signing.`,
+	Args: cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		w, _ := openWallet(walletFile(args[0]))
+		defer w.Wipe()
+		if len(w.Secrets.MultiSigs) == 0 {
+			fmt.Println("The wallet has no multisig accounts, add one using wallet multisig add.")
+			return
+		}
+		t := table.NewWriter()
+		t.SetOutputMirror(os.Stdout)
+		t.AppendHeader(table.Row{"label", "address", "required", "public keys", "fingerprint"})
+		for _, m := range w.Secrets.MultiSigs {
+			_, spawn, err := w.MultiSig(m.Label)
+			checkErr(err)
+			keys := make([]string, len(spawn.PublicKeys))
+			for i, pubkey := range spawn.PublicKeys {
+				keys[i] = hex.EncodeToString(pubkey[:])
+			}
+			t.AppendRow(table.Row{
+				m.Label,
+				wallet.AddressString(spawn.Principal, hrp),
+				fmt.Sprintf("%d of %d", spawn.Required, len(keys)),
+				strings.Join(keys, "\n"),
+				spawn.Fingerprint,
+			})
+		}
+		t.Render()
+	},
+}
+
+// listWalletsCmd lists the wallets in the managed wallet directory.
+var listWalletsCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List the wallets in the managed wallet directory",
+	Long: `List the wallets saved in the managed wallet directory, ~/.spacemesh/wallets, using create --as or added
+using register, along with their display names and the networks they're for. Other commands accept the short
+name of a wallet in place of the path of its wallet file. No password is needed.`,
+	Args: cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		d := walletDirectory()
+		entries := d.List()
+		if len(entries) == 0 {
+			fmt.Printf("No wallets in %s, add one using create --as or register.\n", d.Dir())
+			return
+		}
+		t := table.NewWriter()
+		t.SetOutputMirror(os.Stdout)
+		t.AppendHeader(table.Row{"name", "display name", "genesis ID", "file"})
+		for _, e := range entries {
+			t.AppendRow(table.Row{e.Name, e.DisplayName, e.GenesisID, d.Path(e)})
+		}
+		t.Render()
+	},
+}
+
+// registerWalletCmd adds an existing wallet file to the managed wallet directory.
+var registerWalletCmd = &cobra.Command{
+	Use:   "register [wallet file] [name]",
+	Short: "Add an existing wallet file to the managed wallet directory",
+	Long: `Index an existing wallet file in the managed wallet directory under a short name, so that other commands
+accept the name in place of its path. The file is left where it is. Names must be unique and can't contain path
+separators or whitespace.`,
+	Args: cobra.ExactArgs(2),
+	Run: func(cmd *cobra.Command, args []string) {
+		checkErr(walletDirectory().Add(args[1], args[0]))
+		fmt.Printf("Registered %s as %q.\n", args[0], args[1])
+	},
+}
+
+// importSmappCmd converts a Smapp wallet file into a new smcli wallet file.
+var importSmappCmd = &cobra.Command{
+	Use:   "import-smapp [smapp wallet file] [--kdf name] [--file path [--force] | --as name]",
+	Short: "Import a wallet file from Smapp, the Spacemesh desktop app",
+	Long: `Decrypt a wallet file created by Smapp using its password and save its mnemonic and accounts as a new
+smcli wallet file, encrypted under a new password. Account names and the network the wallet is for are kept.
+Smapp settings that smcli has no use for, such as the address book and the remote API, are not imported and
+a warning is printed for each. Smapp wallets backed by a Ledger device can't be imported: use create --ledger.`,
+	Args: cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		checkNewWalletFile()
+		data, err := os.ReadFile(args[0])
+		checkErr(err)
+		if !wallet.IsSmappWallet(data) {
+			fatalf(common.ErrBadInput, "%s is not a Smapp wallet file\n", args[0])
+		}
+
+		fmt.Print("Enter Smapp wallet password: ")
+		smappPassword, err := readInput()
+		fmt.Println()
+		checkErr(err)
+		w, warnings, err := wallet.ImportSmapp(bytes.NewReader(data), []byte(smappPassword))
+		checkErr(err)
+		defer w.Wipe()
+		for _, warning := range warnings {
+			fmt.Fprintf(os.Stderr, "Warning: %s\n", warning)
+		}
+		fmt.Printf("Imported %q with %d account(s):\n", w.Meta.DisplayName, len(w.Secrets.Accounts))
+		for i, address := range w.Addresses(hrp) {
+			fmt.Printf("  %d: %s\n", i, address)
+		}
+
+		saveNewWallet(w)
+	},
+}
+
+// importKeyCmd saves a raw private key as a new single-account wallet file.
+var importKeyCmd = &cobra.Command{
+	Use:   "import-key [--name name] [--kdf name] [--file path [--force] | --as name | --key-file path [--force]]",
+	Short: "Import a raw ed25519 private key as a wallet with a single account",
+	Long: `Read an ed25519 private key, e.g., one generated by another tool, and save it as a new wallet file with
+a single account, encrypted under a new password. The key is entered without echo as hex, with or without a 0x
+prefix, or as base64, and is either the 32-byte seed of the key or the 64-byte private key made of the seed and
+the public key. The wallet has no mnemonic, so no more accounts can be derived from it and it has no seed:
+add-accounts, find-account past the stored account, verify, repair, and export-seed fail. Nothing but the key
+restores the account, so keep a backup of it.
+
+Add --key-file to save the key as a standalone encrypted key file instead of a wallet file, to sign with using
+sign-with-key.`,
+	Args: cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		if importKeyFile != "" && (newWalletFile != "" || newWalletName != "") {
+			fatalln(common.ErrBadInput, "--key-file can't be combined with --file or --as")
+		}
+		if importKeyFile == "" {
+			checkNewWalletFile()
+		}
+		fmt.Print("Enter the private key (hex or base64): ")
+		key, err := readInput()
+		fmt.Println()
+		checkErr(err)
+		w, err := wallet.NewWalletFromPrivateKey(key,
+			wallet.WithDisplayName(walletName),
+			wallet.WithGenesisID(genesisID),
+		)
+		checkErr(err)
+		defer w.Wipe()
+		fmt.Printf("Imported %q with address %s\n", w.Meta.DisplayName, w.Addresses(hrp)[0])
+
+		if importKeyFile == "" {
+			saveNewWallet(w)
+			return
+		}
+		wk := newWalletKey("key file")
+		backup, err := common.PrepareNewFile(importKeyFile, forceOverwrite)
+		if errors.Is(err, os.ErrExist) && !forceOverwrite {
+			fatalf(common.ErrBadInput, "%s already exists, add --force to replace it (it will be backed up first)\n",
+				importKeyFile)
+		}
+		checkErr(err)
+		if backup != "" {
+			fmt.Printf("Backed up the existing %s to %s.\n", importKeyFile, backup)
+		}
+		checkErr(common.WriteFileAtomic(importKeyFile, 0o600, func(f io.Writer) error {
+			return wk.ExportKeyFile(f, w.Secrets.Accounts[0])
+		}))
+		fmt.Printf("Key saved to %s. BACK UP THIS FILE NOW!\n", importKeyFile)
+	},
+}
+
+// exportSmappCmd writes a wallet file in the Smapp format.
+var exportSmappCmd = &cobra.Command{
+	Use:   "export-smapp [wallet file] [smapp wallet file]",
+	Short: "Export a wallet file for use in Smapp, the Spacemesh desktop app",
+	Long: `Decrypt an existing wallet file and write its mnemonic and accounts to a new file in the Smapp wallet
+format, encrypted under a new password, so that it can be opened in Smapp. Account labels are used as the
+Smapp account names. The file is left unchanged. Wallets backed by a Ledger device can't be exported:
+add the device to Smapp instead. Neither can wallets created with a BIP-39 passphrase, since Smapp derives
+the keys from the mnemonic alone and would reject every account.`,
+	Args: cobra.ExactArgs(2),
+	Run: func(cmd *cobra.Command, args []string) {
+		w, _ := openWallet(walletFile(args[0]))
+		defer w.Wipe()
+		checkErr(wallet.CheckSmappExport(w))
+
+		fmt.Print("Enter a secure password used to encrypt the Smapp wallet file: ")
+		password, err := readInput()
+		fmt.Println()
+		checkErr(err)
+		fmt.Print("Repeat password: ")
+		password2, err := readInput()
+		fmt.Println()
+		checkErr(err)
+		if password != password2 {
+			fatalln(common.ErrBadInput, "Passwords do not match")
+		}
+		checkErr(newPasswordPolicy().Check([]byte(password)))
+
+		f, err := os.OpenFile(args[1], os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0o600)
+		checkErr(err)
+		err = wallet.ExportSmapp(f, w, []byte(password))
+		if closeErr := f.Close(); err == nil {
+			err = closeErr
+		}
+		if err != nil {
+			os.Remove(args[1])
+		}
+		checkErr(err)
+
+		fmt.Printf("Exported %d account(s) to %s.\n", len(w.Secrets.Accounts), args[1])
+	},
+}
+
+// renameCmd changes the display name of a wallet file.
+var renameCmd = &cobra.Command{
+	Use:   "rename [wallet file] [name]",
+	Short: "Change the display name of a wallet file",
+	Long: `Change the display name of an existing wallet file. The name is stored in the unencrypted
+metadata of the file, so no password is needed; the rest of the file is left unchanged.`,
+	Args: cobra.ExactArgs(2),
+	Run: func(cmd *cobra.Command, args []string) {
+		walletFn := walletFile(args[0])
+
+		data, err := os.ReadFile(walletFn)
+		checkErr(err)
+		if wallet.IsHardwareWallet(data) {
+			hw, err := wallet.ReadHardwareWallet(bytes.NewReader(data))
+			checkErr(err)
+			w := hw.Wallet()
+			checkErr(w.SetDisplayName(args[1]))
+			saveHardwareWallet(walletFn, w)
+			fmt.Printf("Renamed %s from %q to %q.\n", walletFn, hw.Meta.DisplayName, w.Meta.DisplayName)
+			return
+		}
+		ew := &wallet.EncryptedWalletFile{}
+		checkErr(json.Unmarshal(data, ew))
+		oldName := ew.Meta.DisplayName
+
+		checkErr(ew.Rename(args[1]))
+		saveEncryptedWallet(walletFn, ew)
+
+		fmt.Printf("Renamed %s from %q to %q.\n", walletFn, oldName, ew.Meta.DisplayName)
+	},
+}
+
+// ledgerDevicesCmd lists the connected Ledger devices.
+var ledgerDevicesCmd = &cobra.Command{
+	Use:   "ledger-devices",
+	Short: "List connected Ledger devices",
+	Long: `List the connected Ledger devices. If more than one is connected, pass the ID of the one
+to use to other commands using --ledger-device.
+
+The SDK used by this version of smcli can't list devices, so this command only reports that: the other
+commands use the first connected device, found at usb://ledger, unless --ledger-device is given. Nor can
+it read which app is open on a device or its version, so none of the Ledger commands check that it's the
+Spacemesh app: make sure it is, version ` + wallet.MinLedgerAppVersion + ` or later.`,
+	Args: cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		devices, err := wallet.EnumerateLedgerDevices()
+		checkErr(err)
+		if len(devices) == 0 {
+			fatalln(common.ErrDevice, wallet.ErrNoLedgerDevice)
+		}
+		t := table.NewWriter()
+		t.SetOutputMirror(os.Stdout)
+		t.AppendHeader(table.Row{"id", "product"})
+		for _, d := range devices {
+			t.AppendRow(table.Row{d.ID, d.Product})
+		}
+		t.Render()
 	},
 }
 
+// ledgerConfirmCmd shows the address of a Ledger wallet account on the device screen.
+var ledgerConfirmCmd = &cobra.Command{
+	Use:   "ledger-confirm [wallet file] [account index] [--ledger-device id]",
+	Short: "Display an account address on the Ledger device to confirm it",
+	Long: `Ask the Ledger device that backs a wallet file to display the address of one of its accounts,
+so that you can check that it matches the address stored in the wallet file before trusting it. Open the
+Spacemesh app on the device first: this version of smcli can't check which app is open or its version, so a
+wrong app only shows up as an error reading the address.`,
+	Args: cobra.ExactArgs(2),
+	Run: func(cmd *cobra.Command, args []string) {
+		walletFn := walletFile(args[0])
+		idx, err := strconv.ParseInt(args[1], 10, 16)
+		checkErr(common.WithCategory(common.ErrBadInput, err))
+
+		w, _ := openWallet(walletFn)
+		defer w.Wipe()
+		if int(idx) < 0 || int(idx) >= len(w.Secrets.Accounts) {
+			fatalf(common.ErrBadInput, "invalid account index %d, wallet has %d account(s)\n", idx, len(w.Secrets.Accounts))
+		}
+
+		address := wallet.PubkeyToAddress(w.Secrets.Accounts[idx].Public, hrp)
+		fmt.Printf("Confirm on the Ledger device that it shows the address %s\n", address)
+		checkErr(w.ConfirmLedgerAddress(int(idx), wallet.WithLedgerDevice(ledgerDevice)))
+		fmt.Println("Address confirmed.")
+	},
+}
+
+// changePasswordCmd re-encrypts an existing wallet file under a new password.
+var changePasswordCmd = &cobra.Command{
+	Use:   "change-password [wallet file]",
+	Short: "Change the password of an existing wallet file",
+	Long: `Decrypt an existing wallet file using its current password and re-encrypt it
+under a new password. The wallet metadata and contents are preserved.`,
+	Args: cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		walletFn := walletFile(args[0])
+
+		data, err := os.ReadFile(walletFn)
+		checkErr(err)
+		if wallet.IsHardwareWallet(data) {
+			fatalf(common.ErrBadInput, "%s is a hardware wallet file, which isn't encrypted: its keys stay on the "+
+				"device\n", walletFn)
+		}
+		ew := &wallet.EncryptedWalletFile{}
+		checkErr(json.Unmarshal(data, ew))
+
+		fmt.Print("Enter current wallet password: ")
+		oldPassword, err := readInput()
+		fmt.Println()
+		checkErr(err)
+		fmt.Print("Enter new wallet password: ")
+		newPassword, err := readInput()
+		fmt.Println()
+		checkErr(err)
+		fmt.Print("Repeat new wallet password: ")
+		newPassword2, err := readInput()
+		fmt.Println()
+		checkErr(err)
+		if newPassword != newPassword2 {
+			fatalln(common.ErrBadInput, "Passwords do not match")
+		}
+		checkErr(newPasswordPolicy().Check([]byte(newPassword)))
+
+		checkErr(ew.ChangePassword([]byte(oldPassword), []byte(newPassword)))
+		saveEncryptedWallet(walletFn, ew)
+
+		fmt.Printf("Password changed for %s.\n", walletFn)
+	},
+}
+
+// showNewMnemonic prints a newly generated mnemonic and waits for the user to save it.
+func showNewMnemonic(m string) {
+	for {
+		fmt.Println("\nThis is your mnemonic (seed phrase). Write it down and store it safely. It is the ONLY way to restore your wallet.")
+		fmt.Println("Neither Spacemesh nor anyone else can help you restore your wallet without this mnemonic.")
+		fmt.Println("\n***********************************\nSAVE THIS MNEMONIC IN A SAFE PLACE!\n***********************************")
+		fmt.Println()
+		fmt.Println(m)
+		if printQR {
+			printMnemonicQRCode(m)
+		}
+		fmt.Println("\nPress enter when you have securely saved your mnemonic.")
+		_, _ = fmt.Scanln()
+		if noVerify {
+			return
+		}
+
+		clearScreen()
+		err := confirmMnemonic(m)
+		if err == nil {
+			return
+		}
+		if !errors.Is(err, wallet.ErrMnemonicMismatch) {
+			checkErr(err)
+		}
+		if !term.IsTerminal(int(os.Stdin.Fd())) {
+			// don't keep asking a script
+			fatalf(common.ErrBadInput, "%v, add --no-verify to skip confirming the mnemonic\n", err)
+		}
+		fmt.Printf("%v. Please check what you wrote down against the mnemonic, which is shown again.\n", err)
+	}
+}
+
+// confirmMnemonic asks the user to enter a few words of a new mnemonic picked at random, once it's no longer on
+// screen, to make sure that it was written down.
+func confirmMnemonic(m string) error {
+	positions, err := wallet.PickConfirmWords(m, wallet.DefaultConfirmWords, nil)
+	if err != nil {
+		return err
+	}
+	fmt.Println("To make sure that you saved your mnemonic, please enter some of its words.")
+	entered := make([]string, len(positions))
+	for i, pos := range positions {
+		fmt.Printf("Enter word #%d: ", pos+1)
+		if entered[i], err = readInput(); err != nil {
+			return err
+		}
+		fmt.Println()
+	}
+	if err := wallet.CheckConfirmWords(m, positions, entered); err != nil {
+		return err
+	}
+	fmt.Println("Thanks, the words match.")
+	return nil
+}
+
+// mnemonicFromRolls prompts for the dice rolls or coin flips selected by --rolls and generates a new mnemonic
+// from them.
+func mnemonicFromRolls() string {
+	needed, err := wallet.RollsNeeded(rollsKind, mnemonicWords)
+	checkErr(err)
+	switch rollsKind {
+	case wallet.RollsDice:
+		fmt.Printf("Roll a die at least %d times and enter the results (1-6), e.g., 3 1 6 4: ", needed)
+	case wallet.RollsCoin:
+		fmt.Printf("Flip a coin at least %d times and enter the results (h or t), e.g., h t t h: ", needed)
+	}
+	rolls, err := readInput()
+	fmt.Println()
+	checkErr(err)
+	m, err := wallet.MnemonicFromRolls(rollsKind, rolls,
+		wallet.WithWordCount(mnemonicWords), wallet.WithLanguage(mnemonicLanguage))
+	checkErr(err)
+	return m
+}
+
+// scanWalletAccounts adds the accounts of a new wallet that were used on the network, or a single account if none
+// were and --accounts-later isn't set.
+func scanWalletAccounts(ctx context.Context, w *wallet.Wallet) {
+	client := newNodeClient()
+	defer client.Close()
+	checkWalletNetwork(ctx, w, client)
+
+	fmt.Printf("Scanning %s for used accounts...\n", client.Endpoint())
+	n, err := w.ScanAccounts(client.Checker(ctx), wallet.WithGapLimit(gapLimit), wallet.WithLedgerDevice(ledgerDevice))
+	checkErr(err)
+	switch {
+	case n > 0:
+		fmt.Printf("Found %d used account(s) (including any unused ones between them).\n", n)
+	case accountsLater:
+		fmt.Println("No used accounts found, the wallet won't contain any accounts.")
+	default:
+		fmt.Println("No used accounts found, the wallet will contain a single new account.")
+		checkErr(w.AddAccounts(1, wallet.WithLedgerDevice(ledgerDevice)))
+	}
+}
+
+// checkNewWalletFile exits early if --file already exists and --force isn't set, or if --as isn't a new wallet
+// name, before asking for anything that saveNewWallet would then have to discard.
+func checkNewWalletFile() {
+	if newWalletName != "" {
+		if newWalletFile != "" {
+			fatalf(common.ErrBadInput, "Only one of --file and --as may be given\n")
+		}
+		var err error
+		newWalletFile, err = walletDirectory().NewWalletFile(newWalletName)
+		checkErr(err)
+	}
+	if newWalletFile == "" || forceOverwrite {
+		return
+	}
+	if _, err := os.Lstat(newWalletFile); err == nil {
+		fatalf(common.ErrBadInput, "%s already exists, add --force to replace it (it will be backed up first)\n",
+			newWalletFile)
+	}
+}
+
+// saveNewWallet reads a password, see readWalletPassword, encrypts a new wallet using the KDF selected by --kdf,
+// and writes it to a new file in the wallet directory, or to --file, indexing it under its --as name. An existing
+// file is only replaced if --force is set, after backing it up. A wallet backed by a Ledger device is written as a
+// hardware wallet file without a password, unless --encrypt is set.
+func saveNewWallet(w *wallet.Wallet) {
+	if w.IsLedger() && !encryptHardware {
+		walletFn := prepareNewWalletFile()
+		saveHardwareWallet(walletFn, w)
+		fmt.Printf("Wallet saved to %s. It contains no secrets, so it isn't encrypted and opens without a "+
+			"password.\n", walletFn)
+		indexNewWallet(walletFn)
+		return
+	}
+
+	wk := newWalletKey("wallet file")
+	walletFn := prepareNewWalletFile()
+	saveWallet(walletFn, wk, w)
+
+	fmt.Printf("Wallet saved to %s. BACK UP THIS FILE NOW!\n", walletFn)
+	indexNewWallet(walletFn)
+}
+
+// newWalletKey reads a new password, see readWalletPassword, for encrypting what, and returns the key that
+// encrypts it using the KDF selected by --kdf.
+func newWalletKey(what string) wallet.WalletKey {
+	password := readWalletPassword(os.Stdout, "Enter a secure password used to encrypt the "+what+": ")
+	checkErr(newPasswordPolicy().Check([]byte(password)))
+	var wk wallet.WalletKey
+	switch strings.ToLower(kdf) {
+	case strings.ToLower(wallet.KDFPbkdf2):
+		if pbkdf2Iterations <= 0 {
+			fatalf(common.ErrBadInput, "Invalid number of PBKDF2 iterations %d\n", pbkdf2Iterations)
+		}
+		if pbkdf2Iterations < wallet.Pbkdf2Iterations {
+			fmt.Fprintf(os.Stderr, "Warning: %d PBKDF2 iterations is fewer than the recommended %d\n",
+				pbkdf2Iterations, wallet.Pbkdf2Iterations)
+		}
+		wk = wallet.NewKey(
+			wallet.WithRandomSalt(),
+			wallet.WithIterations(pbkdf2Iterations),
+			wallet.WithPbkdf2Password([]byte(password)),
+		)
+	case strings.ToLower(wallet.KDFScrypt):
+		wk = wallet.NewKey(wallet.WithRandomSalt(), wallet.WithScryptPassword([]byte(password)))
+	case strings.ToLower(wallet.KDFArgon2):
+		wk = wallet.NewKey(
+			wallet.WithRandomSalt(),
+			wallet.WithArgon2Params(argon2Time, argon2Memory, argon2Threads),
+			wallet.WithArgon2Password([]byte(password)),
+		)
+	default:
+		fatalf(common.ErrBadInput, "Unsupported key derivation function %s\n", kdf)
+	}
+	return wk
+}
+
+// indexNewWallet adds a new wallet file to the managed wallet directory under its --as name, if given.
+func indexNewWallet(walletFn string) {
+	if newWalletName == "" {
+		return
+	}
+	checkErr(walletDirectory().Add(newWalletName, walletFn))
+	fmt.Printf("Other commands accept %q in place of the path of the wallet file.\n", newWalletName)
+}
+
+// prepareNewWalletFile returns the path of a new wallet file, in the wallet directory unless --file is set,
+// backing up the existing file there if --force is set.
+func prepareNewWalletFile() string {
+	walletFn := newWalletFile
+	switch {
+	case walletFn == "":
+		checkWriteErr(os.MkdirAll(common.DotDirectory(), 0o700))
+		walletFn = common.WalletFile()
+	case newWalletName != "":
+		// the first wallet saved under a name creates the managed wallet directory
+		checkWriteErr(os.MkdirAll(common.WalletsDirectory(), 0o700))
+	}
+
+	// Make sure we're not overwriting an existing wallet, which would destroy its keys
+	backup, err := common.PrepareNewFile(walletFn, forceOverwrite)
+	if errors.Is(err, os.ErrExist) && !forceOverwrite {
+		fatalf(common.ErrBadInput, "%s already exists, add --force to replace it (it will be backed up first)\n", walletFn)
+	}
+	checkWriteErr(err)
+	if backup != "" {
+		fmt.Printf("Backed up the existing %s to %s.\n", walletFn, backup)
+	}
+	return walletFn
+}
+
+// checkCanSign exits if the wallet is backed by a Ledger device that can't sign, see wallet.CheckLedgerSigning,
+// before the user is asked to pick or confirm anything.
+func checkCanSign(w *wallet.Wallet) {
+	if w.IsLedger() {
+		checkErr(wallet.CheckLedgerSigning())
+	}
+}
+
+// needsPassphrase reports whether deriving the keys of an existing wallet needs its BIP-39 passphrase, which is
+// the case if they're derived from a mnemonic stored in the wallet: not for Ledger wallets, wallets restored from
+// a seed, which already includes the passphrase, or wallets saved without their mnemonic.
+func needsPassphrase(w *wallet.Wallet) bool {
+	return !w.IsLedger() && w.Mnemonic() != ""
+}
+
+// existingPassphrasePrompt asks for the BIP-39 passphrase of an existing wallet.
+const existingPassphrasePrompt = "Enter the BIP-39 passphrase used to create the wallet (or leave blank for none): "
+
+// readPassphrase returns the BIP-39 passphrase read from --passphrase-file or envPassphrase, or else prompts for
+// it. Only one of the file and the environment variable may be set.
+func readPassphrase(prompt string) string {
+	var env *string
+	if value, ok := os.LookupEnv(envPassphrase); ok {
+		env = &value
+	}
+	passphrase, err := wallet.ReadPassphrase(passphraseFile, env, func() (string, error) {
+		fmt.Print(prompt)
+		passphrase, err := readInput()
+		fmt.Println()
+		return passphrase, err
+	})
+	checkErr(err)
+	return passphrase
+}
+
+// warnedPasswordEnv is set once the user has been warned that the wallet password is read from envWalletPassword.
+var warnedPasswordEnv bool
+
+// readWalletPassword returns the wallet password read from envWalletPassword if it's set, or else prompts for it.
+// The prompt is written to out.
+func readWalletPassword(out io.Writer, prompt string) string {
+	var env *string
+	if value, ok := os.LookupEnv(envWalletPassword); ok {
+		env = &value
+		if !warnedPasswordEnv {
+			fmt.Fprintf(os.Stderr, "Warning: using the wallet password from $%s, which is insecure and only meant "+
+				"for CI\n", envWalletPassword)
+			warnedPasswordEnv = true
+		}
+	}
+	password, err := wallet.ReadPassword(env, func() (string, error) {
+		fmt.Fprint(out, prompt)
+		password, err := readInput()
+		fmt.Fprintln(out)
+		return password, err
+	})
+	checkErr(err)
+	return password
+}
+
+// walletDirectory opens the managed wallet directory.
+func walletDirectory() *wallet.WalletDirectory {
+	d, err := wallet.OpenWalletDirectory(common.WalletsDirectory())
+	checkErr(err)
+	return d
+}
+
+// walletFile returns the path of the wallet file given as arg on the command line: arg itself if there's a file
+// there or it looks like a path, or else the file of the wallet with that short name in the managed wallet
+// directory. An unknown name is returned as is, so that opening it reports the missing file.
+func walletFile(arg string) string {
+	if _, err := os.Stat(arg); err == nil || strings.ContainsAny(arg, `/\`) {
+		return arg
+	}
+	if path, err := walletDirectory().Resolve(arg); err == nil {
+		return path
+	}
+	return arg
+}
+
+// openWallet reads the password, see readWalletPassword, and decrypts a wallet file. If --rekey-iterations is set
+// and the file uses fewer PBKDF2 iterations, it's first re-encrypted using that many. The returned key can be used
+// to save the wallet back to the file, see saveWallet. Hardware wallet files aren't encrypted, so no password is
+// asked for them and the returned key is empty.
+func openWallet(walletFn string) (*wallet.Wallet, wallet.WalletKey) {
+	data, err := os.ReadFile(walletFn)
+	checkErr(err)
+	common.Logger().Debug("opening wallet file", "file", walletFn, "hardware", wallet.IsHardwareWallet(data))
+	if wallet.IsHardwareWallet(data) {
+		hw, err := wallet.ReadHardwareWallet(bytes.NewReader(data))
+		checkErr(err)
+		return hw.Wallet(), wallet.WalletKey{}
+	}
+
+	password := readWalletPassword(promptOutput(), "Enter wallet password: ")
+
+	if rekeyIterations > 0 {
+		data = rekeyWalletFile(walletFn, data, []byte(password))
+	}
+	wk := wallet.NewKey(wallet.WithPasswordOnly([]byte(password)))
+	w, err := wk.Open(bytes.NewReader(data))
+	checkErr(err)
+	return w, wk
+}
+
+// saveWallet encrypts a wallet using wk and writes it to walletFn, replacing the file atomically so that it's
+// never left half-written. A hardware wallet file is written back as such.
+func saveWallet(walletFn string, wk wallet.WalletKey, w *wallet.Wallet) {
+	if data, err := os.ReadFile(walletFn); err == nil && wallet.IsHardwareWallet(data) {
+		saveHardwareWallet(walletFn, w)
+		return
+	}
+	checkWriteErr(common.WriteFileAtomic(walletFn, 0o600, func(f io.Writer) error {
+		return wk.Export(f, w)
+	}))
+}
+
+// saveHardwareWallet atomically replaces walletFn with the hardware wallet file of a Ledger wallet.
+func saveHardwareWallet(walletFn string, w *wallet.Wallet) {
+	hw, err := w.Hardware()
+	checkErr(err)
+	checkWriteErr(common.WriteFileAtomic(walletFn, 0o600, hw.Export))
+}
+
+// saveEncryptedWallet atomically replaces walletFn with an already encrypted wallet file, e.g., after changing
+// its password, and returns the written contents.
+func saveEncryptedWallet(walletFn string, ew *wallet.EncryptedWalletFile) []byte {
+	data, err := json.Marshal(ew)
+	checkErr(err)
+	data = append(data, '\n')
+	checkWriteErr(common.WriteFileAtomic(walletFn, 0o600, func(f io.Writer) error {
+		_, err := f.Write(data)
+		return err
+	}))
+	return data
+}
+
+// rekeyWalletFile re-encrypts a wallet file using rekeyIterations PBKDF2 iterations if it uses fewer, and returns
+// its contents.
+func rekeyWalletFile(walletFn string, data, password []byte) []byte {
+	ew := &wallet.EncryptedWalletFile{}
+	checkErr(json.Unmarshal(data, ew))
+	if !ew.NeedsRekey(rekeyIterations) {
+		return data
+	}
+	oldIterations := ew.Secrets.KDFParams.Iterations
+	checkErr(ew.Rekey(password, rekeyIterations))
+	data = saveEncryptedWallet(walletFn, ew)
+	fmt.Fprintf(os.Stderr, "Re-encrypted %s using %d PBKDF2 iterations (was %d).\n",
+		walletFn, rekeyIterations, oldIterations)
+	return data
+}
+
+// newPasswordPolicy returns the policy for new wallet passwords, honoring --allow-weak-password.
+func newPasswordPolicy() wallet.PasswordPolicy {
+	policy := wallet.DefaultPasswordPolicy
+	policy.AllowWeak = allowWeakPassword
+	return policy
+}
+
 func init() {
 	rootCmd.AddCommand(walletCmd)
 	walletCmd.AddCommand(createCmd)
 	walletCmd.AddCommand(readCmd)
+	walletCmd.AddCommand(pubkeysCmd)
+	walletCmd.AddCommand(walletInfoCmd)
+	walletCmd.AddCommand(validateMnemonicCmd)
+	walletCmd.AddCommand(balanceCmd)
+	walletCmd.AddCommand(exportWatchOnlyCmd)
+	walletCmd.AddCommand(exportSeedCmd)
+	walletCmd.AddCommand(exportAddressesCmd)
+	walletCmd.AddCommand(splitMnemonicCmd)
+	walletCmd.AddCommand(combineSharesCmd)
+	walletCmd.AddCommand(changePasswordCmd)
+	walletCmd.AddCommand(addAccountsCmd)
+	walletCmd.AddCommand(labelCmd)
+	walletCmd.AddCommand(findAccountCmd)
+	walletCmd.AddCommand(verifyWalletCmd)
+	walletCmd.AddCommand(repairWalletCmd)
+	walletCmd.AddCommand(signMessageCmd)
+	walletCmd.AddCommand(verifyMessageCmd)
+	walletCmd.AddCommand(renameCmd)
+	walletCmd.AddCommand(listWalletsCmd)
+	walletCmd.AddCommand(multisigWalletCmd)
+	multisigWalletCmd.AddCommand(addMultisigCmd)
+	multisigWalletCmd.AddCommand(listMultisigsCmd)
+	walletCmd.AddCommand(registerWalletCmd)
+	walletCmd.AddCommand(importSmappCmd)
+	walletCmd.AddCommand(importKeyCmd)
+	walletCmd.AddCommand(exportSmappCmd)
+	walletCmd.AddCommand(ledgerDevicesCmd)
+	walletCmd.AddCommand(ledgerConfirmCmd)
 	readCmd.Flags().BoolVarP(&printPrivate, "private", "p", false, "Print private keys")
 	readCmd.Flags().BoolVarP(&printFull, "full", "f", false, "Print full keys (no abbreviation)")
 	readCmd.Flags().BoolVar(&printBase58, "base58", false, "Print keys in base58 (rather than hex)")
 	readCmd.Flags().BoolVar(&printParent, "parent", false, "Print parent key (not only child keys)")
-	readCmd.Flags().StringVar(&hrp, "hrp", types.NetworkHRP(), "Set human-readable address prefix")
-	readCmd.PersistentFlags().BoolVarP(&debug, "debug", "d", false, "enable debug mode")
+	readCmd.Flags().BoolVar(&printQR, "qr", false, "Draw account addresses (and the mnemonic with --private) as QR codes")
+	readCmd.Flags().IntSliceVar(&qrAccounts, "qr-account", nil, "Only draw the QR code of this account (repeatable)")
 	createCmd.Flags().BoolVarP(&useLedger, "ledger", "l", false, "Create a wallet using a Ledger device")
+	createCmd.Flags().StringVar(&ledgerDevice, "ledger-device", "",
+		"ID of the Ledger device to use if more than one is connected (see ledger-devices)")
+	addAccountsCmd.Flags().StringVar(&ledgerDevice, "ledger-device", "",
+		"ID of the Ledger device to use if more than one is connected (see ledger-devices)")
+	ledgerConfirmCmd.Flags().StringVar(&ledgerDevice, "ledger-device", "",
+		"ID of the Ledger device to use if more than one is connected (see ledger-devices)")
+	findAccountCmd.Flags().IntVar(&gapLimit, "gap-limit", wallet.DefaultGapLimit,
+		fmt.Sprintf("Number of accounts past the stored ones to derive and check (at most %d accounts in total)",
+			common.MaxAccountsPerWallet))
+	findAccountCmd.Flags().StringVar(&ledgerDevice, "ledger-device", "",
+		"ID of the Ledger device to use if more than one is connected (see ledger-devices)")
+	verifyWalletCmd.Flags().StringVar(&ledgerDevice, "ledger-device", "",
+		"ID of the Ledger device to use if more than one is connected (see ledger-devices)")
+	repairWalletCmd.Flags().StringVar(&ledgerDevice, "ledger-device", "",
+		"ID of the Ledger device to use if more than one is connected (see ledger-devices)")
+	repairWalletCmd.Flags().StringVar(&passphraseFile, "passphrase-file", "",
+		"Read the BIP-39 passphrase from this file instead of prompting for it (default $"+envPassphrase+")")
+	repairWalletCmd.Flags().BoolVar(&scanAccounts, "scan", false,
+		"Also restore the accounts used on the network, querying the node at --endpoint")
+	repairWalletCmd.Flags().IntVar(&gapLimit, "gap-limit", wallet.DefaultGapLimit,
+		"Number of consecutive unused accounts after which --scan stops")
+	signMessageCmd.Flags().StringVar(&signatureEncoding, "encoding", wallet.PubkeyEncodingHex,
+		"Signature encoding, hex or base64")
+	addMultisigCmd.Flags().Uint8VarP(&multisigRequired, "required", "k", 1, "Number of required signatures")
+	addMultisigCmd.Flags().BoolVar(&rawKeyOrder, "raw-order", false,
+		"Keep the public keys in the order given instead of sorting them")
+	signMessageCmd.Flags().BoolVar(&pickSigner, "pick", false,
+		"Choose the signing account from a menu instead of giving its index")
+	signMessageCmd.Flags().StringVar(&ledgerDevice, "ledger-device", "",
+		"ID of the Ledger device to use if more than one is connected (see ledger-devices)")
+	verifyMessageCmd.Flags().StringVar(&pubkeyEncoding, "pubkey-encoding", "",
+		"Encoding of the public key, hex or base64 (default detected)")
+	verifyMessageCmd.Flags().StringVar(&signatureEncoding, "encoding", wallet.PubkeyEncodingHex,
+		"Signature encoding, hex or base64")
+	pubkeysCmd.Flags().StringVarP(&outputFormat, "output", "o", outputTable, "Output format, table or json")
+	walletInfoCmd.Flags().StringVarP(&outputFormat, "output", "o", outputTable, "Output format, table or json")
+	walletInfoCmd.Flags().BoolVar(&infoDecrypt, "decrypt", false,
+		"Decrypt an encrypted wallet, asking for its password, to also count its accounts")
+	createCmd.Flags().IntVar(&mnemonicWords, "words", wallet.DefaultMnemonicWords,
+		"Number of words in a newly generated mnemonic (12, 15, 18, 21, or 24)")
+	createCmd.Flags().StringVar(&mnemonicFile, "mnemonic-file", "",
+		"Read the mnemonic from this file (or - for stdin) instead of prompting for it")
+	createCmd.Flags().StringVar(&passphraseFile, "passphrase-file", "",
+		"Read the BIP-39 passphrase from this file instead of prompting for it (default $"+envPassphrase+")")
+	exportAddressesCmd.Flags().StringVar(&passphraseFile, "passphrase-file", "",
+		"Read the BIP-39 passphrase from this file instead of prompting for it (default $"+envPassphrase+")")
+	exportSeedCmd.Flags().StringVar(&passphraseFile, "passphrase-file", "",
+		"Read the BIP-39 passphrase from this file instead of prompting for it (default $"+envPassphrase+")")
+	addAccountsCmd.Flags().StringVar(&passphraseFile, "passphrase-file", "",
+		"Read the BIP-39 passphrase from this file instead of prompting for it (default $"+envPassphrase+")")
+	verifyWalletCmd.Flags().StringVar(&passphraseFile, "passphrase-file", "",
+		"Read the BIP-39 passphrase from this file instead of prompting for it (default $"+envPassphrase+")")
+	findAccountCmd.Flags().StringVar(&passphraseFile, "passphrase-file", "",
+		"Read the BIP-39 passphrase from this file instead of prompting for it (default $"+envPassphrase+")")
+	createCmd.Flags().StringVar(&mnemonicLanguage, "language", wallet.LanguageEnglish,
+		"Mnemonic wordlist language ("+strings.Join(wallet.Languages(), ", ")+")")
+	validateMnemonicCmd.Flags().StringVar(&mnemonicLanguage, "language", wallet.LanguageEnglish,
+		"Mnemonic wordlist language ("+strings.Join(wallet.Languages(), ", ")+")")
+	combineSharesCmd.Flags().StringVar(&mnemonicLanguage, "language", wallet.LanguageEnglish,
+		"Mnemonic wordlist language ("+strings.Join(wallet.Languages(), ", ")+")")
+	splitMnemonicCmd.Flags().IntVar(&shareThreshold, "threshold", 2, "Number of shares required to restore the mnemonic")
+	splitMnemonicCmd.Flags().IntVar(&shareCount, "shares", 3, "Total number of shares")
+	exportSeedCmd.Flags().BoolVar(&understandSeedRisk, "i-understand-this-is-dangerous", false,
+		"Confirm that anyone who sees the seed controls all accounts of the wallet")
+	exportAddressesCmd.Flags().IntVar(&addressStart, "start", 0, "Account index of the first address")
+	exportAddressesCmd.Flags().IntVar(&addressCount, "count", 1, "Number of addresses")
+	exportAddressesCmd.Flags().StringVar(&addressFormat, "format", wallet.AddressFormatCSV,
+		"Output format, csv or jsonl")
+	exportAddressesCmd.Flags().BoolVar(&addressPubkeys, "pubkeys", false, "Include the public key of each address")
+	exportAddressesCmd.Flags().StringVar(&ledgerDevice, "ledger-device", "",
+		"ID of the Ledger device to use if more than one is connected (see ledger-devices)")
+	createCmd.Flags().StringVar(&walletName, "name", wallet.DefaultDisplayName, "Display name of the wallet")
+	createCmd.Flags().BoolVar(&noVerify, "no-verify", false,
+		"Don't ask for words of a newly generated mnemonic to check that it was written down")
+	createCmd.Flags().BoolVar(&printQR, "qr", false, "Also draw a newly generated mnemonic as a QR code")
+	createCmd.Flags().BoolVar(&noStoreMnemonic, "no-store-mnemonic", false,
+		"Don't store the mnemonic in the wallet file, only the keys of its accounts")
+	createCmd.Flags().BoolVar(&seedOnly, "seed-only", false,
+		"Restore the wallet from its hex-encoded BIP-39 seed instead of a mnemonic")
+	createCmd.Flags().StringVar(&pathTemplate, "path-template", "",
+		"HD path template of the accounts, e.g., m/44'/540'/{index}' (default "+wallet.DefaultPathTemplate+")")
+	createCmd.Flags().BoolVar(&encryptHardware, "encrypt", false,
+		"Encrypt the wallet file of a Ledger wallet using a password, to keep its addresses private")
+	for _, c := range []*cobra.Command{createCmd, importSmappCmd, importKeyCmd} {
+		c.Flags().StringVar(&newWalletFile, "file", "", "Path of the new wallet file (default a new file in "+
+			common.DotDirectory()+")")
+		c.Flags().BoolVar(&forceOverwrite, "force", false,
+			"Replace the wallet file if it already exists, after backing it up")
+		c.Flags().StringVar(&newWalletName, "as", "",
+			"Save the wallet in "+common.WalletsDirectory()+" under this short name instead of --file")
+	}
+	importSmappCmd.Flags().StringVar(&kdf, "kdf", wallet.KDFPbkdf2,
+		"Key derivation function used to encrypt the wallet file (PBKDF2, scrypt, or argon2id)")
+	importSmappCmd.Flags().BoolVar(&allowWeakPassword, "allow-weak-password", false,
+		"Skip the password strength check (not recommended)")
+	importKeyCmd.Flags().StringVar(&importKeyFile, "key-file", "",
+		"Save the key to this standalone key file instead of a wallet file")
+	importKeyCmd.Flags().StringVar(&walletName, "name", wallet.DefaultDisplayName, "Display name of the wallet")
+	importKeyCmd.Flags().StringVar(&kdf, "kdf", wallet.KDFPbkdf2,
+		"Key derivation function used to encrypt the wallet file (PBKDF2, scrypt, or argon2id)")
+	importKeyCmd.Flags().BoolVar(&allowWeakPassword, "allow-weak-password", false,
+		"Skip the password strength check (not recommended)")
+	exportSmappCmd.Flags().BoolVar(&allowWeakPassword, "allow-weak-password", false,
+		"Skip the password strength check (not recommended)")
+	createCmd.Flags().BoolVar(&accountsLater, "accounts-later", false,
+		"Allow creating a wallet without any accounts, to add them later")
+	createCmd.Flags().IntVar(&pbkdf2Iterations, "pbkdf2-iterations", wallet.Pbkdf2Iterations,
+		"Number of PBKDF2 iterations used to encrypt the wallet file")
+	walletCmd.PersistentFlags().IntVar(&rekeyIterations, "rekey-iterations", 0,
+		"Re-encrypt wallet files that use fewer PBKDF2 iterations than this when opening them")
+	createCmd.Flags().StringVar(&kdf, "kdf", wallet.KDFPbkdf2,
+		"Key derivation function used to encrypt the wallet file (PBKDF2, scrypt, or argon2id)")
+	createCmd.Flags().StringVar(&rollsKind, "rolls", "",
+		"Generate the new mnemonic from dice rolls or coin flips entered by hand (dice or coin)")
+	createCmd.Flags().BoolVar(&scanAccounts, "scan", false,
+		"Restore the accounts used on the network, querying the node at --endpoint")
+	createCmd.Flags().IntVar(&gapLimit, "gap-limit", wallet.DefaultGapLimit,
+		"Number of consecutive unused accounts after which --scan stops")
+	createCmd.Flags().BoolVar(&dryRun, "dry-run", false,
+		"Print the addresses of the new wallet without writing a wallet file")
+	createCmd.Flags().BoolVar(&allowWeakPassword, "allow-weak-password", false,
+		"Skip the password strength check (not recommended)")
+	changePasswordCmd.Flags().BoolVar(&allowWeakPassword, "allow-weak-password", false,
+		"Skip the password strength check (not recommended)")
+	createCmd.Flags().Uint32Var(&argon2Time, "argon2-time", wallet.Argon2Time, "Number of argon2id passes")
+	createCmd.Flags().Uint32Var(&argon2Memory, "argon2-memory", wallet.Argon2Memory, "Argon2id memory in KiB")
+	createCmd.Flags().Uint8Var(&argon2Threads, "argon2-threads", wallet.Argon2Threads, "Argon2id parallelism")
 }
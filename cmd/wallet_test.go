@@ -0,0 +1,117 @@
+package cmd
+
+import (
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/spacemeshos/smcli/common"
+	"github.com/spacemeshos/smcli/wallet"
+)
+
+const (
+	// testMnemonic is the mnemonic of the accounts of ../wallet/testdata/wallet.json.
+	testMnemonic = "film theme cheese broken kingdom destroy inch ready wear inspire shove pudding"
+	// testPassword passes the password strength check.
+	testPassword = "correct horse battery staple 42!"
+)
+
+// execute runs smcli with args, which exits the test if the command fails.
+func execute(t *testing.T, args ...string) {
+	rootCmd.SetArgs(args)
+	t.Cleanup(func() { rootCmd.SetArgs(nil) })
+	require.NoError(t, rootCmd.Execute())
+}
+
+// envTestArgs holds the arguments of smcli when the test binary is run as smcli by exitCodeOf, separated by
+// newlines.
+const envTestArgs = "SMCLI_TEST_ARGS"
+
+// TestMain runs smcli instead of the tests if envTestArgs is set.
+func TestMain(m *testing.M) {
+	if args, ok := os.LookupEnv(envTestArgs); ok {
+		rootCmd.SetArgs(strings.Split(args, "\n"))
+		Execute()
+		os.Exit(0)
+	}
+	os.Exit(m.Run())
+}
+
+// exitCodeOf runs smcli with args in a child process, since failing commands exit, and returns its exit code.
+func exitCodeOf(t *testing.T, args ...string) int {
+	cmd := exec.Command(os.Args[0])
+	cmd.Env = append(os.Environ(), envTestArgs+"="+strings.Join(args, "\n"))
+	err := cmd.Run()
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) {
+		return exitErr.ExitCode()
+	}
+	require.NoError(t, err)
+	return 0
+}
+
+func TestCreateFirstNamedWallet(t *testing.T) {
+	// a fresh HOME has no ~/.spacemesh, let alone a managed wallet directory
+	t.Setenv("HOME", t.TempDir())
+	t.Setenv(envWalletPassword, testPassword)
+	t.Setenv(envPassphrase, "")
+	mnemonicFn := filepath.Join(t.TempDir(), "mnemonic")
+	require.NoError(t, os.WriteFile(mnemonicFn, []byte(testMnemonic+"\n"), 0o600))
+
+	execute(t, "wallet", "create", "1", "--as", "main", "--mnemonic-file", mnemonicFn)
+	t.Cleanup(func() { newWalletName, mnemonicFile = "", "" })
+
+	walletFn := filepath.Join(common.WalletsDirectory(), "main.json")
+	require.FileExists(t, walletFn)
+	require.Equal(t, walletFn, walletFile("main"))
+	w, _ := openWallet(walletFn)
+	require.Equal(t, []string{"sm1qqqqqqz9rf583slhn38g6q6a562ctltv9fv5w8q2gdz9k"}, w.Addresses("sm"))
+}
+
+func TestOpenWalletPasswordFromEnv(t *testing.T) {
+	// nothing can be read from stdin, so the password must come from the environment
+	stdin := os.Stdin
+	t.Cleanup(func() { os.Stdin = stdin })
+	devNull, err := os.Open(os.DevNull)
+	require.NoError(t, err)
+	defer devNull.Close()
+	os.Stdin = devNull
+
+	t.Setenv(envWalletPassword, "password")
+	w, wk := openWallet(filepath.Join("..", "wallet", "testdata", "wallet.json"))
+	require.Equal(t, testMnemonic, w.Mnemonic())
+	require.Len(t, w.Secrets.Accounts, 2)
+	require.NotEqual(t, wallet.WalletKey{}, wk)
+}
+
+func TestVerifyMessageExitCodes(t *testing.T) {
+	pubkey := "e05123326306be26b61d5b1f29c8752316c15c112879fd73394e667d03969447"
+	require.Equal(t, common.ExitBadInput, exitCodeOf(t, "wallet", "verify-message", "zz", "msg", "00"))
+	require.Equal(t, common.ExitBadInput, exitCodeOf(t, "wallet", "verify-message", "00", "msg", "00"))
+	require.Equal(t, common.ExitBadInput, exitCodeOf(t, "wallet", "verify-message", pubkey, "msg", "zz"))
+	require.Equal(t, common.ExitBadInput,
+		exitCodeOf(t, "wallet", "verify-message", pubkey, "msg", "not base64!", "--encoding", "base64"))
+}
+
+func TestVerifyMessagePubkeyEncodings(t *testing.T) {
+	w, err := wallet.NewMultiWalletFromMnemonic(testMnemonic, 1)
+	require.NoError(t, err)
+	sig, err := w.SignMessage(0, []byte("hello"))
+	require.NoError(t, err)
+	pubkey := w.Secrets.Accounts[0].Public
+	for _, key := range []string{
+		hex.EncodeToString(pubkey),
+		"0x" + hex.EncodeToString(pubkey),
+		base64.StdEncoding.EncodeToString(pubkey),
+	} {
+		require.Equal(t, common.ExitOK, exitCodeOf(t, "wallet", "verify-message", key, "hello", hex.EncodeToString(sig)),
+			key)
+	}
+}
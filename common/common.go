@@ -1,6 +1,9 @@
 package common
 
 import (
+	"errors"
+	"fmt"
+	"io"
 	"os"
 	"path/filepath"
 	"time"
@@ -12,7 +15,107 @@ const (
 	MaxAccountsPerWallet = 128
 )
 
+// Known networks.
+const (
+	NetworkMainnet = "mainnet"
+	NetworkTestnet = "testnet"
+)
+
+// NetworkHRPs maps each known network to the human-readable part (HRP) of its bech32 addresses.
+var NetworkHRPs = map[string]string{
+	NetworkMainnet: "sm",
+	NetworkTestnet: "stest",
+}
+
+// ResolveHRP returns the HRP used to render and parse addresses. An explicit HRP takes precedence over the
+// HRP of the named network.
+func ResolveHRP(network, hrp string) (string, error) {
+	if hrp != "" {
+		return hrp, nil
+	}
+	if networkHRP, ok := NetworkHRPs[network]; ok {
+		return networkHRP, nil
+	}
+	return "", fmt.Errorf("unknown network %q, must be one of %q or %q, or set an explicit HRP",
+		network, NetworkMainnet, NetworkTestnet)
+}
+
+// TimeFormat is the format of the timestamps stored in wallet and key files: RFC 3339 with milliseconds, always
+// in UTC, e.g., "2023-07-12T10:00:00.000Z".
+const TimeFormat = "2006-01-02T15:04:05.000Z07:00"
+
+// legacyTimeFormats are the formats of timestamps written by older versions of smcli and by Smapp, which used
+// dashes rather than colons, accepted by ParseTimeString.
+var legacyTimeFormats = []string{
+	"2006-01-02T15-04-05.000Z",
+	"2006-01-02T15-04-05Z",
+	"2006-01-02 15:04:05",
+	"2006-01-02",
+}
+
+// NowTimeString returns the current time in TimeFormat.
 func NowTimeString() string {
+	return FormatTime(time.Now())
+}
+
+// FormatTime returns t in TimeFormat, in UTC.
+func FormatTime(t time.Time) string {
+	return t.UTC().Format(TimeFormat)
+}
+
+// ParseTimeString parses a timestamp written by NowTimeString, or any other RFC 3339 timestamp. Timestamps in the
+// formats used by older versions of smcli and by Smapp are accepted too, and are in UTC.
+func ParseTimeString(s string) (time.Time, error) {
+	if t, err := time.Parse(time.RFC3339Nano, s); err == nil {
+		return t, nil
+	}
+	for _, layout := range legacyTimeFormats {
+		if t, err := time.Parse(layout, s); err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("unrecognized timestamp %q, expected RFC 3339", s)
+}
+
+// NormalizeTimeString rewrites a timestamp accepted by ParseTimeString in TimeFormat. Timestamps that can't be
+// parsed are returned unchanged, so that nothing is lost.
+func NormalizeTimeString(s string) string {
+	t, err := ParseTimeString(s)
+	if err != nil {
+		return s
+	}
+	return FormatTime(t)
+}
+
+// FormatAge describes how long ago something happened, given how much time passed since, in the largest whole
+// unit, e.g., "3 days ago".
+func FormatAge(d time.Duration) string {
+	const day = 24 * time.Hour
+	plural := func(n int64, unit string) string {
+		if n == 1 {
+			return fmt.Sprintf("1 %s ago", unit)
+		}
+		return fmt.Sprintf("%d %ss ago", n, unit)
+	}
+	switch {
+	case d < 0:
+		return "in the future"
+	case d < time.Minute:
+		return "just now"
+	case d < time.Hour:
+		return plural(int64(d/time.Minute), "minute")
+	case d < day:
+		return plural(int64(d/time.Hour), "hour")
+	case d < 365*day:
+		return plural(int64(d/day), "day")
+	default:
+		return plural(int64(d/(365*day)), "year")
+	}
+}
+
+// fileTimeString returns the current time for use in file names, in UTC. It uses dashes rather than colons, which
+// aren't allowed in file names on Windows.
+func fileTimeString() string {
 	return time.Now().UTC().Format("2006-01-02T15-04-05.000") + "Z"
 }
 
@@ -23,6 +126,9 @@ func NowTimeString() string {
 // │       └── config.json
 // ├── logs
 // │   └── go-spacemesh.log
+// ├── wallets
+// │   ├── index.json
+// │   └── [ name ].json
 // ├── config.yaml
 // └── state.json
 
@@ -43,6 +149,78 @@ func StateFile() string {
 	return filepath.Join(DotDirectory(), "state.json")
 }
 
+// WalletsDirectory returns the managed wallet directory, where wallet files can be kept under a short name.
+func WalletsDirectory() string {
+	return filepath.Join(DotDirectory(), "wallets")
+}
+
 func WalletFile() string {
-	return filepath.Join(DotDirectory(), "wallet_"+NowTimeString()+".json")
+	return filepath.Join(DotDirectory(), "wallet_"+fileTimeString()+".json")
+}
+
+// BackupFileName returns the name of a backup of the file at path, which is unique to the moment it's made.
+func BackupFileName(path string) string {
+	return path + ".bak-" + fileTimeString()
+}
+
+// PrepareNewFile makes sure that a new file can be created at path without destroying an existing one. If a file
+// already exists there, an error wrapping os.ErrExist is returned unless force is set: the existing file is then
+// renamed to a backup (see BackupFileName), whose name is returned so that it can still be recovered. The returned
+// name is empty if nothing was backed up.
+func PrepareNewFile(path string, force bool) (string, error) {
+	_, err := os.Lstat(path)
+	switch {
+	case errors.Is(err, os.ErrNotExist):
+		return "", nil
+	case err != nil:
+		return "", err
+	case !force:
+		return "", fmt.Errorf("%s: %w", path, os.ErrExist)
+	}
+	backup := BackupFileName(path)
+	if _, err := os.Lstat(backup); err == nil {
+		return "", fmt.Errorf("backup %s: %w", backup, os.ErrExist)
+	}
+	if err := os.Rename(path, backup); err != nil {
+		return "", fmt.Errorf("error backing up %s: %w", path, err)
+	}
+	return backup, nil
+}
+
+// WriteFileAtomic replaces the file at path, or creates it, with what write writes, such that the file is either
+// left as it was or completely written even if the process is interrupted: write writes to a temporary file in
+// the same directory, which is synced to disk and then renamed over path. The file is created with mode perm.
+func WriteFileAtomic(path string, perm os.FileMode, write func(io.Writer) error) (err error) {
+	dir := filepath.Dir(path)
+	tmp, err := os.CreateTemp(dir, "."+filepath.Base(path)+".tmp-*")
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err != nil {
+			tmp.Close()
+			os.Remove(tmp.Name())
+		}
+	}()
+	if err := tmp.Chmod(perm); err != nil {
+		return err
+	}
+	if err := write(tmp); err != nil {
+		return err
+	}
+	if err := tmp.Sync(); err != nil {
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return err
+	}
+	// make the rename itself durable, where directories can be synced
+	if d, err := os.Open(dir); err == nil {
+		d.Sync()
+		d.Close()
+	}
+	return nil
 }
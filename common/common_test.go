@@ -0,0 +1,168 @@
+package common
+
+import (
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestResolveHRP(t *testing.T) {
+	hrp, err := ResolveHRP(NetworkMainnet, "")
+	require.NoError(t, err)
+	require.Equal(t, "sm", hrp)
+
+	hrp, err = ResolveHRP(NetworkTestnet, "")
+	require.NoError(t, err)
+	require.Equal(t, "stest", hrp)
+
+	// an explicit HRP always wins
+	hrp, err = ResolveHRP(NetworkMainnet, "custom")
+	require.NoError(t, err)
+	require.Equal(t, "custom", hrp)
+	hrp, err = ResolveHRP("devnet", "custom")
+	require.NoError(t, err)
+	require.Equal(t, "custom", hrp)
+
+	_, err = ResolveHRP("devnet", "")
+	require.Error(t, err)
+}
+
+func TestPrepareNewFile(t *testing.T) {
+	dir := t.TempDir()
+
+	// new path
+	path := filepath.Join(dir, "wallet.json")
+	backup, err := PrepareNewFile(path, false)
+	require.NoError(t, err)
+	require.Empty(t, backup)
+	backup, err = PrepareNewFile(path, true)
+	require.NoError(t, err)
+	require.Empty(t, backup)
+
+	// refuse to clobber
+	require.NoError(t, os.WriteFile(path, []byte("old keys"), 0o600))
+	_, err = PrepareNewFile(path, false)
+	require.ErrorIs(t, err, os.ErrExist)
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+	require.Equal(t, "old keys", string(data))
+
+	// force: the old file is moved out of the way first
+	backup, err = PrepareNewFile(path, true)
+	require.NoError(t, err)
+	require.NotEmpty(t, backup)
+	require.Equal(t, dir, filepath.Dir(backup))
+	_, err = os.Stat(path)
+	require.ErrorIs(t, err, os.ErrNotExist)
+	data, err = os.ReadFile(backup)
+	require.NoError(t, err)
+	require.Equal(t, "old keys", string(data))
+	info, err := os.Stat(backup)
+	require.NoError(t, err)
+	require.Equal(t, os.FileMode(0o600), info.Mode().Perm())
+}
+
+func TestWriteFileAtomic(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "wallet.json")
+	writeString := func(s string) func(io.Writer) error {
+		return func(w io.Writer) error {
+			_, err := io.WriteString(w, s)
+			return err
+		}
+	}
+	requireDir := func(expected ...string) {
+		entries, err := os.ReadDir(dir)
+		require.NoError(t, err)
+		var names []string
+		for _, e := range entries {
+			names = append(names, e.Name())
+		}
+		require.Equal(t, expected, names)
+	}
+
+	require.NoError(t, WriteFileAtomic(path, 0o600, writeString("old keys")))
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+	require.Equal(t, "old keys", string(data))
+	info, err := os.Stat(path)
+	require.NoError(t, err)
+	require.Equal(t, os.FileMode(0o600), info.Mode().Perm())
+
+	// interrupted halfway through: the original is intact and the partial file is cleaned up
+	interrupted := errors.New("interrupted")
+	err = WriteFileAtomic(path, 0o600, func(w io.Writer) error {
+		_, err := io.WriteString(w, "new k")
+		require.NoError(t, err)
+		return interrupted
+	})
+	require.ErrorIs(t, err, interrupted)
+	data, err = os.ReadFile(path)
+	require.NoError(t, err)
+	require.Equal(t, "old keys", string(data))
+	requireDir("wallet.json")
+
+	require.NoError(t, WriteFileAtomic(path, 0o600, writeString("new keys")))
+	data, err = os.ReadFile(path)
+	require.NoError(t, err)
+	require.Equal(t, "new keys", string(data))
+	requireDir("wallet.json")
+
+	// the directory must exist
+	require.Error(t, WriteFileAtomic(filepath.Join(dir, "missing", "wallet.json"), 0o600, writeString("")))
+}
+
+func TestTimeString(t *testing.T) {
+	// NowTimeString round-trips through the parser, to the millisecond
+	before := time.Now().Truncate(time.Millisecond)
+	s := NowTimeString()
+	parsed, err := ParseTimeString(s)
+	require.NoError(t, err)
+	require.False(t, parsed.Before(before))
+	require.WithinDuration(t, time.Now(), parsed, time.Second)
+	require.Equal(t, s, FormatTime(parsed))
+	require.Equal(t, s, NormalizeTimeString(s))
+	_, err = time.Parse(time.RFC3339, s)
+	require.NoError(t, err)
+	require.True(t, strings.HasSuffix(s, "Z"))
+
+	want := time.Date(2023, 7, 12, 10, 0, 0, 0, time.UTC)
+	for _, s := range []string{
+		"2023-07-12T10:00:00.000Z",
+		"2023-07-12T12:00:00+02:00",
+		// older versions of smcli and Smapp used dashes
+		"2023-07-12T10-00-00.000Z",
+		"2023-07-12T10-00-00Z",
+	} {
+		parsed, err := ParseTimeString(s)
+		require.NoError(t, err, s)
+		require.True(t, want.Equal(parsed), s)
+		require.Equal(t, "2023-07-12T10:00:00.000Z", NormalizeTimeString(s), s)
+	}
+
+	for _, s := range []string{"", "yesterday", "2023-13-01T00:00:00Z"} {
+		_, err := ParseTimeString(s)
+		require.Error(t, err, s)
+		require.Equal(t, s, NormalizeTimeString(s), s)
+	}
+}
+
+func TestFormatAge(t *testing.T) {
+	for d, want := range map[time.Duration]string{
+		-time.Hour:            "in the future",
+		0:                     "just now",
+		time.Minute:           "1 minute ago",
+		59 * time.Minute:      "59 minutes ago",
+		25 * time.Hour:        "1 day ago",
+		400 * 24 * time.Hour:  "1 year ago",
+		1000 * 24 * time.Hour: "2 years ago",
+	} {
+		require.Equal(t, want, FormatAge(d), d)
+	}
+}
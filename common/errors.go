@@ -0,0 +1,100 @@
+package common
+
+import (
+	"errors"
+	"io/fs"
+)
+
+// Exit codes of smcli. They're stable so that scripts can tell the categories of errors apart: errors belong to a
+// category if they match one of the category errors below using errors.Is, see ExitCode.
+const (
+	ExitOK = 0
+	// ExitFailure is the exit code of errors that don't belong to any category.
+	ExitFailure = 1
+	// ExitBadInput is the exit code of ErrBadInput. It's also the exit code when the user declines to confirm an
+	// operation, e.g., signing a transaction, since nothing was done.
+	ExitBadInput = 2
+	// ExitAuth is the exit code of ErrAuth.
+	ExitAuth = 3
+	// ExitNetwork is the exit code of ErrNetwork.
+	ExitNetwork = 4
+	// ExitDevice is the exit code of ErrDevice.
+	ExitDevice = 5
+)
+
+// Categories of errors, which determine the exit code of smcli. Errors are put in a category using NewError or
+// WithCategory.
+var (
+	// ErrBadInput is the category of errors caused by invalid arguments, flags, or input, such as a malformed
+	// address or an invalid mnemonic, and of missing files. Exits with ExitBadInput.
+	ErrBadInput = errors.New("bad input")
+	// ErrAuth is the category of errors caused by a wrong password or passphrase, or a token that the node
+	// refuses. Exits with ExitAuth.
+	ErrAuth = errors.New("authentication failed")
+	// ErrNetwork is the category of errors talking to the node, e.g., because it can't be reached. Exits with
+	// ExitNetwork.
+	ErrNetwork = errors.New("network error")
+	// ErrDevice is the category of errors talking to a hardware wallet, e.g., because it isn't connected or the
+	// request was rejected on the device. Exits with ExitDevice.
+	ErrDevice = errors.New("device error")
+)
+
+// exitCodes maps each category to its exit code, in the order in which they're checked.
+var exitCodes = []struct {
+	category error
+	code     int
+}{
+	{ErrAuth, ExitAuth},
+	{ErrDevice, ExitDevice},
+	{ErrNetwork, ExitNetwork},
+	{ErrBadInput, ExitBadInput},
+	{fs.ErrNotExist, ExitBadInput},
+}
+
+// categorizedError is an error that belongs to a category.
+type categorizedError struct {
+	category error
+	err      error
+}
+
+func (e *categorizedError) Error() string {
+	return e.err.Error()
+}
+
+func (e *categorizedError) Unwrap() error {
+	return e.err
+}
+
+func (e *categorizedError) Is(target error) bool {
+	return target == e.category
+}
+
+// NewError returns an error with the given message that belongs to category, one of the category errors. It's
+// meant for sentinel errors: errors wrapping it match both it and the category using errors.Is.
+func NewError(category error, msg string) error {
+	return &categorizedError{category: category, err: errors.New(msg)}
+}
+
+// WithCategory puts err in category, one of the category errors, keeping its message. It returns nil if err is
+// nil.
+func WithCategory(category, err error) error {
+	if err == nil {
+		return nil
+	}
+	return &categorizedError{category: category, err: err}
+}
+
+// ExitCode returns the exit code for err: ExitOK if it's nil, the exit code of its category if it belongs to
+// one, or else ExitFailure. An error that belongs to several categories gets the exit code of the first one of
+// authentication, device, network, and bad input errors.
+func ExitCode(err error) int {
+	if err == nil {
+		return ExitOK
+	}
+	for _, c := range exitCodes {
+		if errors.Is(err, c.category) {
+			return c.code
+		}
+	}
+	return ExitFailure
+}
@@ -0,0 +1,45 @@
+package common
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestExitCode(t *testing.T) {
+	require.Equal(t, ExitOK, ExitCode(nil))
+	require.Equal(t, ExitFailure, ExitCode(errors.New("something went wrong")))
+
+	for category, code := range map[error]int{
+		ErrBadInput: ExitBadInput,
+		ErrAuth:     ExitAuth,
+		ErrNetwork:  ExitNetwork,
+		ErrDevice:   ExitDevice,
+	} {
+		sentinel := NewError(category, "sentinel")
+		require.Equal(t, "sentinel", sentinel.Error())
+		require.ErrorIs(t, sentinel, category)
+		require.Equal(t, code, ExitCode(category))
+		require.Equal(t, code, ExitCode(sentinel))
+
+		// the category survives wrapping, and the sentinel can still be told apart
+		wrapped := fmt.Errorf("context: %w", sentinel)
+		require.Equal(t, code, ExitCode(wrapped))
+		require.ErrorIs(t, wrapped, sentinel)
+		require.NotErrorIs(t, wrapped, NewError(category, "sentinel"))
+
+		categorized := WithCategory(category, errors.New("plain"))
+		require.Equal(t, "plain", categorized.Error())
+		require.Equal(t, code, ExitCode(fmt.Errorf("context: %w", categorized)))
+	}
+	require.NoError(t, WithCategory(ErrAuth, nil))
+
+	_, err := os.Open("does-not-exist")
+	require.Equal(t, ExitBadInput, ExitCode(err))
+
+	// the most specific category wins
+	require.Equal(t, ExitDevice, ExitCode(WithCategory(ErrBadInput, NewError(ErrDevice, "device error"))))
+}
@@ -0,0 +1,87 @@
+package common
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"sync/atomic"
+
+	"golang.org/x/exp/slog"
+)
+
+// Log levels accepted by ParseLogLevel.
+const (
+	LogLevelDebug = "debug"
+	LogLevelInfo  = "info"
+	LogLevelWarn  = "warn"
+	LogLevelError = "error"
+)
+
+// Redacted replaces the value of sensitive log attributes.
+const Redacted = "[REDACTED]"
+
+// sensitiveKeys are the substrings of the keys of log attributes whose values are always redacted, whatever the
+// type of the value. Secret types should also implement slog.LogValuer, so that they're redacted under any key.
+var sensitiveKeys = []string{"password", "passphrase", "mnemonic", "seed", "private", "secret", "token"}
+
+var logger atomic.Value
+
+func init() {
+	SetLogger(NewLogger(os.Stderr, slog.LevelWarn))
+}
+
+// Logger returns the logger used by smcli, which by default writes warnings and errors to stderr.
+func Logger() *slog.Logger {
+	return logger.Load().(*slog.Logger)
+}
+
+// SetLogger replaces the logger used by smcli.
+func SetLogger(l *slog.Logger) {
+	logger.Store(l)
+}
+
+// NewLogger returns a logger that writes the messages at or above level to w, as text. The values of attributes
+// whose keys look sensitive, such as "password" or "mnemonic", are redacted. Timestamps are left out since the
+// logs are meant to be read by the user running the command.
+func NewLogger(w io.Writer, level slog.Leveler) *slog.Logger {
+	return slog.New(slog.NewTextHandler(w, &slog.HandlerOptions{
+		Level: level,
+		ReplaceAttr: func(groups []string, a slog.Attr) slog.Attr {
+			if len(groups) == 0 && a.Key == slog.TimeKey {
+				return slog.Attr{}
+			}
+			if IsSensitiveLogKey(a.Key) {
+				return slog.String(a.Key, Redacted)
+			}
+			return a
+		},
+	}))
+}
+
+// IsSensitiveLogKey reports whether the values of log attributes with the given key are redacted.
+func IsSensitiveLogKey(key string) bool {
+	key = strings.ToLower(key)
+	for _, s := range sensitiveKeys {
+		if strings.Contains(key, s) {
+			return true
+		}
+	}
+	return false
+}
+
+// ParseLogLevel parses one of the LogLevel constants.
+func ParseLogLevel(s string) (slog.Level, error) {
+	switch strings.ToLower(s) {
+	case LogLevelDebug:
+		return slog.LevelDebug, nil
+	case LogLevelInfo:
+		return slog.LevelInfo, nil
+	case LogLevelWarn, "warning":
+		return slog.LevelWarn, nil
+	case LogLevelError:
+		return slog.LevelError, nil
+	}
+	return 0, fmt.Errorf("unknown log level %q, must be one of %s, %s, %s, or %s",
+		s, LogLevelDebug, LogLevelInfo, LogLevelWarn, LogLevelError)
+}
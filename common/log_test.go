@@ -0,0 +1,59 @@
+package common
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"golang.org/x/exp/slog"
+)
+
+func TestParseLogLevel(t *testing.T) {
+	for s, level := range map[string]slog.Level{
+		LogLevelDebug: slog.LevelDebug,
+		LogLevelInfo:  slog.LevelInfo,
+		LogLevelWarn:  slog.LevelWarn,
+		"WARNING":     slog.LevelWarn,
+		LogLevelError: slog.LevelError,
+	} {
+		parsed, err := ParseLogLevel(s)
+		require.NoError(t, err)
+		require.Equal(t, level, parsed)
+	}
+	_, err := ParseLogLevel("trace")
+	require.Error(t, err)
+}
+
+func TestNewLoggerLevel(t *testing.T) {
+	buf := &bytes.Buffer{}
+	l := NewLogger(buf, slog.LevelInfo)
+	l.Debug("hidden")
+	l.Info("shown", "n", 1)
+	require.NotContains(t, buf.String(), "hidden")
+	require.Contains(t, buf.String(), "msg=shown n=1")
+	require.NotContains(t, buf.String(), "time=")
+}
+
+func TestNewLoggerRedactsSensitiveKeys(t *testing.T) {
+	buf := &bytes.Buffer{}
+	l := NewLogger(buf, slog.LevelDebug)
+	l.Debug("secrets",
+		"password", "hunter2",
+		"walletPassword", []byte("hunter3"),
+		"mnemonic", "film theme cheese",
+		"seed", []byte{1, 2, 3},
+		"privateKey", "deadbeef",
+		"token", "letmein",
+		slog.Group("endpoint", slog.String("address", "localhost:9092"), slog.String("token", "letmein2")),
+	)
+	out := buf.String()
+	for _, secret := range []string{"hunter2", "hunter3", "film", "deadbeef", "letmein", "[1 2 3]"} {
+		require.NotContains(t, out, secret)
+	}
+	require.Contains(t, out, "password="+Redacted)
+	require.Contains(t, out, "endpoint.address=localhost:9092")
+	require.Contains(t, out, "endpoint.token="+Redacted)
+
+	require.True(t, IsSensitiveLogKey("BIP39Passphrase"))
+	require.False(t, IsSensitiveLogKey("publicKey"))
+}
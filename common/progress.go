@@ -0,0 +1,135 @@
+package common
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"golang.org/x/term"
+)
+
+// Progress indicators are drawn on a single line that's redrawn in place, so they're only drawn on terminals:
+// redirected output, e.g., to a file or a pipe, and JSON output never contain them. A task that finishes within
+// progressDelay isn't drawn at all, so that fast operations don't flicker.
+var (
+	progressDelay    = 250 * time.Millisecond
+	progressInterval = 100 * time.Millisecond
+)
+
+var spinnerFrames = []string{"|", "/", "-", `\`}
+
+var progress atomic.Value
+
+func init() {
+	SetProgress(NewProgressMeter(io.Discard, true))
+}
+
+// Progress returns the progress meter used by smcli, which by default draws nothing.
+func Progress() *ProgressMeter {
+	return progress.Load().(*ProgressMeter)
+}
+
+// SetProgress replaces the progress meter used by smcli.
+func SetProgress(p *ProgressMeter) {
+	progress.Store(p)
+}
+
+// ProgressMeter draws the progress of long operations, such as deriving many accounts or a wallet key.
+type ProgressMeter struct {
+	w       io.Writer
+	enabled bool
+}
+
+// NewProgressMeter returns a meter that draws on w if it's a terminal and quiet isn't set, and otherwise draws
+// nothing.
+func NewProgressMeter(w io.Writer, quiet bool) *ProgressMeter {
+	return &ProgressMeter{w: w, enabled: !quiet && isTerminal(w)}
+}
+
+// isTerminal reports whether w is a terminal.
+func isTerminal(w io.Writer) bool {
+	f, ok := w.(*os.File)
+	return ok && term.IsTerminal(int(f.Fd()))
+}
+
+// Enabled reports whether the meter draws anything.
+func (p *ProgressMeter) Enabled() bool {
+	return p.enabled
+}
+
+// Start starts drawing the progress of a task described by what, e.g., "Deriving accounts", which takes total
+// steps, reported using Add, or an unknown number of steps if total is zero, in which case a spinner is drawn.
+// The task must be ended using Done. It's safe to call Add from several goroutines.
+func (p *ProgressMeter) Start(what string, total int) *ProgressTask {
+	t := &ProgressTask{meter: p, what: what, total: total}
+	if !p.enabled {
+		return t
+	}
+	t.stop = make(chan struct{})
+	t.wg.Add(1)
+	go t.draw()
+	return t
+}
+
+// ProgressTask is a task whose progress is drawn by a ProgressMeter, see Start.
+type ProgressTask struct {
+	// done is first so that it's 64-bit aligned for atomic access on 32-bit platforms.
+	done  int64
+	meter *ProgressMeter
+	what  string
+	total int
+
+	stop     chan struct{}
+	stopOnce sync.Once
+	wg       sync.WaitGroup
+}
+
+// Add reports that n more steps of the task are done.
+func (t *ProgressTask) Add(n int) {
+	atomic.AddInt64(&t.done, int64(n))
+}
+
+// Done ends the task and erases its progress.
+func (t *ProgressTask) Done() {
+	if t.stop == nil {
+		return
+	}
+	t.stopOnce.Do(func() { close(t.stop) })
+	t.wg.Wait()
+}
+
+// draw redraws the progress of the task until it's done, starting once it's taken progressDelay.
+func (t *ProgressTask) draw() {
+	defer t.wg.Done()
+	select {
+	case <-t.stop:
+		return
+	case <-time.After(progressDelay):
+	}
+	ticker := time.NewTicker(progressInterval)
+	defer ticker.Stop()
+	for frame := 0; ; frame++ {
+		fmt.Fprintf(t.meter.w, "\r\033[K%s", t.line(frame))
+		select {
+		case <-t.stop:
+			fmt.Fprint(t.meter.w, "\r\033[K")
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// line returns the progress line of the task for the given frame of the spinner.
+func (t *ProgressTask) line(frame int) string {
+	if t.total <= 0 {
+		return fmt.Sprintf("%s %s", spinnerFrames[frame%len(spinnerFrames)], t.what)
+	}
+	done := int(atomic.LoadInt64(&t.done))
+	if done > t.total {
+		done = t.total
+	}
+	return fmt.Sprintf("%s %d%% (%d/%d)", t.what, done*100/t.total, done, t.total)
+}
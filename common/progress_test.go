@@ -0,0 +1,68 @@
+package common
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestProgressSuppressedWhenNotInteractive(t *testing.T) {
+	f, err := os.Create(filepath.Join(t.TempDir(), "out"))
+	require.NoError(t, err)
+	defer f.Close()
+	buf := &bytes.Buffer{}
+
+	prevDelay := progressDelay
+	progressDelay = 0
+	t.Cleanup(func() { progressDelay = prevDelay })
+	for _, p := range []*ProgressMeter{
+		NewProgressMeter(buf, false),
+		NewProgressMeter(f, false),
+		NewProgressMeter(os.Stderr, true),
+		Progress(),
+	} {
+		require.False(t, p.Enabled())
+		task := p.Start("Deriving accounts", 10)
+		task.Add(5)
+		time.Sleep(2 * progressInterval)
+		task.Done()
+		p.Start("Deriving the wallet key", 0).Done()
+	}
+	require.Empty(t, buf.String())
+	info, err := f.Stat()
+	require.NoError(t, err)
+	require.Zero(t, info.Size())
+}
+
+func TestProgressDrawing(t *testing.T) {
+	prevDelay := progressDelay
+	progressDelay = 0
+	t.Cleanup(func() { progressDelay = prevDelay })
+	buf := &bytes.Buffer{}
+	p := &ProgressMeter{w: buf, enabled: true}
+
+	task := p.Start("Deriving accounts", 4)
+	task.Add(2)
+	time.Sleep(2 * progressInterval)
+	task.Done()
+	task.Done()
+	require.Contains(t, buf.String(), "Deriving accounts 50% (2/4)")
+	require.True(t, strings.HasSuffix(buf.String(), "\r\033[K"), "the progress is erased")
+
+	buf.Reset()
+	spinner := p.Start("Deriving the wallet key", 0)
+	time.Sleep(2 * progressInterval)
+	spinner.Done()
+	require.Contains(t, buf.String(), "Deriving the wallet key")
+
+	// fast tasks aren't drawn
+	progressDelay = time.Hour
+	buf.Reset()
+	p.Start("Deriving accounts", 1).Done()
+	require.Empty(t, buf.String())
+}
@@ -0,0 +1,18 @@
+package common
+
+import (
+	"fmt"
+
+	"github.com/skip2/go-qrcode"
+)
+
+// QRCode renders content as a QR code to print to a terminal. Each line of text draws two rows of modules using
+// Unicode block characters. The code is drawn light on dark, with a quiet zone around it, which suits terminals
+// with a dark background.
+func QRCode(content string) (string, error) {
+	qr, err := qrcode.New(content, qrcode.Medium)
+	if err != nil {
+		return "", fmt.Errorf("error encoding QR code: %w", err)
+	}
+	return qr.ToSmallString(false), nil
+}
@@ -0,0 +1,115 @@
+package common
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/skip2/go-qrcode"
+	"github.com/stretchr/testify/require"
+)
+
+// parseQRCode reads back the modules drawn by QRCode, true for dark.
+func parseQRCode(t *testing.T, s string) [][]bool {
+	var modules [][]bool
+	for _, line := range strings.Split(strings.TrimSuffix(s, "\n"), "\n") {
+		top, bottom := []bool{}, []bool{}
+		for _, r := range line {
+			switch r {
+			case ' ':
+				top, bottom = append(top, true), append(bottom, true)
+			case '█':
+				top, bottom = append(top, false), append(bottom, false)
+			case '▀':
+				top, bottom = append(top, false), append(bottom, true)
+			case '▄':
+				top, bottom = append(top, true), append(bottom, false)
+			default:
+				require.Failf(t, "unexpected character", "%q", r)
+			}
+		}
+		modules = append(modules, top, bottom)
+	}
+	return modules
+}
+
+func TestQRCode(t *testing.T) {
+	address := "sm1qqqqqqqlgfpwap59jlwmre4p9xrlk8fenfnlsrqva0uyp"
+	s, err := QRCode(address)
+	require.NoError(t, err)
+	modules := parseQRCode(t, s)
+
+	// the number of rows is odd, so the last line only draws the top half
+	modules = modules[:len(modules)-1]
+	qr, err := qrcode.New(address, qrcode.Medium)
+	require.NoError(t, err)
+	require.Equal(t, qr.Bitmap(), modules)
+
+	// the quiet zone is drawn light
+	const border = 4
+	for _, row := range append(modules[:border:border], modules[len(modules)-border:]...) {
+		require.NotContains(t, row, true)
+	}
+	modules = modules[border : len(modules)-border]
+	for i := range modules {
+		require.NotContains(t, modules[i][:border], true)
+		require.NotContains(t, modules[i][len(modules[i])-border:], true)
+		modules[i] = modules[i][border : len(modules[i])-border]
+	}
+
+	// 48 characters in byte mode with medium error correction need version 4, with 33x33 modules
+	size := len(modules)
+	require.Equal(t, 33, size)
+	for _, row := range modules {
+		require.Len(t, row, size)
+	}
+	require.Equal(t, 4, qr.VersionNumber)
+
+	// the finder patterns are in place
+	for _, corner := range [][2]int{{0, 0}, {0, size - 7}, {size - 7, 0}} {
+		for y := 0; y < 7; y++ {
+			for x := 0; x < 7; x++ {
+				ring := max(abs(y-3), abs(x-3))
+				require.Equal(t, ring != 2, modules[corner[0]+y][corner[1]+x], "finder at %v", corner)
+			}
+		}
+	}
+
+	// and so is the format information next to the top left one, which must be a valid BCH codeword for medium
+	// error correction (level bits 00)
+	var format uint
+	for _, p := range [][2]int{
+		{8, 0}, {8, 1}, {8, 2}, {8, 3}, {8, 4}, {8, 5}, {8, 7}, {8, 8},
+		{7, 8}, {5, 8}, {4, 8}, {3, 8}, {2, 8}, {1, 8}, {0, 8},
+	} {
+		format <<= 1
+		if modules[p[0]][p[1]] {
+			format |= 1
+		}
+	}
+	format ^= 0x5412
+	require.Zero(t, format>>13, "error correction level")
+	rem := format
+	for bit := 14; bit >= 10; bit-- {
+		if rem&(1<<bit) != 0 {
+			rem ^= 0x537 << (bit - 10)
+		}
+	}
+	require.Zero(t, rem, "format information checksum")
+
+	_, err = QRCode(strings.Repeat("x", 3000))
+	require.Error(t, err)
+}
+
+func abs(x int) int {
+	if x < 0 {
+		return -x
+	}
+	return x
+}
+
+func max(a, b int) int {
+	if a > b {
+		return a
+	}
+	return b
+}
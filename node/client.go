@@ -0,0 +1,491 @@
+// Package node implements a client for the gRPC API of a Spacemesh node.
+package node
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	pb "github.com/spacemeshos/api/release/go/spacemesh/v1"
+	"github.com/spacemeshos/go-spacemesh/common/types"
+	"github.com/spacemeshos/go-spacemesh/genvm/core"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/emptypb"
+
+	"github.com/spacemeshos/smcli/common"
+	"github.com/spacemeshos/smcli/wallet"
+)
+
+const (
+	// DefaultEndpoint is the default address of the public gRPC API of a local node.
+	DefaultEndpoint = "localhost:9092"
+	// DefaultTimeout is the default timeout of a single request to the node.
+	DefaultTimeout = 10 * time.Second
+	// DefaultRetries is the default number of times a read-only request is retried if the node is unavailable.
+	DefaultRetries = 2
+	// DefaultRetryBackoff is the default delay before the first retry. It doubles after every retry.
+	DefaultRetryBackoff = 500 * time.Millisecond
+)
+
+var (
+	// ErrNodeUnavailable is returned if the node can't be reached, or doesn't respond in time. It's a network
+	// error (common.ErrNetwork).
+	ErrNodeUnavailable = common.NewError(common.ErrNetwork, "node unavailable")
+	// ErrServiceDisabled is returned if the API service required by a request isn't enabled on the node. It's a
+	// network error (common.ErrNetwork).
+	ErrServiceDisabled = common.NewError(common.ErrNetwork, "API service disabled on the node")
+	// ErrNotSpawned is returned if an account hasn't been spawned, and so can't send spend transactions yet.
+	ErrNotSpawned = errors.New("account not spawned")
+	// ErrUnauthenticated is returned if the node refuses a request because of a missing or invalid token. It's an
+	// authentication error (common.ErrAuth).
+	ErrUnauthenticated = common.NewError(common.ErrAuth, "not authorized by the node")
+)
+
+// Client talks to the gRPC API of a node.
+type Client struct {
+	endpoint string
+	timeout  time.Duration
+	retries  int
+	backoff  time.Duration
+	hrp      string
+	conn     *grpc.ClientConn
+
+	debug       pb.DebugServiceClient
+	globalState pb.GlobalStateServiceClient
+	mesh        pb.MeshServiceClient
+	node        pb.NodeServiceClient
+	tx          pb.TransactionServiceClient
+}
+
+// ClientOpt configures a Client.
+type ClientOpt func(*clientOpts)
+
+type clientOpts struct {
+	timeout     time.Duration
+	retries     int
+	backoff     time.Duration
+	hrp         string
+	insecure    bool
+	caCert      []byte
+	token       string
+	dialOptions []grpc.DialOption
+}
+
+// WithTimeout sets the timeout of every request to the node, DefaultTimeout by default.
+func WithTimeout(timeout time.Duration) ClientOpt {
+	return func(o *clientOpts) {
+		o.timeout = timeout
+	}
+}
+
+// WithRetries sets how many times a read-only request, such as QueryAccount, is retried if the node is
+// unavailable or doesn't respond in time, DefaultRetries by default. The first retry is made after backoff, and
+// the delay doubles after every retry. Requests that change the state of the network, i.e.,
+// BroadcastTransaction, are never retried, so that a transaction is never submitted twice.
+func WithRetries(retries int, backoff time.Duration) ClientOpt {
+	return func(o *clientOpts) {
+		o.retries = retries
+		o.backoff = backoff
+	}
+}
+
+// WithHRP sets the HRP of the addresses sent to the node. The default is the mainnet HRP.
+func WithHRP(hrp string) ClientOpt {
+	return func(o *clientOpts) {
+		o.hrp = hrp
+	}
+}
+
+// WithTLS connects to the node using TLS, which is the default. The node's certificate is checked against the
+// PEM-encoded CA certificates in caCert, or against the system's if caCert is empty.
+func WithTLS(caCert []byte) ClientOpt {
+	return func(o *clientOpts) {
+		o.insecure = false
+		o.caCert = caCert
+	}
+}
+
+// WithInsecure connects to the node without TLS. Only use it for nodes that can't be reached by others, e.g.,
+// on the same machine.
+func WithInsecure() ClientOpt {
+	return func(o *clientOpts) {
+		o.insecure = true
+		o.caCert = nil
+	}
+}
+
+// WithToken sends the token in the authorization header of every request, as a bearer token. Unless
+// WithInsecure is used, the token is only ever sent over TLS.
+func WithToken(token string) ClientOpt {
+	return func(o *clientOpts) {
+		o.token = token
+	}
+}
+
+// WithDialOptions adds options used to connect to the node.
+func WithDialOptions(opts ...grpc.DialOption) ClientOpt {
+	return func(o *clientOpts) {
+		o.dialOptions = append(o.dialOptions, opts...)
+	}
+}
+
+// NewClient returns a client for the node at the given endpoint (host:port). It connects using TLS unless
+// WithInsecure is used. It doesn't connect right away: an unreachable node, or one whose certificate can't be
+// verified, is reported by the first request, as ErrNodeUnavailable.
+func NewClient(endpoint string, opts ...ClientOpt) (*Client, error) {
+	o := &clientOpts{
+		timeout: DefaultTimeout,
+		retries: DefaultRetries,
+		backoff: DefaultRetryBackoff,
+		hrp:     common.NetworkHRPs[common.NetworkMainnet],
+	}
+	for _, opt := range opts {
+		opt(o)
+	}
+	if o.timeout <= 0 {
+		return nil, fmt.Errorf("invalid timeout %s", o.timeout)
+	}
+	if o.retries < 0 || o.backoff < 0 {
+		return nil, fmt.Errorf("invalid retries %d with backoff %s", o.retries, o.backoff)
+	}
+	dialOptions, err := o.credentials()
+	if err != nil {
+		return nil, err
+	}
+	dialOptions = append(dialOptions, o.dialOptions...)
+	conn, err := grpc.Dial(endpoint, dialOptions...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to node at %s: %w", endpoint, err)
+	}
+	return &Client{
+		endpoint:    endpoint,
+		timeout:     o.timeout,
+		retries:     o.retries,
+		backoff:     o.backoff,
+		hrp:         o.hrp,
+		conn:        conn,
+		debug:       pb.NewDebugServiceClient(conn),
+		globalState: pb.NewGlobalStateServiceClient(conn),
+		mesh:        pb.NewMeshServiceClient(conn),
+		node:        pb.NewNodeServiceClient(conn),
+		tx:          pb.NewTransactionServiceClient(conn),
+	}, nil
+}
+
+// credentials returns the dial options that set up TLS and the token.
+func (o *clientOpts) credentials() ([]grpc.DialOption, error) {
+	var opts []grpc.DialOption
+	if o.insecure {
+		opts = append(opts, grpc.WithTransportCredentials(insecure.NewCredentials()))
+	} else {
+		cfg := &tls.Config{MinVersion: tls.VersionTLS12}
+		if len(o.caCert) > 0 {
+			cfg.RootCAs = x509.NewCertPool()
+			if !cfg.RootCAs.AppendCertsFromPEM(o.caCert) {
+				return nil, fmt.Errorf("no valid PEM-encoded CA certificate found")
+			}
+		}
+		opts = append(opts, grpc.WithTransportCredentials(credentials.NewTLS(cfg)))
+	}
+	if o.token != "" {
+		opts = append(opts, grpc.WithPerRPCCredentials(tokenCredentials{token: o.token, requireTLS: !o.insecure}))
+	}
+	return opts, nil
+}
+
+// tokenCredentials adds a bearer token to the metadata of each request.
+type tokenCredentials struct {
+	token      string
+	requireTLS bool
+}
+
+func (t tokenCredentials) GetRequestMetadata(context.Context, ...string) (map[string]string, error) {
+	return map[string]string{"authorization": "Bearer " + t.token}, nil
+}
+
+func (t tokenCredentials) RequireTransportSecurity() bool {
+	return t.requireTLS
+}
+
+// Close closes the connection to the node.
+func (c *Client) Close() error {
+	return c.conn.Close()
+}
+
+// Endpoint returns the endpoint of the node.
+func (c *Client) Endpoint() string {
+	return c.endpoint
+}
+
+// context returns the context for a single request made on behalf of ctx.
+func (c *Client) context(ctx context.Context) (context.Context, context.CancelFunc) {
+	return context.WithTimeout(ctx, c.timeout)
+}
+
+// read makes a read-only request to the node, which is safe to repeat: each attempt gets its own timeout, and
+// failed attempts are retried with exponential backoff if the node is unavailable or doesn't respond in time.
+// The error of the last attempt is returned as is, unless ctx is done, in which case its error is returned.
+// method names the request in the logs.
+func (c *Client) read(ctx context.Context, method string, request func(ctx context.Context) error) error {
+	backoff := c.backoff
+	for attempt := 0; ; attempt++ {
+		common.Logger().Debug("node request", "endpoint", c.endpoint, "method", method, "attempt", attempt+1)
+		reqCtx, cancel := c.context(ctx)
+		err := request(reqCtx)
+		cancel()
+		if err != nil && ctx.Err() != nil {
+			return ctx.Err()
+		}
+		if err == nil || attempt >= c.retries || !retryable(err) {
+			if err != nil {
+				common.Logger().Debug("node request failed", "endpoint", c.endpoint, "method", method,
+					"code", status.Code(err).String(), "error", status.Convert(err).Message())
+			}
+			return err
+		}
+		common.Logger().Warn("node request failed, retrying", "endpoint", c.endpoint, "method", method,
+			"code", status.Code(err).String(), "error", status.Convert(err).Message(), "backoff", backoff)
+		if err := sleep(ctx, backoff); err != nil {
+			return err
+		}
+		backoff *= 2
+	}
+}
+
+// sleep waits for d, or until ctx is done, in which case it returns its error.
+func sleep(ctx context.Context, d time.Duration) error {
+	t := time.NewTimer(d)
+	defer t.Stop()
+	select {
+	case <-t.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// retryable reports whether a failed request may succeed if it's made again. A node whose TLS certificate can't
+// be verified is reported as unavailable too, but won't become available by retrying.
+func retryable(err error) bool {
+	switch status.Code(err) {
+	case codes.Unavailable:
+		return !strings.Contains(status.Convert(err).Message(), "authentication handshake failed")
+	case codes.DeadlineExceeded, codes.ResourceExhausted, codes.Aborted:
+		return true
+	}
+	return false
+}
+
+// wrapError turns connection failures into ErrNodeUnavailable, requests to services that the node doesn't serve
+// into ErrServiceDisabled, and refused tokens into ErrUnauthenticated.
+func (c *Client) wrapError(err error) error {
+	switch status.Code(err) {
+	case codes.Unavailable, codes.DeadlineExceeded:
+		return fmt.Errorf("%w: %s: %s", ErrNodeUnavailable, c.endpoint, status.Convert(err).Message())
+	case codes.Unimplemented:
+		return fmt.Errorf("%w: %s: %s", ErrServiceDisabled, c.endpoint, status.Convert(err).Message())
+	case codes.Unauthenticated, codes.PermissionDenied:
+		return fmt.Errorf("%w: %s: %s", ErrUnauthenticated, c.endpoint, status.Convert(err).Message())
+	}
+	return err
+}
+
+// AccountState is the state of an account.
+type AccountState struct {
+	// Balance is denominated in smidge.
+	Balance uint64
+	// Nonce is the nonce of the next transaction.
+	Nonce uint64
+	// ProjectedBalance and ProjectedNonce include the effect of pending transactions.
+	ProjectedBalance uint64
+	ProjectedNonce   uint64
+}
+
+// QueryAccount returns the current state of an account.
+func (c *Client) QueryAccount(ctx context.Context, addr core.Address) (*AccountState, error) {
+	var resp *pb.AccountResponse
+	err := c.read(ctx, "GlobalStateService.Account", func(ctx context.Context) (err error) {
+		resp, err = c.globalState.Account(ctx, &pb.AccountRequest{
+			AccountId: &pb.AccountId{Address: wallet.AddressString(addr, c.hrp)},
+		})
+		return err
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to query account: %w", c.wrapError(err))
+	}
+	acct := resp.GetAccountWrapper()
+	return &AccountState{
+		Balance:          acct.GetStateCurrent().GetBalance().GetValue(),
+		Nonce:            acct.GetStateCurrent().GetCounter(),
+		ProjectedBalance: acct.GetStateProjected().GetBalance().GetValue(),
+		ProjectedNonce:   acct.GetStateProjected().GetCounter(),
+	}, nil
+}
+
+// AccountUsed reports whether an account has ever been used: that is, whether it has received funds or sent a
+// transaction, including pending ones. An account unknown to the node is unused. It implements
+// wallet.AccountChecker once bound to a context, see Checker.
+func (c *Client) AccountUsed(ctx context.Context, addr core.Address) (bool, error) {
+	state, err := c.QueryAccount(ctx, addr)
+	if status.Code(err) == codes.NotFound {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	return state.Nonce > 0 || state.Balance > 0 || state.ProjectedNonce > 0 || state.ProjectedBalance > 0, nil
+}
+
+// AccountBalance returns the balance of an account, counting pending transactions. An account unknown to the node
+// has none. It implements wallet.SweepChecker once bound to a context, see Checker.
+func (c *Client) AccountBalance(ctx context.Context, addr core.Address) (uint64, error) {
+	state, err := c.QueryAccount(ctx, addr)
+	if status.Code(err) == codes.NotFound {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, err
+	}
+	return state.ProjectedBalance, nil
+}
+
+// NextNonce returns the nonce of the next transaction of an account, counting pending transactions so that
+// several can be sent in a row. Every account starts by spawning itself using nonce 0, which is the first
+// transaction it sends: if it hasn't sent any, including a pending spawn, NextNonce returns ErrNotSpawned along
+// with nonce 0, the nonce of the spawn transaction. An account unknown to the node hasn't been spawned.
+func (c *Client) NextNonce(ctx context.Context, addr core.Address) (uint64, error) {
+	state, err := c.QueryAccount(ctx, addr)
+	if status.Code(err) == codes.NotFound {
+		return 0, fmt.Errorf("%w: %s", ErrNotSpawned, wallet.AddressString(addr, c.hrp))
+	}
+	if err != nil {
+		return 0, err
+	}
+	if state.ProjectedNonce == 0 {
+		return 0, fmt.Errorf("%w: %s", ErrNotSpawned, wallet.AddressString(addr, c.hrp))
+	}
+	return state.ProjectedNonce, nil
+}
+
+// AccountSpawned reports whether an account has been spawned, counting a pending spawn, and the nonce of its next
+// transaction, see NextNonce. It implements wallet.SpawnChecker once bound to a context, see Checker.
+func (c *Client) AccountSpawned(ctx context.Context, addr core.Address) (bool, uint64, error) {
+	nonce, err := c.NextNonce(ctx, addr)
+	if errors.Is(err, ErrNotSpawned) {
+		return false, 0, nil
+	}
+	if err != nil {
+		return false, 0, err
+	}
+	return true, nonce, nil
+}
+
+// Checker answers the account queries made while scanning, spending from, or sweeping a wallet, which implement
+// wallet.AccountChecker, wallet.SpawnChecker, and wallet.SweepChecker, using a client on behalf of a context.
+type Checker struct {
+	c   *Client
+	ctx context.Context
+}
+
+// Checker returns a Checker that makes its requests on behalf of ctx.
+func (c *Client) Checker(ctx context.Context) *Checker {
+	return &Checker{c: c, ctx: ctx}
+}
+
+// AccountUsed implements wallet.AccountChecker, see Client.AccountUsed.
+func (k *Checker) AccountUsed(addr core.Address) (bool, error) {
+	return k.c.AccountUsed(k.ctx, addr)
+}
+
+// AccountSpawned implements wallet.SpawnChecker, see Client.AccountSpawned.
+func (k *Checker) AccountSpawned(addr core.Address) (bool, uint64, error) {
+	return k.c.AccountSpawned(k.ctx, addr)
+}
+
+// AccountBalance implements wallet.SweepChecker, see Client.AccountBalance.
+func (k *Checker) AccountBalance(addr core.Address) (uint64, error) {
+	return k.c.AccountBalance(k.ctx, addr)
+}
+
+// GenesisID returns the genesis ID of the node's network.
+func (c *Client) GenesisID(ctx context.Context) (types.Hash20, error) {
+	var resp *pb.GenesisIDResponse
+	err := c.read(ctx, "MeshService.GenesisID", func(ctx context.Context) (err error) {
+		resp, err = c.mesh.GenesisID(ctx, &pb.GenesisIDRequest{})
+		return err
+	})
+	if err != nil {
+		return types.Hash20{}, fmt.Errorf("failed to query genesis ID: %w", c.wrapError(err))
+	}
+	var genesisID types.Hash20
+	if len(resp.GetGenesisId()) != len(genesisID) {
+		return types.Hash20{}, fmt.Errorf("invalid genesis ID length %d", len(resp.GetGenesisId()))
+	}
+	copy(genesisID[:], resp.GetGenesisId())
+	return genesisID, nil
+}
+
+// Status is the sync and network status of a node. The JSON encoding is part of the machine-readable CLI output.
+type Status struct {
+	// Version is the version of the node software.
+	Version string `json:"version"`
+	// Synced is set once the node has caught up with the network.
+	Synced bool `json:"synced"`
+	// TopLayer is the current layer of the network, SyncedLayer the last layer the node has synced, and
+	// VerifiedLayer the last layer it has verified.
+	TopLayer      uint32 `json:"topLayer"`
+	SyncedLayer   uint32 `json:"syncedLayer"`
+	VerifiedLayer uint32 `json:"verifiedLayer"`
+	// ConnectedPeers is the number of peers the node is connected to.
+	ConnectedPeers uint64 `json:"connectedPeers"`
+}
+
+// Status returns the sync and network status of the node, and its version.
+func (c *Client) Status(ctx context.Context) (*Status, error) {
+	var resp *pb.StatusResponse
+	err := c.read(ctx, "NodeService.Status", func(ctx context.Context) (err error) {
+		resp, err = c.node.Status(ctx, &pb.StatusRequest{})
+		return err
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to query node status: %w", c.wrapError(err))
+	}
+	var version *pb.VersionResponse
+	err = c.read(ctx, "NodeService.Version", func(ctx context.Context) (err error) {
+		version, err = c.node.Version(ctx, &emptypb.Empty{})
+		return err
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to query node version: %w", c.wrapError(err))
+	}
+	st := resp.GetStatus()
+	return &Status{
+		Version:        version.GetVersionString().GetValue(),
+		Synced:         st.GetIsSynced(),
+		TopLayer:       st.GetTopLayer().GetNumber(),
+		SyncedLayer:    st.GetSyncedLayer().GetNumber(),
+		VerifiedLayer:  st.GetVerifiedLayer().GetNumber(),
+		ConnectedPeers: st.GetConnectedPeers(),
+	}, nil
+}
+
+// PeerID returns the libp2p peer ID of the node. It uses the debug service, which is disabled by default.
+func (c *Client) PeerID(ctx context.Context) (string, error) {
+	var resp *pb.NetworkInfoResponse
+	err := c.read(ctx, "DebugService.NetworkInfo", func(ctx context.Context) (err error) {
+		resp, err = c.debug.NetworkInfo(ctx, &emptypb.Empty{})
+		return err
+	})
+	if err != nil {
+		return "", fmt.Errorf("failed to query network info: %w", c.wrapError(err))
+	}
+	return resp.GetId(), nil
+}
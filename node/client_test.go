@@ -0,0 +1,642 @@
+package node
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"fmt"
+	"math/big"
+	"net"
+	"sync"
+	"testing"
+	"time"
+
+	pb "github.com/spacemeshos/api/release/go/spacemesh/v1"
+	"github.com/spacemeshos/go-spacemesh/common/types"
+	"github.com/spacemeshos/go-spacemesh/genvm/core"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+	"google.golang.org/protobuf/types/known/emptypb"
+
+	"github.com/spacemeshos/smcli/common"
+	"github.com/spacemeshos/smcli/wallet"
+)
+
+// mockNode implements the node services used by the client, backed by in-memory state.
+type mockNode struct {
+	pb.UnimplementedDebugServiceServer
+	pb.UnimplementedGlobalStateServiceServer
+	pb.UnimplementedMeshServiceServer
+	pb.UnimplementedNodeServiceServer
+	pb.UnimplementedTransactionServiceServer
+
+	mu        sync.Mutex
+	accounts  map[string]*pb.Account
+	genesisID []byte
+	status    *pb.NodeStatus
+	version   string
+	peerID    string
+	delay     time.Duration
+	submit    func(tx []byte) (*pb.SubmitTransactionResponse, error)
+	txStates  []pb.TransactionState_TransactionState
+	txState   pb.TransactionState_TransactionState
+	txResult  *pb.TransactionResult
+	auth      []string
+
+	// failures is the number of account queries that fail as if the node were unavailable before one succeeds,
+	// and calls counts the queries.
+	failures int
+	calls    int
+
+	// noDebug disables the debug service, like on a node with the default configuration.
+	noDebug bool
+	// creds, if set, are the transport credentials of the server, e.g., for TLS.
+	creds credentials.TransportCredentials
+}
+
+// recordAuth records the authorization header of each request in auth.
+func (m *mockNode) recordAuth(
+	ctx context.Context,
+	req any,
+	_ *grpc.UnaryServerInfo,
+	handler grpc.UnaryHandler,
+) (any, error) {
+	md, _ := metadata.FromIncomingContext(ctx)
+	m.mu.Lock()
+	m.auth = append(m.auth, md.Get("authorization")...)
+	m.mu.Unlock()
+	return handler(ctx, req)
+}
+
+func (m *mockNode) Account(ctx context.Context, req *pb.AccountRequest) (*pb.AccountResponse, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.calls++
+	if m.failures > 0 {
+		m.failures--
+		return nil, status.Error(codes.Unavailable, "connection reset")
+	}
+	if m.delay > 0 {
+		select {
+		case <-time.After(m.delay):
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+	acct, ok := m.accounts[req.GetAccountId().GetAddress()]
+	if !ok {
+		return nil, status.Error(codes.NotFound, "account not found")
+	}
+	return &pb.AccountResponse{AccountWrapper: acct}, nil
+}
+
+func (m *mockNode) GenesisID(context.Context, *pb.GenesisIDRequest) (*pb.GenesisIDResponse, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return &pb.GenesisIDResponse{GenesisId: m.genesisID}, nil
+}
+
+func (m *mockNode) Status(context.Context, *pb.StatusRequest) (*pb.StatusResponse, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return &pb.StatusResponse{Status: m.status}, nil
+}
+
+func (m *mockNode) Version(context.Context, *emptypb.Empty) (*pb.VersionResponse, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return &pb.VersionResponse{VersionString: &pb.SimpleString{Value: m.version}}, nil
+}
+
+func (m *mockNode) NetworkInfo(context.Context, *emptypb.Empty) (*pb.NetworkInfoResponse, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return &pb.NetworkInfoResponse{Id: m.peerID}, nil
+}
+
+func (m *mockNode) SubmitTransaction(
+	_ context.Context,
+	req *pb.SubmitTransactionRequest,
+) (*pb.SubmitTransactionResponse, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.submit(req.GetTransaction())
+}
+
+// TransactionsState reports the first of txStates and moves on to the next one, so that each query sees the
+// transaction one step further along. The last state is repeated.
+func (m *mockNode) TransactionsState(
+	_ context.Context,
+	req *pb.TransactionsStateRequest,
+) (*pb.TransactionsStateResponse, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.txState = pb.TransactionState_TRANSACTION_STATE_UNSPECIFIED
+	if len(m.txStates) > 0 {
+		m.txState = m.txStates[0]
+	}
+	resp := &pb.TransactionsStateResponse{}
+	for _, id := range req.GetTransactionId() {
+		resp.TransactionsState = append(resp.TransactionsState, &pb.TransactionState{Id: id, State: m.txState})
+	}
+	if len(m.txStates) > 1 {
+		m.txStates = m.txStates[1:]
+	}
+	return resp, nil
+}
+
+// StreamResults sends txResult if the last state reported by TransactionsState is processed.
+func (m *mockNode) StreamResults(
+	req *pb.TransactionResultsRequest,
+	stream pb.TransactionService_StreamResultsServer,
+) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.txResult == nil || m.txState != pb.TransactionState_TRANSACTION_STATE_PROCESSED {
+		return nil
+	}
+	return stream.Send(m.txResult)
+}
+
+// startMockNode serves m over an in-memory connection and returns a client connected to it, without TLS and
+// retrying without delay unless the options say otherwise.
+func startMockNode(t *testing.T, m *mockNode, opts ...ClientOpt) *Client {
+	t.Helper()
+	lis := bufconn.Listen(1 << 20)
+	srvOpts := []grpc.ServerOption{grpc.UnaryInterceptor(m.recordAuth)}
+	if m.creds != nil {
+		srvOpts = append(srvOpts, grpc.Creds(m.creds))
+	}
+	srv := grpc.NewServer(srvOpts...)
+	if !m.noDebug {
+		pb.RegisterDebugServiceServer(srv, m)
+	}
+	pb.RegisterGlobalStateServiceServer(srv, m)
+	pb.RegisterMeshServiceServer(srv, m)
+	pb.RegisterNodeServiceServer(srv, m)
+	pb.RegisterTransactionServiceServer(srv, m)
+	go srv.Serve(lis)
+	t.Cleanup(srv.Stop)
+
+	dialer := func(ctx context.Context, _ string) (net.Conn, error) { return lis.DialContext(ctx) }
+	opts = append([]ClientOpt{WithInsecure(), WithRetries(DefaultRetries, time.Millisecond)}, opts...)
+	opts = append(opts, WithDialOptions(grpc.WithContextDialer(dialer)))
+	c, err := NewClient("bufnet", opts...)
+	require.NoError(t, err)
+	t.Cleanup(func() { c.Close() })
+	return c
+}
+
+func TestQueryAccount(t *testing.T) {
+	addr := types.Address{1, 2, 3}
+	m := &mockNode{accounts: map[string]*pb.Account{
+		addr.String(): {
+			AccountId:      &pb.AccountId{Address: addr.String()},
+			StateCurrent:   &pb.AccountState{Counter: 3, Balance: &pb.Amount{Value: 1000}},
+			StateProjected: &pb.AccountState{Counter: 4, Balance: &pb.Amount{Value: 900}},
+		},
+	}}
+	c := startMockNode(t, m)
+
+	state, err := c.QueryAccount(context.Background(), addr)
+	require.NoError(t, err)
+	require.Equal(t, &AccountState{Balance: 1000, Nonce: 3, ProjectedBalance: 900, ProjectedNonce: 4}, state)
+
+	_, err = c.QueryAccount(context.Background(), types.Address{4})
+	require.Error(t, err)
+	require.Equal(t, codes.NotFound, status.Code(err))
+	require.NotErrorIs(t, err, ErrNodeUnavailable)
+}
+
+func TestNextNonce(t *testing.T) {
+	spawned, pending, pendingSpawn, unspawned := types.Address{1}, types.Address{2}, types.Address{3}, types.Address{4}
+	m := &mockNode{accounts: map[string]*pb.Account{
+		spawned.String(): {
+			StateCurrent:   &pb.AccountState{Counter: 5, Balance: &pb.Amount{Value: 1000}},
+			StateProjected: &pb.AccountState{Counter: 5, Balance: &pb.Amount{Value: 1000}},
+		},
+		pending.String(): {
+			StateCurrent:   &pb.AccountState{Counter: 5, Balance: &pb.Amount{Value: 1000}},
+			StateProjected: &pb.AccountState{Counter: 7, Balance: &pb.Amount{Value: 800}},
+		},
+		pendingSpawn.String(): {
+			StateCurrent:   &pb.AccountState{Balance: &pb.Amount{Value: 1000}},
+			StateProjected: &pb.AccountState{Counter: 1, Balance: &pb.Amount{Value: 900}},
+		},
+		unspawned.String(): {
+			StateCurrent:   &pb.AccountState{Balance: &pb.Amount{Value: 1000}},
+			StateProjected: &pb.AccountState{Balance: &pb.Amount{Value: 1000}},
+		},
+	}}
+	c := startMockNode(t, m)
+
+	for addr, expected := range map[types.Address]uint64{spawned: 5, pending: 7, pendingSpawn: 1} {
+		nonce, err := c.NextNonce(context.Background(), addr)
+		require.NoError(t, err)
+		require.Equal(t, expected, nonce)
+	}
+	for _, addr := range []types.Address{unspawned, {5}} {
+		nonce, err := c.NextNonce(context.Background(), addr)
+		require.ErrorIs(t, err, ErrNotSpawned)
+		require.Zero(t, nonce)
+	}
+}
+
+func TestTransferTxns(t *testing.T) {
+	mnemonic := "film theme cheese broken kingdom destroy inch ready wear inspire shove pudding"
+	w, err := wallet.NewMultiWalletFromMnemonic(mnemonic, 2)
+	require.NoError(t, err)
+	principals, err := w.Principals()
+	require.NoError(t, err)
+	m := &mockNode{accounts: map[string]*pb.Account{
+		principals[0].String(): {
+			StateCurrent:   &pb.AccountState{Counter: 3, Balance: &pb.Amount{Value: 1000}},
+			StateProjected: &pb.AccountState{Counter: 3, Balance: &pb.Amount{Value: 1000}},
+		},
+		principals[1].String(): {
+			StateCurrent:   &pb.AccountState{Balance: &pb.Amount{Value: 1000}},
+			StateProjected: &pb.AccountState{Balance: &pb.Amount{Value: 1000}},
+		},
+	}}
+	c := startMockNode(t, m)
+	var _ wallet.SpawnChecker = c.Checker(context.Background())
+
+	methods := func(from int) []string {
+		var pubkey core.PublicKey
+		copy(pubkey[:], w.Secrets.Accounts[from].Public)
+		data := wallet.TxnData{Principal: principals[from], Recipient: principals[1-from], Amount: 10, GasPrice: 1}
+		txs, err := wallet.TransferTxns(c.Checker(context.Background()), data, pubkey, wallet.SpawnAuto)
+		require.NoError(t, err)
+		var methods []string
+		for _, tx := range txs {
+			d, err := wallet.DecodeTransaction(tx)
+			require.NoError(t, err)
+			methods = append(methods, fmt.Sprintf("%s %d", d.MethodName, d.Nonce))
+		}
+		return methods
+	}
+	require.Equal(t, []string{"spend 3"}, methods(0))
+	require.Equal(t, []string{"spawn 0", "spend 1"}, methods(1))
+}
+
+func TestPlanSweep(t *testing.T) {
+	mnemonic := "film theme cheese broken kingdom destroy inch ready wear inspire shove pudding"
+	from, err := wallet.NewMultiWalletFromMnemonic(mnemonic, 3)
+	require.NoError(t, err)
+	to, err := wallet.NewMultiWalletRandomMnemonic(3)
+	require.NoError(t, err)
+	principals, err := from.Principals()
+	require.NoError(t, err)
+	// account 0 is spawned with a pending spend, 1 hasn't been spawned, and 2 is unknown to the node
+	m := &mockNode{accounts: map[string]*pb.Account{
+		principals[0].String(): {
+			StateCurrent:   &pb.AccountState{Counter: 3, Balance: &pb.Amount{Value: 1000000}},
+			StateProjected: &pb.AccountState{Counter: 4, Balance: &pb.Amount{Value: 900000}},
+		},
+		principals[1].String(): {
+			StateCurrent:   &pb.AccountState{Balance: &pb.Amount{Value: 500000}},
+			StateProjected: &pb.AccountState{Balance: &pb.Amount{Value: 500000}},
+		},
+	}}
+	c := startMockNode(t, m)
+	var _ wallet.SweepChecker = c.Checker(context.Background())
+
+	plan, err := wallet.PlanSweep(c.Checker(context.Background()), from, to, 1)
+	require.NoError(t, err)
+	require.Empty(t, plan.Dust)
+	var transfers []string
+	for _, tr := range plan.Transfers {
+		require.Equal(t, tr.Balance, tr.Amount+tr.Fee)
+		for _, tx := range tr.Txns {
+			d, err := wallet.DecodeTransaction(tx)
+			require.NoError(t, err)
+			transfers = append(transfers, fmt.Sprintf("%d: %s %d", tr.Index, d.MethodName, d.Nonce))
+		}
+	}
+	require.Equal(t, []string{"0: spend 4", "1: spawn 0", "1: spend 1"}, transfers)
+	require.EqualValues(t, 900000, plan.Transfers[0].Balance)
+}
+
+func TestQueryAccountHRP(t *testing.T) {
+	// addresses are sent using the configured HRP, independent of the global one
+	addr := types.Address{1}
+	m := &mockNode{accounts: map[string]*pb.Account{
+		wallet.AddressString(addr, "stest"): {},
+	}}
+	c := startMockNode(t, m, WithHRP("stest"))
+	_, err := c.QueryAccount(context.Background(), addr)
+	require.NoError(t, err)
+}
+
+func TestAccountUsed(t *testing.T) {
+	m := &mockNode{accounts: map[string]*pb.Account{
+		types.Address{1}.String(): {StateCurrent: &pb.AccountState{Balance: &pb.Amount{Value: 1}}},
+		types.Address{2}.String(): {StateCurrent: &pb.AccountState{Counter: 1}, StateProjected: &pb.AccountState{Counter: 1}},
+		types.Address{3}.String(): {StateProjected: &pb.AccountState{Balance: &pb.Amount{Value: 5}}},
+		types.Address{4}.String(): {StateCurrent: &pb.AccountState{}, StateProjected: &pb.AccountState{}},
+	}}
+	c := startMockNode(t, m)
+	var _ wallet.AccountChecker = c.Checker(context.Background())
+
+	for addr, expected := range map[types.Address]bool{
+		{1}: true,
+		{2}: true,
+		{3}: true,
+		{4}: false,
+		// unknown to the node
+		{5}: false,
+	} {
+		used, err := c.AccountUsed(context.Background(), addr)
+		require.NoError(t, err)
+		require.Equal(t, expected, used, addr)
+	}
+
+	// the wallet scans accounts until the gap limit
+	mnemonic := "film theme cheese broken kingdom destroy inch ready wear inspire shove pudding"
+	ref, err := wallet.NewMultiWalletFromMnemonic(mnemonic, 10)
+	require.NoError(t, err)
+	m.accounts = map[string]*pb.Account{}
+	for _, i := range []int{0, 2, 6} {
+		addr := wallet.PubkeyToPrincipal(ref.Secrets.Accounts[i].Public)
+		m.accounts[addr.String()] = &pb.Account{StateCurrent: &pb.AccountState{Counter: 1}}
+	}
+	w, err := wallet.NewMultiWalletFromMnemonic(mnemonic, 0, wallet.WithAccountsLater(true))
+	require.NoError(t, err)
+	n, err := w.ScanAccounts(c.Checker(context.Background()), wallet.WithGapLimit(4))
+	require.NoError(t, err)
+	require.Equal(t, 7, n)
+	require.Equal(t, ref.Addresses("sm")[:7], w.Addresses("sm"))
+}
+
+func TestGenesisID(t *testing.T) {
+	m := &mockNode{genesisID: []byte{1, 2, 3, 4, 5, 6, 7, 8, 9, 10, 11, 12, 13, 14, 15, 16, 17, 18, 19, 20}}
+	c := startMockNode(t, m)
+	id, err := c.GenesisID(context.Background())
+	require.NoError(t, err)
+	require.Equal(t, types.Hash20{1, 2, 3, 4, 5, 6, 7, 8, 9, 10, 11, 12, 13, 14, 15, 16, 17, 18, 19, 20}, id)
+
+	m.genesisID = []byte{1, 2, 3}
+	_, err = c.GenesisID(context.Background())
+	require.Error(t, err)
+}
+
+func TestStatus(t *testing.T) {
+	m := &mockNode{
+		status: &pb.NodeStatus{
+			ConnectedPeers: 12,
+			IsSynced:       true,
+			SyncedLayer:    &pb.LayerNumber{Number: 4031},
+			TopLayer:       &pb.LayerNumber{Number: 4032},
+			VerifiedLayer:  &pb.LayerNumber{Number: 4030},
+		},
+		version: "v1.0.2",
+	}
+	c := startMockNode(t, m)
+	st, err := c.Status(context.Background())
+	require.NoError(t, err)
+	require.Equal(t, &Status{
+		Version:        "v1.0.2",
+		Synced:         true,
+		TopLayer:       4032,
+		SyncedLayer:    4031,
+		VerifiedLayer:  4030,
+		ConnectedPeers: 12,
+	}, st)
+
+	// a node that is still syncing may not report all layers
+	m.status = &pb.NodeStatus{TopLayer: &pb.LayerNumber{Number: 4032}}
+	st, err = c.Status(context.Background())
+	require.NoError(t, err)
+	require.False(t, st.Synced)
+	require.Zero(t, st.SyncedLayer)
+	require.EqualValues(t, 4032, st.TopLayer)
+}
+
+func TestPeerID(t *testing.T) {
+	m := &mockNode{peerID: "12D3KooWJNrdg6mXweqUSRVxh7jHYtm6Q6iyC8zZPNrmp4VTnHVe"}
+	c := startMockNode(t, m)
+	id, err := c.PeerID(context.Background())
+	require.NoError(t, err)
+	require.Equal(t, m.peerID, id)
+
+	// the debug service is disabled by default
+	c = startMockNode(t, &mockNode{noDebug: true})
+	_, err = c.PeerID(context.Background())
+	require.ErrorIs(t, err, ErrServiceDisabled)
+	require.NotErrorIs(t, err, ErrNodeUnavailable)
+}
+
+func TestNodeUnavailable(t *testing.T) {
+	// nothing listens on this port
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	endpoint := lis.Addr().String()
+	require.NoError(t, lis.Close())
+
+	c, err := NewClient(endpoint, WithTimeout(time.Second), WithRetries(DefaultRetries, time.Millisecond))
+	require.NoError(t, err)
+	defer c.Close()
+	_, err = c.QueryAccount(context.Background(), types.Address{1})
+	require.ErrorIs(t, err, ErrNodeUnavailable)
+	require.Contains(t, err.Error(), endpoint)
+	require.Equal(t, common.ExitNetwork, common.ExitCode(err))
+}
+
+func TestErrorExitCodes(t *testing.T) {
+	c := &Client{endpoint: "localhost:9092"}
+	for code, exit := range map[codes.Code]int{
+		codes.Unavailable:      common.ExitNetwork,
+		codes.DeadlineExceeded: common.ExitNetwork,
+		codes.Unimplemented:    common.ExitNetwork,
+		codes.Unauthenticated:  common.ExitAuth,
+		codes.PermissionDenied: common.ExitAuth,
+		codes.NotFound:         common.ExitFailure,
+	} {
+		require.Equal(t, exit, common.ExitCode(c.wrapError(status.Error(code, "error"))), code.String())
+	}
+}
+
+func TestTimeout(t *testing.T) {
+	addr := types.Address{1}
+	m := &mockNode{
+		accounts: map[string]*pb.Account{addr.String(): {}},
+		delay:    time.Second,
+	}
+	c := startMockNode(t, m, WithTimeout(50*time.Millisecond))
+	_, err := c.QueryAccount(context.Background(), addr)
+	require.ErrorIs(t, err, ErrNodeUnavailable)
+
+	_, err = NewClient("localhost:1", WithTimeout(0))
+	require.Error(t, err)
+	_, err = NewClient("localhost:1", WithRetries(-1, time.Second))
+	require.Error(t, err)
+}
+
+func TestRetries(t *testing.T) {
+	addr := types.Address{1}
+	m := &mockNode{accounts: map[string]*pb.Account{addr.String(): {
+		StateCurrent: &pb.AccountState{Balance: &pb.Amount{Value: 100}},
+	}}}
+
+	// a flaky node answers eventually
+	m.failures = DefaultRetries
+	c := startMockNode(t, m)
+	state, err := c.QueryAccount(context.Background(), addr)
+	require.NoError(t, err)
+	require.EqualValues(t, 100, state.Balance)
+	require.Equal(t, DefaultRetries+1, m.calls)
+
+	// but not if it fails more often than the client retries
+	m.failures, m.calls = DefaultRetries+1, 0
+	_, err = c.QueryAccount(context.Background(), addr)
+	require.ErrorIs(t, err, ErrNodeUnavailable)
+	require.Equal(t, DefaultRetries+1, m.calls)
+
+	// errors that won't go away aren't retried
+	m.calls = 0
+	_, err = c.QueryAccount(context.Background(), types.Address{2})
+	require.Equal(t, codes.NotFound, status.Code(err))
+	require.Equal(t, 1, m.calls)
+
+	// and neither is anything if retries are disabled
+	m.failures, m.calls = 1, 0
+	c = startMockNode(t, m, WithRetries(0, 0))
+	_, err = c.QueryAccount(context.Background(), addr)
+	require.ErrorIs(t, err, ErrNodeUnavailable)
+	require.Equal(t, 1, m.calls)
+}
+
+func TestRetriesTimeout(t *testing.T) {
+	addr := types.Address{1}
+	m := &mockNode{
+		accounts: map[string]*pb.Account{addr.String(): {}},
+		delay:    time.Minute,
+	}
+	c := startMockNode(t, m, WithTimeout(50*time.Millisecond), WithRetries(2, 10*time.Millisecond))
+
+	// every attempt gets its own timeout, with backoff in between: 50 + 10 + 50 + 20 + 50 ms
+	start := time.Now()
+	_, err := c.QueryAccount(context.Background(), addr)
+	require.ErrorIs(t, err, ErrNodeUnavailable)
+	require.Equal(t, 3, m.calls)
+	require.GreaterOrEqual(t, time.Since(start), 180*time.Millisecond)
+	require.Less(t, time.Since(start), 5*time.Second)
+}
+
+func TestCancel(t *testing.T) {
+	addr := types.Address{1}
+	m := &mockNode{
+		accounts: map[string]*pb.Account{addr.String(): {}},
+		delay:    time.Minute,
+	}
+	c := startMockNode(t, m, WithTimeout(time.Minute))
+
+	// cancelling aborts the request in flight, and doesn't retry it
+	ctx, cancel := context.WithCancel(context.Background())
+	time.AfterFunc(50*time.Millisecond, cancel)
+	start := time.Now()
+	_, err := c.QueryAccount(ctx, addr)
+	require.ErrorIs(t, err, context.Canceled)
+	require.NotErrorIs(t, err, ErrNodeUnavailable)
+	require.Less(t, time.Since(start), 5*time.Second)
+	require.Equal(t, 1, m.calls)
+
+	// so does a deadline
+	ctx, cancel = context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	_, err = c.AccountUsed(ctx, addr)
+	require.ErrorIs(t, err, context.DeadlineExceeded)
+
+	// and a context that's already done doesn't make a request at all
+	m.calls = 0
+	_, err = c.NextNonce(ctx, addr)
+	require.ErrorIs(t, err, context.DeadlineExceeded)
+	require.Equal(t, 0, m.calls)
+}
+
+// newTestCert returns a self-signed certificate for bufnet, the name that startMockNode's clients connect to,
+// and its PEM encoding.
+func newTestCert(t *testing.T) (tls.Certificate, []byte) {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	tmpl := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "bufnet"},
+		DNSNames:              []string{"bufnet"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		KeyUsage:              x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign,
+		ExtKeyUsage:           []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &key.PublicKey, key)
+	require.NoError(t, err)
+	return tls.Certificate{Certificate: [][]byte{der}, PrivateKey: key},
+		pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})
+}
+
+func TestTLS(t *testing.T) {
+	cert, certPEM := newTestCert(t)
+	m := &mockNode{
+		genesisID: make([]byte, 20),
+		creds:     credentials.NewTLS(&tls.Config{Certificates: []tls.Certificate{cert}}),
+	}
+
+	c := startMockNode(t, m, WithTLS(certPEM))
+	_, err := c.GenesisID(context.Background())
+	require.NoError(t, err)
+
+	// the certificate isn't signed by a CA that the system trusts
+	c = startMockNode(t, m, WithTLS(nil))
+	_, err = c.GenesisID(context.Background())
+	require.ErrorIs(t, err, ErrNodeUnavailable)
+
+	_, otherPEM := newTestCert(t)
+	c = startMockNode(t, m, WithTLS(otherPEM))
+	_, err = c.GenesisID(context.Background())
+	require.ErrorIs(t, err, ErrNodeUnavailable)
+
+	c = startMockNode(t, m, WithInsecure())
+	_, err = c.GenesisID(context.Background())
+	require.ErrorIs(t, err, ErrNodeUnavailable)
+
+	_, err = NewClient("bufnet", WithTLS([]byte("not a certificate")))
+	require.Error(t, err)
+}
+
+func TestToken(t *testing.T) {
+	cert, certPEM := newTestCert(t)
+	m := &mockNode{
+		genesisID: make([]byte, 20),
+		creds:     credentials.NewTLS(&tls.Config{Certificates: []tls.Certificate{cert}}),
+	}
+	c := startMockNode(t, m, WithTLS(certPEM), WithToken("secret"))
+	_, err := c.GenesisID(context.Background())
+	require.NoError(t, err)
+	require.Equal(t, []string{"Bearer secret"}, m.auth)
+
+	// without TLS, the token is only sent if insecure connections were explicitly allowed
+	m = &mockNode{genesisID: make([]byte, 20)}
+	c = startMockNode(t, m, WithToken("secret"))
+	_, err = c.GenesisID(context.Background())
+	require.NoError(t, err)
+	require.Equal(t, []string{"Bearer secret"}, m.auth)
+
+	m = &mockNode{genesisID: make([]byte, 20)}
+	c = startMockNode(t, m)
+	_, err = c.GenesisID(context.Background())
+	require.NoError(t, err)
+	require.Empty(t, m.auth)
+}
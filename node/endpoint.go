@@ -0,0 +1,90 @@
+package node
+
+import (
+	"fmt"
+	"net"
+	"os"
+	"sort"
+	"strings"
+)
+
+// Endpoint is the address of a node and how to connect to it.
+type Endpoint struct {
+	// Address is host:port.
+	Address string `mapstructure:"address"`
+	// Insecure connects without TLS, which must be asked for explicitly, even for nodes on localhost: the token
+	// is then sent in plaintext. If unset, TLS is used.
+	Insecure *bool `mapstructure:"insecure"`
+	// CACert is the path of a file with the PEM-encoded CA certificates that the node's certificate is checked
+	// against, instead of the system's.
+	CACert string `mapstructure:"ca-cert"`
+	// Token is sent to the node with every request, in the authorization header.
+	Token string `mapstructure:"token"`
+}
+
+// ClientOpts returns the options that connect a Client to the endpoint.
+func (e Endpoint) ClientOpts() ([]ClientOpt, error) {
+	var opts []ClientOpt
+	if e.Token != "" {
+		opts = append(opts, WithToken(e.Token))
+	}
+	if e.Insecure != nil && *e.Insecure {
+		if e.CACert != "" {
+			return nil, fmt.Errorf("a CA certificate can't be used without TLS")
+		}
+		return append(opts, WithInsecure()), nil
+	}
+	var caCert []byte
+	if e.CACert != "" {
+		var err error
+		if caCert, err = os.ReadFile(e.CACert); err != nil {
+			return nil, fmt.Errorf("failed to read CA certificate: %w", err)
+		}
+	}
+	return append(opts, WithTLS(caCert)), nil
+}
+
+// ResolveEndpoint returns the node to use. The endpoint is taken from the first of flag, env, and configured
+// that's set, in that order, or else it's DefaultEndpoint. Each of them is either an address (host:port) or the
+// name of one of the named endpoints, e.g., from a config file, so that switching networks only takes a name.
+func ResolveEndpoint(flag, env, configured string, named map[string]Endpoint) (Endpoint, error) {
+	for _, source := range []struct{ name, value string }{
+		{"--endpoint", flag},
+		{"environment", env},
+		{"config file", configured},
+	} {
+		value := strings.TrimSpace(source.value)
+		if value == "" {
+			continue
+		}
+		endpoint, err := lookupEndpoint(value, named)
+		if err != nil {
+			return Endpoint{}, fmt.Errorf("endpoint from %s: %w", source.name, err)
+		}
+		return endpoint, nil
+	}
+	return Endpoint{Address: DefaultEndpoint}, nil
+}
+
+// lookupEndpoint resolves the name of a named endpoint, or checks that value is an address.
+func lookupEndpoint(value string, named map[string]Endpoint) (Endpoint, error) {
+	if endpoint, ok := named[value]; ok {
+		if _, _, err := net.SplitHostPort(endpoint.Address); err != nil {
+			return Endpoint{}, fmt.Errorf("invalid address %q for endpoint %q: %w", endpoint.Address, value, err)
+		}
+		return endpoint, nil
+	}
+	if _, _, err := net.SplitHostPort(value); err == nil {
+		return Endpoint{Address: value}, nil
+	}
+	if len(named) == 0 {
+		return Endpoint{}, fmt.Errorf("invalid endpoint %q, must be host:port", value)
+	}
+	names := make([]string, 0, len(named))
+	for name := range named {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return Endpoint{}, fmt.Errorf("invalid endpoint %q, must be host:port or one of the named endpoints %s",
+		value, strings.Join(names, ", "))
+}
@@ -0,0 +1,102 @@
+package node
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestResolveEndpoint(t *testing.T) {
+	named := map[string]Endpoint{
+		"mainnet": {Address: "mainnet.example.com:9092"},
+		"testnet": {Address: "testnet.example.com:9092", Token: "secret"},
+	}
+	testCases := map[string]struct {
+		flag, env, configured string
+		expected              string
+	}{
+		"default":                  {expected: DefaultEndpoint},
+		"flag":                     {flag: "node:1", expected: "node:1"},
+		"env":                      {env: "node:2", expected: "node:2"},
+		"config file":              {configured: "node:3", expected: "node:3"},
+		"flag over env":            {flag: "node:1", env: "node:2", expected: "node:1"},
+		"flag over config file":    {flag: "node:1", configured: "node:3", expected: "node:1"},
+		"env over config file":     {env: "node:2", configured: "node:3", expected: "node:2"},
+		"flag over all":            {flag: "node:1", env: "node:2", configured: "node:3", expected: "node:1"},
+		"named flag":               {flag: "testnet", configured: "node:3", expected: "testnet.example.com:9092"},
+		"named env":                {env: "testnet", configured: "mainnet", expected: "testnet.example.com:9092"},
+		"named config file":        {configured: "mainnet", expected: "mainnet.example.com:9092"},
+		"blank values are not set": {flag: " ", env: "node:2", expected: "node:2"},
+	}
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			endpoint, err := ResolveEndpoint(tc.flag, tc.env, tc.configured, named)
+			require.NoError(t, err)
+			require.Equal(t, tc.expected, endpoint.Address)
+		})
+	}
+
+	// a named endpoint comes with its settings
+	endpoint, err := ResolveEndpoint("testnet", "", "", named)
+	require.NoError(t, err)
+	require.Equal(t, named["testnet"], endpoint)
+}
+
+func TestResolveEndpointInvalid(t *testing.T) {
+	named := map[string]Endpoint{"broken": {Address: "no-port"}}
+
+	_, err := ResolveEndpoint("devnet", "", "", named)
+	require.ErrorContains(t, err, "--endpoint")
+	require.ErrorContains(t, err, "broken")
+	_, err = ResolveEndpoint("", "devnet", "", nil)
+	require.ErrorContains(t, err, "environment")
+	_, err = ResolveEndpoint("", "", "broken", named)
+	require.ErrorContains(t, err, "config file")
+
+	// an invalid value with a lower precedence is ignored
+	endpoint, err := ResolveEndpoint("node:1", "devnet", "broken", named)
+	require.NoError(t, err)
+	require.Equal(t, Endpoint{Address: "node:1"}, endpoint)
+}
+
+func TestEndpointClientOpts(t *testing.T) {
+	apply := func(t *testing.T, e Endpoint) *clientOpts {
+		opts, err := e.ClientOpts()
+		require.NoError(t, err)
+		o := &clientOpts{}
+		for _, opt := range opts {
+			opt(o)
+		}
+		return o
+	}
+	yes, no := true, false
+
+	// TLS unless insecure connections are explicitly allowed, even for local nodes
+	require.False(t, apply(t, Endpoint{Address: "node.example.com:9092"}).insecure)
+	require.False(t, apply(t, Endpoint{Address: DefaultEndpoint}).insecure)
+	require.False(t, apply(t, Endpoint{Address: "127.0.0.1:9092"}).insecure)
+	require.False(t, apply(t, Endpoint{Address: "[::1]:9092"}).insecure)
+	require.False(t, apply(t, Endpoint{Address: "localhost:9092", Insecure: &no}).insecure)
+	require.True(t, apply(t, Endpoint{Address: "localhost:9092", Insecure: &yes}).insecure)
+	require.True(t, apply(t, Endpoint{Address: "node.example.com:9092", Insecure: &yes}).insecure)
+
+	o := apply(t, Endpoint{Address: "node.example.com:9092", Token: "secret"})
+	require.Equal(t, "secret", o.token)
+	o = apply(t, Endpoint{Address: DefaultEndpoint, Token: "secret"})
+	require.Equal(t, "secret", o.token)
+	require.False(t, o.insecure)
+
+	caFile := filepath.Join(t.TempDir(), "ca.pem")
+	require.NoError(t, os.WriteFile(caFile, []byte("certificate"), 0o600))
+	o = apply(t, Endpoint{Address: "node.example.com:9092", CACert: caFile})
+	require.False(t, o.insecure)
+	require.Equal(t, []byte("certificate"), o.caCert)
+
+	// a CA certificate can't be used with an insecure connection
+	_, err := Endpoint{Address: "localhost:9092", CACert: caFile, Insecure: &yes}.ClientOpts()
+	require.Error(t, err)
+	_, err = Endpoint{Address: "node.example.com:9092", CACert: caFile + ".missing"}.ClientOpts()
+	require.Error(t, err)
+}
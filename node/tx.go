@@ -0,0 +1,223 @@
+package node
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"time"
+
+	pb "github.com/spacemeshos/api/release/go/spacemesh/v1"
+	"github.com/spacemeshos/go-spacemesh/common/types"
+	"google.golang.org/genproto/googleapis/rpc/code"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/spacemeshos/smcli/common"
+)
+
+// ErrTransactionRejected is returned if the node refuses a transaction. The error includes the node's reason.
+var ErrTransactionRejected = errors.New("transaction rejected")
+
+// TxState is the state of a transaction as seen by the node.
+type TxState string
+
+// States reported by TransactionStatus. Applied, failed, and rejected are final.
+const (
+	// TxStateUnknown means the node doesn't know the transaction, e.g., because it hasn't received it yet.
+	TxStateUnknown TxState = "unknown"
+	// TxStatePending means the transaction is in the mempool, waiting to be included in a block.
+	TxStatePending TxState = "pending"
+	// TxStateIncluded means the transaction is in a block, but hasn't been executed yet.
+	TxStateIncluded TxState = "included"
+	// TxStateApplied means the transaction was executed successfully.
+	TxStateApplied TxState = "applied"
+	// TxStateFailed means the transaction was executed but failed, e.g., because the principal ran out of gas.
+	// The fee is still paid.
+	TxStateFailed TxState = "failed"
+	// TxStateRejected means the transaction will never be executed, e.g., because its nonce was reused.
+	TxStateRejected TxState = "rejected"
+)
+
+// TxStatus describes the state of a transaction.
+type TxStatus struct {
+	ID    types.TransactionID
+	State TxState
+	// Reason is the node's reason for rejecting the transaction, or the error of a failed transaction.
+	Reason string
+	// Layer, GasConsumed, and Fee are set once the transaction has been executed.
+	Layer       uint32
+	GasConsumed uint64
+	Fee         uint64
+}
+
+// Final reports whether the state of the transaction can no longer change.
+func (s *TxStatus) Final() bool {
+	switch s.State {
+	case TxStateApplied, TxStateFailed, TxStateRejected:
+		return true
+	}
+	return false
+}
+
+// String describes the state of the transaction in words.
+func (s *TxStatus) String() string {
+	switch s.State {
+	case TxStateUnknown:
+		return "unknown to the node"
+	case TxStatePending:
+		return "pending in the mempool"
+	case TxStateIncluded:
+		return "included in a block, not executed yet"
+	case TxStateApplied:
+		return fmt.Sprintf("applied in layer %d, fee %d smidge", s.Layer, s.Fee)
+	case TxStateFailed:
+		return fmt.Sprintf("failed in layer %d, fee %d smidge: %s", s.Layer, s.Fee, s.Reason)
+	case TxStateRejected:
+		return fmt.Sprintf("rejected: %s", s.Reason)
+	}
+	return string(s.State)
+}
+
+// BroadcastTransaction submits a signed transaction to the node, which adds it to its mempool and gossips it
+// to the network. It returns the ID of the transaction. If the node rejects the transaction, e.g., because it's
+// malformed or the signature is invalid, the error wraps ErrTransactionRejected and contains the node's reason
+// verbatim. It's never retried: if the node doesn't respond in time, the transaction may still have been
+// submitted, so check its status (see TransactionStatus) before broadcasting it again.
+func (c *Client) BroadcastTransaction(ctx context.Context, tx []byte) (types.TransactionID, error) {
+	if len(tx) == 0 {
+		return types.TransactionID{}, fmt.Errorf("empty transaction")
+	}
+	reqCtx, cancel := c.context(ctx)
+	defer cancel()
+	common.Logger().Debug("node request", "endpoint", c.endpoint, "method", "TransactionService.SubmitTransaction",
+		"size", len(tx))
+	resp, err := c.tx.SubmitTransaction(reqCtx, &pb.SubmitTransactionRequest{Transaction: tx})
+	if err != nil {
+		common.Logger().Debug("node request failed", "endpoint", c.endpoint,
+			"method", "TransactionService.SubmitTransaction", "code", status.Code(err).String(),
+			"error", status.Convert(err).Message())
+	}
+	if err != nil && ctx.Err() != nil {
+		return types.TransactionID{}, fmt.Errorf("failed to submit transaction: %w", ctx.Err())
+	}
+	if err != nil {
+		switch status.Code(err) {
+		case codes.InvalidArgument, codes.FailedPrecondition, codes.Internal:
+			return types.TransactionID{}, fmt.Errorf("%w: %s", ErrTransactionRejected, status.Convert(err).Message())
+		}
+		return types.TransactionID{}, fmt.Errorf("failed to submit transaction: %w", c.wrapError(err))
+	}
+	if s := resp.GetStatus(); s != nil && s.GetCode() != int32(code.Code_OK) {
+		return types.TransactionID{}, fmt.Errorf("%w: %s", ErrTransactionRejected, s.GetMessage())
+	}
+	switch state := resp.GetTxstate().GetState(); state {
+	case pb.TransactionState_TRANSACTION_STATE_REJECTED,
+		pb.TransactionState_TRANSACTION_STATE_INSUFFICIENT_FUNDS,
+		pb.TransactionState_TRANSACTION_STATE_CONFLICTING:
+		return types.TransactionID{}, fmt.Errorf("%w: %s", ErrTransactionRejected, state)
+	}
+	var id types.TransactionID
+	if len(resp.GetTxstate().GetId().GetId()) != len(id) {
+		return types.TransactionID{}, fmt.Errorf("invalid transaction ID in node response")
+	}
+	copy(id[:], resp.GetTxstate().GetId().GetId())
+	return id, nil
+}
+
+// TransactionStatus queries the node for the state of a transaction. Once the transaction has been executed, the
+// status includes the layer, the gas consumed, and the fee.
+func (c *Client) TransactionStatus(ctx context.Context, id types.TransactionID) (*TxStatus, error) {
+	// the state, and the result if the transaction has been executed, are read together so that they agree
+	var (
+		state  pb.TransactionState_TransactionState
+		res    *pb.TransactionResult
+		method = "state"
+	)
+	err := c.read(ctx, "TransactionService.TransactionsState", func(ctx context.Context) error {
+		state, res, method = pb.TransactionState_TRANSACTION_STATE_UNSPECIFIED, nil, "state"
+		resp, err := c.tx.TransactionsState(ctx, &pb.TransactionsStateRequest{
+			TransactionId: []*pb.TransactionId{{Id: id[:]}},
+		})
+		if err != nil {
+			return err
+		}
+		if states := resp.GetTransactionsState(); len(states) > 0 {
+			state = states[0].GetState()
+		}
+		if state != pb.TransactionState_TRANSACTION_STATE_MESH && state != pb.TransactionState_TRANSACTION_STATE_PROCESSED {
+			return nil
+		}
+		method = "result"
+		stream, err := c.tx.StreamResults(ctx, &pb.TransactionResultsRequest{Id: id[:]})
+		if err != nil {
+			return err
+		}
+		res, err = stream.Recv()
+		if errors.Is(err, io.EOF) {
+			res, err = nil, nil
+		}
+		return err
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to query transaction %s: %w", method, c.wrapError(err))
+	}
+	st := &TxStatus{ID: id, State: TxStateUnknown}
+	switch state {
+	case pb.TransactionState_TRANSACTION_STATE_UNSPECIFIED:
+		return st, nil
+	case pb.TransactionState_TRANSACTION_STATE_MEMPOOL:
+		st.State = TxStatePending
+		return st, nil
+	case pb.TransactionState_TRANSACTION_STATE_REJECTED:
+		st.State, st.Reason = TxStateRejected, "invalid transaction"
+		return st, nil
+	case pb.TransactionState_TRANSACTION_STATE_INSUFFICIENT_FUNDS:
+		st.State, st.Reason = TxStateRejected, "insufficient funds"
+		return st, nil
+	case pb.TransactionState_TRANSACTION_STATE_CONFLICTING:
+		st.State, st.Reason = TxStateRejected, "conflicts with another transaction"
+		return st, nil
+	case pb.TransactionState_TRANSACTION_STATE_MESH, pb.TransactionState_TRANSACTION_STATE_PROCESSED:
+		st.State = TxStateIncluded
+	default:
+		return nil, fmt.Errorf("unknown transaction state %s", state)
+	}
+	if res == nil {
+		return st, nil
+	}
+	st.State = TxStateApplied
+	if res.GetStatus() != pb.TransactionResult_SUCCESS {
+		st.State, st.Reason = TxStateFailed, res.GetMessage()
+	}
+	st.Layer, st.GasConsumed, st.Fee = res.GetLayer(), res.GetGasConsumed(), res.GetFee()
+	return st, nil
+}
+
+// WatchTransaction polls the state of a transaction every interval until it's final, and returns the final
+// status. onChange, if not nil, is called with the first status and then every time the state changes. It stops
+// as soon as ctx is done, returning its error.
+func (c *Client) WatchTransaction(
+	ctx context.Context,
+	id types.TransactionID,
+	interval time.Duration,
+	onChange func(*TxStatus),
+) (*TxStatus, error) {
+	var last TxState
+	for {
+		st, err := c.TransactionStatus(ctx, id)
+		if err != nil {
+			return nil, err
+		}
+		if st.State != last && onChange != nil {
+			onChange(st)
+		}
+		if st.Final() {
+			return st, nil
+		}
+		last = st.State
+		if err := sleep(ctx, interval); err != nil {
+			return nil, err
+		}
+	}
+}
@@ -0,0 +1,198 @@
+package node
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	pb "github.com/spacemeshos/api/release/go/spacemesh/v1"
+	"github.com/spacemeshos/go-spacemesh/common/types"
+	"github.com/spacemeshos/go-spacemesh/hash"
+	"github.com/stretchr/testify/require"
+	rpcstatus "google.golang.org/genproto/googleapis/rpc/status"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestBroadcastTransaction(t *testing.T) {
+	var submitted []byte
+	m := &mockNode{submit: func(tx []byte) (*pb.SubmitTransactionResponse, error) {
+		submitted = tx
+		id := hash.Sum(tx)
+		return &pb.SubmitTransactionResponse{
+			Status: &rpcstatus.Status{},
+			Txstate: &pb.TransactionState{
+				Id:    &pb.TransactionId{Id: id[:]},
+				State: pb.TransactionState_TRANSACTION_STATE_MEMPOOL,
+			},
+		}, nil
+	}}
+	c := startMockNode(t, m)
+
+	tx := []byte{1, 2, 3, 4}
+	id, err := c.BroadcastTransaction(context.Background(), tx)
+	require.NoError(t, err)
+	require.Equal(t, tx, submitted)
+	require.Equal(t, types.TransactionID(hash.Sum(tx)), id)
+
+	_, err = c.BroadcastTransaction(context.Background(), nil)
+	require.Error(t, err)
+}
+
+func TestBroadcastTransactionRejected(t *testing.T) {
+	reason := "Failed to verify transaction: tx nonce 3 is lower than account nonce 5"
+	testCases := map[string]func([]byte) (*pb.SubmitTransactionResponse, error){
+		"invalid argument": func([]byte) (*pb.SubmitTransactionResponse, error) {
+			return nil, status.Error(codes.InvalidArgument, reason)
+		},
+		"not synced": func([]byte) (*pb.SubmitTransactionResponse, error) {
+			return nil, status.Error(codes.FailedPrecondition, reason)
+		},
+		"status": func([]byte) (*pb.SubmitTransactionResponse, error) {
+			return &pb.SubmitTransactionResponse{
+				Status: &rpcstatus.Status{Code: int32(codes.InvalidArgument), Message: reason},
+			}, nil
+		},
+	}
+	for name, submit := range testCases {
+		t.Run(name, func(t *testing.T) {
+			c := startMockNode(t, &mockNode{submit: submit})
+			_, err := c.BroadcastTransaction(context.Background(), []byte{1})
+			require.ErrorIs(t, err, ErrTransactionRejected)
+			require.Contains(t, err.Error(), reason)
+		})
+	}
+
+	// a rejected state without a reason is reported too
+	c := startMockNode(t, &mockNode{submit: func([]byte) (*pb.SubmitTransactionResponse, error) {
+		return &pb.SubmitTransactionResponse{
+			Txstate: &pb.TransactionState{State: pb.TransactionState_TRANSACTION_STATE_INSUFFICIENT_FUNDS},
+		}, nil
+	}})
+	_, err := c.BroadcastTransaction(context.Background(), []byte{1})
+	require.ErrorIs(t, err, ErrTransactionRejected)
+	require.Contains(t, err.Error(), "INSUFFICIENT_FUNDS")
+}
+
+func TestBroadcastTransactionNotRetried(t *testing.T) {
+	// the node may have received the transaction even if it didn't respond, so it must not be submitted again
+	submitted := 0
+	c := startMockNode(t, &mockNode{submit: func([]byte) (*pb.SubmitTransactionResponse, error) {
+		submitted++
+		return nil, status.Error(codes.Unavailable, "connection reset")
+	}})
+	_, err := c.BroadcastTransaction(context.Background(), []byte{1})
+	require.ErrorIs(t, err, ErrNodeUnavailable)
+	require.Equal(t, 1, submitted)
+}
+
+func TestTransactionStatus(t *testing.T) {
+	id := types.TransactionID{1, 2, 3}
+	testCases := map[string]struct {
+		state    pb.TransactionState_TransactionState
+		result   *pb.TransactionResult
+		expected *TxStatus
+	}{
+		"unknown": {
+			state:    pb.TransactionState_TRANSACTION_STATE_UNSPECIFIED,
+			expected: &TxStatus{ID: id, State: TxStateUnknown},
+		},
+		"pending": {
+			state:    pb.TransactionState_TRANSACTION_STATE_MEMPOOL,
+			expected: &TxStatus{ID: id, State: TxStatePending},
+		},
+		"included": {
+			state:    pb.TransactionState_TRANSACTION_STATE_MESH,
+			expected: &TxStatus{ID: id, State: TxStateIncluded},
+		},
+		"processed without result": {
+			state:    pb.TransactionState_TRANSACTION_STATE_PROCESSED,
+			expected: &TxStatus{ID: id, State: TxStateIncluded},
+		},
+		"applied": {
+			state:    pb.TransactionState_TRANSACTION_STATE_PROCESSED,
+			result:   &pb.TransactionResult{Status: pb.TransactionResult_SUCCESS, Layer: 42, GasConsumed: 10, Fee: 20},
+			expected: &TxStatus{ID: id, State: TxStateApplied, Layer: 42, GasConsumed: 10, Fee: 20},
+		},
+		"failed": {
+			state: pb.TransactionState_TRANSACTION_STATE_PROCESSED,
+			result: &pb.TransactionResult{
+				Status:  pb.TransactionResult_FAILURE,
+				Message: "out of gas",
+				Layer:   42,
+				Fee:     20,
+			},
+			expected: &TxStatus{ID: id, State: TxStateFailed, Reason: "out of gas", Layer: 42, Fee: 20},
+		},
+		"insufficient funds": {
+			state:    pb.TransactionState_TRANSACTION_STATE_INSUFFICIENT_FUNDS,
+			expected: &TxStatus{ID: id, State: TxStateRejected, Reason: "insufficient funds"},
+		},
+	}
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			c := startMockNode(t, &mockNode{
+				txStates: []pb.TransactionState_TransactionState{tc.state},
+				txResult: tc.result,
+			})
+			st, err := c.TransactionStatus(context.Background(), id)
+			require.NoError(t, err)
+			require.Equal(t, tc.expected, st)
+			require.Equal(t, tc.expected.State != TxStateUnknown &&
+				tc.expected.State != TxStatePending &&
+				tc.expected.State != TxStateIncluded, st.Final())
+			require.NotEmpty(t, st.String())
+		})
+	}
+}
+
+func TestWatchTransaction(t *testing.T) {
+	id := types.TransactionID{1, 2, 3}
+	c := startMockNode(t, &mockNode{
+		txStates: []pb.TransactionState_TransactionState{
+			pb.TransactionState_TRANSACTION_STATE_UNSPECIFIED,
+			pb.TransactionState_TRANSACTION_STATE_MEMPOOL,
+			pb.TransactionState_TRANSACTION_STATE_MEMPOOL,
+			pb.TransactionState_TRANSACTION_STATE_MESH,
+			pb.TransactionState_TRANSACTION_STATE_PROCESSED,
+		},
+		txResult: &pb.TransactionResult{Status: pb.TransactionResult_SUCCESS, Layer: 7, Fee: 1},
+	})
+
+	var seen []TxState
+	st, err := c.WatchTransaction(context.Background(), id, time.Millisecond, func(st *TxStatus) { seen = append(seen, st.State) })
+	require.NoError(t, err)
+	require.Equal(t, TxStateApplied, st.State)
+	require.EqualValues(t, 7, st.Layer)
+	require.Equal(t, []TxState{TxStateUnknown, TxStatePending, TxStateIncluded, TxStateApplied}, seen)
+}
+
+func TestWatchTransactionRejected(t *testing.T) {
+	c := startMockNode(t, &mockNode{
+		txStates: []pb.TransactionState_TransactionState{
+			pb.TransactionState_TRANSACTION_STATE_MEMPOOL,
+			pb.TransactionState_TRANSACTION_STATE_CONFLICTING,
+		},
+	})
+	st, err := c.WatchTransaction(context.Background(), types.TransactionID{1}, time.Millisecond, nil)
+	require.NoError(t, err)
+	require.Equal(t, TxStateRejected, st.State)
+	require.True(t, st.Final())
+}
+
+func TestWatchTransactionCancel(t *testing.T) {
+	m := &mockNode{txStates: []pb.TransactionState_TransactionState{pb.TransactionState_TRANSACTION_STATE_MEMPOOL}}
+	c := startMockNode(t, m)
+
+	// the transaction stays pending, so only cancelling stops watching it
+	ctx, cancel := context.WithCancel(context.Background())
+	var seen []TxState
+	start := time.Now()
+	_, err := c.WatchTransaction(ctx, types.TransactionID{1}, time.Minute, func(st *TxStatus) {
+		seen = append(seen, st.State)
+		cancel()
+	})
+	require.ErrorIs(t, err, context.Canceled)
+	require.Equal(t, []TxState{TxStatePending}, seen)
+	require.Less(t, time.Since(start), 5*time.Second)
+}
@@ -0,0 +1,41 @@
+package wallet
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/spacemeshos/smcli/common"
+)
+
+// DefaultAccountCount is the number of accounts of a new wallet if neither a number of accounts nor a configured
+// default is given.
+const DefaultAccountCount = 1
+
+// ResolveAccountCount returns the number of accounts of a new wallet. It's taken from the first of arg, env, and
+// configured that's set, in that order, or else it's DefaultAccountCount, so that a default set in the environment
+// or the config file only applies when no number is given. The number must be between 0 and
+// common.MaxAccountsPerWallet, wherever it comes from.
+func ResolveAccountCount(arg, env, configured string) (int, error) {
+	for _, source := range []struct{ name, value string }{
+		{"argument", arg},
+		{"environment", env},
+		{"config file", configured},
+	} {
+		value := strings.TrimSpace(source.value)
+		if value == "" {
+			continue
+		}
+		n, err := strconv.Atoi(value)
+		if err != nil {
+			return 0, common.NewError(common.ErrBadInput,
+				fmt.Sprintf("number of accounts from %s: %q is not a number", source.name, value))
+		}
+		if n < 0 || n > common.MaxAccountsPerWallet {
+			return 0, fmt.Errorf("%w %d from %s, must be between 0 and %d", ErrInvalidAccountCount, n, source.name,
+				common.MaxAccountsPerWallet)
+		}
+		return n, nil
+	}
+	return DefaultAccountCount, nil
+}
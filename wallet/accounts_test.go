@@ -0,0 +1,47 @@
+package wallet
+
+import (
+	"strconv"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/spacemeshos/smcli/common"
+)
+
+func TestResolveAccountCount(t *testing.T) {
+	for _, tc := range []struct {
+		name                 string
+		arg, env, configured string
+		want                 int
+	}{
+		{"nothing set", "", "", "", DefaultAccountCount},
+		{"argument", "3", "", "", 3},
+		{"argument overrides defaults", "2", "5", "7", 2},
+		{"default applies without argument", "", "", "4", 4},
+		{"environment overrides config", "", "5", "7", 5},
+		{"zero", "", "", "0", 0},
+		{"whitespace", "", " 6\n", "", 6},
+		{"limit", "", "", strconv.Itoa(common.MaxAccountsPerWallet), common.MaxAccountsPerWallet},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			n, err := ResolveAccountCount(tc.arg, tc.env, tc.configured)
+			require.NoError(t, err)
+			require.Equal(t, tc.want, n)
+		})
+	}
+
+	tooMany := strconv.Itoa(common.MaxAccountsPerWallet + 1)
+	for _, args := range [][3]string{{tooMany, "", ""}, {"", tooMany, ""}, {"", "", tooMany}, {"", "", "-1"}} {
+		_, err := ResolveAccountCount(args[0], args[1], args[2])
+		require.ErrorIs(t, err, ErrInvalidAccountCount, args)
+		require.ErrorIs(t, err, common.ErrBadInput)
+	}
+	_, err := ResolveAccountCount("", "", "many")
+	require.ErrorIs(t, err, common.ErrBadInput)
+	require.ErrorContains(t, err, "config file")
+	// an invalid default doesn't matter if a number is given
+	n, err := ResolveAccountCount("1", "", "many")
+	require.NoError(t, err)
+	require.Equal(t, 1, n)
+}
@@ -0,0 +1,83 @@
+package wallet
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/cosmos/btcutil/bech32"
+	"github.com/spacemeshos/go-spacemesh/common/types"
+	"github.com/spacemeshos/go-spacemesh/genvm/core"
+
+	"github.com/spacemeshos/smcli/common"
+)
+
+// Errors parsing addresses, which are all bad input errors (common.ErrBadInput).
+var (
+	ErrAddressEncoding = common.NewError(common.ErrBadInput, "malformed bech32 address")
+	ErrAddressChecksum = common.NewError(common.ErrBadInput, "bad address checksum")
+	ErrAddressHRP      = common.NewError(common.ErrBadInput, "wrong address network prefix (HRP)")
+	ErrAddressLength   = common.NewError(common.ErrBadInput, "wrong address length")
+	ErrAddressReserved = common.NewError(common.ErrBadInput, "address reserved bytes must be zero")
+)
+
+// Errors returned by CheckRecipient for recipients that are valid addresses but probably a mistake.
+var (
+	ErrSelfSend    = common.NewError(common.ErrBadInput, "recipient is the sending account")
+	ErrZeroAddress = common.NewError(common.ErrBadInput, "recipient is the zero address")
+)
+
+// ValidateAddress parses a bech32-encoded Spacemesh address and checks that it belongs to the network with the
+// given HRP. The returned error wraps one of the ErrAddress* errors describing why the address is invalid.
+func ValidateAddress(addr, hrp string) (core.Address, error) {
+	var address core.Address
+	addrHRP, data, err := bech32.DecodeNoLimit(addr)
+	if err != nil {
+		var checksumErr bech32.ErrInvalidChecksum
+		if errors.As(err, &checksumErr) {
+			return address, fmt.Errorf("%w: %v", ErrAddressChecksum, err)
+		}
+		return address, fmt.Errorf("%w: %v", ErrAddressEncoding, err)
+	}
+	if addrHRP != hrp {
+		return address, fmt.Errorf("%w: expected %q, got %q", ErrAddressHRP, hrp, addrHRP)
+	}
+	decoded, err := bech32.ConvertBits(data, 5, 8, false)
+	if err != nil {
+		return address, fmt.Errorf("%w: %v", ErrAddressEncoding, err)
+	}
+	if len(decoded) != types.AddressLength {
+		return address, fmt.Errorf("%w: expected %d bytes, got %d", ErrAddressLength, types.AddressLength, len(decoded))
+	}
+	for i := 0; i < types.AddressReservedSpace; i++ {
+		if decoded[i] != 0 {
+			return address, fmt.Errorf("%w: byte %d is %d", ErrAddressReserved, i, decoded[i])
+		}
+	}
+	copy(address[:], decoded)
+	return address, nil
+}
+
+// CheckRecipient checks the recipient of a spend sent by principal, once it's been parsed using ValidateAddress, for
+// mistakes that the bech32 checksum can't catch: the zero address, whose funds no one can spend, and the sending
+// account itself, which only pays the fee. The returned error wraps ErrZeroAddress or ErrSelfSend; either way the
+// spend is valid, so the caller should ask for confirmation rather than refuse it.
+func CheckRecipient(principal, recipient core.Address) error {
+	if recipient == (core.Address{}) {
+		return fmt.Errorf("%w, funds sent to it can never be spent", ErrZeroAddress)
+	}
+	if recipient == principal {
+		return fmt.Errorf("%w, the spend only pays the fee", ErrSelfSend)
+	}
+	return nil
+}
+
+// AddressString bech32-encodes an address using the given HRP. Unlike core.Address.String it doesn't depend on
+// the global network HRP.
+func AddressString(addr core.Address, hrp string) string {
+	s, err := bech32.EncodeFromBase256(hrp, addr[:])
+	if err != nil {
+		// can't happen: any byte slice can be regrouped into valid 5-bit groups
+		panic(err)
+	}
+	return s
+}
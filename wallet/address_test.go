@@ -0,0 +1,94 @@
+package wallet
+
+import (
+	"encoding/hex"
+	"strings"
+	"testing"
+
+	"github.com/cosmos/btcutil/bech32"
+	"github.com/spacemeshos/go-spacemesh/genvm/core"
+	"github.com/stretchr/testify/require"
+
+	"github.com/spacemeshos/smcli/common"
+)
+
+const validMainnetAddress = "sm1qqqqqqz9rf583slhn38g6q6a562ctltv9fv5w8q2gdz9k"
+
+func encodeAddress(t *testing.T, hrp string, data []byte) string {
+	converted, err := bech32.ConvertBits(data, 8, 5, true)
+	require.NoError(t, err)
+	addr, err := bech32.Encode(hrp, converted)
+	require.NoError(t, err)
+	return addr
+}
+
+func TestValidateAddress(t *testing.T) {
+	pubkey, err := hex.DecodeString("de30fc9b812248583da6259433626fcdd2cb5ce589b00047b81e127950b9bca6")
+	require.NoError(t, err)
+
+	addr, err := ValidateAddress(validMainnetAddress, "sm")
+	require.NoError(t, err)
+	require.Equal(t, validMainnetAddress, PubkeyToAddress(pubkey, "sm"))
+	require.Equal(t, validMainnetAddress, encodeAddress(t, "sm", addr[:]))
+
+	// same address, different network
+	_, err = ValidateAddress(validMainnetAddress, "stest")
+	require.ErrorIs(t, err, ErrAddressHRP)
+
+	// flip the last character of the checksum
+	corrupted := []byte(validMainnetAddress)
+	if corrupted[len(corrupted)-1] == 'q' {
+		corrupted[len(corrupted)-1] = 'p'
+	} else {
+		corrupted[len(corrupted)-1] = 'q'
+	}
+	_, err = ValidateAddress(string(corrupted), "sm")
+	require.ErrorIs(t, err, ErrAddressChecksum)
+
+	// well-formed bech32 with the wrong payload length
+	_, err = ValidateAddress(encodeAddress(t, "sm", addr[:20]), "sm")
+	require.ErrorIs(t, err, ErrAddressLength)
+
+	// reserved bytes must be zero
+	reserved := addr
+	reserved[0] = 1
+	_, err = ValidateAddress(encodeAddress(t, "sm", reserved[:]), "sm")
+	require.ErrorIs(t, err, ErrAddressReserved)
+
+	_, err = ValidateAddress("not an address", "sm")
+	require.ErrorIs(t, err, ErrAddressEncoding)
+}
+
+func TestCheckRecipient(t *testing.T) {
+	sender, err := ValidateAddress(validMainnetAddress, "sm")
+	require.NoError(t, err)
+	recipient := sender
+	recipient[len(recipient)-1]++
+
+	// a mistyped recipient doesn't parse: changing a character of the data part breaks the checksum
+	typo := []byte(AddressString(recipient, "sm"))
+	if typo[10] == 'q' {
+		typo[10] = 'p'
+	} else {
+		typo[10] = 'q'
+	}
+	_, err = ValidateAddress(string(typo), "sm")
+	require.ErrorIs(t, err, ErrAddressChecksum)
+	// ... as does a recipient on another network, or one that's changed case halfway
+	_, err = ValidateAddress(AddressString(recipient, "stest"), "sm")
+	require.ErrorIs(t, err, ErrAddressHRP)
+	mixed := AddressString(recipient, "sm")
+	_, err = ValidateAddress(mixed[:10]+strings.ToUpper(mixed[10:]), "sm")
+	require.ErrorIs(t, err, ErrAddressEncoding)
+
+	require.NoError(t, CheckRecipient(sender, recipient))
+
+	err = CheckRecipient(sender, sender)
+	require.ErrorIs(t, err, ErrSelfSend)
+	require.ErrorIs(t, err, common.ErrBadInput)
+
+	zero, err := ValidateAddress(AddressString(core.Address{}, "sm"), "sm")
+	require.NoError(t, err)
+	require.ErrorIs(t, CheckRecipient(sender, zero), ErrZeroAddress)
+	require.ErrorIs(t, CheckRecipient(zero, zero), ErrZeroAddress)
+}
@@ -0,0 +1,97 @@
+package wallet
+
+import (
+	"fmt"
+	"math/bits"
+	"strconv"
+	"strings"
+
+	"github.com/spacemeshos/economics/constants"
+
+	"github.com/spacemeshos/smcli/common"
+)
+
+// Amounts are denominated in smidge, the base unit: one smesh (SMH) is constants.OneSmesh (a billion) smidge.
+// Users can write them in either unit, see ParseAmount.
+
+// Units accepted by ParseAmount.
+const (
+	UnitSMH    = "SMH"
+	UnitSmidge = "smidge"
+)
+
+// smhDecimals is the number of decimal places of an amount in SMH that can be represented in smidge.
+const smhDecimals = 9
+
+// ErrAmountOverflow is returned by ParseAmount if an amount doesn't fit in a uint64 number of smidge.
+var ErrAmountOverflow = common.NewError(common.ErrBadInput, "amount too large")
+
+// ParseAmount parses an amount written in SMH or in smidge, e.g., "1.5 SMH" or "1500000000 smidge", and returns it
+// in smidge. The unit is case-insensitive and a space before it is optional; an amount without a unit is in smidge.
+// Amounts in SMH can have up to 9 decimal places, while amounts in smidge must be whole numbers.
+func ParseAmount(s string) (uint64, error) {
+	amount := strings.TrimSpace(s)
+	unit := UnitSmidge
+	for _, u := range []string{UnitSMH, UnitSmidge} {
+		if len(amount) >= len(u) && strings.EqualFold(amount[len(amount)-len(u):], u) {
+			amount, unit = strings.TrimSpace(amount[:len(amount)-len(u)]), u
+			break
+		}
+	}
+	whole, frac, hasFrac := strings.Cut(amount, ".")
+	if whole == "" && frac == "" || strings.HasPrefix(whole, "+") || strings.HasPrefix(whole, "-") {
+		return 0, fmt.Errorf("invalid amount %q", s)
+	}
+	if hasFrac && unit == UnitSmidge {
+		return 0, fmt.Errorf("invalid amount %q, amounts in smidge must be whole numbers", s)
+	}
+	if len(frac) > smhDecimals {
+		return 0, fmt.Errorf("invalid amount %q, amounts in SMH can't have more than %d decimal places",
+			s, smhDecimals)
+	}
+	n, err := parseDigits(whole)
+	if err != nil {
+		return 0, fmt.Errorf("invalid amount %q: %w", s, err)
+	}
+	if unit == UnitSmidge {
+		return n, nil
+	}
+	fracSmidge, err := parseDigits(frac + strings.Repeat("0", smhDecimals-len(frac)))
+	if err != nil {
+		return 0, fmt.Errorf("invalid amount %q: %w", s, err)
+	}
+	hi, smidge := bits.Mul64(n, constants.OneSmesh)
+	smidge, carry := bits.Add64(smidge, fracSmidge, 0)
+	if hi != 0 || carry != 0 {
+		return 0, fmt.Errorf("%w: %q", ErrAmountOverflow, s)
+	}
+	return smidge, nil
+}
+
+// parseDigits parses a whole number made of decimal digits only, an empty string being zero.
+func parseDigits(digits string) (uint64, error) {
+	if digits == "" {
+		return 0, nil
+	}
+	for _, r := range digits {
+		if r < '0' || r > '9' {
+			return 0, fmt.Errorf("not a number")
+		}
+	}
+	n, err := strconv.ParseUint(digits, 10, 64)
+	if err != nil {
+		return 0, ErrAmountOverflow
+	}
+	return n, nil
+}
+
+// FormatAmount writes an amount of smidge in SMH, without trailing zeros, e.g., "1.5 SMH". ParseAmount parses it
+// back to the same amount.
+func FormatAmount(smidge uint64) string {
+	whole, frac := smidge/constants.OneSmesh, smidge%constants.OneSmesh
+	if frac == 0 {
+		return fmt.Sprintf("%d %s", whole, UnitSMH)
+	}
+	decimals := strings.TrimRight(fmt.Sprintf("%0*d", smhDecimals, frac), "0")
+	return fmt.Sprintf("%d.%s %s", whole, decimals, UnitSMH)
+}
@@ -0,0 +1,59 @@
+package wallet
+
+import (
+	"math"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseAmount(t *testing.T) {
+	for s, expected := range map[string]uint64{
+		"1.5 SMH":           1500000000,
+		"1.5SMH":            1500000000,
+		"0.000000001 smh":   1,
+		".25 SMH":           250000000,
+		"3. SMH":            3000000000,
+		"2 SMH":             2000000000,
+		"1500000000 smidge": 1500000000,
+		"42smidge":          42,
+		" 42 ":              42,
+		"0":                 0,
+		// the largest amounts that fit
+		"18446744073709551615 smidge": math.MaxUint64,
+		"18446744073.709551615 SMH":   math.MaxUint64,
+	} {
+		amount, err := ParseAmount(s)
+		require.NoError(t, err, s)
+		require.Equal(t, expected, amount, s)
+	}
+
+	for _, s := range []string{
+		"", "SMH", ". SMH", "1.5", "1.5 smidge", "1.0000000001 SMH", "-1 SMH", "+1", "1e9", "0x10", "1,5 SMH",
+		"1 SM", "one SMH", "1 SMH smidge",
+	} {
+		_, err := ParseAmount(s)
+		require.Error(t, err, s)
+	}
+
+	for _, s := range []string{"18446744073709551616 smidge", "18446744073.709551616 SMH", "18446744074 SMH",
+		"99999999999999999999999 SMH"} {
+		_, err := ParseAmount(s)
+		require.ErrorIs(t, err, ErrAmountOverflow, s)
+	}
+}
+
+func TestFormatAmount(t *testing.T) {
+	for smidge, expected := range map[uint64]string{
+		0:              "0 SMH",
+		1:              "0.000000001 SMH",
+		1500000000:     "1.5 SMH",
+		2000000000:     "2 SMH",
+		math.MaxUint64: "18446744073.709551615 SMH",
+	} {
+		require.Equal(t, expected, FormatAmount(smidge))
+		amount, err := ParseAmount(expected)
+		require.NoError(t, err)
+		require.Equal(t, smidge, amount)
+	}
+}
@@ -0,0 +1,110 @@
+package wallet
+
+import (
+	"bufio"
+	"encoding/csv"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+
+	"github.com/spacemeshos/smcli/common"
+)
+
+// Formats of the address lists written by WriteAddresses.
+const (
+	AddressFormatCSV   = "csv"
+	AddressFormatJSONL = "jsonl"
+)
+
+// addressBatchSize is the number of accounts that WriteAddresses derives at a time, which bounds the number of
+// private keys in memory.
+const addressBatchSize = 32
+
+// addressLine is a line of a JSONL address list.
+type addressLine struct {
+	Index     int    `json:"index"`
+	Address   string `json:"address"`
+	PublicKey string `json:"publicKey,omitempty"`
+}
+
+// WriteAddresses derives count accounts starting at account index start, and writes their indices and addresses,
+// and their hex-encoded public keys if withPubkeys is set, to out in order: either as CSV with a header line or
+// as JSON lines. The accounts aren't added to the wallet, and their private keys are wiped as soon as they've been
+// derived. Indices are limited to the accounts a wallet can contain so that the wallet can spend from all of the
+// addresses. Like AddAccounts, deriving needs the BIP39 passphrase or Ledger device passed in using opts.
+func (w *Wallet) WriteAddresses(
+	out io.Writer,
+	start, count int,
+	hrp, format string,
+	withPubkeys bool,
+	opts ...WalletOpt,
+) error {
+	switch {
+	case start < 0:
+		return fmt.Errorf("invalid start index %d, must be at least 0", start)
+	case count < 0 || start+count > common.MaxAccountsPerWallet:
+		return fmt.Errorf("%w %d from index %d, must end at index %d at most",
+			ErrInvalidAccountCount, count, start, common.MaxAccountsPerWallet-1)
+	case format != AddressFormatCSV && format != AddressFormatJSONL:
+		return fmt.Errorf("unsupported address format %q, must be %s or %s", format, AddressFormatCSV,
+			AddressFormatJSONL)
+	}
+	seed, err := w.derivationSeed(opts)
+	if err != nil {
+		return err
+	}
+	defer wipe(seed)
+
+	buf := bufio.NewWriter(out)
+	csvOut := csv.NewWriter(buf)
+	jsonOut := json.NewEncoder(buf)
+	write := func(line *addressLine) error {
+		if format == AddressFormatJSONL {
+			return jsonOut.Encode(line)
+		}
+		record := []string{strconv.Itoa(line.Index), line.Address}
+		if withPubkeys {
+			record = append(record, line.PublicKey)
+		}
+		return csvOut.Write(record)
+	}
+	if format == AddressFormatCSV {
+		header := []string{"index", "address"}
+		if withPubkeys {
+			header = append(header, "publicKey")
+		}
+		if err := csvOut.Write(header); err != nil {
+			return err
+		}
+	}
+
+	for batchStart := start; batchStart < start+count; batchStart += addressBatchSize {
+		indices := make([]int, 0, addressBatchSize)
+		for i := batchStart; i < batchStart+addressBatchSize && i < start+count; i++ {
+			indices = append(indices, i)
+		}
+		accounts, err := accountsFromMasterAtIndices(w.Secrets.MasterKeypair, seed, indices)
+		if err != nil {
+			return err
+		}
+		for _, acct := range accounts {
+			wipe(acct.Private)
+		}
+		for i, acct := range accounts {
+			line := &addressLine{Index: indices[i], Address: AddressString(PubkeyToPrincipal(acct.Public), hrp)}
+			if withPubkeys {
+				line.PublicKey = hex.EncodeToString(acct.Public)
+			}
+			if err := write(line); err != nil {
+				return err
+			}
+		}
+	}
+	csvOut.Flush()
+	if err := csvOut.Error(); err != nil {
+		return err
+	}
+	return buf.Flush()
+}
@@ -0,0 +1,95 @@
+package wallet
+
+import (
+	"bufio"
+	"encoding/csv"
+	"encoding/hex"
+	"encoding/json"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/spacemeshos/smcli/common"
+)
+
+func TestWriteAddresses(t *testing.T) {
+	mnemonic := "film theme cheese broken kingdom destroy inch ready wear inspire shove pudding"
+	w, err := NewMultiWalletFromMnemonic(mnemonic, 8)
+	require.NoError(t, err)
+
+	t.Run("csv", func(t *testing.T) {
+		var buf strings.Builder
+		require.NoError(t, w.WriteAddresses(&buf, 2, 5, "sm", AddressFormatCSV, true))
+		records, err := csv.NewReader(strings.NewReader(buf.String())).ReadAll()
+		require.NoError(t, err)
+		require.Len(t, records, 6)
+		require.Equal(t, []string{"index", "address", "publicKey"}, records[0])
+		for i, record := range records[1:] {
+			acct := w.Secrets.Accounts[2+i]
+			require.Equal(t, []string{
+				strconv.Itoa(2 + i),
+				AddressString(PubkeyToPrincipal(acct.Public), "sm"),
+				hex.EncodeToString(acct.Public),
+			}, record)
+		}
+		require.NotContains(t, buf.String(), hex.EncodeToString(w.Secrets.Accounts[2].Private))
+	})
+
+	t.Run("jsonl", func(t *testing.T) {
+		var buf strings.Builder
+		require.NoError(t, w.WriteAddresses(&buf, 0, 3, "stest", AddressFormatJSONL, false))
+		scanner := bufio.NewScanner(strings.NewReader(buf.String()))
+		var lines []map[string]interface{}
+		for scanner.Scan() {
+			var line map[string]interface{}
+			require.NoError(t, json.Unmarshal(scanner.Bytes(), &line))
+			lines = append(lines, line)
+		}
+		require.Len(t, lines, 3)
+		for i, line := range lines {
+			require.Equal(t, map[string]interface{}{
+				"index":   float64(i),
+				"address": AddressString(PubkeyToPrincipal(w.Secrets.Accounts[i].Public), "stest"),
+			}, line)
+		}
+	})
+
+	t.Run("several batches", func(t *testing.T) {
+		start, count := 20, 2*addressBatchSize+3
+		var buf strings.Builder
+		require.NoError(t, w.WriteAddresses(&buf, start, count, "sm", AddressFormatCSV, false))
+		records, err := csv.NewReader(strings.NewReader(buf.String())).ReadAll()
+		require.NoError(t, err)
+		require.Len(t, records, count+1)
+
+		indices := make([]int, count)
+		for i := range indices {
+			indices[i] = start + i
+		}
+		seed := mnemonicToSeed(mnemonic, "")
+		accounts, err := accountsFromMasterAtIndices(w.Secrets.MasterKeypair, seed, indices)
+		require.NoError(t, err)
+		for i, record := range records[1:] {
+			require.Equal(t, []string{
+				strconv.Itoa(start + i),
+				AddressString(PubkeyToPrincipal(accounts[i].Public), "sm"),
+			}, record)
+		}
+		// nothing is added to the wallet
+		require.Len(t, w.Secrets.Accounts, 8)
+	})
+
+	t.Run("invalid", func(t *testing.T) {
+		var buf strings.Builder
+		require.Error(t, w.WriteAddresses(&buf, -1, 1, "sm", AddressFormatCSV, false))
+		require.ErrorIs(t, w.WriteAddresses(&buf, 0, -1, "sm", AddressFormatCSV, false), ErrInvalidAccountCount)
+		require.ErrorIs(t, w.WriteAddresses(&buf, common.MaxAccountsPerWallet-1, 2, "sm", AddressFormatCSV, false),
+			ErrInvalidAccountCount)
+		require.Error(t, w.WriteAddresses(&buf, 0, 1, "sm", "xml", false))
+		require.ErrorIs(t, w.WriteAddresses(&buf, 0, 1, "sm", AddressFormatCSV, false, WithPassphrase("wrong")),
+			errPassphraseMismatch)
+		require.Empty(t, buf.String())
+	})
+}
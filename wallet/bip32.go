@@ -2,12 +2,14 @@ package wallet
 
 import (
 	"crypto/ed25519"
+	"encoding/base64"
 	"encoding/hex"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"strings"
 
 	smbip32 "github.com/spacemeshos/smkeys/bip32"
-	ledger "github.com/spacemeshos/smkeys/remote-wallet"
 
 	"github.com/spacemeshos/smcli/common"
 )
@@ -17,11 +19,23 @@ import (
 
 type PublicKey ed25519.PublicKey
 
+// Public key encodings supported by ExportPublicKey.
+const (
+	PubkeyEncodingHex    = "hex"
+	PubkeyEncodingBase64 = "base64"
+)
+
+// ErrInvalidPublicKey is returned by ParsePublicKey for keys that can't be decoded or aren't 32 bytes long.
+var ErrInvalidPublicKey = common.NewError(common.ErrBadInput, "invalid public key")
+
 type keyType int
 
 const (
 	typeSoftware keyType = iota
 	typeLedger
+	// typeImported is the key of the only account of a wallet imported from a private key, which has no master
+	// keypair, see NewWalletFromPrivateKey.
+	typeImported
 )
 
 func (k *PublicKey) MarshalJSON() ([]byte, error) {
@@ -59,10 +73,20 @@ type EDKeyPair struct {
 	Public      PublicKey  `json:"publicKey"`
 	Private     PrivateKey `json:"secretKey"`
 	KeyType     keyType    `json:"keyType"`
+	// Label is an optional name for the account chosen by the user, see SetAccountLabel.
+	Label string `json:"label,omitempty"`
+
+	// ledgerDevice is the ID of the Ledger device holding the private key, if any. It's not persisted since
+	// device IDs aren't stable: a wallet file that's reopened uses the only connected device.
+	ledgerDevice string
 }
 
 func NewMasterKeyPair(seed []byte) (*EDKeyPair, error) {
-	path := DefaultPath()
+	return newMasterKeyPairAt(seed, DefaultPath())
+}
+
+// newMasterKeyPairAt derives the master keypair at path, the parent of the accounts, see ParsePathTemplate.
+func newMasterKeyPairAt(seed []byte, path HDPath) (*EDKeyPair, error) {
 	key, err := smbip32.Derive(HDPathToString(path), seed)
 	if err != nil {
 		return nil, err
@@ -81,35 +105,51 @@ func (kp *EDKeyPair) NewChildKeyPair(seed []byte, childIdx int) (*EDKeyPair, err
 	path := kp.Path.Extend(BIP44HardenedAccountIndex(uint32(childIdx)))
 	switch kp.KeyType {
 	case typeLedger:
-		return pubkeyFromLedger(path, false)
+		return pubkeyFromLedger(kp.ledgerDevice, path, false)
 	case typeSoftware:
 		key, err := smbip32.Derive(HDPathToString(path), seed)
 		if err != nil {
 			return nil, err
 		}
-		return &EDKeyPair{
+		child := &EDKeyPair{
 			DisplayName: fmt.Sprintf("Child Key %d", childIdx),
 			Created:     common.NowTimeString(),
 			Private:     key[:],
 			Public:      PublicKey(ed25519.PrivateKey(key).Public().(ed25519.PublicKey)),
 			Path:        path,
-		}, nil
+		}
+		common.Logger().Debug("derived account", "account", child)
+		return child, nil
 	default:
 		return nil, fmt.Errorf("unknown key type")
 	}
 }
 
 func NewMasterKeyPairFromLedger() (*EDKeyPair, error) {
-	return pubkeyFromLedger(DefaultPath(), true)
+	return masterKeyPairFromLedger("")
+}
+
+// masterKeyPairFromLedger reads the master public key from the Ledger device with the given ID, or from the
+// only connected device if the ID is empty, once it's checked that a compatible Spacemesh app is open on it.
+func masterKeyPairFromLedger(deviceID string) (*EDKeyPair, error) {
+	device, err := selectLedgerDevice(ledgerTransport, deviceID)
+	if err != nil {
+		return nil, err
+	}
+	if err := checkLedgerApp(ledgerTransport, device.ID); err != nil {
+		return nil, err
+	}
+	return pubkeyFromLedger(device.ID, DefaultPath(), true)
 }
 
-func pubkeyFromLedger(path HDPath, master bool) (*EDKeyPair, error) {
-	// TODO: support multiple ledger devices (https://github.com/spacemeshos/smcli/issues/46)
+func pubkeyFromLedger(deviceID string, path HDPath, master bool) (*EDKeyPair, error) {
 	// don't bother confirming the master key; we only want the user to have to confirm a single key,
 	// the one they really care about, which is the first child key.
-	key, err := ledger.ReadPubkeyFromLedger("", HDPathToString(path), !master)
-	if err != nil {
-		return nil, fmt.Errorf("error reading pubkey from ledger. Are you sure it's connected, unlocked, and the Spacemesh app is open? err: %w", err)
+	key, err := ledgerTransport.ReadPubkey(deviceID, path, !master)
+	if errors.Is(err, ErrLedgerRejected) {
+		return nil, err
+	} else if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrLedgerAppNotOpen, err)
 	}
 
 	name := "Ledger Master Key"
@@ -121,8 +161,67 @@ func pubkeyFromLedger(path HDPath, master bool) (*EDKeyPair, error) {
 		DisplayName: name,
 		Created:     common.NowTimeString(),
 		// note: we do not set a Private key here (it lives on the device)
-		Public:  key[:],
-		Path:    path,
-		KeyType: typeLedger,
+		Public:       key[:],
+		Path:         path,
+		KeyType:      typeLedger,
+		ledgerDevice: deviceID,
 	}, nil
 }
+
+// Sign produces a detached ed25519 signature over message. The keypair must contain a private key:
+// it panics for keys that live on a hardware device.
+func (kp *EDKeyPair) Sign(message []byte) []byte {
+	return ed25519.Sign(ed25519.PrivateKey(kp.Private), message)
+}
+
+// Verify checks that sig is a valid signature over message by this keypair's public key.
+func (kp *EDKeyPair) Verify(message, sig []byte) bool {
+	return len(kp.Public) == ed25519.PublicKeySize && ed25519.Verify(ed25519.PublicKey(kp.Public), message, sig)
+}
+
+// ExportPublicKey renders the raw ed25519 public key using one of the PubkeyEncoding* encodings.
+func (kp *EDKeyPair) ExportPublicKey(encoding string) (string, error) {
+	if len(kp.Public) != ed25519.PublicKeySize {
+		return "", fmt.Errorf("keypair does not contain a valid public key")
+	}
+	switch encoding {
+	case PubkeyEncodingHex:
+		return hex.EncodeToString(kp.Public), nil
+	case PubkeyEncodingBase64:
+		return base64.StdEncoding.EncodeToString(kp.Public), nil
+	default:
+		return "", fmt.Errorf("unsupported public key encoding %q", encoding)
+	}
+}
+
+// ParsePublicKey decodes a raw ed25519 public key written using one of the PubkeyEncoding* encodings, e.g., by
+// ExportPublicKey. If encoding is empty, it's detected: keys that are valid hex, with or without a 0x prefix, are
+// read as hex, and anything else as base64. The key must be exactly ed25519.PublicKeySize bytes long.
+func ParsePublicKey(s, encoding string) (PublicKey, error) {
+	s = strings.TrimSpace(s)
+	if encoding == "" {
+		encoding = PubkeyEncodingBase64
+		if _, err := hex.DecodeString(strings.TrimPrefix(s, "0x")); err == nil {
+			encoding = PubkeyEncodingHex
+		}
+	}
+	var (
+		key []byte
+		err error
+	)
+	switch encoding {
+	case PubkeyEncodingHex:
+		key, err = hex.DecodeString(strings.TrimPrefix(s, "0x"))
+	case PubkeyEncodingBase64:
+		key, err = base64.StdEncoding.DecodeString(s)
+	default:
+		return nil, fmt.Errorf("unsupported public key encoding %q", encoding)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%w: not %s: %v", ErrInvalidPublicKey, encoding, err)
+	}
+	if len(key) != ed25519.PublicKeySize {
+		return nil, fmt.Errorf("%w: expected %d bytes, got %d", ErrInvalidPublicKey, ed25519.PublicKeySize, len(key))
+	}
+	return key, nil
+}
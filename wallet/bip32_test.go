@@ -2,6 +2,7 @@ package wallet
 
 import (
 	"crypto/ed25519"
+	"encoding/base64"
 	"encoding/hex"
 	"testing"
 
@@ -73,3 +74,93 @@ func TestChildKeyPair(t *testing.T) {
 	require.Equal(t, "05fe9affa5562ca833faf3803ce5f6f7615d3c37c4a27903492027f6853e486dfeae6977b42bf3441d04314d09c72c5d6f2d1cb4bf94834680785b819f8738dd", hex.EncodeToString(privkey2))
 	require.Equal(t, hex.EncodeToString(childKeyPair1.Private), hex.EncodeToString(privkey2))
 }
+
+func TestSignAndVerify(t *testing.T) {
+	masterKeyPair, err := NewMasterKeyPair(goodSeed)
+	require.NoError(t, err)
+	kp, err := masterKeyPair.NewChildKeyPair(goodSeed, 0)
+	require.NoError(t, err)
+
+	msg := []byte("known payload")
+	sig := kp.Sign(msg)
+	require.Len(t, sig, ed25519.SignatureSize)
+	require.True(t, kp.Verify(msg, sig))
+	require.True(t, ed25519.Verify(ed25519.PublicKey(kp.Public), msg, sig))
+
+	// ed25519 signatures are deterministic
+	require.Equal(t, sig, kp.Sign(msg))
+
+	// tampered payload
+	tampered := append([]byte{}, msg...)
+	tampered[0] ^= 1
+	require.False(t, kp.Verify(tampered, sig))
+
+	// tampered signature
+	badSig := append([]byte{}, sig...)
+	badSig[0] ^= 1
+	require.False(t, kp.Verify(msg, badSig))
+
+	// wrong key
+	kp2, err := masterKeyPair.NewChildKeyPair(goodSeed, 1)
+	require.NoError(t, err)
+	require.False(t, kp2.Verify(msg, sig))
+}
+
+func TestExportPublicKey(t *testing.T) {
+	// account 17 of goodSeed
+	const pubkeyHex = "edb1f6c390193978230af0117b906d3f0d5c05c51f8abc1b5cb935c364889627"
+	pubkey, err := hex.DecodeString(pubkeyHex)
+	require.NoError(t, err)
+	kp := &EDKeyPair{Public: pubkey}
+
+	hexKey, err := kp.ExportPublicKey(PubkeyEncodingHex)
+	require.NoError(t, err)
+	require.Equal(t, pubkeyHex, hexKey)
+	base64Key, err := kp.ExportPublicKey(PubkeyEncodingBase64)
+	require.NoError(t, err)
+	require.Equal(t, "7bH2w5AZOXgjCvARe5BtPw1cBcUfirwbXLk1w2SIlic=", base64Key)
+
+	// both encodings decode to the same key
+	decoded, err := base64.StdEncoding.DecodeString(base64Key)
+	require.NoError(t, err)
+	require.Equal(t, pubkey, decoded)
+
+	_, err = kp.ExportPublicKey("base32")
+	require.Error(t, err)
+	_, err = (&EDKeyPair{}).ExportPublicKey(PubkeyEncodingHex)
+	require.Error(t, err)
+}
+
+func TestParsePublicKey(t *testing.T) {
+	const (
+		pubkeyHex    = "f628d5733ba18640769ea46a24000be53a42fbfef0cd2c826044bbc640d119e2"
+		pubkeyBase64 = "9ijVczuhhkB2nqRqJAAL5TpC+/7wzSyCYES7xkDRGeI="
+		address      = "sm1qqqqqqqlgfpwap59jlwmre4p9xrlk8fenfnlsrqva0uyp"
+	)
+	for _, tc := range []struct{ key, encoding string }{
+		{pubkeyHex, ""},
+		{"0x" + pubkeyHex, ""},
+		{pubkeyHex, PubkeyEncodingHex},
+		{pubkeyBase64, ""},
+		{" " + pubkeyBase64 + "\n", PubkeyEncodingBase64},
+	} {
+		pubkey, err := ParsePublicKey(tc.key, tc.encoding)
+		require.NoError(t, err, tc.key)
+		require.Equal(t, pubkeyHex, hex.EncodeToString(pubkey))
+		require.Equal(t, address, PubkeyToAddress(pubkey, "sm"))
+	}
+
+	for _, tc := range []struct{ key, encoding string }{
+		{pubkeyHex[:62], ""},
+		{pubkeyHex + "00", ""},
+		{"AAAA", ""},
+		{"not a key", ""},
+		{pubkeyBase64, PubkeyEncodingHex},
+		{pubkeyHex, PubkeyEncodingBase64},
+	} {
+		_, err := ParsePublicKey(tc.key, tc.encoding)
+		require.ErrorIs(t, err, ErrInvalidPublicKey, tc.key)
+	}
+	_, err := ParsePublicKey(pubkeyHex, "base32")
+	require.Error(t, err)
+}
@@ -4,6 +4,10 @@ import (
 	"encoding/json"
 	"fmt"
 	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/spacemeshos/smcli/common"
 )
 
 //lint:file-ignore SA4016 ignore ineffective bitwise operations to aid readability
@@ -31,6 +35,11 @@ func (p *HDPath) UnmarshalJSON(data []byte) (err error) {
 	if err = json.Unmarshal(data, &s); err != nil {
 		return
 	}
+	// an empty path, e.g., of an account imported from a private key, is written as the root
+	if s == "m" {
+		*p = HDPath{}
+		return
+	}
 	*p, err = StringToHDPath(s)
 	return
 }
@@ -59,8 +68,12 @@ func (p *HDPath) Index() uint32 {
 	return (*p)[HDIndexSegment]
 }
 
+// Extend returns a new path with idx appended. p is left untouched and doesn't share its backing array with the
+// result, so that the paths of sibling accounts extended from the same master path don't overwrite each other.
 func (p *HDPath) Extend(idx uint32) HDPath {
-	return append(*p, idx)
+	path := make(HDPath, len(*p), len(*p)+1)
+	copy(path, *p)
+	return append(path, idx)
 }
 
 // Root of the path is m/purpose' (m/44')
@@ -164,3 +177,75 @@ func StringToHDPath(s string) (HDPath, error) {
 	}
 	return path, nil
 }
+
+// The accounts of a wallet are derived at the path set by a path template, normally DefaultPathTemplate: a BIP32
+// path whose last segment is the placeholder {index}', which is replaced by the account index. Other tools put
+// their accounts at other levels of the Spacemesh BIP44 hierarchy, e.g., m/44'/540'/{index}', so a custom
+// template can be used to reproduce their keys. The keys are derived by smkeys, which limits what a template can
+// be: SLIP-10 only defines hardened derivation for ed25519 keys, so every segment must be hardened, and smkeys
+// only derives Spacemesh keys, so the path must start with m/44'/540' and have at most five segments. Since the
+// account index is read back from the last segment of the path of an account, the placeholder must come last.
+// The master key of the wallet is the key at the template's path without the placeholder.
+
+// PathTemplateIndex is the placeholder for the account index in a path template.
+const PathTemplateIndex = "{index}"
+
+// DefaultPathTemplate is the path template of the accounts of Spacemesh wallets, see DefaultPath.
+const DefaultPathTemplate = "m/44'/540'/0'/0'/" + PathTemplateIndex + "'"
+
+// maxPathDepth is the number of segments of the longest path that smkeys derives keys at.
+const maxPathDepth = 5
+
+// ErrInvalidPathTemplate is returned by ParsePathTemplate for a template that can't be used to derive accounts.
+var ErrInvalidPathTemplate = common.NewError(common.ErrBadInput, "invalid HD path template")
+
+// pathTemplateSyntax matches the BIP32 syntax of a path template, see ParsePathTemplate.
+var pathTemplateSyntax = regexp.MustCompile(`^m(/(\d+|\{index\})'?)+$`)
+
+// ParsePathTemplate parses a path template such as DefaultPathTemplate and returns the path of the master key,
+// which the accounts are the hardened children of. The template must be valid BIP32 syntax, and a path that keys
+// can be derived at: smkeys, which derives the keys of mnemonic wallets, only derives hardened paths of at most
+// maxPathDepth segments under m/44'/540', and fails for any other purpose or coin type, so other templates are
+// rejected here rather than when the first account is derived.
+func ParsePathTemplate(template string) (HDPath, error) {
+	template = strings.TrimSpace(template)
+	if !pathTemplateSyntax.MatchString(template) {
+		return nil, fmt.Errorf("%w %q: must be a BIP32 path such as %s", ErrInvalidPathTemplate, template,
+			DefaultPathTemplate)
+	}
+	segments := strings.Split(template, "/")[1:]
+	last := segments[len(segments)-1]
+	switch {
+	case strings.Count(template, PathTemplateIndex) != 1 || strings.TrimSuffix(last, "'") != PathTemplateIndex:
+		return nil, fmt.Errorf("%w %q: the last segment must be %s', the account index, and it must be the "+
+			"only placeholder", ErrInvalidPathTemplate, template, PathTemplateIndex)
+	case len(segments) > maxPathDepth:
+		return nil, fmt.Errorf("%w %q: at most %d segments are supported", ErrInvalidPathTemplate, template,
+			maxPathDepth)
+	}
+	path := HDPath{}
+	for _, segment := range segments {
+		if !strings.HasSuffix(segment, "'") {
+			return nil, fmt.Errorf("%w %q: segment %s must be hardened (%s'), ed25519 keys only support hardened "+
+				"derivation", ErrInvalidPathTemplate, template, segment, segment)
+		}
+		if segment == last {
+			break
+		}
+		index, err := strconv.ParseUint(strings.TrimSuffix(segment, "'"), 10, 31)
+		if err != nil {
+			return nil, fmt.Errorf("%w %q: segment %s is out of range", ErrInvalidPathTemplate, template, segment)
+		}
+		path = append(path, BIP32HardenedKeyStart|uint32(index))
+	}
+	if len(path) < 2 || path.Purpose() != BIP44Purpose() || path.CoinType() != BIP44SpacemeshCoinType() {
+		return nil, fmt.Errorf("%w %q: must start with m/44'/540', smkeys only derives Spacemesh keys",
+			ErrInvalidPathTemplate, template)
+	}
+	return path, nil
+}
+
+// PathTemplate returns the path template of the accounts of a wallet whose master key is at path.
+func PathTemplate(path HDPath) string {
+	return HDPathToString(path) + "/" + PathTemplateIndex + "'"
+}
@@ -1,8 +1,11 @@
 package wallet
 
 import (
+	"bytes"
+	"encoding/hex"
 	"testing"
 
+	"github.com/spacemeshos/smkeys/bip32"
 	"github.com/stretchr/testify/require"
 )
 
@@ -32,3 +35,96 @@ func TestStringToHDPath(t *testing.T) {
 		})
 	}
 }
+
+func TestParsePathTemplate(t *testing.T) {
+	path, err := ParsePathTemplate(DefaultPathTemplate)
+	require.NoError(t, err)
+	require.Equal(t, DefaultPath(), path)
+	require.Equal(t, DefaultPathTemplate, PathTemplate(path))
+
+	path, err = ParsePathTemplate("m/44'/540'/{index}'")
+	require.NoError(t, err)
+	require.Equal(t, HDPath{BIP32HardenedKeyStart | 44, BIP32HardenedKeyStart | 540}, path)
+
+	for _, template := range []string{
+		"",
+		"m",
+		"m/{index}'",
+		"m/44'/{index}'",
+		"m/44'/540'/0'/0'/{index}",
+		"m/44'/540'/0'/0'",
+		"m/44'/540'/{index}'/0'",
+		"m/44'/540'/{index}'/{index}'",
+		"m/44'/540'/0/{index}'",
+		"m/44'/1'/0'/0'/{index}'",
+		"m/49'/540'/0'/0'/{index}'",
+		"m/44'/540'/0'/0'/0'/{index}'",
+		"44'/540'/{index}'",
+		"m/44'/540'/x'/{index}'",
+		"m/44'/540'//{index}'",
+		"m/44'/540'/2147483648'/{index}'",
+	} {
+		_, err := ParsePathTemplate(template)
+		require.ErrorIs(t, err, ErrInvalidPathTemplate, template)
+	}
+}
+
+func TestPathTemplateSmkeysPaths(t *testing.T) {
+	seed := []byte("seedlen seedlen seedlen seedlen seedlen seedlen seedlen seedlen ")
+
+	// smkeys fails for the templates that ParsePathTemplate rejects because they aren't Spacemesh paths
+	for _, path := range []string{"m/44'/1'/0'/0'/0'", "m/49'/540'/0'/0'/0'", "m/44'/540'/0'/0'/0'/0'"} {
+		_, err := bip32.Derive(path, seed)
+		require.Error(t, err, path)
+	}
+	_, err := bip32.Derive("m/44'/540'/0'", seed)
+	require.NoError(t, err)
+}
+
+func TestPathTemplateDerivation(t *testing.T) {
+	// the accounts are at the paths of the template, with the keys derived there per SLIP-10
+	w, err := NewMultiWalletFromMnemonic(testMnemonic, 2, WithPathTemplate("m/44'/540'/1'/{index}'"))
+	require.NoError(t, err)
+	require.Equal(t, "m/44'/540'/1'/0'", w.Secrets.Accounts[0].Path.String())
+	require.Equal(t, "m/44'/540'/1'/1'", w.Secrets.Accounts[1].Path.String())
+	require.Equal(t, "d7534b54bbcd46998b3672c98dd7843d238e2295430766e76af89321659b46b1",
+		hex.EncodeToString(w.Secrets.Accounts[0].Public))
+	require.Equal(t, "1d35edaf4943793d8fc69e33727af26a8f28bb9153297dd34c03d236cf62ca9f",
+		hex.EncodeToString(w.Secrets.Accounts[1].Public))
+	require.Equal(t, []string{
+		"sm1qqqqqqy4srhc2nugtt8n3wj57pnan2wf8270hec459xyu",
+		"sm1qqqqqqxxad95wrays7gudxsl2n3pdjdf5xfsvqqgjjnmv",
+	}, w.Addresses("sm"))
+
+	// spelling out the default template changes nothing
+	w1, err := NewMultiWalletFromMnemonic(testMnemonic, 2)
+	require.NoError(t, err)
+	w2, err := NewMultiWalletFromMnemonic(testMnemonic, 2, WithPathTemplate(DefaultPathTemplate))
+	require.NoError(t, err)
+	require.Equal(t, w1.Addresses("sm"), w2.Addresses("sm"))
+
+	_, err = NewMultiWalletFromMnemonic(testMnemonic, 1, WithPathTemplate("m/44'/540'/{index}"))
+	require.ErrorIs(t, err, ErrInvalidPathTemplate)
+}
+
+func TestPathTemplateWallet(t *testing.T) {
+	w, err := NewMultiWalletFromMnemonic(testMnemonic, 2, WithPathTemplate("m/44'/540'/{index}'"))
+	require.NoError(t, err)
+	require.Equal(t, "m/44'/540'/1'", w.Secrets.Accounts[1].Path.String())
+
+	// the template is kept in the wallet file, so accounts added later use it too
+	wKey := NewKey(WithRandomSalt(), WithPbkdf2Password([]byte("password")))
+	var buf bytes.Buffer
+	require.NoError(t, wKey.Export(&buf, w))
+	w2, err := wKey.Open(&buf)
+	require.NoError(t, err)
+	require.Equal(t, w.Secrets.Accounts, w2.Secrets.Accounts)
+	require.NoError(t, w2.AddAccounts(1))
+	require.Len(t, w2.Secrets.Accounts, 3)
+	require.Equal(t, "m/44'/540'/2'", w2.Secrets.Accounts[2].Path.String())
+	require.Equal(t, []uint32{0, 1, 2}, []uint32{
+		accountIndex(w2.Secrets.Accounts[0], 0),
+		accountIndex(w2.Secrets.Accounts[1], 1),
+		accountIndex(w2.Secrets.Accounts[2], 2),
+	})
+}
@@ -0,0 +1,72 @@
+package wallet
+
+import (
+	"crypto/rand"
+	"fmt"
+	"io"
+	"math/big"
+	"sort"
+	"strings"
+
+	"golang.org/x/text/unicode/norm"
+
+	"github.com/spacemeshos/smcli/common"
+)
+
+// To make sure that a new mnemonic was written down, the user can be asked to enter a few of its words again,
+// picked at random, after it's no longer on screen.
+
+// DefaultConfirmWords is the number of words of a new mnemonic that the user is asked to enter again.
+const DefaultConfirmWords = 3
+
+// ErrMnemonicMismatch is returned if a word entered to confirm a mnemonic is wrong.
+var ErrMnemonicMismatch = common.NewError(common.ErrBadInput, "mnemonic word mismatch")
+
+// PickConfirmWords picks n distinct positions of words in m for the user to enter again, in increasing order.
+// The positions are zero-based. They're drawn from rnd, which is normally crypto/rand.Reader.
+func PickConfirmWords(m string, n int, rnd io.Reader) ([]int, error) {
+	words := len(strings.Fields(m))
+	if n < 1 || n > words {
+		return nil, fmt.Errorf("invalid number of words to confirm %d, must be between 1 and %d", n, words)
+	}
+	if rnd == nil {
+		rnd = rand.Reader
+	}
+	// shuffle the first n positions into place, Fisher-Yates style
+	positions := make([]int, words)
+	for i := range positions {
+		positions[i] = i
+	}
+	for i := 0; i < n; i++ {
+		j, err := rand.Int(rnd, big.NewInt(int64(words-i)))
+		if err != nil {
+			return nil, fmt.Errorf("error picking words to confirm: %w", err)
+		}
+		k := i + int(j.Int64())
+		positions[i], positions[k] = positions[k], positions[i]
+	}
+	positions = positions[:n]
+	sort.Ints(positions)
+	return positions, nil
+}
+
+// CheckConfirmWords checks the words entered by the user against the words of m at the given positions, see
+// PickConfirmWords. Surrounding whitespace and case are ignored, and the words are compared after NFKD
+// normalization so that accented words match however they were typed. The error doesn't include the expected
+// word, only its position (counting from 1).
+func CheckConfirmWords(m string, positions []int, entered []string) error {
+	words := strings.Fields(m)
+	if len(entered) != len(positions) {
+		return fmt.Errorf("got %d words to confirm, expected %d", len(entered), len(positions))
+	}
+	for i, pos := range positions {
+		if pos < 0 || pos >= len(words) {
+			return fmt.Errorf("invalid word position %d, the mnemonic has %d words", pos, len(words))
+		}
+		word := norm.NFKD.String(strings.TrimSpace(entered[i]))
+		if !strings.EqualFold(word, norm.NFKD.String(words[pos])) {
+			return fmt.Errorf("%w: word %d is wrong", ErrMnemonicMismatch, pos+1)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,65 @@
+package wallet
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"golang.org/x/text/unicode/norm"
+)
+
+func TestPickConfirmWords(t *testing.T) {
+	m := "film theme cheese broken kingdom destroy inch ready wear inspire shove pudding"
+
+	positions, err := PickConfirmWords(m, DefaultConfirmWords, nil)
+	require.NoError(t, err)
+	require.Len(t, positions, DefaultConfirmWords)
+	for i, pos := range positions {
+		require.GreaterOrEqual(t, pos, 0)
+		require.Less(t, pos, 12)
+		if i > 0 {
+			require.Greater(t, pos, positions[i-1], "positions must be distinct and increasing")
+		}
+	}
+
+	// the same randomness picks the same words
+	rnd := bytes.Repeat([]byte{0x5a, 0xc3, 0x17, 0x88}, 16)
+	first, err := PickConfirmWords(m, 4, bytes.NewReader(rnd))
+	require.NoError(t, err)
+	second, err := PickConfirmWords(m, 4, bytes.NewReader(rnd))
+	require.NoError(t, err)
+	require.Equal(t, first, second)
+
+	// every word can be picked
+	all, err := PickConfirmWords(m, 12, nil)
+	require.NoError(t, err)
+	require.Equal(t, []int{0, 1, 2, 3, 4, 5, 6, 7, 8, 9, 10, 11}, all)
+
+	_, err = PickConfirmWords(m, 0, nil)
+	require.Error(t, err)
+	_, err = PickConfirmWords(m, 13, nil)
+	require.Error(t, err)
+	_, err = PickConfirmWords(m, 3, bytes.NewReader(nil))
+	require.ErrorContains(t, err, "error picking words")
+}
+
+func TestCheckConfirmWords(t *testing.T) {
+	m := "film theme cheese broken kingdom destroy inch ready wear inspire shove pudding"
+	positions := []int{2, 6, 11}
+
+	require.NoError(t, CheckConfirmWords(m, positions, []string{"cheese", "inch", "pudding"}))
+	require.NoError(t, CheckConfirmWords(m, positions, []string{" Cheese", "INCH ", "pudding\r"}))
+
+	err := CheckConfirmWords(m, positions, []string{"cheese", "ready", "pudding"})
+	require.ErrorIs(t, err, ErrMnemonicMismatch)
+	require.ErrorContains(t, err, "word 7 is wrong")
+	require.NotContains(t, err.Error(), "inch")
+
+	require.Error(t, CheckConfirmWords(m, positions, []string{"cheese", "inch"}))
+	require.Error(t, CheckConfirmWords(m, []int{12}, []string{"pudding"}))
+
+	// accented words match whether they're typed precomposed or decomposed
+	spanish := "\u00e1baco abierto"
+	require.NoError(t, CheckConfirmWords(spanish, []int{0}, []string{"a\u0301baco"}))
+	require.NoError(t, CheckConfirmWords(norm.NFKD.String(spanish), []int{0}, []string{"\u00e1baco"}))
+}
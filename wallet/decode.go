@@ -0,0 +1,180 @@
+package wallet
+
+import (
+	"bytes"
+	"crypto/ed25519"
+	"fmt"
+
+	"github.com/spacemeshos/go-scale"
+	"github.com/spacemeshos/go-spacemesh/genvm/core"
+	"github.com/spacemeshos/go-spacemesh/genvm/sdk"
+	"github.com/spacemeshos/go-spacemesh/genvm/templates/multisig"
+	"github.com/spacemeshos/go-spacemesh/genvm/templates/vault"
+	"github.com/spacemeshos/go-spacemesh/genvm/templates/vesting"
+	walletTemplate "github.com/spacemeshos/go-spacemesh/genvm/templates/wallet"
+
+	"github.com/spacemeshos/smcli/common"
+)
+
+// Names of the templates and methods reported by DecodeTransaction.
+const (
+	TemplateNameWallet   = "wallet"
+	TemplateNameMultiSig = "multisig"
+	TemplateNameVesting  = "vesting"
+	TemplateNameVault    = "vault"
+
+	MethodNameSpawn      = "spawn"
+	MethodNameSpend      = "spend"
+	MethodNameDrainVault = "drain vault"
+)
+
+// templateNames maps the addresses of the builtin templates to their names.
+var templateNames = map[core.Address]string{
+	walletTemplate.TemplateAddress: TemplateNameWallet,
+	multisig.TemplateAddress:       TemplateNameMultiSig,
+	vesting.TemplateAddress:        TemplateNameVesting,
+	vault.TemplateAddress:          TemplateNameVault,
+}
+
+// DecodedTxn describes a transaction. Which fields are set depends on the method and the template.
+type DecodedTxn struct {
+	Principal core.Address
+	Method    uint8
+	// MethodName is one of the MethodName constants.
+	MethodName string
+	// Template is set for spawn transactions only: other transactions don't include the template of the principal.
+	Template *core.Address
+	// TemplateName is one of the TemplateName constants, or empty for an unknown or unspecified template.
+	TemplateName string
+	Nonce        core.Nonce
+	GasPrice     uint64
+
+	// Recipient and Amount are set for spend and drain vault transactions.
+	Recipient core.Address
+	Amount    uint64
+	// Vault is the vault drained by a drain vault transaction.
+	Vault core.Address
+
+	// Required and PublicKeys are set for wallet, multisig, and vesting spawn transactions.
+	Required   uint8
+	PublicKeys []core.PublicKey
+	// VaultArgs is set for vault spawn transactions.
+	VaultArgs *vault.SpawnArguments
+	// SelfSpawn is set if a spawn transaction spawns its own principal.
+	SelfSpawn bool
+
+	// Signature is the signature of a single-sig transaction, and Parts the signatures of a multisig transaction.
+	// Both are empty for an unsigned transaction.
+	Signature []byte
+	Parts     []multisig.Part
+}
+
+// DecodeTransaction parses an encoded transaction, signed or not, e.g., one produced by GenerateTxnData. It
+// understands spawn transactions for all builtin templates, and spend and drain vault transactions. Transactions
+// that can't be decoded are bad input.
+func DecodeTransaction(tx []byte) (*DecodedTxn, error) {
+	d, err := decodeTransaction(tx)
+	if err != nil {
+		return nil, common.WithCategory(common.ErrBadInput, err)
+	}
+	return d, nil
+}
+
+func decodeTransaction(tx []byte) (*DecodedTxn, error) {
+	r := bytes.NewReader(tx)
+	dec := scale.NewDecoder(r)
+	version, _, err := scale.DecodeCompact8(dec)
+	if err != nil {
+		return nil, fmt.Errorf("error decoding version: %w", err)
+	}
+	if version != uint8(sdk.TxVersion) {
+		return nil, fmt.Errorf("unsupported transaction version %d", version)
+	}
+	d := &DecodedTxn{}
+	if _, err := d.Principal.DecodeScale(dec); err != nil {
+		return nil, fmt.Errorf("error decoding principal: %w", err)
+	}
+	if d.Method, _, err = scale.DecodeCompact8(dec); err != nil {
+		return nil, fmt.Errorf("error decoding method: %w", err)
+	}
+	if d.Method == core.MethodSpawn {
+		d.Template = &core.Address{}
+		if _, err := d.Template.DecodeScale(dec); err != nil {
+			return nil, fmt.Errorf("error decoding template: %w", err)
+		}
+		d.TemplateName = templateNames[*d.Template]
+	}
+	var payload core.Payload
+	if _, err := payload.DecodeScale(dec); err != nil {
+		return nil, fmt.Errorf("error decoding payload: %w", err)
+	}
+	d.Nonce, d.GasPrice = payload.Nonce, payload.GasPrice
+
+	switch d.Method {
+	case core.MethodSpawn:
+		d.MethodName = MethodNameSpawn
+		err = d.decodeSpawn(dec)
+	case core.MethodSpend:
+		d.MethodName = MethodNameSpend
+		var args walletTemplate.SpendArguments
+		_, err = args.DecodeScale(dec)
+		d.Recipient, d.Amount = args.Destination, args.Amount
+	case vesting.MethodDrainVault:
+		d.MethodName = MethodNameDrainVault
+		d.TemplateName = TemplateNameVesting
+		var args vesting.DrainVaultArguments
+		_, err = args.DecodeScale(dec)
+		d.Vault, d.Recipient, d.Amount = args.Vault, args.Destination, args.Amount
+	default:
+		return nil, fmt.Errorf("unknown method %d", d.Method)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error decoding %s arguments: %w", d.MethodName, err)
+	}
+
+	// whatever follows is the signature
+	sigs := tx[len(tx)-r.Len():]
+	switch {
+	case len(sigs) == 0:
+	case len(sigs) == ed25519.SignatureSize:
+		d.Signature = sigs
+	case len(sigs)%(1+ed25519.SignatureSize) == 0:
+		d.Parts = make([]multisig.Part, len(sigs)/(1+ed25519.SignatureSize))
+		if _, err := scale.DecodeStructArray(scale.NewDecoder(bytes.NewReader(sigs)), d.Parts); err != nil {
+			return nil, fmt.Errorf("error decoding signatures: %w", err)
+		}
+	default:
+		return nil, fmt.Errorf("invalid signature length %d", len(sigs))
+	}
+	return d, nil
+}
+
+func (d *DecodedTxn) decodeSpawn(dec *scale.Decoder) error {
+	var args scale.Encodable
+	switch d.TemplateName {
+	case TemplateNameWallet:
+		var wa walletTemplate.SpawnArguments
+		if _, err := wa.DecodeScale(dec); err != nil {
+			return err
+		}
+		d.Required, d.PublicKeys = 1, []core.PublicKey{wa.PublicKey}
+		args = &wa
+	case TemplateNameMultiSig, TemplateNameVesting:
+		var ma multisig.SpawnArguments
+		if _, err := ma.DecodeScale(dec); err != nil {
+			return err
+		}
+		d.Required, d.PublicKeys = ma.Required, ma.PublicKeys
+		args = &ma
+	case TemplateNameVault:
+		d.VaultArgs = &vault.SpawnArguments{}
+		if _, err := d.VaultArgs.DecodeScale(dec); err != nil {
+			return err
+		}
+		args = d.VaultArgs
+	default:
+		return fmt.Errorf("unknown template %x", d.Template[:])
+	}
+	d.SelfSpawn = core.ComputePrincipal(*d.Template, args) == d.Principal
+	return nil
+}
@@ -0,0 +1,141 @@
+package wallet
+
+import (
+	"crypto/ed25519"
+	"testing"
+
+	"github.com/spacemeshos/go-scale"
+	"github.com/spacemeshos/go-spacemesh/common/types"
+	"github.com/spacemeshos/go-spacemesh/genvm/core"
+	"github.com/spacemeshos/go-spacemesh/genvm/sdk"
+	sdkWallet "github.com/spacemeshos/go-spacemesh/genvm/sdk/wallet"
+	"github.com/spacemeshos/go-spacemesh/genvm/templates/multisig"
+	"github.com/spacemeshos/go-spacemesh/genvm/templates/vesting"
+	walletTemplate "github.com/spacemeshos/go-spacemesh/genvm/templates/wallet"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDecodeSpend(t *testing.T) {
+	master, err := NewMasterKeyPair(goodSeed)
+	require.NoError(t, err)
+	accts, err := accountsFromMaster(master, goodSeed, 2)
+	require.NoError(t, err)
+	data := TxnData{
+		Principal: testPrincipal(t, accts[0]),
+		Recipient: testPrincipal(t, accts[1]),
+		Amount:    123456789,
+		Nonce:     7,
+		GasPrice:  2,
+	}
+	unsigned, err := GenerateTxnData(data)
+	require.NoError(t, err)
+
+	expected := &DecodedTxn{
+		Principal:  data.Principal,
+		Method:     core.MethodSpend,
+		MethodName: MethodNameSpend,
+		Nonce:      data.Nonce,
+		GasPrice:   data.GasPrice,
+		Recipient:  data.Recipient,
+		Amount:     data.Amount,
+	}
+	d, err := DecodeTransaction(unsigned)
+	require.NoError(t, err)
+	require.Equal(t, expected, d)
+
+	signed, err := SignTxn(accts[0], types.Hash20{1}, unsigned)
+	require.NoError(t, err)
+	d, err = DecodeTransaction(signed)
+	require.NoError(t, err)
+	expected.Signature = signed[len(unsigned):]
+	require.Equal(t, expected, d)
+
+	// truncated or with trailing garbage
+	for _, tx := range [][]byte{unsigned[:len(unsigned)-1], append(signed, 1, 2), nil, {2}} {
+		_, err = DecodeTransaction(tx)
+		require.Error(t, err)
+	}
+}
+
+func TestDecodeSingleSigSpawn(t *testing.T) {
+	master, err := NewMasterKeyPair(goodSeed)
+	require.NoError(t, err)
+	acct, err := accountFromMaster(master, goodSeed, 0)
+	require.NoError(t, err)
+	tx := sdkWallet.SelfSpawn(ed25519.PrivateKey(acct.Private), 0, sdk.WithGasPrice(3))
+
+	d, err := DecodeTransaction(tx)
+	require.NoError(t, err)
+	require.Equal(t, MethodNameSpawn, d.MethodName)
+	require.Equal(t, walletTemplate.TemplateAddress, *d.Template)
+	require.Equal(t, TemplateNameWallet, d.TemplateName)
+	require.Equal(t, testPrincipal(t, acct), d.Principal)
+	require.True(t, d.SelfSpawn)
+	require.EqualValues(t, 1, d.Required)
+	require.Equal(t, testPubkeys(t, 1), d.PublicKeys)
+	require.EqualValues(t, 0, d.Nonce)
+	require.EqualValues(t, 3, d.GasPrice)
+	require.Len(t, d.Signature, ed25519.SignatureSize)
+}
+
+func TestDecodeMultiSigSpawn(t *testing.T) {
+	master, err := NewMasterKeyPair(goodSeed)
+	require.NoError(t, err)
+	accts, err := accountsFromMaster(master, goodSeed, 3)
+	require.NoError(t, err)
+	pubkeys := SortPublicKeys(testPubkeys(t, 3))
+	principal, _, err := SpawnMultiSig(2, pubkeys)
+	require.NoError(t, err)
+	args := &multisig.SpawnArguments{Required: 2, PublicKeys: pubkeys}
+	payload := core.Payload{Nonce: 1, GasPrice: 1}
+	template := multisig.TemplateAddress
+	unsigned := sdk.Encode(&sdk.TxVersion, &principal, &sdk.MethodSpawn, &template, &payload, args)
+	genesisID := types.Hash20{1}
+	parts := make([]multisig.Part, 2)
+	for i := range parts {
+		parts[i], err = SignMultiSigPart(accts[i], pubkeys, genesisID, unsigned)
+		require.NoError(t, err)
+	}
+	signed, err := AggregateMultiSig(2, pubkeys, genesisID, unsigned, parts)
+	require.NoError(t, err)
+
+	d, err := DecodeTransaction(signed)
+	require.NoError(t, err)
+	require.Equal(t, MethodNameSpawn, d.MethodName)
+	require.Equal(t, TemplateNameMultiSig, d.TemplateName)
+	require.Equal(t, principal, d.Principal)
+	require.True(t, d.SelfSpawn)
+	require.EqualValues(t, 2, d.Required)
+	require.Equal(t, pubkeys, d.PublicKeys)
+	require.EqualValues(t, 1, d.Nonce)
+	require.Empty(t, d.Signature)
+	require.ElementsMatch(t, parts, d.Parts)
+
+	// spawning a vesting account uses the same arguments
+	template = vesting.TemplateAddress
+	unsigned = sdk.Encode(&sdk.TxVersion, &principal, &sdk.MethodSpawn, &template, &payload, args)
+	d, err = DecodeTransaction(unsigned)
+	require.NoError(t, err)
+	require.Equal(t, TemplateNameVesting, d.TemplateName)
+	require.False(t, d.SelfSpawn)
+}
+
+func TestDecodeDrainVault(t *testing.T) {
+	principal, vaultAddr, receiver := types.Address{1}, types.Address{2}, types.Address{3}
+	payload := core.Payload{Nonce: 5, GasPrice: 1}
+	method := scale.U8(vesting.MethodDrainVault)
+	args := &vesting.DrainVaultArguments{Vault: vaultAddr}
+	args.Destination, args.Amount = receiver, 100
+	tx := sdk.Encode(&sdk.TxVersion, &principal, &method, &payload, args)
+
+	d, err := DecodeTransaction(tx)
+	require.NoError(t, err)
+	require.EqualValues(t, vesting.MethodDrainVault, d.Method)
+	require.Equal(t, MethodNameDrainVault, d.MethodName)
+	require.Equal(t, TemplateNameVesting, d.TemplateName)
+	require.Nil(t, d.Template)
+	require.Equal(t, principal, d.Principal)
+	require.Equal(t, vaultAddr, d.Vault)
+	require.Equal(t, receiver, d.Recipient)
+	require.EqualValues(t, 100, d.Amount)
+}
@@ -0,0 +1,124 @@
+package wallet
+
+import (
+	"crypto/sha256"
+	"fmt"
+	"math"
+	"strings"
+	"unicode"
+
+	"github.com/spacemeshos/smcli/common"
+)
+
+// Rather than trusting the system RNG, the entropy of a new mnemonic can be generated by hand by rolling a die or
+// flipping a coin. The rolls are written as a string of digits (1-6 for a die, or h/t or 1/0 for a coin), and the
+// entropy is the SHA-256 of that string truncated to the length required by the mnemonic. Enough rolls must be
+// given for them to contain at least as much entropy as the mnemonic: e.g., 100 dice rolls or 256 coin flips for
+// 24 words.
+
+// Kinds of rolls accepted by MnemonicFromRolls.
+const (
+	RollsDice = "dice"
+	RollsCoin = "coin"
+)
+
+var (
+	// ErrNotEnoughRolls is returned if too few rolls are given for the requested mnemonic length.
+	ErrNotEnoughRolls = common.NewError(common.ErrBadInput, "not enough rolls")
+	// ErrBiasedRolls is returned if the rolls are too regular to have been random, e.g., all identical.
+	ErrBiasedRolls = common.NewError(common.ErrBadInput, "rolls look biased")
+)
+
+// rollSides maps each kind of rolls to its number of sides.
+var rollSides = map[string]int{
+	RollsDice: 6,
+	RollsCoin: 2,
+}
+
+// RollsNeeded returns the minimum number of rolls of the given kind needed for a mnemonic of the given length.
+func RollsNeeded(kind string, words int) (int, error) {
+	sides, ok := rollSides[kind]
+	if !ok {
+		return 0, fmt.Errorf("unsupported kind of rolls %q, must be %s or %s", kind, RollsDice, RollsCoin)
+	}
+	bits, ok := mnemonicEntropyBits[words]
+	if !ok {
+		return 0, common.WithCategory(common.ErrBadInput,
+			fmt.Errorf("invalid mnemonic length %d, must be one of 12, 15, 18, 21, or 24 words", words))
+	}
+	return int(math.Ceil(float64(bits) / math.Log2(float64(sides)))), nil
+}
+
+// MnemonicFromRolls generates a new mnemonic from dice rolls or coin flips entered by the user, see RollsNeeded.
+// Whitespace and commas between rolls are ignored. The same rolls always produce the same mnemonic. Use
+// WithWordCount and WithLanguage to choose the mnemonic.
+func MnemonicFromRolls(kind, rolls string, opts ...WalletOpt) (string, error) {
+	o := newWalletOpts(opts)
+	needed, err := RollsNeeded(kind, o.words)
+	if err != nil {
+		return "", err
+	}
+	normalized, err := normalizeRolls(kind, rolls)
+	if err != nil {
+		return "", err
+	}
+	defer wipe(normalized)
+	if len(normalized) < needed {
+		return "", fmt.Errorf("%w: got %d, need at least %d for %d words",
+			ErrNotEnoughRolls, len(normalized), needed, o.words)
+	}
+	if err := checkRollBias(normalized, rollSides[kind]); err != nil {
+		return "", err
+	}
+
+	sum := sha256.Sum256(normalized)
+	defer wipe(sum[:])
+	return newMnemonic(o.language, sum[:mnemonicEntropyBits[o.words]/8])
+}
+
+// normalizeRolls removes separators and writes coin flips as 1 (heads) and 0 (tails).
+func normalizeRolls(kind, rolls string) ([]byte, error) {
+	normalized := make([]byte, 0, len(rolls))
+	for _, r := range strings.ToLower(rolls) {
+		switch {
+		case unicode.IsSpace(r) || r == ',':
+			continue
+		case kind == RollsDice && r >= '1' && r <= '6':
+			normalized = append(normalized, byte(r))
+		case kind == RollsCoin && (r == 'h' || r == '1'):
+			normalized = append(normalized, '1')
+		case kind == RollsCoin && (r == 't' || r == '0'):
+			normalized = append(normalized, '0')
+		default:
+			wipe(normalized)
+			return nil, fmt.Errorf("invalid %s roll %q", kind, r)
+		}
+	}
+	return normalized, nil
+}
+
+// checkRollBias rejects rolls that are very unlikely to be random: ones in which a value comes up more than six
+// standard deviations more often than expected, or that repeat a short pattern such as 123456123456.
+func checkRollBias(rolls []byte, sides int) error {
+	counts := make(map[byte]int, sides)
+	for _, r := range rolls {
+		counts[r]++
+	}
+	n, p := float64(len(rolls)), 1/float64(sides)
+	limit := n*p + 6*math.Sqrt(n*p*(1-p))
+	for r, count := range counts {
+		if float64(count) > limit {
+			return fmt.Errorf("%w: %c came up %d times out of %d", ErrBiasedRolls, r, count, len(rolls))
+		}
+	}
+	for period := 1; period <= 2*sides && period < len(rolls); period++ {
+		repeats := true
+		for i := period; repeats && i < len(rolls); i++ {
+			repeats = rolls[i] == rolls[i-period]
+		}
+		if repeats {
+			return fmt.Errorf("%w: the rolls repeat every %d", ErrBiasedRolls, period)
+		}
+	}
+	return nil
+}
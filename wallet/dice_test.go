@@ -0,0 +1,91 @@
+package wallet
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+const (
+	testDiceRolls = "3145453354451461113465135422553421536635355226123135455316263246145363253355143543652516244355241256"
+	testCoinFlips = "hhtttthttthhhtthhthhhhtthtthhthththhhthtthhhhthhhthhhththhthtthhttttthhhtththtthhtththhttthhthttthhtttthhhtttthhtththttththttttt"
+)
+
+func TestRollsNeeded(t *testing.T) {
+	for words, expected := range map[int]int{12: 50, 15: 62, 18: 75, 21: 87, 24: 100} {
+		n, err := RollsNeeded(RollsDice, words)
+		require.NoError(t, err)
+		require.Equal(t, expected, n, words)
+		n, err = RollsNeeded(RollsCoin, words)
+		require.NoError(t, err)
+		require.Equal(t, mnemonicEntropyBits[words], n, words)
+	}
+	_, err := RollsNeeded(RollsDice, 13)
+	require.Error(t, err)
+	_, err = RollsNeeded("d20", 24)
+	require.Error(t, err)
+}
+
+func TestMnemonicFromRolls(t *testing.T) {
+	// expected mnemonics computed independently: BIP39 over the truncated SHA-256 of the rolls
+	m, err := MnemonicFromRolls(RollsDice, testDiceRolls)
+	require.NoError(t, err)
+	require.Equal(t, "pioneer click digital marriage acoustic sing giant treat guide bomb project profit main "+
+		"arctic message use banana provide wish final stuff clarify farm normal", m)
+
+	m, err = MnemonicFromRolls(RollsDice, testDiceRolls[:50], WithWordCount(12))
+	require.NoError(t, err)
+	require.Equal(t, "drip regret drama coach crime pumpkin crack panther machine wise carry decorate", m)
+
+	m, err = MnemonicFromRolls(RollsCoin, testCoinFlips, WithWordCount(12))
+	require.NoError(t, err)
+	require.Equal(t, "nice blur bird dial flock debate predict roof artefact menu coil plastic", m)
+
+	// separators, case, and the way coin flips are written don't matter
+	spaced := strings.Join(strings.Split(testDiceRolls, ""), " ")
+	m2, err := MnemonicFromRolls(RollsDice, "  "+strings.ReplaceAll(spaced, " 6", ",6")+"\n")
+	require.NoError(t, err)
+	require.Equal(t, "pioneer", strings.Fields(m2)[0])
+	binary := strings.NewReplacer("h", "1", "t", "0").Replace(testCoinFlips)
+	m2, err = MnemonicFromRolls(RollsCoin, strings.ToUpper(binary[:64])+binary[64:], WithWordCount(12))
+	require.NoError(t, err)
+	require.Equal(t, m, m2)
+
+	// the result is a valid mnemonic in the requested language
+	m, err = MnemonicFromRolls(RollsDice, testDiceRolls, WithLanguage(LanguageSpanish))
+	require.NoError(t, err)
+	lang, err := mnemonicLanguage(m)
+	require.NoError(t, err)
+	require.Equal(t, LanguageSpanish, lang)
+}
+
+func TestMnemonicFromRollsInvalid(t *testing.T) {
+	// not enough rolls for 24 words, though enough for 12
+	_, err := MnemonicFromRolls(RollsDice, testDiceRolls[:99])
+	require.ErrorIs(t, err, ErrNotEnoughRolls)
+	_, err = MnemonicFromRolls(RollsCoin, testCoinFlips[:127], WithWordCount(12))
+	require.ErrorIs(t, err, ErrNotEnoughRolls)
+
+	// not a roll
+	_, err = MnemonicFromRolls(RollsDice, testDiceRolls+"7")
+	require.Error(t, err)
+	_, err = MnemonicFromRolls(RollsCoin, testCoinFlips+"x", WithWordCount(12))
+	require.Error(t, err)
+	_, err = MnemonicFromRolls("d20", testDiceRolls)
+	require.Error(t, err)
+
+	// biased
+	for name, rolls := range map[string]string{
+		"identical": strings.Repeat("4", 100),
+		"pattern":   strings.Repeat("123456", 17),
+		"lopsided":  strings.Repeat("6", 40) + testDiceRolls[:60],
+	} {
+		_, err = MnemonicFromRolls(RollsDice, rolls)
+		require.ErrorIs(t, err, ErrBiasedRolls, name)
+	}
+	_, err = MnemonicFromRolls(RollsCoin, strings.Repeat("ht", 64), WithWordCount(12))
+	require.ErrorIs(t, err, ErrBiasedRolls)
+	_, err = MnemonicFromRolls(RollsCoin, strings.Repeat("h", 100)+testCoinFlips[:28], WithWordCount(12))
+	require.ErrorIs(t, err, ErrBiasedRolls)
+}
@@ -0,0 +1,196 @@
+package wallet
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"unicode"
+
+	"github.com/spacemeshos/smcli/common"
+)
+
+// Wallet files can be kept in a managed wallet directory, normally common.WalletsDirectory, so that they can be
+// referred to by a short name instead of their path. The directory holds an index file mapping each name to its
+// wallet file, along with the unencrypted metadata of the file so that wallets can be listed without a password.
+// The wallet files themselves are unchanged: any wallet file can be added to the index, wherever it is.
+
+// WalletIndexFile is the name of the index file in a managed wallet directory.
+const WalletIndexFile = "index.json"
+
+var (
+	// ErrWalletNameTaken is returned by WalletDirectory.Add if a wallet is already indexed under the name.
+	ErrWalletNameTaken = common.NewError(common.ErrBadInput, "wallet name already in use")
+	// ErrWalletNotIndexed is returned by WalletDirectory.Resolve if no wallet is indexed under the name.
+	ErrWalletNotIndexed = common.NewError(common.ErrBadInput, "no wallet with this name")
+)
+
+// WalletIndexEntry describes a wallet file in a managed wallet directory.
+type WalletIndexEntry struct {
+	// Name is the short name of the wallet.
+	Name string `json:"name"`
+	// File is the path of the wallet file, relative to the directory if it's inside it.
+	File        string `json:"file"`
+	DisplayName string `json:"displayName"`
+	GenesisID   string `json:"genesisID,omitempty"`
+}
+
+// WalletDirectory is a managed wallet directory, see OpenWalletDirectory.
+type WalletDirectory struct {
+	dir     string
+	entries []WalletIndexEntry
+}
+
+// OpenWalletDirectory reads the index of the managed wallet directory dir. A directory without an index, or one
+// that doesn't exist yet, has no wallets.
+func OpenWalletDirectory(dir string) (*WalletDirectory, error) {
+	d := &WalletDirectory{dir: dir}
+	data, err := os.ReadFile(filepath.Join(dir, WalletIndexFile))
+	if errors.Is(err, os.ErrNotExist) {
+		return d, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(data, &d.entries); err != nil {
+		return nil, fmt.Errorf("invalid wallet index %s: %w", filepath.Join(dir, WalletIndexFile), err)
+	}
+	return d, nil
+}
+
+// Dir returns the path of the directory.
+func (d *WalletDirectory) Dir() string {
+	return d.dir
+}
+
+// Path returns the path of the wallet file of an entry.
+func (d *WalletDirectory) Path(e WalletIndexEntry) string {
+	if filepath.IsAbs(e.File) {
+		return e.File
+	}
+	return filepath.Join(d.dir, e.File)
+}
+
+// NewWalletFile returns the path of a new wallet file in the directory for a wallet that's to be added under name,
+// checking that the name is valid and not taken yet.
+func (d *WalletDirectory) NewWalletFile(name string) (string, error) {
+	if err := d.checkNewName(name); err != nil {
+		return "", err
+	}
+	return filepath.Join(d.dir, name+".json"), nil
+}
+
+// List returns the indexed wallets, sorted by name. The metadata of each one is read again from its wallet file,
+// so that it's up to date even if the wallet was renamed: the indexed metadata is returned for files that can't
+// be read, e.g., because they were moved.
+func (d *WalletDirectory) List() []WalletIndexEntry {
+	entries := make([]WalletIndexEntry, len(d.entries))
+	copy(entries, d.entries)
+	for i := range entries {
+		if meta, err := readWalletMeta(d.Path(entries[i])); err == nil {
+			entries[i].DisplayName = meta.DisplayName
+			entries[i].GenesisID = meta.GenesisID
+		}
+	}
+	sort.Slice(entries, func(i, j int) bool { return entries[i].Name < entries[j].Name })
+	return entries
+}
+
+// Resolve returns the path of the wallet file indexed under name. The returned error wraps ErrWalletNotIndexed if
+// there's none.
+func (d *WalletDirectory) Resolve(name string) (string, error) {
+	for _, e := range d.entries {
+		if e.Name == name {
+			return d.Path(e), nil
+		}
+	}
+	return "", fmt.Errorf("%w %q in %s", ErrWalletNotIndexed, name, d.dir)
+}
+
+// Add indexes the wallet file at path under name and saves the index. The name must be unique, and can't contain
+// path separators or whitespace so that it can't be mistaken for a path. The metadata of the file is recorded too,
+// so the file must be a readable wallet file.
+func (d *WalletDirectory) Add(name, path string) error {
+	if err := d.checkNewName(name); err != nil {
+		return err
+	}
+	meta, err := readWalletMeta(path)
+	if err != nil {
+		return err
+	}
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return err
+	}
+	// files in the directory are indexed by relative path, so that the directory can be moved
+	file := abs
+	if dir, err := filepath.Abs(d.dir); err == nil {
+		rel, err := filepath.Rel(dir, abs)
+		if err == nil && !strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+			file = rel
+		}
+	}
+	d.entries = append(d.entries, WalletIndexEntry{
+		Name:        name,
+		File:        file,
+		DisplayName: meta.DisplayName,
+		GenesisID:   meta.GenesisID,
+	})
+	return d.save()
+}
+
+// checkNewName checks that name is a valid wallet name that isn't indexed yet.
+func (d *WalletDirectory) checkNewName(name string) error {
+	if err := checkWalletName(name); err != nil {
+		return err
+	}
+	for _, e := range d.entries {
+		if e.Name == name {
+			return fmt.Errorf("%w: %q is %s", ErrWalletNameTaken, name, d.Path(e))
+		}
+	}
+	return nil
+}
+
+// save writes the index to the directory, creating the directory if needed.
+func (d *WalletDirectory) save() error {
+	if err := os.MkdirAll(d.dir, 0o700); err != nil {
+		return err
+	}
+	return common.WriteFileAtomic(filepath.Join(d.dir, WalletIndexFile), 0o600, func(f io.Writer) error {
+		enc := json.NewEncoder(f)
+		enc.SetIndent("", "  ")
+		return enc.Encode(d.entries)
+	})
+}
+
+// checkWalletName checks the short name of a wallet in a managed wallet directory.
+func checkWalletName(name string) error {
+	if name == "" {
+		return fmt.Errorf("wallet name must not be empty")
+	}
+	if strings.ContainsAny(name, `/\`) || strings.IndexFunc(name, unicode.IsSpace) >= 0 ||
+		strings.HasPrefix(name, ".") {
+		return fmt.Errorf("wallet name %q must not contain path separators or whitespace, or start with a dot", name)
+	}
+	return checkName("wallet name", name)
+}
+
+// readWalletMeta reads the unencrypted metadata of an encrypted or hardware wallet file.
+func readWalletMeta(path string) (walletMetadata, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return walletMetadata{}, err
+	}
+	var file struct {
+		Meta walletMetadata `json:"meta"`
+	}
+	if err := json.Unmarshal(data, &file); err != nil {
+		return walletMetadata{}, fmt.Errorf("%s is not a wallet file: %w", path, err)
+	}
+	return file.Meta, nil
+}
@@ -0,0 +1,76 @@
+package wallet
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestWalletDirectory(t *testing.T) {
+	fixture, err := os.ReadFile("testdata/wallet.json")
+	require.NoError(t, err)
+	dir := filepath.Join(t.TempDir(), "wallets")
+	outside := filepath.Join(t.TempDir(), "savings.json")
+	require.NoError(t, os.WriteFile(outside, fixture, 0o600))
+
+	// the directory doesn't exist yet
+	d, err := OpenWalletDirectory(dir)
+	require.NoError(t, err)
+	require.Empty(t, d.List())
+	_, err = d.Resolve("main")
+	require.ErrorIs(t, err, ErrWalletNotIndexed)
+
+	inside, err := d.NewWalletFile("main")
+	require.NoError(t, err)
+	require.Equal(t, filepath.Join(dir, "main.json"), inside)
+	require.NoError(t, os.MkdirAll(dir, 0o700))
+	require.NoError(t, os.WriteFile(inside, fixture, 0o600))
+	require.NoError(t, d.Add("main", inside))
+	require.NoError(t, d.Add("savings", outside))
+	require.ErrorIs(t, d.Add("main", outside), ErrWalletNameTaken)
+	_, err = d.NewWalletFile("main")
+	require.ErrorIs(t, err, ErrWalletNameTaken)
+	_, err = d.NewWalletFile("a/b")
+	require.Error(t, err)
+	for _, name := range []string{"", "a/b", `a\b`, "my wallet", ".hidden", ".."} {
+		require.Error(t, d.Add(name, outside), name)
+	}
+	require.Error(t, d.Add("missing", filepath.Join(dir, "missing.json")))
+
+	// the index is persisted, with files in the directory indexed relative to it
+	d, err = OpenWalletDirectory(dir)
+	require.NoError(t, err)
+	for name, want := range map[string]string{"main": inside, "savings": outside} {
+		path, err := d.Resolve(name)
+		require.NoError(t, err)
+		require.Equal(t, want, path)
+	}
+	data, err := os.ReadFile(filepath.Join(dir, WalletIndexFile))
+	require.NoError(t, err)
+	var entries []WalletIndexEntry
+	require.NoError(t, json.Unmarshal(data, &entries))
+	require.Equal(t, "main.json", entries[0].File)
+
+	// listing reads the metadata from the files again, so renamed wallets are listed under their new name
+	ew := &EncryptedWalletFile{}
+	require.NoError(t, json.Unmarshal(fixture, ew))
+	require.NoError(t, ew.Rename("Savings"))
+	data, err = json.Marshal(ew)
+	require.NoError(t, err)
+	require.NoError(t, os.WriteFile(outside, data, 0o600))
+	require.Equal(t, []WalletIndexEntry{
+		{Name: "main", File: "main.json", DisplayName: "Main Wallet"},
+		{Name: "savings", File: outside, DisplayName: "Savings"},
+	}, d.List())
+
+	// a wallet file that's gone is still listed as indexed
+	require.NoError(t, os.Remove(outside))
+	require.Equal(t, "Main Wallet", d.List()[1].DisplayName)
+
+	require.NoError(t, os.WriteFile(filepath.Join(dir, WalletIndexFile), []byte("{"), 0o600))
+	_, err = OpenWalletDirectory(dir)
+	require.Error(t, err)
+}
@@ -0,0 +1,61 @@
+package wallet
+
+import (
+	"crypto/ed25519"
+	"fmt"
+	"math/bits"
+
+	"github.com/spacemeshos/go-spacemesh/genvm/core"
+	walletTemplate "github.com/spacemeshos/go-spacemesh/genvm/templates/wallet"
+)
+
+// DefaultGasPrice is the gas price used when none is given, in smidge per unit of gas.
+const DefaultGasPrice = 1
+
+// FeeEstimate is the maximum fee of a transaction.
+type FeeEstimate struct {
+	// Gas is the maximum amount of gas the transaction can consume.
+	Gas uint64
+	// GasPrice is denominated in smidge per unit of gas.
+	GasPrice uint64
+	// Fee is Gas times GasPrice, denominated in smidge.
+	Fee uint64
+}
+
+// EstimateFee computes the maximum fee of a single-sig wallet transaction calling the given method (spawn or
+// spend), where txSize is the size of the signed transaction in bytes. This uses the same gas schedule as
+// go-spacemesh: a fixed cost per method plus a cost per 8 bytes of transaction data.
+func EstimateFee(method uint8, txSize int, gasPrice uint64) (*FeeEstimate, error) {
+	var fixed uint64
+	switch method {
+	case core.MethodSpawn:
+		// self spawn
+		fixed = walletTemplate.ExecGas(method)
+	case core.MethodSpend:
+		fixed = walletTemplate.LoadGas() + walletTemplate.ExecGas(method)
+	default:
+		return nil, fmt.Errorf("unknown method %d", method)
+	}
+	if txSize <= 0 {
+		return nil, fmt.Errorf("invalid transaction size %d", txSize)
+	}
+	gas := walletTemplate.BaseGas(method) + fixed + core.TxDataGas(txSize)
+	hi, fee := bits.Mul64(gas, gasPrice)
+	if hi != 0 {
+		return nil, fmt.Errorf("fee overflows at gas price %d", gasPrice)
+	}
+	return &FeeEstimate{Gas: gas, GasPrice: gasPrice, Fee: fee}, nil
+}
+
+// EstimateTxnFee computes the maximum fee of an unsigned spend transaction produced by GenerateTxnData, or self
+// spawn produced by GenerateSpawnTxnData, once it's signed.
+func EstimateTxnFee(unsigned []byte, gasPrice uint64) (*FeeEstimate, error) {
+	var method uint8 = core.MethodSpend
+	if d, err := DecodeTransaction(unsigned); err == nil && d.Method == core.MethodSpawn {
+		if d.TemplateName != TemplateNameWallet || !d.SelfSpawn {
+			return nil, fmt.Errorf("only single-sig self spawns and spends are supported")
+		}
+		method = core.MethodSpawn
+	}
+	return EstimateFee(method, len(unsigned)+ed25519.SignatureSize, gasPrice)
+}
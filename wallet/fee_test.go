@@ -0,0 +1,81 @@
+package wallet
+
+import (
+	"math"
+	"testing"
+
+	"github.com/spacemeshos/go-spacemesh/genvm/core"
+	walletTemplate "github.com/spacemeshos/go-spacemesh/genvm/templates/wallet"
+	"github.com/stretchr/testify/require"
+)
+
+func TestEstimateFee(t *testing.T) {
+	// spend: 23000 base + 11170 fixed + 128 per 8 bytes of transaction data
+	for _, tc := range []struct {
+		size     int
+		gasPrice uint64
+		gas      uint64
+		fee      uint64
+	}{
+		{size: 120, gasPrice: 1, gas: 36090, fee: 36090},
+		{size: 120, gasPrice: 2, gas: 36090, fee: 72180},
+		{size: 121, gasPrice: 10, gas: 36218, fee: 362180},
+		{size: 1, gasPrice: 0, gas: 34298, fee: 0},
+	} {
+		est, err := EstimateFee(core.MethodSpend, tc.size, tc.gasPrice)
+		require.NoError(t, err)
+		require.Equal(t, &FeeEstimate{Gas: tc.gas, GasPrice: tc.gasPrice, Fee: tc.fee}, est, tc)
+	}
+
+	// self spawn: 53000 base + 45000 fixed
+	est, err := EstimateFee(core.MethodSpawn, 80, 3)
+	require.NoError(t, err)
+	require.Equal(t, &FeeEstimate{Gas: 99280, GasPrice: 3, Fee: 297840}, est)
+
+	_, err = EstimateFee(core.MethodSpend, 100, math.MaxUint64)
+	require.Error(t, err)
+	_, err = EstimateFee(core.MethodSpend, 0, 1)
+	require.Error(t, err)
+	_, err = EstimateFee(42, 100, 1)
+	require.Error(t, err)
+}
+
+func TestEstimateTxnFee(t *testing.T) {
+	master, err := NewMasterKeyPair(goodSeed)
+	require.NoError(t, err)
+	accts, err := accountsFromMaster(master, goodSeed, 2)
+	require.NoError(t, err)
+	unsigned, err := GenerateTxnData(TxnData{
+		Principal: testPrincipal(t, accts[0]),
+		Recipient: testPrincipal(t, accts[1]),
+		Amount:    100,
+		Nonce:     1,
+		GasPrice:  2,
+	})
+	require.NoError(t, err)
+	signed, err := SignTxn(accts[0], [20]byte{}, unsigned)
+	require.NoError(t, err)
+
+	// matches the max gas that go-spacemesh computes for the signed transaction
+	est, err := EstimateTxnFee(unsigned, 2)
+	require.NoError(t, err)
+	gas := core.MaxGas(
+		walletTemplate.BaseGas(core.MethodSpend),
+		walletTemplate.LoadGas()+walletTemplate.ExecGas(core.MethodSpend),
+		signed,
+	)
+	require.Equal(t, gas, est.Gas)
+	require.Equal(t, 2*gas, est.Fee)
+
+	// self spawn
+	var pubkey core.PublicKey
+	copy(pubkey[:], accts[0].Public)
+	unsigned, err = GenerateSpawnTxnData(pubkey, 2)
+	require.NoError(t, err)
+	signed, err = SignTxn(accts[0], [20]byte{}, unsigned)
+	require.NoError(t, err)
+	est, err = EstimateTxnFee(unsigned, 2)
+	require.NoError(t, err)
+	gas = core.MaxGas(walletTemplate.BaseGas(core.MethodSpawn), walletTemplate.ExecGas(core.MethodSpawn), signed)
+	require.Equal(t, gas, est.Gas)
+}
@@ -0,0 +1,71 @@
+package wallet
+
+import (
+	"fmt"
+
+	"github.com/spacemeshos/go-spacemesh/genvm/core"
+
+	"github.com/spacemeshos/smcli/common"
+)
+
+// DefaultGapLimit is the number of accounts past the stored ones that are commonly derived to look for an address,
+// matching the BIP44 address gap limit.
+const DefaultGapLimit = 20
+
+// WithGapLimit makes FindAccount also derive up to n accounts following the last account stored in the wallet,
+// though never beyond common.MaxAccountsPerWallet, if none of the stored accounts match. For ScanAccounts it's
+// the number of consecutive unused accounts after which scanning stops. Deriving accounts needs the same
+// passphrase or Ledger device as AddAccounts.
+func WithGapLimit(n int) WalletOpt {
+	return func(opts *walletOpts) {
+		opts.gapLimit = n
+	}
+}
+
+// FindAccount returns the HD account index of the account that controls the single-sig wallet address addr. The
+// accounts stored in the wallet are checked first, then, if WithGapLimit is set, the ones following them. A
+// derived account is not added to the wallet: use AddAccounts for that. It returns ErrAccountNotFound if no
+// account matches.
+func (w *Wallet) FindAccount(addr core.Address, opts ...WalletOpt) (uint32, error) {
+	o := &walletOpts{}
+	for _, opt := range opts {
+		opt(o)
+	}
+	if o.gapLimit < 0 {
+		return 0, fmt.Errorf("invalid gap limit %d", o.gapLimit)
+	}
+
+	next := 0
+	for i, acct := range w.Secrets.Accounts {
+		index := int(accountIndex(acct, i))
+		if PubkeyToPrincipal(acct.Public) == addr {
+			return uint32(index), nil
+		}
+		if index >= next {
+			next = index + 1
+		}
+	}
+
+	var indices []int
+	for i := next; i < next+o.gapLimit && i < common.MaxAccountsPerWallet; i++ {
+		indices = append(indices, i)
+	}
+	if len(indices) > 0 {
+		seed, err := w.derivationSeed(opts)
+		if err != nil {
+			return 0, err
+		}
+		defer wipe(seed)
+		for _, index := range indices {
+			acct, err := accountFromMaster(w.Secrets.MasterKeypair, seed, index)
+			if err != nil {
+				return 0, err
+			}
+			wipe(acct.Private)
+			if PubkeyToPrincipal(acct.Public) == addr {
+				return uint32(index), nil
+			}
+		}
+	}
+	return 0, fmt.Errorf("%w: no account controls the address", ErrAccountNotFound)
+}
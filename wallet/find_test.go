@@ -0,0 +1,73 @@
+package wallet
+
+import (
+	"testing"
+
+	"github.com/spacemeshos/go-spacemesh/common/types"
+	"github.com/stretchr/testify/require"
+
+	"github.com/spacemeshos/smcli/common"
+)
+
+func TestFindAccount(t *testing.T) {
+	mnemonic := "film theme cheese broken kingdom destroy inch ready wear inspire shove pudding"
+	all, err := NewMultiWalletFromMnemonic(mnemonic, common.MaxAccountsPerWallet)
+	require.NoError(t, err)
+	principal := func(i int) types.Address { return PubkeyToPrincipal(all.Secrets.Accounts[i].Public) }
+
+	w, err := NewMultiWalletFromMnemonic(mnemonic, 3)
+	require.NoError(t, err)
+
+	// stored accounts
+	for i := 0; i < 3; i++ {
+		index, err := w.FindAccount(principal(i))
+		require.NoError(t, err)
+		require.EqualValues(t, i, index)
+	}
+
+	// derived accounts are only found within the gap limit, and aren't added
+	_, err = w.FindAccount(principal(7))
+	require.ErrorIs(t, err, ErrAccountNotFound)
+	_, err = w.FindAccount(principal(7), WithGapLimit(4))
+	require.ErrorIs(t, err, ErrAccountNotFound)
+	index, err := w.FindAccount(principal(7), WithGapLimit(5))
+	require.NoError(t, err)
+	require.EqualValues(t, 7, index)
+	require.Len(t, w.Secrets.Accounts, 3)
+
+	// but never beyond the maximum
+	index, err = w.FindAccount(principal(common.MaxAccountsPerWallet-1), WithGapLimit(1000))
+	require.NoError(t, err)
+	require.EqualValues(t, common.MaxAccountsPerWallet-1, index)
+
+	// an unrelated address
+	_, err = w.FindAccount(types.Address{1, 2, 3}, WithGapLimit(DefaultGapLimit))
+	require.ErrorIs(t, err, ErrAccountNotFound)
+	_, err = w.FindAccount(principal(0), WithGapLimit(-1))
+	require.Error(t, err)
+
+	// a reopened wallet needs the passphrase to derive more accounts
+	hidden, err := NewMultiWalletFromMnemonic(mnemonic, 5, WithPassphrase("hidden"))
+	require.NoError(t, err)
+	w, err = NewMultiWalletFromMnemonic(mnemonic, 1, WithPassphrase("hidden"))
+	require.NoError(t, err)
+	w.Secrets.passphrase = ""
+	target := PubkeyToPrincipal(hidden.Secrets.Accounts[4].Public)
+	_, err = w.FindAccount(target, WithGapLimit(DefaultGapLimit))
+	require.ErrorIs(t, err, errPassphraseMismatch)
+	index, err = w.FindAccount(target, WithGapLimit(DefaultGapLimit), WithPassphrase("hidden"))
+	require.NoError(t, err)
+	require.EqualValues(t, 4, index)
+}
+
+func TestFindAccountLedger(t *testing.T) {
+	useMockLedger(t, newMockLedger(LedgerDevice{ID: "usb://ledger?id=1", Product: "Nano S"}))
+	all, err := NewMultiWalletFromLedger(4)
+	require.NoError(t, err)
+	w, err := NewMultiWalletFromLedger(1)
+	require.NoError(t, err)
+
+	index, err := w.FindAccount(PubkeyToPrincipal(all.Secrets.Accounts[3].Public), WithGapLimit(DefaultGapLimit))
+	require.NoError(t, err)
+	require.EqualValues(t, 3, index)
+}
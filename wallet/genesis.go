@@ -0,0 +1,57 @@
+package wallet
+
+import (
+	"encoding/hex"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/spacemeshos/go-spacemesh/common/types"
+	"github.com/spacemeshos/go-spacemesh/hash"
+
+	"github.com/spacemeshos/smcli/common"
+)
+
+// ErrGenesisIDMismatch is returned when a wallet is used with a network other than the one it was created for.
+var ErrGenesisIDMismatch = common.NewError(common.ErrBadInput, "wallet genesis ID does not match the target network")
+
+// ComputeGenesisID computes the genesis ID of a network from its genesis time and extra data, the same way
+// go-spacemesh does.
+func ComputeGenesisID(genesisTime time.Time, extraData string) types.Hash20 {
+	sum := hash.Sum([]byte(strconv.FormatInt(genesisTime.Unix(), 10)), []byte(extraData))
+	return types.Hash32(sum).ToHash20()
+}
+
+// ParseGenesisID parses a hex-encoded genesis ID, with or without a 0x prefix.
+func ParseGenesisID(s string) (types.Hash20, error) {
+	var id types.Hash20
+	b, err := hex.DecodeString(strings.TrimPrefix(s, "0x"))
+	if err != nil {
+		return id, common.WithCategory(common.ErrBadInput, fmt.Errorf("invalid genesis ID %q: %w", s, err))
+	}
+	if len(b) != len(id) {
+		return id, common.WithCategory(common.ErrBadInput,
+			fmt.Errorf("invalid genesis ID %q: expected %d bytes, got %d", s, len(id), len(b)))
+	}
+	copy(id[:], b)
+	return id, nil
+}
+
+// CheckGenesisID checks that the wallet was created for the network with the given genesis ID, unless
+// allowMismatch is set. Wallet files created before the genesis ID was recorded aren't bound to any network and
+// always pass.
+func (w *Wallet) CheckGenesisID(genesisID types.Hash20, allowMismatch bool) error {
+	if w.Meta.GenesisID == "" {
+		return nil
+	}
+	walletID, err := ParseGenesisID(w.Meta.GenesisID)
+	if err != nil {
+		return fmt.Errorf("error reading wallet metadata: %w", err)
+	}
+	if walletID != genesisID && !allowMismatch {
+		return fmt.Errorf("%w: wallet %s, network %s", ErrGenesisIDMismatch,
+			hex.EncodeToString(walletID[:]), hex.EncodeToString(genesisID[:]))
+	}
+	return nil
+}
@@ -0,0 +1,61 @@
+package wallet
+
+import (
+	"encoding/hex"
+	"testing"
+	"time"
+
+	"github.com/spacemeshos/go-spacemesh/common/types"
+	"github.com/spacemeshos/go-spacemesh/hash"
+	"github.com/stretchr/testify/require"
+)
+
+func TestComputeGenesisID(t *testing.T) {
+	// same construction as go-spacemesh's config.GenesisConfig
+	expected := hash.Sum([]byte("10101"), []byte("one"))
+	id := ComputeGenesisID(time.Unix(10101, 0), "one")
+	require.Equal(t, expected[:20], id.Bytes())
+	require.NotEqual(t, id, ComputeGenesisID(time.Unix(10101, 0), "two"))
+}
+
+func TestParseGenesisID(t *testing.T) {
+	expected := types.Hash20{0xde, 0xad, 0xbe, 0xef}
+	s := hex.EncodeToString(expected[:])
+	id, err := ParseGenesisID(s)
+	require.NoError(t, err)
+	require.Equal(t, expected, id)
+	id, err = ParseGenesisID("0x" + s)
+	require.NoError(t, err)
+	require.Equal(t, expected, id)
+
+	_, err = ParseGenesisID(s[:38])
+	require.Error(t, err)
+	_, err = ParseGenesisID("zz" + s[2:])
+	require.Error(t, err)
+}
+
+func TestCheckGenesisID(t *testing.T) {
+	mainnet := types.Hash20{1}
+	testnet := types.Hash20{2}
+	w, err := NewMultiWalletRandomMnemonic(1, WithGenesisID(mainnet))
+	require.NoError(t, err)
+	require.Equal(t, hex.EncodeToString(mainnet[:]), w.Meta.GenesisID)
+
+	// match
+	require.NoError(t, w.CheckGenesisID(mainnet, false))
+
+	// mismatch, unless explicitly overridden
+	require.ErrorIs(t, w.CheckGenesisID(testnet, false), ErrGenesisIDMismatch)
+	require.NoError(t, w.CheckGenesisID(testnet, true))
+
+	// legacy wallet files don't record a genesis ID
+	legacy, err := NewMultiWalletRandomMnemonic(1)
+	require.NoError(t, err)
+	require.Empty(t, legacy.Meta.GenesisID)
+	require.NoError(t, legacy.CheckGenesisID(mainnet, false))
+	require.NoError(t, legacy.CheckGenesisID(testnet, false))
+
+	// corrupted metadata
+	legacy.Meta.GenesisID = "not hex"
+	require.Error(t, legacy.CheckGenesisID(mainnet, true))
+}
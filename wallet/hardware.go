@@ -0,0 +1,149 @@
+package wallet
+
+import (
+	"crypto/ed25519"
+	"encoding/json"
+	"fmt"
+	"io"
+)
+
+// The private keys of a wallet backed by a Ledger device never leave the device, so there's nothing secret to
+// encrypt. Such a wallet is stored as a hardware wallet file instead: like a watch-only export it holds only the
+// public keys of the accounts, but it also records the device and the master public key needed to derive more
+// accounts and to sign using the device, so that it can be reopened as a full wallet without a password.
+
+// HardwareType identifies a hardware wallet file, to tell it apart from encrypted and watch-only wallet files.
+const HardwareType = "hardware"
+
+// Devices that back hardware wallets.
+const (
+	HardwareDeviceLedger = "ledger"
+)
+
+// HardwareWallet is a wallet backed by a hardware device, stored unencrypted. See Wallet.Hardware.
+type HardwareWallet struct {
+	// Type is always HardwareType.
+	Type string `json:"type"`
+	// Device is one of the HardwareDevice constants.
+	Device string         `json:"device"`
+	Meta   walletMetadata `json:"meta"`
+	// MasterPublicKey and MasterPath identify the master key on the device, which accounts are derived from.
+	MasterPublicKey PublicKey          `json:"masterPublicKey"`
+	MasterPath      HDPath             `json:"masterPath"`
+	Accounts        []WatchOnlyAccount `json:"accounts"`
+	// MultiSigs are the multisig accounts stored in the wallet, see Wallet.AddMultiSig.
+	MultiSigs []*MultiSigAccount `json:"multisigs,omitempty"`
+}
+
+// IsLedger reports whether the wallet is backed by a Ledger device, so that it has no private keys or mnemonic
+// and signing goes through the device.
+func (w *Wallet) IsLedger() bool {
+	master := w.Secrets.MasterKeypair
+	return master != nil && master.KeyType == typeLedger
+}
+
+// Hardware returns the hardware wallet representation of a wallet backed by a Ledger device, to be persisted
+// using Export. It does not share any memory with the wallet.
+func (w *Wallet) Hardware() (*HardwareWallet, error) {
+	if !w.IsLedger() {
+		return nil, fmt.Errorf("wallet is not backed by a Ledger device")
+	}
+	master := w.Secrets.MasterKeypair
+	// hardware wallet files need no migration, they're written in the current version like encrypted ones
+	meta := w.Meta
+	meta.Version = WalletVersion
+	return &HardwareWallet{
+		Type:            HardwareType,
+		Device:          HardwareDeviceLedger,
+		Meta:            meta,
+		MasterPublicKey: append(PublicKey(nil), master.Public...),
+		MasterPath:      append(HDPath(nil), master.Path...),
+		Accounts:        w.WatchOnly().Accounts,
+		MultiSigs:       copyMultiSigs(w.Secrets.MultiSigs),
+	}, nil
+}
+
+// Wallet returns the wallet that the hardware wallet file stands for. Its keys are marked as living on the device,
+// so that AddAccounts, SignOfflineTxn, SignMessage, and ConfirmLedgerAddress use the device.
+func (hw *HardwareWallet) Wallet() *Wallet {
+	master := &EDKeyPair{
+		DisplayName: "Ledger Master Key",
+		Created:     hw.Meta.Created,
+		Path:        append(HDPath(nil), hw.MasterPath...),
+		Public:      append(PublicKey(nil), hw.MasterPublicKey...),
+		KeyType:     typeLedger,
+	}
+	accounts := make([]*EDKeyPair, 0, len(hw.Accounts))
+	for _, acct := range hw.Accounts {
+		accounts = append(accounts, &EDKeyPair{
+			DisplayName: acct.DisplayName,
+			Created:     hw.Meta.Created,
+			Path:        append(HDPath(nil), acct.Path...),
+			Public:      append(PublicKey(nil), acct.PublicKey...),
+			KeyType:     typeLedger,
+			Label:       acct.Label,
+		})
+	}
+	w := &Wallet{
+		Meta: hw.Meta,
+		Secrets: walletSecrets{
+			MasterKeypair: master,
+			Accounts:      accounts,
+			MultiSigs:     copyMultiSigs(hw.MultiSigs),
+		},
+	}
+	w.normalizeTimestamps()
+	return w
+}
+
+// Export writes a hardware wallet as JSON.
+func (hw *HardwareWallet) Export(file io.Writer) error {
+	enc := json.NewEncoder(file)
+	enc.SetIndent("", "  ")
+	return enc.Encode(hw)
+}
+
+// IsHardwareWallet reports whether data looks like a hardware wallet file rather than an encrypted one.
+func IsHardwareWallet(data []byte) bool {
+	var file struct {
+		Type string `json:"type"`
+	}
+	return json.Unmarshal(data, &file) == nil && file.Type == HardwareType
+}
+
+// ReadHardwareWallet reads a hardware wallet written by HardwareWallet.Export.
+func ReadHardwareWallet(file io.Reader) (*HardwareWallet, error) {
+	hw := &HardwareWallet{}
+	if err := json.NewDecoder(file).Decode(hw); err != nil {
+		return nil, fmt.Errorf("failed to read hardware wallet: %w", err)
+	}
+	if hw.Type != HardwareType {
+		return nil, fmt.Errorf("not a hardware wallet: type %q", hw.Type)
+	}
+	if hw.Device != HardwareDeviceLedger {
+		return nil, fmt.Errorf("unsupported hardware wallet device %q", hw.Device)
+	}
+	if hw.Meta.Version > WalletVersion || hw.Meta.Version < 0 {
+		return nil, fmt.Errorf("%w %d, this version of smcli supports wallet files up to version %d",
+			ErrUnsupportedWalletVersion, hw.Meta.Version, WalletVersion)
+	}
+	if len(hw.MasterPublicKey) != ed25519.PublicKeySize {
+		return nil, fmt.Errorf("invalid master public key length %d", len(hw.MasterPublicKey))
+	}
+	for i, acct := range hw.Accounts {
+		if len(acct.PublicKey) != ed25519.PublicKeySize {
+			return nil, fmt.Errorf("account %d: invalid public key length %d", i, len(acct.PublicKey))
+		}
+		child := len(acct.Path) == len(hw.MasterPath)+1
+		for j := 0; child && j < len(hw.MasterPath); j++ {
+			child = acct.Path[j] == hw.MasterPath[j]
+		}
+		if !child {
+			return nil, fmt.Errorf("account %d: HD path %s is not a child of the master key", i, acct.Path.String())
+		}
+	}
+	if err := checkMultiSigs(hw.MultiSigs); err != nil {
+		return nil, err
+	}
+	return hw, nil
+}
@@ -0,0 +1,93 @@
+package wallet
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/spacemeshos/go-spacemesh/common/types"
+	"github.com/spacemeshos/go-spacemesh/genvm/core"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHardwareWallet(t *testing.T) {
+	m := newMockLedger(LedgerDevice{ID: "usb://ledger?id=1", Product: "Nano S"})
+	useMockLedger(t, m)
+	w, err := NewMultiWalletFromLedger(2, WithDisplayName("Cold"), WithGenesisID(types.Hash20{1}))
+	require.NoError(t, err)
+	require.True(t, w.IsLedger())
+	require.NoError(t, w.SetAccountLabel(1, "savings"))
+
+	hw, err := w.Hardware()
+	require.NoError(t, err)
+	var file bytes.Buffer
+	require.NoError(t, hw.Export(&file))
+	require.True(t, IsHardwareWallet(file.Bytes()))
+	require.NotContains(t, file.String(), "secretKey")
+	require.NotContains(t, file.String(), "mnemonic")
+
+	// reopens without a password, with the same addresses
+	read, err := ReadHardwareWallet(&file)
+	require.NoError(t, err)
+	reopened := read.Wallet()
+	require.True(t, reopened.IsLedger())
+	require.Equal(t, w.Addresses("sm"), reopened.Addresses("sm"))
+	require.Equal(t, w.Meta, reopened.Meta)
+	require.Equal(t, "savings", reopened.Secrets.Accounts[1].Label)
+
+	// and still uses the device
+	require.NoError(t, reopened.AddAccounts(1))
+	require.Len(t, reopened.Secrets.Accounts, 3)
+	require.NoError(t, w.AddAccounts(1))
+	require.Equal(t, w.Addresses("sm"), reopened.Addresses("sm"))
+	principals, err := reopened.Principals()
+	require.NoError(t, err)
+	txn, err := NewOfflineTxn(TxnData{Principal: principals[2], Recipient: principals[0], Amount: 1, GasPrice: 1},
+		types.Hash20{1})
+	require.NoError(t, err)
+	signed, index, err := reopened.SignOfflineTxn(txn)
+	require.NoError(t, err)
+	require.EqualValues(t, 2, index)
+	d, err := DecodeTransaction(signed)
+	require.NoError(t, err)
+	require.True(t, reopened.Secrets.Accounts[2].Verify(core.SigningBody(types.Hash20{1}.Bytes(), txn.Unsigned),
+		d.Signature))
+
+	// software wallets have no hardware representation
+	mnemonic := "film theme cheese broken kingdom destroy inch ready wear inspire shove pudding"
+	sw, err := NewMultiWalletFromMnemonic(mnemonic, 1)
+	require.NoError(t, err)
+	require.False(t, sw.IsLedger())
+	_, err = sw.Hardware()
+	require.Error(t, err)
+}
+
+func TestReadHardwareWalletInvalid(t *testing.T) {
+	m := newMockLedger(LedgerDevice{ID: "usb://ledger?id=1", Product: "Nano S"})
+	useMockLedger(t, m)
+	w, err := NewMultiWalletFromLedger(1)
+	require.NoError(t, err)
+	var file bytes.Buffer
+	require.NoError(t, w.WatchOnly().Export(&file))
+	require.False(t, IsHardwareWallet(file.Bytes()))
+	_, err = ReadHardwareWallet(&file)
+	require.Error(t, err)
+
+	hw, err := w.Hardware()
+	require.NoError(t, err)
+	for name, corrupt := range map[string]func(hw *HardwareWallet){
+		"device":     func(hw *HardwareWallet) { hw.Device = "trezor" },
+		"version":    func(hw *HardwareWallet) { hw.Meta.Version = WalletVersion + 1 },
+		"master key": func(hw *HardwareWallet) { hw.MasterPublicKey = hw.MasterPublicKey[1:] },
+		"account":    func(hw *HardwareWallet) { hw.Accounts[0].PublicKey = nil },
+		"path":       func(hw *HardwareWallet) { hw.Accounts[0].Path = hw.MasterPath },
+	} {
+		bad := *hw
+		bad.Accounts = append([]WatchOnlyAccount(nil), hw.Accounts...)
+		corrupt(&bad)
+		var file strings.Builder
+		require.NoError(t, bad.Export(&file))
+		_, err := ReadHardwareWallet(strings.NewReader(file.String()))
+		require.Error(t, err, name)
+	}
+}
@@ -0,0 +1,110 @@
+package wallet
+
+import (
+	"fmt"
+
+	"github.com/spacemeshos/go-spacemesh/genvm/core"
+	"github.com/spacemeshos/go-spacemesh/genvm/templates/vault"
+
+	"github.com/spacemeshos/smcli/common"
+)
+
+// ErrNoMatchingPrincipal is returned by IdentifyPrincipal if none of the candidates spawns the address.
+var ErrNoMatchingPrincipal = common.NewError(common.ErrBadInput, "no candidate spawn arguments match the address")
+
+// An address is a hash of the template and spawn arguments of its account, so it can't be decoded to tell what
+// kind of account it is. IdentifyPrincipal instead recomputes the principal of every candidate the caller
+// suspects, e.g., the keys of the participants of a multisig account, and reports which one matches.
+
+// SpawnCandidate is a guess at the spawn arguments of an account. Set exactly one of PublicKey, for a single-sig
+// wallet account, PublicKeys, for a multisig or vesting account requiring Required of them, or Vault.
+type SpawnCandidate struct {
+	PublicKey  core.PublicKey
+	Required   uint8
+	PublicKeys []core.PublicKey
+	Vault      *vault.SpawnArguments
+}
+
+// PrincipalMatch describes the candidate that spawns an address.
+type PrincipalMatch struct {
+	// Index is the position of the candidate among the ones given to IdentifyPrincipal.
+	Index int
+	// TemplateName is one of the TemplateName constants.
+	TemplateName string
+	// RawKeyOrder is set if a multisig or vesting account matches with the public keys in the order given rather
+	// than in canonical order, see WithRawKeyOrder.
+	RawKeyOrder bool
+}
+
+// spawnedPrincipal is a principal address a candidate could spawn.
+type spawnedPrincipal struct {
+	match PrincipalMatch
+	addr  core.Address
+}
+
+// principals returns the principal addresses the candidate could spawn, by template and key order.
+func (c *SpawnCandidate) principals() ([]spawnedPrincipal, error) {
+	set := 0
+	for _, ok := range []bool{c.PublicKey != (core.PublicKey{}), len(c.PublicKeys) > 0, c.Vault != nil} {
+		if ok {
+			set++
+		}
+	}
+	if set != 1 {
+		return nil, fmt.Errorf("must set exactly one of a public key, multisig public keys, or vault arguments")
+	}
+	switch {
+	case c.PublicKey != (core.PublicKey{}):
+		addr, _, err := SpawnSingleSig(c.PublicKey)
+		if err != nil {
+			return nil, err
+		}
+		return []spawnedPrincipal{{PrincipalMatch{TemplateName: TemplateNameWallet}, addr}}, nil
+	case len(c.PublicKeys) > 0:
+		sorted, _, err := SpawnMultiSig(c.Required, c.PublicKeys)
+		if err != nil {
+			return nil, err
+		}
+		raw, _, err := SpawnMultiSig(c.Required, c.PublicKeys, WithRawKeyOrder())
+		if err != nil {
+			return nil, err
+		}
+		// vesting accounts always keep the keys in the order given
+		vest, _, err := SpawnVesting(c.Required, c.PublicKeys)
+		if err != nil {
+			return nil, err
+		}
+		return []spawnedPrincipal{
+			{PrincipalMatch{TemplateName: TemplateNameMultiSig}, sorted},
+			{PrincipalMatch{TemplateName: TemplateNameMultiSig, RawKeyOrder: true}, raw},
+			{PrincipalMatch{TemplateName: TemplateNameVesting, RawKeyOrder: true}, vest},
+		}, nil
+	default:
+		addr, _, err := SpawnVault(c.Vault)
+		if err != nil {
+			return nil, err
+		}
+		return []spawnedPrincipal{{PrincipalMatch{TemplateName: TemplateNameVault}, addr}}, nil
+	}
+}
+
+// IdentifyPrincipal recomputes the principal address of every candidate and returns the first one that matches
+// target, along with the template of the account. Multisig candidates are tried as multisig accounts with the
+// keys in canonical order and in the order given, and as vesting accounts. The returned error wraps
+// ErrNoMatchingPrincipal if no candidate matches, or describes the first invalid candidate.
+func IdentifyPrincipal(target core.Address, candidates ...SpawnCandidate) (*PrincipalMatch, error) {
+	for i := range candidates {
+		spawned, err := candidates[i].principals()
+		if err != nil {
+			return nil, fmt.Errorf("candidate %d: %w", i, err)
+		}
+		for _, p := range spawned {
+			if p.addr == target {
+				match := p.match
+				match.Index = i
+				return &match, nil
+			}
+		}
+	}
+	return nil, fmt.Errorf("%w: tried %d candidates", ErrNoMatchingPrincipal, len(candidates))
+}
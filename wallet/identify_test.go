@@ -0,0 +1,89 @@
+package wallet
+
+import (
+	"testing"
+
+	"github.com/spacemeshos/economics/constants"
+	"github.com/spacemeshos/go-spacemesh/common/types"
+	"github.com/spacemeshos/go-spacemesh/genvm/core"
+	"github.com/spacemeshos/go-spacemesh/genvm/templates/vault"
+	"github.com/stretchr/testify/require"
+)
+
+func TestIdentifyPrincipal(t *testing.T) {
+	keys := testPubkeys(t, 3)
+	// non-canonical order, so that the raw-order multisig account differs from the canonical one
+	if SortPublicKeys(keys)[0] == keys[0] {
+		keys[0], keys[1] = keys[1], keys[0]
+	}
+	single, _, err := SpawnSingleSig(keys[0])
+	require.NoError(t, err)
+	sorted, _, err := SpawnMultiSig(2, keys)
+	require.NoError(t, err)
+	raw, _, err := SpawnMultiSig(2, keys, WithRawKeyOrder())
+	require.NoError(t, err)
+	vest, _, err := SpawnVesting(2, keys)
+	require.NoError(t, err)
+	vaultArgs := &vault.SpawnArguments{
+		Owner:               vest,
+		TotalAmount:         1000 * constants.OneSmesh,
+		InitialUnlockAmount: 250 * constants.OneSmesh,
+		VestingStart:        types.LayerID(constants.VestStart),
+		VestingEnd:          types.LayerID(constants.VestEnd),
+	}
+	vaultAddr, _, err := SpawnVault(vaultArgs)
+	require.NoError(t, err)
+
+	candidates := []SpawnCandidate{
+		{PublicKey: keys[1]},
+		{PublicKey: keys[0]},
+		{Required: 2, PublicKeys: keys},
+		{Vault: vaultArgs},
+	}
+	testCases := map[string]struct {
+		target core.Address
+		want   PrincipalMatch
+	}{
+		"single-sig":   {single, PrincipalMatch{Index: 1, TemplateName: TemplateNameWallet}},
+		"multisig":     {sorted, PrincipalMatch{Index: 2, TemplateName: TemplateNameMultiSig}},
+		"multisig raw": {raw, PrincipalMatch{Index: 2, TemplateName: TemplateNameMultiSig, RawKeyOrder: true}},
+		"vesting":      {vest, PrincipalMatch{Index: 2, TemplateName: TemplateNameVesting, RawKeyOrder: true}},
+		"vault":        {vaultAddr, PrincipalMatch{Index: 3, TemplateName: TemplateNameVault}},
+	}
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			match, err := IdentifyPrincipal(tc.target, candidates...)
+			require.NoError(t, err)
+			require.Equal(t, tc.want, *match)
+		})
+	}
+
+	// each template type is rejected if its arguments don't match
+	otherVault := *vaultArgs
+	otherVault.TotalAmount++
+	for name, candidate := range map[string]SpawnCandidate{
+		"single-sig": {PublicKey: keys[2]},
+		"multisig":   {Required: 1, PublicKeys: keys},
+		"vault":      {Vault: &otherVault},
+	} {
+		t.Run("reject "+name, func(t *testing.T) {
+			for _, target := range []core.Address{single, sorted, raw, vest, vaultAddr} {
+				_, err := IdentifyPrincipal(target, candidate)
+				require.ErrorIs(t, err, ErrNoMatchingPrincipal)
+			}
+		})
+	}
+	_, err = IdentifyPrincipal(single)
+	require.ErrorIs(t, err, ErrNoMatchingPrincipal)
+
+	// invalid candidates are reported rather than skipped
+	for _, candidate := range []SpawnCandidate{
+		{},
+		{PublicKey: keys[0], Required: 1, PublicKeys: keys},
+		{Required: 4, PublicKeys: keys},
+	} {
+		_, err := IdentifyPrincipal(single, candidate)
+		require.Error(t, err)
+		require.NotErrorIs(t, err, ErrNoMatchingPrincipal)
+	}
+}
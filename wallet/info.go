@@ -0,0 +1,96 @@
+package wallet
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/spacemeshos/smcli/common"
+)
+
+// EncryptedType is the type reported by ReadWalletInfo for encrypted wallet files, which have no type field.
+const EncryptedType = "encrypted"
+
+// WalletInfo describes a wallet file using only its unencrypted parts, see ReadWalletInfo, so that a wallet can be
+// identified without its password.
+type WalletInfo struct {
+	// Type is EncryptedType, HardwareType, WatchOnlyType, or KeyFileType.
+	Type        string `json:"type"`
+	Version     int    `json:"version"`
+	DisplayName string `json:"displayName,omitempty"`
+	Created     string `json:"created,omitempty"`
+	GenesisID   string `json:"genesisID,omitempty"`
+	// Accounts is the number of accounts, or nil if they're encrypted and unknown.
+	Accounts *int `json:"accounts,omitempty"`
+	// Device is the device backing a hardware wallet, one of the HardwareDevice constants.
+	Device string `json:"device,omitempty"`
+	// Cipher, KDF, and KDFParams describe how the secrets of an encrypted wallet or key file are encrypted.
+	Cipher    string `json:"cipher,omitempty"`
+	KDF       string `json:"kdf,omitempty"`
+	KDFParams string `json:"kdfParams,omitempty"`
+}
+
+// ReadWalletInfo reads the unencrypted metadata of an encrypted, hardware, or watch-only wallet file, or of a key
+// file, without decrypting it. The number of accounts of an encrypted wallet isn't known until it's decrypted.
+func ReadWalletInfo(file io.Reader) (*WalletInfo, error) {
+	var f struct {
+		Type     string                 `json:"type"`
+		Device   string                 `json:"device"`
+		Meta     walletMetadata         `json:"meta"`
+		Accounts []json.RawMessage      `json:"accounts"`
+		Created  string                 `json:"created"`
+		Version  int                    `json:"version"`
+		Secrets  walletSecretsEncrypted `json:"crypto"`
+	}
+	if err := json.NewDecoder(file).Decode(&f); err != nil {
+		return nil, fmt.Errorf("not a wallet file: %w", err)
+	}
+	info := &WalletInfo{
+		Type:        f.Type,
+		Version:     f.Meta.Version,
+		DisplayName: f.Meta.DisplayName,
+		Created:     common.NormalizeTimeString(f.Meta.Created),
+		GenesisID:   f.Meta.GenesisID,
+	}
+	switch f.Type {
+	case HardwareType, WatchOnlyType:
+		n := len(f.Accounts)
+		info.Accounts = &n
+		info.Device = f.Device
+		return info, nil
+	case KeyFileType:
+		// a key file holds a single key and no wallet metadata
+		n := 1
+		info.Accounts = &n
+		info.Version = f.Version
+		info.Created = common.NormalizeTimeString(f.Created)
+	case "":
+		if f.Secrets.Cipher == "" {
+			return nil, fmt.Errorf("not a wallet file: no encrypted secrets")
+		}
+		info.Type = EncryptedType
+	default:
+		return nil, fmt.Errorf("unsupported wallet file type %q", f.Type)
+	}
+	info.Cipher = f.Secrets.Cipher
+	info.KDF = f.Secrets.KDF
+	info.KDFParams = describeKDFParams(f.Secrets.KDF, f.Secrets.KDFParams)
+	return info, nil
+}
+
+// describeKDFParams returns a short description of the params of a KDF, e.g., "210000 iterations, SHA-512". The
+// hash of PBKDF2 is always Pbkdf2HashFunc, whatever the file's label says, see Pbkdf2HashName.
+func describeKDFParams(kdf string, params kdfParams) string {
+	var desc []string
+	switch kdf {
+	case KDFPbkdf2:
+		desc = append(desc, fmt.Sprintf("%d iterations", params.Iterations), Pbkdf2HashName)
+	case KDFScrypt:
+		desc = append(desc, fmt.Sprintf("N=%d", params.N), fmt.Sprintf("r=%d", params.R), fmt.Sprintf("p=%d", params.P))
+	case KDFArgon2:
+		desc = append(desc, fmt.Sprintf("%d passes", params.Iterations), fmt.Sprintf("%d MiB", params.Memory/1024),
+			fmt.Sprintf("%d threads", params.Parallelism))
+	}
+	return strings.Join(desc, ", ")
+}
@@ -0,0 +1,72 @@
+package wallet
+
+import (
+	"bytes"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestReadWalletInfoFixture(t *testing.T) {
+	// no password is needed
+	f, err := os.Open("testdata/wallet.json")
+	require.NoError(t, err)
+	defer f.Close()
+	info, err := ReadWalletInfo(f)
+	require.NoError(t, err)
+	// the fixture labels its hash "SHA-256", but it's derived using SHA-512
+	require.Equal(t, &WalletInfo{
+		Type:        EncryptedType,
+		DisplayName: "Main Wallet",
+		Created:     "2023-07-12T10:00:00.000Z",
+		Cipher:      "AES-GCM",
+		KDF:         KDFPbkdf2,
+		KDFParams:   "210000 iterations, SHA-512",
+	}, info)
+}
+
+func TestReadWalletInfo(t *testing.T) {
+	w, err := NewMultiWalletFromMnemonic(testMnemonic, 3, WithDisplayName("Savings"))
+	require.NoError(t, err)
+
+	wk := NewKey(WithRandomSalt(), WithArgon2Params(1, 64*1024, 2), WithArgon2Password([]byte("password")))
+	var file bytes.Buffer
+	require.NoError(t, wk.Export(&file, w))
+	info, err := ReadWalletInfo(&file)
+	require.NoError(t, err)
+	require.Equal(t, EncryptedType, info.Type)
+	require.Equal(t, WalletVersion, info.Version)
+	require.Equal(t, "Savings", info.DisplayName)
+	require.Equal(t, w.Meta.Created, info.Created)
+	require.Nil(t, info.Accounts)
+	require.Equal(t, KDFArgon2, info.KDF)
+	require.Equal(t, "1 passes, 64 MiB, 2 threads", info.KDFParams)
+
+	// PBKDF2 files are labeled with the hash they actually use
+	file.Reset()
+	wk = NewKey(WithRandomSalt(), WithIterations(1000), WithPbkdf2Password([]byte("password")))
+	require.NoError(t, wk.Export(&file, w))
+	require.Contains(t, file.String(), `"hash":"`+Pbkdf2HashName+`"`)
+	info, err = ReadWalletInfo(&file)
+	require.NoError(t, err)
+	require.Equal(t, KDFPbkdf2, info.KDF)
+	require.Equal(t, "1000 iterations, SHA-512", info.KDFParams)
+
+	// watch-only wallets list their accounts in the clear
+	file.Reset()
+	require.NoError(t, w.WatchOnly().Export(&file))
+	info, err = ReadWalletInfo(&file)
+	require.NoError(t, err)
+	require.Equal(t, WatchOnlyType, info.Type)
+	require.Equal(t, "Savings", info.DisplayName)
+	require.NotNil(t, info.Accounts)
+	require.Equal(t, 3, *info.Accounts)
+	require.Empty(t, info.Cipher)
+
+	for _, data := range []string{"", "not json", `{"type":"other"}`, `{"meta":{}}`} {
+		_, err := ReadWalletInfo(strings.NewReader(data))
+		require.Error(t, err, data)
+	}
+}
@@ -0,0 +1,118 @@
+package wallet
+
+import (
+	"bytes"
+	"crypto/ed25519"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/spacemeshos/smcli/common"
+)
+
+// A key file holds a single ed25519 private key, e.g., one imported using NewWalletFromPrivateKey, for quick
+// operations without a full wallet. The key is encrypted like the secrets of a wallet file, using the same
+// ciphers and KDFs, and the public key is stored in the clear so that the file can be told apart from others.
+
+// KeyFileType identifies a key file, to tell it apart from wallet files.
+const KeyFileType = "key"
+
+// KeyFileVersion is the version of the key file format written by ExportKeyFile.
+const KeyFileVersion = 1
+
+// ErrMalformedKeyFile is returned by OpenKeyFile for files that aren't valid key files. A file that decodes but
+// can't be decrypted returns ErrWrongPassword instead.
+var ErrMalformedKeyFile = common.NewError(common.ErrBadInput, "malformed key file")
+
+// EncryptedKeyFile is the encrypted representation of a key file on the filesystem.
+type EncryptedKeyFile struct {
+	// Type is always KeyFileType.
+	Type      string                 `json:"type"`
+	Version   int                    `json:"version"`
+	Created   string                 `json:"created"`
+	PublicKey PublicKey              `json:"publicKey"`
+	Secrets   walletSecretsEncrypted `json:"crypto"`
+}
+
+// keyFileSecrets is the plaintext of a key file.
+type keyFileSecrets struct {
+	Private PrivateKey `json:"secretKey"`
+}
+
+// IsKeyFile reports whether data looks like a key file written by ExportKeyFile.
+func IsKeyFile(data []byte) bool {
+	var file struct {
+		Type string `json:"type"`
+	}
+	return json.Unmarshal(data, &file) == nil && file.Type == KeyFileType
+}
+
+// ExportKeyFile encrypts the private key of kp using k and writes it to file as a key file.
+func (k *WalletKey) ExportKeyFile(file io.Writer, kp *EDKeyPair) error {
+	if len(kp.Private) != ed25519.PrivateKeySize {
+		return fmt.Errorf("keypair does not contain a private key")
+	}
+	plaintext, err := json.Marshal(&keyFileSecrets{Private: kp.Private})
+	if err != nil {
+		return err
+	}
+	defer wipe(plaintext)
+	ew := &EncryptedWalletFile{}
+	if err := k.encryptSecrets(ew, plaintext); err != nil {
+		return err
+	}
+	return json.NewEncoder(file).Encode(&EncryptedKeyFile{
+		Type:      KeyFileType,
+		Version:   KeyFileVersion,
+		Created:   common.NowTimeString(),
+		PublicKey: append(PublicKey(nil), kp.Public...),
+		Secrets:   ew.Secrets,
+	})
+}
+
+// OpenKeyFile reads and decrypts a key file written by ExportKeyFile and returns its keypair, which has no HD
+// path. It returns ErrMalformedKeyFile if the file isn't a valid key file, and ErrWrongPassword if it can't be
+// decrypted using k.
+func (k *WalletKey) OpenKeyFile(file io.Reader) (*EDKeyPair, error) {
+	ek := &EncryptedKeyFile{}
+	if err := json.NewDecoder(file).Decode(ek); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrMalformedKeyFile, err)
+	}
+	if ek.Type != KeyFileType {
+		return nil, fmt.Errorf("%w: type %q", ErrMalformedKeyFile, ek.Type)
+	}
+	if ek.Version != KeyFileVersion {
+		return nil, fmt.Errorf("%w: unsupported version %d", ErrMalformedKeyFile, ek.Version)
+	}
+	if len(ek.PublicKey) != ed25519.PublicKeySize {
+		return nil, fmt.Errorf("%w: invalid public key", ErrMalformedKeyFile)
+	}
+
+	plaintext, err := k.decryptSecrets(&EncryptedWalletFile{
+		Meta:    walletMetadata{Version: WalletVersion},
+		Secrets: ek.Secrets,
+	})
+	if errors.Is(err, ErrWrongPassword) {
+		return nil, err
+	} else if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrMalformedKeyFile, err)
+	}
+	defer wipe(plaintext)
+	secrets := &keyFileSecrets{}
+	if err := json.Unmarshal(plaintext, secrets); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrMalformedKeyFile, err)
+	}
+	if len(secrets.Private) != ed25519.PrivateKeySize ||
+		!bytes.Equal(ed25519.PrivateKey(secrets.Private).Public().(ed25519.PublicKey), ek.PublicKey) {
+		wipe(secrets.Private)
+		return nil, fmt.Errorf("%w: private key doesn't match the public key", ErrMalformedKeyFile)
+	}
+	return &EDKeyPair{
+		DisplayName: "Key File",
+		Created:     ek.Created,
+		Public:      ek.PublicKey,
+		Private:     secrets.Private,
+		KeyType:     typeImported,
+	}, nil
+}
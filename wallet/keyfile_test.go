@@ -0,0 +1,103 @@
+package wallet
+
+import (
+	"bytes"
+	"crypto/ed25519"
+	"encoding/hex"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/spacemeshos/go-spacemesh/common/types"
+	"github.com/spacemeshos/go-spacemesh/genvm/core"
+	"github.com/stretchr/testify/require"
+)
+
+// testKeyFile writes a key file holding a new random key, encrypted using password, and returns it with the key.
+func testKeyFile(t *testing.T, password []byte) ([]byte, *EDKeyPair) {
+	t.Helper()
+	_, priv, err := ed25519.GenerateKey(nil)
+	require.NoError(t, err)
+	w, err := NewWalletFromPrivateKey(hex.EncodeToString(priv))
+	require.NoError(t, err)
+	kp := w.Secrets.Accounts[0]
+	wk := NewKey(WithRandomSalt(), WithIterations(1000), WithPbkdf2Password(password))
+	var file bytes.Buffer
+	require.NoError(t, wk.ExportKeyFile(&file, kp))
+	return file.Bytes(), kp
+}
+
+func TestKeyFileSign(t *testing.T) {
+	password := []byte("password")
+	file, kp := testKeyFile(t, password)
+	require.True(t, IsKeyFile(file))
+	require.False(t, IsHardwareWallet(file))
+	require.NotContains(t, string(file), hex.EncodeToString(kp.Private))
+
+	rk := NewKey(WithPasswordOnly(password))
+	opened, err := rk.OpenKeyFile(bytes.NewReader(file))
+	require.NoError(t, err)
+	require.Equal(t, kp.Public, opened.Public)
+	require.Equal(t, kp.Private, opened.Private)
+
+	// signatures are the same as from a wallet holding the key
+	message := []byte("hello")
+	sig, err := opened.SignMessage(message)
+	require.NoError(t, err)
+	require.True(t, VerifyMessage(kp.Public, message, sig))
+	w := &Wallet{Secrets: walletSecrets{Accounts: []*EDKeyPair{kp}}}
+	walletSig, err := w.SignMessage(0, message)
+	require.NoError(t, err)
+	require.Equal(t, walletSig, sig)
+
+	genesisID := types.Hash20{1}
+	signed, err := SignTxn(opened, genesisID, []byte("tx"))
+	require.NoError(t, err)
+	require.True(t, kp.Verify(core.SigningBody(genesisID[:], []byte("tx")), signed[len("tx"):]))
+
+	_, err = (&EDKeyPair{Public: kp.Public}).SignMessage(message)
+	require.Error(t, err)
+}
+
+func TestKeyFileWrongPassword(t *testing.T) {
+	file, _ := testKeyFile(t, []byte("password"))
+	rk := NewKey(WithPasswordOnly([]byte("wrong")))
+	_, err := rk.OpenKeyFile(bytes.NewReader(file))
+	require.ErrorIs(t, err, ErrWrongPassword)
+	require.NotErrorIs(t, err, ErrMalformedKeyFile)
+}
+
+func TestKeyFileMalformed(t *testing.T) {
+	password := []byte("password")
+	file, kp := testKeyFile(t, password)
+	other, _ := testKeyFile(t, password)
+	edit := func(f func(ek map[string]interface{})) string {
+		var ek map[string]interface{}
+		require.NoError(t, json.Unmarshal(file, &ek))
+		f(ek)
+		data, err := json.Marshal(ek)
+		require.NoError(t, err)
+		return string(data)
+	}
+	var otherKey map[string]interface{}
+	require.NoError(t, json.Unmarshal(other, &otherKey))
+
+	for name, data := range map[string]string{
+		"not json":     "not a key file",
+		"wallet file":  `{"meta":{"version":2},"crypto":{}}`,
+		"version":      edit(func(ek map[string]interface{}) { ek["version"] = 2 }),
+		"public key":   edit(func(ek map[string]interface{}) { ek["publicKey"] = "00" }),
+		"salt":         edit(func(ek map[string]interface{}) { ek["crypto"].(map[string]interface{})["kdfparams"] = nil }),
+		"kdf":          edit(func(ek map[string]interface{}) { ek["crypto"].(map[string]interface{})["kdf"] = "none" }),
+		"swapped keys": edit(func(ek map[string]interface{}) { ek["crypto"] = otherKey["crypto"] }),
+	} {
+		rk := NewKey(WithPasswordOnly(password))
+		_, err := rk.OpenKeyFile(strings.NewReader(data))
+		require.ErrorIs(t, err, ErrMalformedKeyFile, name)
+		require.NotErrorIs(t, err, ErrWrongPassword, name)
+	}
+
+	// keypairs without a private key can't be exported
+	wk := NewKey(WithRandomSalt(), WithIterations(1000), WithPbkdf2Password(password))
+	require.Error(t, wk.ExportKeyFile(&bytes.Buffer{}, &EDKeyPair{Public: kp.Public}))
+}
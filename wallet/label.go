@@ -0,0 +1,87 @@
+package wallet
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/spacemeshos/smcli/common"
+)
+
+// MaxLabelLength is the maximum length of an account label or wallet display name, in characters.
+const MaxLabelLength = 64
+
+// DefaultDisplayName is the display name of a new wallet unless another one is set using WithDisplayName.
+const DefaultDisplayName = "Main Wallet"
+
+// ErrAccountNotFound is returned if a wallet has no account with the requested index.
+var ErrAccountNotFound = common.NewError(common.ErrBadInput, "account not found")
+
+// accountIndex returns the HD account index of the account at position pos in the wallet. Accounts are derived
+// in order so the two normally match, but an account's path is authoritative: the index is its last segment, see
+// ParsePathTemplate.
+func accountIndex(acct *EDKeyPair, pos int) uint32 {
+	if len(acct.Path) > 0 {
+		return acct.Path[len(acct.Path)-1] &^ BIP32HardenedKeyStart
+	}
+	return uint32(pos)
+}
+
+// Account returns the account with the given HD account index, as reported by PublicKeys.
+func (w *Wallet) Account(index uint32) (*EDKeyPair, error) {
+	for i, acct := range w.Secrets.Accounts {
+		if accountIndex(acct, i) == index {
+			return acct, nil
+		}
+	}
+	return nil, fmt.Errorf("%w: no account with index %d", ErrAccountNotFound, index)
+}
+
+// SetAccountLabel sets the label of the account with the given HD account index. Labels are free-form names
+// chosen by the user, such as "savings", and are stored in the wallet file along with the account. An empty
+// label removes it.
+func (w *Wallet) SetAccountLabel(index uint32, label string) error {
+	if err := checkName("label", label); err != nil {
+		return err
+	}
+	acct, err := w.Account(index)
+	if err != nil {
+		return err
+	}
+	acct.Label = label
+	return nil
+}
+
+// SetDisplayName sets the display name of the wallet, which is stored unencrypted in its metadata. It can't
+// be empty.
+func (w *Wallet) SetDisplayName(name string) error {
+	if err := checkDisplayName(name); err != nil {
+		return err
+	}
+	w.Meta.DisplayName = name
+	return nil
+}
+
+func checkDisplayName(name string) error {
+	if strings.TrimSpace(name) == "" {
+		return fmt.Errorf("wallet name must not be empty")
+	}
+	return checkName("wallet name", name)
+}
+
+// checkName checks a user-chosen label or name. what describes it in errors.
+func checkName(what, name string) error {
+	if !utf8.ValidString(name) {
+		return fmt.Errorf("%s must be valid UTF-8", what)
+	}
+	if n := utf8.RuneCountInString(name); n > MaxLabelLength {
+		return fmt.Errorf("%s is too long (%d characters, at most %d allowed)", what, n, MaxLabelLength)
+	}
+	for _, r := range name {
+		if unicode.IsControl(r) {
+			return fmt.Errorf("%s must not contain control characters", what)
+		}
+	}
+	return nil
+}
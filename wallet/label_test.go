@@ -0,0 +1,126 @@
+package wallet
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestSetAccountLabel(t *testing.T) {
+	w, err := NewMultiWalletRandomMnemonic(3)
+	require.NoError(t, err)
+
+	require.NoError(t, w.SetAccountLabel(0, "savings"))
+	require.NoError(t, w.SetAccountLabel(2, "donations 🎁"))
+	require.Equal(t, "savings", w.Secrets.Accounts[0].Label)
+	require.Empty(t, w.Secrets.Accounts[1].Label)
+	require.Equal(t, "donations 🎁", w.Secrets.Accounts[2].Label)
+
+	// rename, then remove
+	require.NoError(t, w.SetAccountLabel(0, "hot"))
+	require.Equal(t, "hot", w.Secrets.Accounts[0].Label)
+	require.NoError(t, w.SetAccountLabel(0, ""))
+	require.Empty(t, w.Secrets.Accounts[0].Label)
+
+	require.ErrorIs(t, w.SetAccountLabel(3, "missing"), ErrAccountNotFound)
+	require.Error(t, w.SetAccountLabel(1, strings.Repeat("a", MaxLabelLength+1)))
+	require.NoError(t, w.SetAccountLabel(1, strings.Repeat("é", MaxLabelLength)))
+	require.Error(t, w.SetAccountLabel(1, "two\nlines"))
+	require.Error(t, w.SetAccountLabel(1, "\xff"))
+
+	// labels are surfaced in the public listings
+	keys, err := w.PublicKeys("sm")
+	require.NoError(t, err)
+	require.Equal(t, "donations 🎁", keys[2].Label)
+	require.Equal(t, "donations 🎁", w.WatchOnly().Accounts[2].Label)
+}
+
+func TestAccountByIndex(t *testing.T) {
+	master, err := NewMasterKeyPair(goodSeed)
+	require.NoError(t, err)
+	accts, err := accountsFromMasterAtIndices(master, goodSeed, []int{5, 17})
+	require.NoError(t, err)
+	w, err := walletFromMnemonicAndAccounts("", master, accts)
+	require.NoError(t, err)
+
+	// the index is the HD index, not the position in the wallet
+	acct, err := w.Account(17)
+	require.NoError(t, err)
+	require.Equal(t, accts[1], acct)
+	_, err = w.Account(1)
+	require.ErrorIs(t, err, ErrAccountNotFound)
+}
+
+func TestLabelsSurviveRoundTrip(t *testing.T) {
+	oldPassword := []byte("old password")
+	newPassword := []byte("new password")
+	w, err := NewMultiWalletRandomMnemonic(2)
+	require.NoError(t, err)
+	require.NoError(t, w.SetAccountLabel(1, "savings"))
+
+	buf := &bytes.Buffer{}
+	wKey := NewKey(WithRandomSalt(), WithPbkdf2Password(oldPassword))
+	require.NoError(t, wKey.Export(buf, w))
+	// labels are encrypted along with the other secrets
+	require.NotContains(t, buf.String(), "savings")
+
+	ew := &EncryptedWalletFile{}
+	require.NoError(t, json.Unmarshal(buf.Bytes(), ew))
+	require.NoError(t, ew.ChangePassword(oldPassword, newPassword))
+	data, err := json.Marshal(ew)
+	require.NoError(t, err)
+
+	wKey = NewKey(WithPasswordOnly(newPassword))
+	w2, err := wKey.Open(bytes.NewReader(data))
+	require.NoError(t, err)
+	require.Empty(t, w2.Secrets.Accounts[0].Label)
+	require.Equal(t, "savings", w2.Secrets.Accounts[1].Label)
+	require.Equal(t, w.Secrets.Accounts, w2.Secrets.Accounts)
+}
+
+func TestLabelMigratedFixture(t *testing.T) {
+	// a file from before labels and versioning can be labeled once migrated
+	f, err := os.Open("testdata/wallet.json")
+	require.NoError(t, err)
+	defer f.Close()
+	password := []byte("password")
+	wKey := NewKey(WithPasswordOnly(password))
+	w, err := wKey.Open(f)
+	require.NoError(t, err)
+	for _, acct := range w.Secrets.Accounts {
+		require.Empty(t, acct.Label)
+	}
+	require.NoError(t, w.SetAccountLabel(0, "hot"))
+
+	buf := &bytes.Buffer{}
+	require.NoError(t, wKey.Export(buf, w))
+	wKey = NewKey(WithPasswordOnly(password))
+	w2, err := wKey.Open(buf)
+	require.NoError(t, err)
+	require.Equal(t, WalletVersion, w2.Meta.Version)
+	require.Equal(t, "hot", w2.Secrets.Accounts[0].Label)
+	require.Empty(t, w2.Secrets.Accounts[1].Label)
+}
+
+func TestDisplayName(t *testing.T) {
+	w, err := NewMultiWalletRandomMnemonic(1)
+	require.NoError(t, err)
+	require.Equal(t, DefaultDisplayName, w.Meta.DisplayName)
+
+	w, err = NewMultiWalletRandomMnemonic(1, WithDisplayName("Savings"))
+	require.NoError(t, err)
+	require.Equal(t, "Savings", w.Meta.DisplayName)
+	require.NoError(t, w.SetDisplayName("Cold storage"))
+	require.Equal(t, "Cold storage", w.Meta.DisplayName)
+
+	for _, name := range []string{"", "  ", "a\tb", strings.Repeat("x", MaxLabelLength+1)} {
+		_, err = NewMultiWalletRandomMnemonic(1, WithDisplayName(name))
+		require.Error(t, err, "%q", name)
+		require.Error(t, w.SetDisplayName(name), "%q", name)
+	}
+	require.Equal(t, "Cold storage", w.Meta.DisplayName)
+}
@@ -0,0 +1,318 @@
+package wallet
+
+import (
+	"bytes"
+	"crypto/ed25519"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	ledger "github.com/spacemeshos/smkeys/remote-wallet"
+
+	"github.com/spacemeshos/smcli/common"
+)
+
+// Errors talking to a Ledger device, which are all device errors (common.ErrDevice).
+var (
+	ErrNoLedgerDevice = common.NewError(common.ErrDevice,
+		"no Ledger device found, make sure it's connected and unlocked")
+	ErrLedgerDeviceNotFound  = common.NewError(common.ErrDevice, "selected Ledger device not found")
+	ErrMultipleLedgerDevices = common.NewError(common.ErrDevice, "multiple Ledger devices found, select one")
+	ErrLedgerAppNotOpen      = common.NewError(common.ErrDevice,
+		"error talking to the Spacemesh app, make sure it's open on the Ledger device")
+	ErrLedgerRejected     = common.NewError(common.ErrDevice, "request rejected on the Ledger device")
+	ErrLedgerDisconnected = common.NewError(common.ErrDevice, "Ledger device disconnected")
+	ErrLedgerTimeout      = common.NewError(common.ErrDevice, "timed out waiting for approval on the Ledger device")
+	ErrLedgerWrongApp     = common.NewError(common.ErrDevice,
+		"a different app is open on the Ledger device, open the Spacemesh app")
+	ErrLedgerAppOutdated = common.NewError(common.ErrDevice,
+		"the Spacemesh app on the Ledger device is outdated, update it using Ledger Live")
+	ErrLedgerSigningUnsupported = common.NewError(common.ErrDevice,
+		"signing using a Ledger device is not supported by this version of smcli")
+	ErrLedgerListingUnsupported = common.NewError(common.ErrDevice,
+		"listing Ledger devices is not supported by this version of smcli, it uses the first connected device "+
+			"unless another one is selected")
+)
+
+// errLedgerAppInfoUnsupported is returned by transports that can't tell which app is open, in which case the
+// app isn't checked and checkLedgerApp warns the user instead.
+var errLedgerAppInfoUnsupported = errors.New("reading the app name and version is not supported")
+
+// LedgerAppName is the name that the Spacemesh app reports.
+const LedgerAppName = "Spacemesh"
+
+// ledgerDashboardName is the name reported by the device when no app is open.
+const ledgerDashboardName = "BOLOS"
+
+// MinLedgerAppVersion is the oldest version of the Spacemesh app that smcli works with. It isn't enforced yet: the
+// SDK in use can't read the version of the app, see checkLedgerApp.
+var MinLedgerAppVersion = "1.0.0"
+
+// LedgerDevice describes a connected Ledger hardware wallet.
+type LedgerDevice struct {
+	// ID identifies the device to the transport.
+	ID string
+	// Product is the human-readable product name, e.g., "Nano S Plus".
+	Product string
+}
+
+// LedgerTransport is the low-level interface to Ledger devices. It allows the device logic to be tested
+// without a physical device.
+type LedgerTransport interface {
+	// Enumerate lists the connected devices. Transports that can't list them return ErrLedgerListingUnsupported,
+	// and are then given the ID that the user selected, or defaultLedgerDeviceID, without checking it.
+	Enumerate() ([]LedgerDevice, error)
+	// ReadPubkey reads the public key at path from the Spacemesh app on the device. If confirm is set the device
+	// displays the corresponding address and the call blocks until the user approves or rejects it. A rejection
+	// must be reported using ErrLedgerRejected.
+	ReadPubkey(deviceID string, path HDPath, confirm bool) ([]byte, error)
+	// Sign sends message to the device to be signed with the key at path. It blocks until the user approves or
+	// rejects it on the device. A rejection must be reported using ErrLedgerRejected, and a device that goes away
+	// during the exchange using ErrLedgerDisconnected.
+	Sign(deviceID string, path HDPath, message []byte) ([]byte, error)
+	// CanSign reports whether Sign is supported at all. If not, Sign returns ErrLedgerSigningUnsupported.
+	CanSign() bool
+	// AppInfo reads the name and the version of the app open on the device.
+	AppInfo(deviceID string) (LedgerApp, error)
+}
+
+// LedgerApp is the app open on a Ledger device.
+type LedgerApp struct {
+	Name string
+	// Version is the semantic version of the app, e.g., "1.2.3".
+	Version string
+}
+
+// defaultLedgerDeviceID is the locator that the Ledger library resolves to the first connected device.
+const defaultLedgerDeviceID = "usb://ledger"
+
+// smkeysTransport talks to Ledger devices using the Spacemesh SDK.
+type smkeysTransport struct{}
+
+// Enumerate isn't supported: the version of the SDK in use can't list devices, it's only given a locator such as
+// defaultLedgerDeviceID and fails if no device is found there.
+func (smkeysTransport) Enumerate() ([]LedgerDevice, error) {
+	return nil, ErrLedgerListingUnsupported
+}
+
+func (smkeysTransport) ReadPubkey(deviceID string, path HDPath, confirm bool) ([]byte, error) {
+	return ledger.ReadPubkeyFromLedger(deviceID, HDPathToString(path), confirm)
+}
+
+// Sign isn't supported: the version of the SDK in use can only read public keys from the device.
+func (smkeysTransport) Sign(string, HDPath, []byte) ([]byte, error) {
+	return nil, ErrLedgerSigningUnsupported
+}
+
+func (smkeysTransport) CanSign() bool {
+	return false
+}
+
+// AppInfo isn't supported either: the SDK in use can't read the name or version of the open app, so it isn't
+// checked.
+func (smkeysTransport) AppInfo(string) (LedgerApp, error) {
+	return LedgerApp{}, errLedgerAppInfoUnsupported
+}
+
+// ledgerTransport is the transport used for all Ledger operations. It's replaced in tests.
+var ledgerTransport LedgerTransport = smkeysTransport{}
+
+// ledgerSignTimeout bounds how long SignWithLedger waits for the user to act on the device.
+var ledgerSignTimeout = 2 * time.Minute
+
+// CheckLedgerSigning returns ErrLedgerSigningUnsupported if signing using a Ledger device isn't supported, so that
+// commands can fail before asking the user to confirm anything.
+func CheckLedgerSigning() error {
+	if !ledgerTransport.CanSign() {
+		return ErrLedgerSigningUnsupported
+	}
+	return nil
+}
+
+// EnumerateLedgerDevices lists the connected Ledger devices. It returns ErrLedgerListingUnsupported if they can't
+// be listed, which is the case outside tests.
+func EnumerateLedgerDevices() ([]LedgerDevice, error) {
+	return ledgerTransport.Enumerate()
+}
+
+// selectLedgerDevice picks the device with the given ID, or the only connected device if the ID is empty. If the
+// transport can't list devices, the ID is used as is, or defaultLedgerDeviceID if it's empty: whether there's a
+// device is then only found out when it's used.
+func selectLedgerDevice(t LedgerTransport, id string) (LedgerDevice, error) {
+	devices, err := t.Enumerate()
+	if errors.Is(err, ErrLedgerListingUnsupported) {
+		if id == "" {
+			id = defaultLedgerDeviceID
+		}
+		return LedgerDevice{ID: id}, nil
+	}
+	if err != nil {
+		return LedgerDevice{}, fmt.Errorf("error enumerating Ledger devices: %w", err)
+	}
+	if len(devices) == 0 {
+		return LedgerDevice{}, ErrNoLedgerDevice
+	}
+	if id == "" {
+		if len(devices) > 1 {
+			ids := make([]string, len(devices))
+			for i, d := range devices {
+				ids[i] = fmt.Sprintf("%s (%s)", d.ID, d.Product)
+			}
+			return LedgerDevice{}, fmt.Errorf("%w: %s", ErrMultipleLedgerDevices, strings.Join(ids, ", "))
+		}
+		return devices[0], nil
+	}
+	for _, d := range devices {
+		if d.ID == id {
+			return d, nil
+		}
+	}
+	return LedgerDevice{}, fmt.Errorf("%w: %s", ErrLedgerDeviceNotFound, id)
+}
+
+// checkLedgerApp checks that the app open on the device is a version of the Spacemesh app that smcli works with,
+// so that an outdated or a different app is reported as such rather than as a failure to derive or sign. It
+// returns ErrLedgerAppNotOpen if no app is open, ErrLedgerWrongApp if another app is, and ErrLedgerAppOutdated if
+// the Spacemesh app is older than MinLedgerAppVersion. Transports that can't read the app, which includes the
+// smkeys transport used outside tests, aren't checked: a wrong or outdated app then goes through, and a warning
+// is logged so that a failure that follows can be told apart.
+func checkLedgerApp(t LedgerTransport, deviceID string) error {
+	app, err := t.AppInfo(deviceID)
+	if errors.Is(err, errLedgerAppInfoUnsupported) {
+		common.Logger().Warn("can't check which app is open on the Ledger device, make sure it's the Spacemesh app "+
+			"version "+MinLedgerAppVersion+" or later", "device", deviceID)
+		return nil
+	} else if err != nil {
+		return fmt.Errorf("%w: %v", ErrLedgerAppNotOpen, err)
+	}
+	switch app.Name {
+	case LedgerAppName:
+	case ledgerDashboardName:
+		return ErrLedgerAppNotOpen
+	default:
+		return fmt.Errorf("%w: found %q", ErrLedgerWrongApp, app.Name)
+	}
+	version, err := parseLedgerAppVersion(app.Version)
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrLedgerAppOutdated, err)
+	}
+	required, err := parseLedgerAppVersion(MinLedgerAppVersion)
+	if err != nil {
+		return err
+	}
+	for i := range version {
+		if version[i] != required[i] {
+			if version[i] < required[i] {
+				return fmt.Errorf("%w: Spacemesh app v>=%s required, found %s", ErrLedgerAppOutdated,
+					MinLedgerAppVersion, app.Version)
+			}
+			break
+		}
+	}
+	return nil
+}
+
+// parseLedgerAppVersion parses a version of the form major.minor.patch, with an optional v prefix.
+func parseLedgerAppVersion(s string) ([3]int, error) {
+	var version [3]int
+	parts := strings.Split(strings.TrimPrefix(s, "v"), ".")
+	if len(parts) != len(version) {
+		return version, fmt.Errorf("invalid app version %q", s)
+	}
+	for i, part := range parts {
+		n, err := strconv.Atoi(part)
+		if err != nil || n < 0 {
+			return version, fmt.Errorf("invalid app version %q", s)
+		}
+		version[i] = n
+	}
+	return version, nil
+}
+
+// ConfirmLedgerAddress asks the Ledger device to display the address of the wallet account with the given index
+// so that the user can check that it matches the one shown by smcli. It blocks until the user approves or
+// rejects it on the device, and returns ErrLedgerRejected if they reject it.
+func (w *Wallet) ConfirmLedgerAddress(idx int, opts ...WalletOpt) error {
+	master := w.Secrets.MasterKeypair
+	if master == nil || master.KeyType != typeLedger {
+		return fmt.Errorf("wallet is not backed by a Ledger device")
+	}
+	if idx < 0 || idx >= len(w.Secrets.Accounts) {
+		return fmt.Errorf("invalid account index %d, wallet has %d account(s)", idx, len(w.Secrets.Accounts))
+	}
+	o := &walletOpts{ledgerDevice: master.ledgerDevice}
+	for _, opt := range opts {
+		opt(o)
+	}
+	device, err := selectLedgerDevice(ledgerTransport, o.ledgerDevice)
+	if err != nil {
+		return err
+	}
+
+	acct := w.Secrets.Accounts[idx]
+	key, err := ledgerTransport.ReadPubkey(device.ID, acct.Path, true)
+	if errors.Is(err, ErrLedgerRejected) {
+		return err
+	} else if err != nil {
+		return fmt.Errorf("%w: %v", ErrLedgerAppNotOpen, err)
+	}
+	if !bytes.Equal(key, acct.Public) {
+		common.Logger().Debug("Ledger public key does not match the wallet", "account", acct,
+			"ledgerPublicKey", PublicKey(key))
+		return fmt.Errorf("account %d on the Ledger device does not match the wallet: %w", idx, errLedgerMismatch)
+	}
+	return nil
+}
+
+// SignWithLedger signs message with the key at path on the Ledger device, without the private key ever leaving
+// the device. Pass the path stored with the wallet account (EDKeyPair.Path), which is where its key was read from
+// when the wallet was created. It blocks until the user approves or rejects the request on the device, and
+// returns ErrLedgerRejected, ErrLedgerDisconnected, or ErrLedgerTimeout if the signature can't be obtained, and
+// ErrLedgerSigningUnsupported if the device can't sign at all, which is the case outside tests, see
+// CheckLedgerSigning. To sign a transaction (e.g., one produced by GenerateTxnData), pass
+// core.SigningBody(genesisID, tx) as the message.
+func SignWithLedger(path HDPath, message []byte, opts ...WalletOpt) ([]byte, error) {
+	if err := CheckLedgerSigning(); err != nil {
+		return nil, err
+	}
+	if len(path) == 0 || !IsPathCompletelyHardened(path) {
+		return nil, fmt.Errorf("invalid path %q, Ledger keys must be at a non-empty, fully hardened path",
+			HDPathToString(path))
+	}
+	o := newWalletOpts(opts)
+	device, err := selectLedgerDevice(ledgerTransport, o.ledgerDevice)
+	if err != nil {
+		return nil, err
+	}
+	if err := checkLedgerApp(ledgerTransport, device.ID); err != nil {
+		return nil, err
+	}
+	type result struct {
+		sig []byte
+		err error
+	}
+	// buffered so that the exchange can finish in the background after a timeout
+	done := make(chan result, 1)
+	go func() {
+		sig, err := ledgerTransport.Sign(device.ID, path, message)
+		done <- result{sig, err}
+	}()
+
+	select {
+	case res := <-done:
+		switch {
+		case errors.Is(res.err, ErrLedgerRejected), errors.Is(res.err, ErrLedgerDisconnected),
+			errors.Is(res.err, ErrLedgerSigningUnsupported):
+			return nil, res.err
+		case res.err != nil:
+			return nil, fmt.Errorf("%w: %v", ErrLedgerAppNotOpen, res.err)
+		case len(res.sig) != ed25519.SignatureSize:
+			return nil, fmt.Errorf("invalid signature length %d from Ledger device", len(res.sig))
+		}
+		return res.sig, nil
+	case <-time.After(ledgerSignTimeout):
+		return nil, ErrLedgerTimeout
+	}
+}
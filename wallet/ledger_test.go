@@ -0,0 +1,384 @@
+package wallet
+
+import (
+	"bytes"
+	"crypto/ed25519"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/spacemeshos/go-spacemesh/common/types"
+	"github.com/spacemeshos/go-spacemesh/genvm/core"
+	smbip32 "github.com/spacemeshos/smkeys/bip32"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/exp/slog"
+
+	"github.com/spacemeshos/smcli/common"
+)
+
+// mockLedger simulates one or more connected Ledger devices, each with its own seed.
+type mockLedger struct {
+	devices []LedgerDevice
+	seeds   map[string][]byte
+	// appClosed lists the devices where the Spacemesh app isn't open.
+	appClosed map[string]bool
+	// apps overrides the app open on a device, which is otherwise the oldest compatible Spacemesh app.
+	apps map[string]LedgerApp
+	// reject simulates the user rejecting every on-device confirmation.
+	reject bool
+	// confirmed records the paths that were displayed for confirmation.
+	confirmed []HDPath
+	// signErr, if set, is returned by Sign; signDelay delays it.
+	signErr   error
+	signDelay time.Duration
+	// disconnect simulates the device going away during an exchange.
+	disconnect bool
+	// noSign simulates a transport that can't sign.
+	noSign bool
+	mu     sync.Mutex
+}
+
+func newMockLedger(devices ...LedgerDevice) *mockLedger {
+	m := &mockLedger{
+		devices:   devices,
+		seeds:     make(map[string][]byte),
+		appClosed: make(map[string]bool),
+		apps:      make(map[string]LedgerApp),
+	}
+	for i, d := range devices {
+		seed := make([]byte, len(goodSeed))
+		copy(seed, goodSeed)
+		seed[0] += byte(i)
+		m.seeds[d.ID] = seed
+	}
+	return m
+}
+
+func (m *mockLedger) Enumerate() ([]LedgerDevice, error) {
+	return m.devices, nil
+}
+
+func (m *mockLedger) ReadPubkey(deviceID string, path HDPath, confirm bool) ([]byte, error) {
+	seed, ok := m.seeds[deviceID]
+	if !ok {
+		return nil, fmt.Errorf("device %s disconnected", deviceID)
+	}
+	if m.appClosed[deviceID] {
+		return nil, fmt.Errorf("app not open")
+	}
+	if confirm {
+		m.confirmed = append(m.confirmed, path)
+		if m.reject {
+			return nil, ErrLedgerRejected
+		}
+	}
+	key, err := smbip32.Derive(HDPathToString(path), seed)
+	if err != nil {
+		return nil, err
+	}
+	return ed25519.PrivateKey(key).Public().(ed25519.PublicKey), nil
+}
+
+func (m *mockLedger) Sign(deviceID string, path HDPath, message []byte) ([]byte, error) {
+	m.mu.Lock()
+	delay := m.signDelay
+	m.mu.Unlock()
+	time.Sleep(delay)
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	seed, ok := m.seeds[deviceID]
+	switch {
+	case !ok || m.disconnect:
+		return nil, ErrLedgerDisconnected
+	case m.reject:
+		return nil, ErrLedgerRejected
+	case m.signErr != nil:
+		return nil, m.signErr
+	}
+	key, err := smbip32.Derive(HDPathToString(path), seed)
+	if err != nil {
+		return nil, err
+	}
+	return ed25519.Sign(key, message), nil
+}
+
+func (m *mockLedger) CanSign() bool {
+	return !m.noSign
+}
+
+func (m *mockLedger) AppInfo(deviceID string) (LedgerApp, error) {
+	if _, ok := m.seeds[deviceID]; !ok {
+		return LedgerApp{}, fmt.Errorf("device %s disconnected", deviceID)
+	}
+	if m.appClosed[deviceID] {
+		return LedgerApp{Name: ledgerDashboardName, Version: "2.1.0"}, nil
+	}
+	if app, ok := m.apps[deviceID]; ok {
+		return app, nil
+	}
+	return LedgerApp{Name: LedgerAppName, Version: MinLedgerAppVersion}, nil
+}
+
+func useMockLedger(t *testing.T, m *mockLedger) {
+	prev := ledgerTransport
+	ledgerTransport = m
+	t.Cleanup(func() { ledgerTransport = prev })
+}
+
+func TestSelectLedgerDevice(t *testing.T) {
+	nanoS := LedgerDevice{ID: "usb://ledger?id=1", Product: "Nano S"}
+	nanoX := LedgerDevice{ID: "usb://ledger?id=2", Product: "Nano X"}
+
+	_, err := selectLedgerDevice(newMockLedger(), "")
+	require.ErrorIs(t, err, ErrNoLedgerDevice)
+
+	d, err := selectLedgerDevice(newMockLedger(nanoS), "")
+	require.NoError(t, err)
+	require.Equal(t, nanoS, d)
+
+	two := newMockLedger(nanoS, nanoX)
+	_, err = selectLedgerDevice(two, "")
+	require.ErrorIs(t, err, ErrMultipleLedgerDevices)
+	require.ErrorContains(t, err, nanoX.ID)
+	d, err = selectLedgerDevice(two, nanoX.ID)
+	require.NoError(t, err)
+	require.Equal(t, nanoX, d)
+	_, err = selectLedgerDevice(two, "usb://ledger?id=3")
+	require.ErrorIs(t, err, ErrLedgerDeviceNotFound)
+
+	// the SDK can't list devices, so none are reported, and the selected one is used as is
+	devices, err := smkeysTransport{}.Enumerate()
+	require.ErrorIs(t, err, ErrLedgerListingUnsupported)
+	require.Empty(t, devices)
+	d, err = selectLedgerDevice(smkeysTransport{}, "")
+	require.NoError(t, err)
+	require.Equal(t, LedgerDevice{ID: defaultLedgerDeviceID}, d)
+	d, err = selectLedgerDevice(smkeysTransport{}, nanoX.ID)
+	require.NoError(t, err)
+	require.Equal(t, LedgerDevice{ID: nanoX.ID}, d)
+}
+
+func TestNewMultiWalletFromLedgerDevice(t *testing.T) {
+	nanoS := LedgerDevice{ID: "usb://ledger?id=1", Product: "Nano S"}
+	nanoX := LedgerDevice{ID: "usb://ledger?id=2", Product: "Nano X"}
+	m := newMockLedger(nanoS, nanoX)
+	useMockLedger(t, m)
+
+	_, err := NewMultiWalletFromLedger(1)
+	require.ErrorIs(t, err, ErrMultipleLedgerDevices)
+
+	w1, err := NewMultiWalletFromLedger(2, WithLedgerDevice(nanoS.ID))
+	require.NoError(t, err)
+	w2, err := NewMultiWalletFromLedger(2, WithLedgerDevice(nanoX.ID))
+	require.NoError(t, err)
+	require.NotEqual(t, w1.Secrets.MasterKeypair.Public, w2.Secrets.MasterKeypair.Public)
+
+	// keys come from the selected device
+	for i, acct := range w1.Secrets.Accounts {
+		require.Equal(t, typeLedger, acct.KeyType)
+		require.Empty(t, acct.Private)
+		expected, err := m.ReadPubkey(nanoS.ID, acct.Path, false)
+		require.NoError(t, err)
+		require.Equal(t, PublicKey(expected), acct.Public, "account %d", i)
+	}
+
+	// accounts can only be added from the device that holds the master key
+	require.ErrorIs(t, w1.AddAccounts(1, WithLedgerDevice(nanoX.ID)), errLedgerMismatch)
+	require.NoError(t, w1.AddAccounts(1))
+	require.Len(t, w1.Secrets.Accounts, 3)
+
+	m.appClosed[nanoX.ID] = true
+	_, err = NewMultiWalletFromLedger(1, WithLedgerDevice(nanoX.ID))
+	require.ErrorIs(t, err, ErrLedgerAppNotOpen)
+}
+
+func TestLedgerAppVersion(t *testing.T) {
+	nanoS := LedgerDevice{ID: "usb://ledger?id=1", Product: "Nano S"}
+	m := newMockLedger(nanoS)
+	useMockLedger(t, m)
+	prev := MinLedgerAppVersion
+	MinLedgerAppVersion = "1.2.3"
+	t.Cleanup(func() { MinLedgerAppVersion = prev })
+
+	for _, version := range []string{"1.2.3", "v1.2.3", "1.2.10", "1.3.0", "2.0.0"} {
+		m.apps[nanoS.ID] = LedgerApp{Name: LedgerAppName, Version: version}
+		_, err := NewMasterKeyPairFromLedger()
+		require.NoError(t, err, version)
+	}
+
+	for _, version := range []string{"1.2.2", "1.1.9", "0.9.9", "1.2", "1.2.x", ""} {
+		m.apps[nanoS.ID] = LedgerApp{Name: LedgerAppName, Version: version}
+		_, err := NewMasterKeyPairFromLedger()
+		require.ErrorIs(t, err, ErrLedgerAppOutdated, version)
+		require.NotErrorIs(t, err, ErrLedgerAppNotOpen, version)
+	}
+	root := DefaultPath()
+	path := root.Extend(BIP44HardenedAccountIndex(0))
+	m.apps[nanoS.ID] = LedgerApp{Name: LedgerAppName, Version: "1.1.0"}
+	_, err := NewMasterKeyPairFromLedger()
+	require.ErrorContains(t, err, "Spacemesh app v>=1.2.3 required, found 1.1.0")
+	_, err = SignWithLedger(path, []byte("message"))
+	require.ErrorIs(t, err, ErrLedgerAppOutdated)
+
+	// another app, or none, is open
+	m.apps[nanoS.ID] = LedgerApp{Name: "Bitcoin", Version: "2.1.0"}
+	_, err = NewMasterKeyPairFromLedger()
+	require.ErrorIs(t, err, ErrLedgerWrongApp)
+	require.ErrorContains(t, err, "Bitcoin")
+	_, err = SignWithLedger(path, []byte("message"))
+	require.ErrorIs(t, err, ErrLedgerWrongApp)
+	delete(m.apps, nanoS.ID)
+	m.appClosed[nanoS.ID] = true
+	_, err = NewMasterKeyPairFromLedger()
+	require.ErrorIs(t, err, ErrLedgerAppNotOpen)
+
+	// transports that can't read the app aren't checked, but the user is warned
+	logs := &bytes.Buffer{}
+	defaultLogger := common.Logger()
+	common.SetLogger(common.NewLogger(logs, slog.LevelWarn))
+	t.Cleanup(func() { common.SetLogger(defaultLogger) })
+	require.NoError(t, checkLedgerApp(smkeysTransport{}, defaultLedgerDeviceID))
+	require.Contains(t, logs.String(), "can't check which app is open")
+}
+
+func TestConfirmLedgerAddress(t *testing.T) {
+	nanoS := LedgerDevice{ID: "usb://ledger?id=1", Product: "Nano S"}
+	m := newMockLedger(nanoS)
+	useMockLedger(t, m)
+
+	w, err := NewMultiWalletFromLedger(3)
+	require.NoError(t, err)
+	m.confirmed = nil
+
+	// approve
+	require.NoError(t, w.ConfirmLedgerAddress(2))
+	require.Equal(t, []HDPath{w.Secrets.Accounts[2].Path}, m.confirmed)
+
+	// reject
+	m.reject = true
+	require.ErrorIs(t, w.ConfirmLedgerAddress(1), ErrLedgerRejected)
+	m.reject = false
+
+	require.Error(t, w.ConfirmLedgerAddress(3))
+	require.Error(t, w.ConfirmLedgerAddress(-1))
+
+	// rejecting the first account when creating the wallet isn't mistaken for the app not being open
+	m.reject = true
+	_, err = NewMultiWalletFromLedger(1)
+	require.ErrorIs(t, err, ErrLedgerRejected)
+	require.NotErrorIs(t, err, ErrLedgerAppNotOpen)
+	m.reject = false
+
+	// a different device shows a different address
+	m.seeds[nanoS.ID][0]++
+	require.ErrorIs(t, w.ConfirmLedgerAddress(0), errLedgerMismatch)
+
+	// software wallets have nothing to confirm
+	sw, err := NewMultiWalletRandomMnemonic(1)
+	require.NoError(t, err)
+	require.Error(t, sw.ConfirmLedgerAddress(0))
+}
+
+func TestSignWithLedger(t *testing.T) {
+	nanoS := LedgerDevice{ID: "usb://ledger?id=1", Product: "Nano S"}
+	m := newMockLedger(nanoS)
+	useMockLedger(t, m)
+
+	w, err := NewMultiWalletFromLedger(2)
+	require.NoError(t, err)
+	acct := w.Secrets.Accounts[1]
+	master, err := NewMasterKeyPair(goodSeed)
+	require.NoError(t, err)
+	accts, err := accountsFromMaster(master, goodSeed, 2)
+	require.NoError(t, err)
+	unsigned, err := GenerateTxnData(TxnData{
+		Principal: testPrincipal(t, accts[1]),
+		Recipient: testPrincipal(t, accts[0]),
+		Amount:    100,
+		Nonce:     1,
+		GasPrice:  1,
+	})
+	require.NoError(t, err)
+	message := core.SigningBody(types.Hash20{1}.Bytes(), unsigned)
+
+	t.Run("success", func(t *testing.T) {
+		sig, err := SignWithLedger(acct.Path, message)
+		require.NoError(t, err)
+		require.True(t, acct.Verify(message, sig))
+		require.False(t, w.Secrets.Accounts[0].Verify(message, sig))
+	})
+
+	t.Run("rejected", func(t *testing.T) {
+		m.reject = true
+		t.Cleanup(func() { m.reject = false })
+		_, err := SignWithLedger(acct.Path, message)
+		require.ErrorIs(t, err, ErrLedgerRejected)
+	})
+
+	t.Run("disconnected", func(t *testing.T) {
+		m.disconnect = true
+		t.Cleanup(func() { m.disconnect = false })
+		_, err := SignWithLedger(acct.Path, message)
+		require.ErrorIs(t, err, ErrLedgerDisconnected)
+		require.NotErrorIs(t, err, ErrLedgerRejected)
+	})
+
+	t.Run("timeout", func(t *testing.T) {
+		prev := ledgerSignTimeout
+		ledgerSignTimeout = 10 * time.Millisecond
+		m.signDelay = time.Second
+		t.Cleanup(func() {
+			ledgerSignTimeout = prev
+			m.mu.Lock()
+			m.signDelay = 0
+			m.mu.Unlock()
+		})
+		_, err := SignWithLedger(acct.Path, message)
+		require.ErrorIs(t, err, ErrLedgerTimeout)
+	})
+
+	t.Run("invalid path", func(t *testing.T) {
+		_, err := SignWithLedger(nil, message)
+		require.Error(t, err)
+		_, err = SignWithLedger(HDPath{BIP44Purpose(), BIP44SpacemeshCoinType(), 1}, message)
+		require.Error(t, err)
+	})
+
+	t.Run("stored path", func(t *testing.T) {
+		// the key is read from the path stored with the account, not the default path of its index
+		path := HDPath{BIP44Purpose(), BIP44SpacemeshCoinType(), BIP44HardenedAccountIndex(3), BIP44HardenedChain(),
+			BIP44HardenedAccountIndex(1)}
+		kp, err := pubkeyFromLedger(nanoS.ID, path, false)
+		require.NoError(t, err)
+		other, err := NewMultiWalletFromLedger(2)
+		require.NoError(t, err)
+		other.Secrets.Accounts[1] = kp
+		require.NotEqual(t, acct.Public, kp.Public)
+
+		sig, err := SignWithLedger(path, message)
+		require.NoError(t, err)
+		require.True(t, kp.Verify(message, sig))
+		sig, err = other.SignMessage(1, []byte("hello"))
+		require.NoError(t, err)
+		require.True(t, kp.Verify(messageSigningBody([]byte("hello")), sig))
+	})
+
+	t.Run("unsupported", func(t *testing.T) {
+		m.noSign = true
+		t.Cleanup(func() { m.noSign = false })
+		require.ErrorIs(t, CheckLedgerSigning(), ErrLedgerSigningUnsupported)
+		_, err := SignWithLedger(acct.Path, message)
+		require.ErrorIs(t, err, ErrLedgerSigningUnsupported)
+		require.NotErrorIs(t, err, ErrLedgerAppNotOpen)
+		_, err = w.SignMessage(1, []byte("hello"))
+		require.ErrorIs(t, err, ErrLedgerSigningUnsupported)
+	})
+}
+
+func TestSmkeysTransportSign(t *testing.T) {
+	require.False(t, smkeysTransport{}.CanSign())
+	_, err := smkeysTransport{}.Sign(defaultLedgerDeviceID, DefaultPath(), []byte("tx"))
+	require.ErrorIs(t, err, ErrLedgerSigningUnsupported)
+	require.NotErrorIs(t, err, ErrLedgerAppNotOpen)
+}
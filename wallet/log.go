@@ -0,0 +1,65 @@
+package wallet
+
+import (
+	"encoding/hex"
+
+	"golang.org/x/exp/slog"
+
+	"github.com/spacemeshos/smcli/common"
+)
+
+// The types holding secrets implement slog.LogValuer so that logging them, under any key and at any level,
+// never reveals the mnemonic or a private key. Types that contain secrets log only their public parts.
+
+// LogValue redacts the private key.
+func (k PrivateKey) LogValue() slog.Value {
+	return slog.StringValue(common.Redacted)
+}
+
+// LogValue redacts the secret.
+func (s secretBytes) LogValue() slog.Value {
+	return slog.StringValue(common.Redacted)
+}
+
+// LogValue logs the public key in hex, as it's written to the wallet file.
+func (k PublicKey) LogValue() slog.Value {
+	return slog.StringValue(hex.EncodeToString(k))
+}
+
+// LogValue logs the public parts of the keypair.
+func (kp *EDKeyPair) LogValue() slog.Value {
+	if kp == nil {
+		return slog.AnyValue(nil)
+	}
+	return slog.GroupValue(
+		slog.String("path", HDPathToString(kp.Path)),
+		slog.Any("publicKey", kp.Public),
+		slog.Bool("ledger", kp.KeyType == typeLedger),
+	)
+}
+
+// LogValue logs the metadata of the wallet and the number of accounts it holds, but none of its secrets.
+func (w *Wallet) LogValue() slog.Value {
+	if w == nil {
+		return slog.AnyValue(nil)
+	}
+	return slog.GroupValue(
+		slog.Int("version", w.Meta.Version),
+		slog.String("displayName", w.Meta.DisplayName),
+		slog.String("genesisID", w.Meta.GenesisID),
+		slog.Int("accounts", len(w.Secrets.Accounts)),
+	)
+}
+
+// LogValue logs the public keys of the accounts.
+func (s walletSecrets) LogValue() slog.Value {
+	attrs := make([]slog.Attr, 0, len(s.Accounts)+1)
+	attrs = append(attrs, slog.Any("mnemonic", s.Mnemonic))
+	for _, acct := range s.Accounts {
+		if acct == nil {
+			continue
+		}
+		attrs = append(attrs, slog.Any(HDPathToString(acct.Path), acct))
+	}
+	return slog.GroupValue(attrs...)
+}
@@ -0,0 +1,63 @@
+package wallet
+
+import (
+	"bytes"
+	"encoding/hex"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"golang.org/x/exp/slog"
+
+	"github.com/spacemeshos/smcli/common"
+)
+
+func TestSecretsNotLogged(t *testing.T) {
+	logs := &bytes.Buffer{}
+	defaultLogger := common.Logger()
+	common.SetLogger(common.NewLogger(logs, slog.LevelDebug))
+	t.Cleanup(func() { common.SetLogger(defaultLogger) })
+
+	mnemonic := "film theme cheese broken kingdom destroy inch ready wear inspire shove pudding"
+	password := []byte("Correct-Horse-9-Battery!")
+	w, err := NewMultiWalletFromMnemonic(mnemonic, 2)
+	require.NoError(t, err)
+
+	buf := &bytes.Buffer{}
+	wk := NewKey(WithRandomSalt(), WithPbkdf2Password(password))
+	require.NoError(t, wk.Export(buf, w))
+	data := buf.Bytes()
+	wk = NewKey(WithPasswordOnly(password))
+	_, err = wk.Open(bytes.NewReader(data))
+	require.NoError(t, err)
+	wk = NewKey(WithPasswordOnly([]byte("wrong")))
+	_, err = wk.Open(bytes.NewReader(data))
+	require.ErrorIs(t, err, ErrWrongPassword)
+
+	// secret types are redacted whatever the key they're logged under
+	common.Logger().Debug("everything",
+		"w", w,
+		"s", w.Secrets,
+		"m", w.Secrets.Mnemonic,
+		"master", w.Secrets.MasterKeypair,
+		"k", w.Secrets.Accounts[0].Private,
+		"accounts", w.Secrets.Accounts,
+	)
+
+	out := logs.String()
+	require.Contains(t, out, "opened wallet")
+	require.Contains(t, out, "derived account")
+	require.Contains(t, out, hex.EncodeToString(w.Secrets.Accounts[0].Public))
+	require.NotContains(t, out, string(password))
+	for _, word := range strings.Fields(mnemonic) {
+		require.NotContains(t, out, word)
+	}
+	for _, kp := range append(w.Secrets.Accounts, w.Secrets.MasterKeypair) {
+		private := hex.EncodeToString(kp.Private)
+		require.NotContains(t, out, private)
+		// neither the seed half of the key, nor the key printed as bytes
+		require.NotContains(t, out, private[:32])
+		require.NotContains(t, out, strings.TrimSuffix(fmt.Sprint([]byte(kp.Private[:8])), "]"))
+	}
+}
@@ -0,0 +1,95 @@
+package wallet
+
+import (
+	"crypto/ed25519"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"strconv"
+
+	"github.com/spacemeshos/smcli/common"
+)
+
+// ErrInvalidSignature is returned when a signature doesn't verify, e.g., by verify-message.
+var ErrInvalidSignature = common.NewError(common.ErrBadInput, "invalid signature")
+
+// messagePrefix is prepended, along with the length of the message, to every message signed using SignMessage.
+// Transactions are signed over the genesis ID followed by the transaction, so a message signature can't be passed
+// off as the signature of a transaction, or the other way around.
+const messagePrefix = "\x19Spacemesh Signed Message:\n"
+
+// messageSigningBody returns the bytes actually signed for a message.
+func messageSigningBody(message []byte) []byte {
+	length := strconv.Itoa(len(message))
+	body := make([]byte, 0, len(messagePrefix)+len(length)+len(message))
+	body = append(body, messagePrefix...)
+	body = append(body, length...)
+	return append(body, message...)
+}
+
+// SignMessage signs an arbitrary message, such as a challenge sent by a dApp, with the key of the account with
+// the given HD account index, proving ownership of the account. The message is prefixed before signing so that
+// the signature is never valid for a transaction. A Ledger wallet asks the device to sign, which requires the
+// user's approval; use WithLedgerDevice to select the device. That returns ErrLedgerSigningUnsupported until the
+// Ledger transport can sign, see CheckLedgerSigning.
+func (w *Wallet) SignMessage(accountIndex int, message []byte, opts ...WalletOpt) ([]byte, error) {
+	if accountIndex < 0 {
+		return nil, fmt.Errorf("invalid account index %d", accountIndex)
+	}
+	acct, err := w.Account(uint32(accountIndex))
+	if err != nil {
+		return nil, err
+	}
+	body := messageSigningBody(message)
+	if master := w.Secrets.MasterKeypair; master != nil && master.KeyType == typeLedger {
+		sig, err := SignWithLedger(acct.Path, body, opts...)
+		if err != nil {
+			return nil, err
+		}
+		if !acct.Verify(body, sig) {
+			return nil, fmt.Errorf("account %d on the Ledger device does not match the wallet: %w",
+				accountIndex, errLedgerMismatch)
+		}
+		return sig, nil
+	}
+	if len(acct.Private) != ed25519.PrivateKeySize {
+		return nil, fmt.Errorf("account %d does not contain a private key", accountIndex)
+	}
+	return acct.Sign(body), nil
+}
+
+// SignMessage signs an arbitrary message like Wallet.SignMessage, using a keypair that holds its private key,
+// e.g., one read from a key file using OpenKeyFile.
+func (kp *EDKeyPair) SignMessage(message []byte) ([]byte, error) {
+	if len(kp.Private) != ed25519.PrivateKeySize {
+		return nil, fmt.Errorf("keypair does not contain a private key")
+	}
+	return kp.Sign(messageSigningBody(message)), nil
+}
+
+// ParseSignature decodes a message signature written using encoding, one of the PubkeyEncoding* encodings, as
+// printed by sign-message.
+func ParseSignature(s, encoding string) ([]byte, error) {
+	var (
+		sig []byte
+		err error
+	)
+	switch encoding {
+	case PubkeyEncodingHex:
+		sig, err = hex.DecodeString(s)
+	case PubkeyEncodingBase64:
+		sig, err = base64.StdEncoding.DecodeString(s)
+	default:
+		return nil, common.WithCategory(common.ErrBadInput, fmt.Errorf("unsupported signature encoding %s", encoding))
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%w: not %s: %v", ErrInvalidSignature, encoding, err)
+	}
+	return sig, nil
+}
+
+// VerifyMessage checks that sig is a signature over message produced by SignMessage using the key with the given
+// public key.
+func VerifyMessage(pubkey, message, sig []byte) bool {
+	return len(pubkey) == ed25519.PublicKeySize && ed25519.Verify(pubkey, messageSigningBody(message), sig)
+}
@@ -0,0 +1,91 @@
+package wallet
+
+import (
+	"testing"
+
+	"github.com/spacemeshos/go-spacemesh/common/types"
+	"github.com/spacemeshos/go-spacemesh/genvm/core"
+	"github.com/stretchr/testify/require"
+)
+
+const messageMnemonic = "film theme cheese broken kingdom destroy inch ready wear inspire shove pudding"
+
+func TestSignMessage(t *testing.T) {
+	w, err := NewMultiWalletFromMnemonic(messageMnemonic, 2)
+	require.NoError(t, err)
+	message := []byte("prove you own this account: 8f3a1c")
+
+	sig, err := w.SignMessage(1, message)
+	require.NoError(t, err)
+	require.True(t, VerifyMessage(w.Secrets.Accounts[1].Public, message, sig))
+
+	// deterministic
+	sig2, err := w.SignMessage(1, message)
+	require.NoError(t, err)
+	require.Equal(t, sig, sig2)
+
+	// wrong key, message, or signature
+	require.False(t, VerifyMessage(w.Secrets.Accounts[0].Public, message, sig))
+	require.False(t, VerifyMessage(w.Secrets.Accounts[1].Public, []byte("another message"), sig))
+	sig2[0] ^= 1
+	require.False(t, VerifyMessage(w.Secrets.Accounts[1].Public, message, sig2))
+	require.False(t, VerifyMessage([]byte{1, 2, 3}, message, sig))
+
+	// the signature isn't a plain signature over the message
+	require.False(t, w.Secrets.Accounts[1].Verify(message, sig))
+
+	// the account must exist and have a private key
+	_, err = w.SignMessage(2, message)
+	require.ErrorIs(t, err, ErrAccountNotFound)
+	_, err = w.SignMessage(-1, message)
+	require.Error(t, err)
+	w.Secrets.Accounts[0].Private = nil
+	_, err = w.SignMessage(0, message)
+	require.Error(t, err)
+}
+
+func TestSignMessageNotTransaction(t *testing.T) {
+	w, err := NewMultiWalletFromMnemonic(messageMnemonic, 1)
+	require.NoError(t, err)
+	acct := w.Secrets.Accounts[0]
+	genesisID := types.Hash20{1, 2, 3}
+
+	// a message signature over the bytes of a transaction doesn't validate as the transaction's signature
+	unsigned, err := GenerateTxnData(TxnData{
+		Principal: PubkeyToPrincipal(acct.Public),
+		Recipient: types.Address{1},
+		Amount:    100,
+		Nonce:     1,
+		GasPrice:  1,
+	})
+	require.NoError(t, err)
+	body := core.SigningBody(genesisID[:], unsigned)
+	for _, message := range [][]byte{unsigned, body} {
+		sig, err := w.SignMessage(0, message)
+		require.NoError(t, err)
+		require.False(t, acct.Verify(body, sig))
+	}
+
+	// and a transaction signature doesn't validate as a message signature
+	signed, err := SignTxn(acct, genesisID, unsigned)
+	require.NoError(t, err)
+	txSig := signed[len(unsigned):]
+	require.True(t, acct.Verify(body, txSig))
+	require.False(t, VerifyMessage(acct.Public, unsigned, txSig))
+	require.False(t, VerifyMessage(acct.Public, body, txSig))
+}
+
+func TestSignMessageLedger(t *testing.T) {
+	m := newMockLedger(LedgerDevice{ID: "usb://ledger?id=1", Product: "Nano S"})
+	useMockLedger(t, m)
+	w, err := NewMultiWalletFromLedger(2)
+	require.NoError(t, err)
+
+	sig, err := w.SignMessage(1, []byte("challenge"))
+	require.NoError(t, err)
+	require.True(t, VerifyMessage(w.Secrets.Accounts[1].Public, []byte("challenge"), sig))
+
+	m.reject = true
+	_, err = w.SignMessage(1, []byte("challenge"))
+	require.ErrorIs(t, err, ErrLedgerRejected)
+}
@@ -0,0 +1,265 @@
+package wallet
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/tyler-smith/go-bip39"
+	"github.com/tyler-smith/go-bip39/wordlists"
+	"golang.org/x/text/unicode/norm"
+
+	"github.com/spacemeshos/smcli/common"
+)
+
+// Supported mnemonic languages, i.e., BIP39 wordlists.
+// https://github.com/bitcoin/bips/blob/master/bip-0039/bip-0039-wordlists.md
+const (
+	LanguageEnglish            = "english"
+	LanguageChineseSimplified  = "chinese-simplified"
+	LanguageChineseTraditional = "chinese-traditional"
+	LanguageCzech              = "czech"
+	LanguageFrench             = "french"
+	LanguageItalian            = "italian"
+	LanguageJapanese           = "japanese"
+	LanguageKorean             = "korean"
+	LanguageSpanish            = "spanish"
+)
+
+var wordLists = map[string][]string{
+	LanguageEnglish:            wordlists.English,
+	LanguageChineseSimplified:  wordlists.ChineseSimplified,
+	LanguageChineseTraditional: wordlists.ChineseTraditional,
+	LanguageCzech:              wordlists.Czech,
+	LanguageFrench:             wordlists.French,
+	LanguageItalian:            wordlists.Italian,
+	LanguageJapanese:           wordlists.Japanese,
+	LanguageKorean:             wordlists.Korean,
+	LanguageSpanish:            wordlists.Spanish,
+}
+
+// ideographicSpace separates the words of a Japanese mnemonic, as recommended by BIP39.
+const ideographicSpace = "　"
+
+// wordListMu guards the wordlist, which the bip39 lib keeps in a package-level variable.
+var wordListMu sync.Mutex
+
+// Languages returns the supported mnemonic languages, in alphabetical order.
+func Languages() []string {
+	languages := make([]string, 0, len(wordLists))
+	for lang := range wordLists {
+		languages = append(languages, lang)
+	}
+	sort.Strings(languages)
+	return languages
+}
+
+// withWordList runs f with the bip39 lib using the wordlist of the given language.
+func withWordList(lang string, f func() error) error {
+	list, ok := wordLists[lang]
+	if !ok {
+		return fmt.Errorf("unsupported mnemonic language %q, must be one of %s",
+			lang, strings.Join(Languages(), ", "))
+	}
+	wordListMu.Lock()
+	defer wordListMu.Unlock()
+	bip39.SetWordList(list)
+	defer bip39.SetWordList(wordlists.English)
+	return f()
+}
+
+// newMnemonic generates a new mnemonic in the given language from the given entropy. Japanese mnemonics use
+// ideographic spaces.
+func newMnemonic(lang string, entropy []byte) (m string, err error) {
+	err = withWordList(lang, func() (err error) {
+		m, err = bip39.NewMnemonic(entropy)
+		return
+	})
+	if lang == LanguageJapanese {
+		m = strings.ReplaceAll(m, " ", ideographicSpace)
+	}
+	return
+}
+
+// isMnemonicValid checks the word count, the words, and the checksum of a mnemonic in the given language. The
+// words are compared in NFKD form, like the wordlists.
+func isMnemonicValid(lang, m string) (valid bool, err error) {
+	err = withWordList(lang, func() error {
+		valid = bip39.IsMnemonicValid(norm.NFKD.String(m))
+		return nil
+	})
+	return
+}
+
+// mnemonicLanguage returns the language of a valid mnemonic, or an error if it's not valid in any language.
+func mnemonicLanguage(m string) (string, error) {
+	for _, lang := range Languages() {
+		if valid, _ := isMnemonicValid(lang, m); valid {
+			return lang, nil
+		}
+	}
+	return "", errInvalidMnemonic
+}
+
+// NormalizeMnemonic returns the NFKD form of a mnemonic, which BIP39 derives the seed from, and whether it
+// differs from m. Mnemonics pasted from some editors contain non-breaking spaces or precomposed accented letters
+// that look the same as the normalized ones: smcli derives the same seed from them, but a wallet that doesn't
+// normalize them wouldn't. The ideographic spaces of a Japanese mnemonic are kept.
+func NormalizeMnemonic(m string, opts ...WalletOpt) (string, bool) {
+	var normalized string
+	if newWalletOpts(opts).language == LanguageJapanese {
+		words := strings.Split(m, ideographicSpace)
+		for i, word := range words {
+			words[i] = norm.NFKD.String(word)
+		}
+		normalized = strings.Join(words, ideographicSpace)
+	} else {
+		normalized = norm.NFKD.String(m)
+	}
+	return normalized, normalized != m
+}
+
+// normalizeMnemonic normalizes a mnemonic given by the user, see NormalizeMnemonic, and warns if that changed it.
+func normalizeMnemonic(m string, opts []WalletOpt) string {
+	normalized, changed := NormalizeMnemonic(m, opts...)
+	if changed {
+		common.Logger().Warn("mnemonic is not NFKD-normalized as BIP39 requires, e.g., it contains non-breaking " +
+			"spaces or precomposed accented letters: using its normalized form")
+	}
+	return normalized
+}
+
+// mnemonicToSeed derives the BIP39 seed. Both the mnemonic and the passphrase are NFKD-normalized first, as
+// BIP39 requires, which also turns ideographic spaces into plain spaces.
+func mnemonicToSeed(m, passphrase string) []byte {
+	return bip39.NewSeed(norm.NFKD.String(m), norm.NFKD.String(passphrase))
+}
+
+// maxMnemonicLength bounds the input read by ReadMnemonic and ReadMnemonicFile. The longest mnemonics, 24
+// Japanese or Czech words, are well below this.
+const maxMnemonicLength = 1024
+
+// ReadMnemonic reads a mnemonic from the first line of r, e.g., from stdin. It reads one byte at a time so
+// that the following lines are left for other prompts. The line ending is removed, but no other whitespace:
+// the mnemonic is validated the same way as by NewMultiWalletFromMnemonic, so extra whitespace is an error.
+func ReadMnemonic(r io.Reader, opts ...WalletOpt) (string, error) {
+	var line []byte
+	var buf [1]byte
+	for len(line) <= maxMnemonicLength {
+		n, err := r.Read(buf[:])
+		if n == 0 && err == io.EOF {
+			break
+		}
+		if err != nil && err != io.EOF {
+			return "", fmt.Errorf("error reading mnemonic: %w", err)
+		}
+		if n == 1 && buf[0] == '\n' {
+			break
+		}
+		line = append(line, buf[:n]...)
+	}
+	return checkMnemonicInput(strings.TrimSuffix(string(line), "\r"), opts)
+}
+
+// ReadMnemonicFile reads a mnemonic from a file, which must contain nothing else but may end with a newline.
+// Reading it from a file keeps it out of the shell history.
+func ReadMnemonicFile(fn string, opts ...WalletOpt) (string, error) {
+	f, err := os.Open(fn)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+	data, err := io.ReadAll(io.LimitReader(f, maxMnemonicLength+1))
+	if err != nil {
+		return "", fmt.Errorf("error reading mnemonic file: %w", err)
+	}
+	m := strings.TrimSuffix(string(data), "\n")
+	return checkMnemonicInput(strings.TrimSuffix(m, "\r"), opts)
+}
+
+func checkMnemonicInput(m string, opts []WalletOpt) (string, error) {
+	if len(m) > maxMnemonicLength {
+		return "", common.WithCategory(common.ErrBadInput, fmt.Errorf("mnemonic too long"))
+	}
+	if m == "" {
+		return "", common.WithCategory(common.ErrBadInput, fmt.Errorf("no mnemonic found"))
+	}
+	m = normalizeMnemonic(m, opts)
+	o := newWalletOpts(opts)
+	if err := checkWhitespace(o.language, m); err != nil {
+		return "", err
+	}
+	valid, err := isMnemonicValid(o.language, m)
+	if err != nil {
+		return "", err
+	}
+	if !valid {
+		return "", errInvalidMnemonic
+	}
+	return m, nil
+}
+
+// MnemonicDiagnostics describes everything that is wrong with a mnemonic, so that a user can fix typos.
+type MnemonicDiagnostics struct {
+	// WordCount is the number of words in the mnemonic.
+	WordCount int
+	// ValidWordCount is set if WordCount is one of 12, 15, 18, 21, or 24.
+	ValidWordCount bool
+	// UnknownWords lists the words that aren't in the wordlist, in order.
+	UnknownWords []string
+	// WhitespaceViolation is set if the words aren't separated by single spaces, or there's leading or
+	// trailing whitespace.
+	WhitespaceViolation bool
+	// NotNormalized is set if the mnemonic isn't in NFKD form, see NormalizeMnemonic. It's still valid, since
+	// it's normalized before use, and the other checks apply to the normalized mnemonic.
+	NotNormalized bool
+	// ValidChecksum is set if the checksum encoded in the last word matches. It's only checked when the word
+	// count is valid and every word is in the wordlist.
+	ValidChecksum bool
+}
+
+// Valid reports whether the mnemonic can be used to create a wallet.
+func (d *MnemonicDiagnostics) Valid() bool {
+	return d.ValidWordCount && len(d.UnknownWords) == 0 && !d.WhitespaceViolation && d.ValidChecksum
+}
+
+// checkWhitespace makes sure that the words of a mnemonic are separated by single spaces (or single ideographic
+// spaces, for Japanese). The bip39 lib doesn't properly validate whitespace so we have to do that manually.
+func checkWhitespace(lang, m string) error {
+	if lang == LanguageJapanese {
+		m = strings.ReplaceAll(m, ideographicSpace, " ")
+	}
+	if expected := strings.Join(strings.Fields(m), " "); m != expected {
+		return errWhitespace
+	}
+	return nil
+}
+
+// DiagnoseMnemonic validates a BIP39 mnemonic and reports every problem it finds rather than just the first.
+// The mnemonic is checked against the English wordlist unless another language is set using WithLanguage.
+func DiagnoseMnemonic(m string, opts ...WalletOpt) (MnemonicDiagnostics, error) {
+	o := newWalletOpts(opts)
+	m, notNormalized := NormalizeMnemonic(m, opts...)
+	words := strings.Fields(m)
+	d := MnemonicDiagnostics{
+		WordCount:           len(words),
+		WhitespaceViolation: checkWhitespace(o.language, m) != nil,
+		NotNormalized:       notNormalized,
+	}
+	_, d.ValidWordCount = mnemonicEntropyBits[len(words)]
+	err := withWordList(o.language, func() error {
+		for _, word := range words {
+			if _, ok := bip39.GetWordIndex(norm.NFKD.String(word)); !ok {
+				d.UnknownWords = append(d.UnknownWords, word)
+			}
+		}
+		if d.ValidWordCount && len(d.UnknownWords) == 0 {
+			d.ValidChecksum = bip39.IsMnemonicValid(norm.NFKD.String(strings.Join(words, " ")))
+		}
+		return nil
+	})
+	return d, err
+}
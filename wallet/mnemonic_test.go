@@ -0,0 +1,264 @@
+package wallet
+
+import (
+	"bytes"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"golang.org/x/exp/slog"
+	"golang.org/x/text/unicode/norm"
+
+	"github.com/spacemeshos/smcli/common"
+)
+
+const testMnemonic = "film theme cheese broken kingdom destroy inch ready wear inspire shove pudding"
+
+func TestDiagnoseMnemonic(t *testing.T) {
+	d := diagnose(t, testMnemonic)
+	require.True(t, d.Valid())
+	require.Equal(t, MnemonicDiagnostics{WordCount: 12, ValidWordCount: true, ValidChecksum: true}, d)
+
+	// a typo is reported as an unknown word, not as a bad checksum
+	d = diagnose(t, "film theme cheese brokn kingdom destroy inch ready wear inspire shove puding")
+	require.False(t, d.Valid())
+	require.Equal(t, []string{"brokn", "puding"}, d.UnknownWords)
+	require.True(t, d.ValidWordCount)
+	require.False(t, d.WhitespaceViolation)
+	require.False(t, d.ValidChecksum)
+
+	// valid words in the wrong order
+	d = diagnose(t, "theme film cheese broken kingdom destroy inch ready wear inspire shove pudding")
+	require.False(t, d.Valid())
+	require.Empty(t, d.UnknownWords)
+	require.False(t, d.ValidChecksum)
+
+	// extra whitespace is flagged, but the words are still checked
+	for _, m := range []string{
+		" " + testMnemonic,
+		testMnemonic + "\n",
+		"film  theme cheese broken kingdom destroy inch ready wear inspire shove pudding",
+		"film\ttheme cheese broken kingdom destroy inch ready wear inspire shove pudding",
+	} {
+		d = diagnose(t, m)
+		require.False(t, d.Valid(), m)
+		require.True(t, d.WhitespaceViolation, m)
+		require.True(t, d.ValidChecksum, m)
+		_, err := NewMultiWalletFromMnemonic(m, 1)
+		require.ErrorIs(t, err, errWhitespace)
+	}
+
+	// wrong number of words
+	d = diagnose(t, "film theme cheese broken kingdom destroy inch ready wear inspire shove")
+	require.Equal(t, 11, d.WordCount)
+	require.False(t, d.ValidWordCount)
+	require.False(t, d.Valid())
+}
+
+func diagnose(t *testing.T, m string) MnemonicDiagnostics {
+	d, err := DiagnoseMnemonic(m)
+	require.NoError(t, err)
+	return d
+}
+
+func TestMnemonicLanguages(t *testing.T) {
+	for _, lang := range []string{LanguageJapanese, LanguageSpanish, LanguageKorean} {
+		t.Run(lang, func(t *testing.T) {
+			w, err := NewMultiWalletRandomMnemonic(2, WithLanguage(lang), WithWordCount(12))
+			require.NoError(t, err)
+			m := w.Mnemonic()
+
+			// every word is from the selected wordlist
+			sep := " "
+			if lang == LanguageJapanese {
+				sep = ideographicSpace
+			}
+			words := strings.Split(m, sep)
+			require.Len(t, words, 12)
+			for _, word := range words {
+				require.Contains(t, wordLists[lang], word)
+			}
+
+			// re-importing yields the same keys
+			w2, err := NewMultiWalletFromMnemonic(m, 2, WithLanguage(lang))
+			require.NoError(t, err)
+			require.Equal(t, w.Secrets.MasterKeypair.Private, w2.Secrets.MasterKeypair.Private)
+			require.Equal(t, w.Addresses("sm"), w2.Addresses("sm"))
+
+			d, err := DiagnoseMnemonic(m, WithLanguage(lang))
+			require.NoError(t, err)
+			require.True(t, d.Valid())
+
+			// the mnemonic isn't valid English
+			_, err = NewMultiWalletFromMnemonic(m, 1)
+			require.Error(t, err)
+			d, err = DiagnoseMnemonic(m)
+			require.NoError(t, err)
+			require.Len(t, d.UnknownWords, 12)
+
+			// accounts can be added without specifying the language again
+			require.NoError(t, w2.AddAccounts(1))
+			require.Len(t, w2.Secrets.Accounts, 3)
+		})
+	}
+
+	// the wordlist is restored afterwards
+	d := diagnose(t, testMnemonic)
+	require.True(t, d.Valid())
+}
+
+func TestJapaneseMnemonicSpaces(t *testing.T) {
+	w, err := NewMultiWalletRandomMnemonic(1, WithLanguage(LanguageJapanese))
+	require.NoError(t, err)
+	m := w.Mnemonic()
+	require.Contains(t, m, ideographicSpace)
+
+	// plain spaces are accepted and derive the same seed
+	ascii := strings.ReplaceAll(m, ideographicSpace, " ")
+	w2, err := NewMultiWalletFromMnemonic(ascii, 1, WithLanguage(LanguageJapanese))
+	require.NoError(t, err)
+	require.Equal(t, w.Addresses("sm"), w2.Addresses("sm"))
+
+	// as are NFC-composed words
+	w3, err := NewMultiWalletFromMnemonic(norm.NFC.String(m), 1, WithLanguage(LanguageJapanese))
+	require.NoError(t, err)
+	require.Equal(t, w.Addresses("sm"), w3.Addresses("sm"))
+
+	// but not doubled separators
+	_, err = NewMultiWalletFromMnemonic(strings.ReplaceAll(m, ideographicSpace, ideographicSpace+" "), 1,
+		WithLanguage(LanguageJapanese))
+	require.ErrorIs(t, err, errWhitespace)
+}
+
+func TestNormalizeMnemonic(t *testing.T) {
+	logs := &bytes.Buffer{}
+	defaultLogger := common.Logger()
+	common.SetLogger(common.NewLogger(logs, slog.LevelWarn))
+	t.Cleanup(func() { common.SetLogger(defaultLogger) })
+
+	want, err := NewMultiWalletFromMnemonic(testMnemonic, 1)
+	require.NoError(t, err)
+	require.Empty(t, logs.String())
+
+	// non-breaking spaces, as pasted from some editors, look like the spaces of the mnemonic but aren't
+	nbsp := strings.ReplaceAll(testMnemonic, " ", "\u00a0")
+	normalized, changed := NormalizeMnemonic(nbsp)
+	require.True(t, changed)
+	require.Equal(t, testMnemonic, normalized)
+	w, err := NewMultiWalletFromMnemonic(nbsp, 1)
+	require.NoError(t, err)
+	require.Equal(t, testMnemonic, w.Mnemonic())
+	require.Equal(t, want.Addresses("sm"), w.Addresses("sm"))
+	require.Contains(t, logs.String(), "not NFKD-normalized")
+	require.NotContains(t, logs.String(), "film")
+
+	d := diagnose(t, nbsp)
+	require.True(t, d.Valid())
+	require.True(t, d.NotNormalized)
+	logs.Reset()
+	m, err := ReadMnemonic(strings.NewReader(nbsp + "\n"))
+	require.NoError(t, err)
+	require.Equal(t, testMnemonic, m)
+	require.Contains(t, logs.String(), "not NFKD-normalized")
+
+	// precomposed accented letters, which the wordlists don't use
+	var composed []string
+	for _, word := range wordLists[LanguageFrench] {
+		if nfc := norm.NFC.String(word); nfc != word {
+			composed = append(composed, nfc)
+		}
+	}
+	require.NotEmpty(t, composed)
+	normalized, changed = NormalizeMnemonic(strings.Join(composed, " "), WithLanguage(LanguageFrench))
+	require.True(t, changed)
+	for i, word := range strings.Split(normalized, " ") {
+		require.Contains(t, wordLists[LanguageFrench], word, composed[i])
+	}
+
+	// a generated mnemonic is already normalized, including the ideographic spaces of Japanese ones
+	for _, lang := range []string{LanguageEnglish, LanguageFrench, LanguageJapanese} {
+		w, err := NewMultiWalletRandomMnemonic(1, WithLanguage(lang))
+		require.NoError(t, err)
+		normalized, changed := NormalizeMnemonic(w.Mnemonic(), WithLanguage(lang))
+		require.False(t, changed, lang)
+		require.Equal(t, w.Mnemonic(), normalized)
+	}
+}
+
+func TestUnknownLanguage(t *testing.T) {
+	_, err := NewMultiWalletRandomMnemonic(1, WithLanguage("klingon"))
+	require.Error(t, err)
+	_, err = NewMultiWalletFromMnemonic(testMnemonic, 1, WithLanguage("klingon"))
+	require.Error(t, err)
+	_, err = DiagnoseMnemonic(testMnemonic, WithLanguage("klingon"))
+	require.Error(t, err)
+}
+
+func TestReadMnemonicFile(t *testing.T) {
+	dir := t.TempDir()
+	write := func(content string) string {
+		fn := filepath.Join(dir, "mnemonic")
+		require.NoError(t, os.WriteFile(fn, []byte(content), 0o600))
+		return fn
+	}
+
+	for _, content := range []string{testMnemonic, testMnemonic + "\n", testMnemonic + "\r\n"} {
+		m, err := ReadMnemonicFile(write(content))
+		require.NoError(t, err)
+		require.Equal(t, testMnemonic, m)
+	}
+
+	// anything but a single trailing newline is a whitespace violation
+	for _, content := range []string{
+		testMnemonic + "\n\n",
+		" " + testMnemonic,
+		testMnemonic + " \n",
+		"film  theme cheese broken kingdom destroy inch ready wear inspire shove pudding\n",
+	} {
+		_, err := ReadMnemonicFile(write(content))
+		require.ErrorIs(t, err, errWhitespace, content)
+	}
+
+	// the checksum is checked too
+	_, err := ReadMnemonicFile(write("theme film cheese broken kingdom destroy inch ready wear inspire shove pudding"))
+	require.Error(t, err)
+	_, err = ReadMnemonicFile(write(""))
+	require.Error(t, err)
+	_, err = ReadMnemonicFile(write(strings.Repeat(testMnemonic+" ", 100)))
+	require.Error(t, err)
+	_, err = ReadMnemonicFile(filepath.Join(dir, "missing"))
+	require.Error(t, err)
+}
+
+func TestReadMnemonic(t *testing.T) {
+	// only the first line is consumed, so that later prompts can read the rest
+	r := strings.NewReader(testMnemonic + "\npassphrase\n")
+	m, err := ReadMnemonic(r)
+	require.NoError(t, err)
+	require.Equal(t, testMnemonic, m)
+	rest, err := io.ReadAll(r)
+	require.NoError(t, err)
+	require.Equal(t, "passphrase\n", string(rest))
+
+	m, err = ReadMnemonic(strings.NewReader(testMnemonic + "\r\n"))
+	require.NoError(t, err)
+	require.Equal(t, testMnemonic, m)
+	m, err = ReadMnemonic(strings.NewReader(testMnemonic))
+	require.NoError(t, err)
+	require.Equal(t, testMnemonic, m)
+
+	_, err = ReadMnemonic(strings.NewReader(testMnemonic + " \n"))
+	require.ErrorIs(t, err, errWhitespace)
+	_, err = ReadMnemonic(strings.NewReader("\n" + testMnemonic))
+	require.Error(t, err)
+
+	// the language is honored
+	w, err := NewMultiWalletRandomMnemonic(1, WithLanguage(LanguageJapanese))
+	require.NoError(t, err)
+	m, err = ReadMnemonic(strings.NewReader(w.Mnemonic()+"\n"), WithLanguage(LanguageJapanese))
+	require.NoError(t, err)
+	require.Equal(t, w.Mnemonic(), m)
+}
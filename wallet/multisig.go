@@ -0,0 +1,118 @@
+package wallet
+
+import (
+	"bytes"
+	"crypto/ed25519"
+	"fmt"
+	"sort"
+
+	"github.com/spacemeshos/go-scale"
+	"github.com/spacemeshos/go-spacemesh/common/types"
+	"github.com/spacemeshos/go-spacemesh/genvm/core"
+	"github.com/spacemeshos/go-spacemesh/genvm/templates/multisig"
+
+	"github.com/spacemeshos/smcli/common"
+)
+
+// ErrNotParticipant is returned by Wallet.MultiSigSigner if the wallet controls none of the participants of a
+// multisig account.
+var ErrNotParticipant = common.NewError(common.ErrBadInput, "wallet controls none of the multisig participants")
+
+// MultiSigSigner returns the account of the wallet that co-signs for a multisig account with the given public
+// keys, found by matching the public keys of the accounts against them, so that the user doesn't have to pick it.
+// It also returns the position of its key among pubkeys in spawn order (canonical order unless WithRawKeyOrder is
+// given, as for SignMultiSigPart), which is the ref of its partial signature. If the wallet controls more than one
+// participant, the first one in spawn order is returned.
+func (w *Wallet) MultiSigSigner(pubkeys []core.PublicKey, opts ...WalletOpt) (*EDKeyPair, int, error) {
+	pubkeys = newWalletOpts(opts).multiSigKeys(pubkeys)
+	for ref, pubkey := range pubkeys {
+		for _, acct := range w.Secrets.Accounts {
+			if acct != nil && bytes.Equal(acct.Public, pubkey[:]) {
+				return acct, ref, nil
+			}
+		}
+	}
+	return nil, 0, fmt.Errorf("%w: none of the %d accounts of the wallet is one of the %d participants",
+		ErrNotParticipant, len(w.Secrets.Accounts), len(pubkeys))
+}
+
+// SignMultiSigPart produces one participant's partial signature over an unsigned multisig transaction (e.g., one
+// produced by GenerateTxnData with the multisig account as the principal). The participant is identified by the
+// position of its public key among pubkeys, the public keys of the multisig account, in spawn order: canonical
+// order unless WithRawKeyOrder is given, as for SpawnMultiSig.
+func SignMultiSigPart(
+	kp *EDKeyPair,
+	pubkeys []core.PublicKey,
+	genesisID types.Hash20,
+	unsigned []byte,
+	opts ...WalletOpt,
+) (multisig.Part, error) {
+	if len(kp.Private) != ed25519.PrivateKeySize {
+		return multisig.Part{}, fmt.Errorf("keypair does not contain a private key")
+	}
+	if len(pubkeys) > MaxMultiSigKeys {
+		return multisig.Part{}, fmt.Errorf("%w: got %d, a multisig account can have at most %d",
+			ErrTooManyPublicKeys, len(pubkeys), MaxMultiSigKeys)
+	}
+	pubkeys = newWalletOpts(opts).multiSigKeys(pubkeys)
+	ref := -1
+	for i, pubkey := range pubkeys {
+		if bytes.Equal(pubkey[:], kp.Public) {
+			ref = i
+			break
+		}
+	}
+	if ref < 0 {
+		return multisig.Part{}, fmt.Errorf("keypair is not a participant in the multisig account")
+	}
+	part := multisig.Part{Ref: uint8(ref)}
+	copy(part.Sig[:], kp.Sign(core.SigningBody(genesisID[:], unsigned)))
+	return part, nil
+}
+
+// AggregateMultiSig combines the partial signatures collected from the participants of a multisig account that
+// requires `required` of pubkeys to sign, and returns the complete, signed transaction ready to be submitted to a
+// node. Every part is checked against the public key it refers to. If more parts than required are given only
+// the ones with the lowest refs are used, since go-spacemesh expects exactly `required` signatures in increasing
+// ref order. The public keys are put in spawn order as for SignMultiSigPart.
+func AggregateMultiSig(
+	required uint8,
+	pubkeys []core.PublicKey,
+	genesisID types.Hash20,
+	unsigned []byte,
+	parts []multisig.Part,
+	opts ...WalletOpt,
+) ([]byte, error) {
+	if _, err := newMultiSigArgs(required, pubkeys); err != nil {
+		return nil, err
+	}
+	pubkeys = newWalletOpts(opts).multiSigKeys(pubkeys)
+	body := core.SigningBody(genesisID[:], unsigned)
+	seen := make(map[uint8]struct{}, len(parts))
+	sigs := make(multisig.Signatures, 0, len(parts))
+	for _, part := range parts {
+		if _, ok := seen[part.Ref]; ok {
+			return nil, fmt.Errorf("duplicate signature from participant %d", part.Ref)
+		}
+		seen[part.Ref] = struct{}{}
+		if int(part.Ref) >= len(pubkeys) {
+			return nil, fmt.Errorf("invalid participant %d, multisig has %d public keys", part.Ref, len(pubkeys))
+		}
+		if !ed25519.Verify(pubkeys[part.Ref][:], body, part.Sig[:]) {
+			return nil, fmt.Errorf("invalid signature from participant %d", part.Ref)
+		}
+		sigs = append(sigs, part)
+	}
+	if len(sigs) < int(required) {
+		return nil, fmt.Errorf("not enough signatures: have %d, require %d", len(sigs), required)
+	}
+	sort.Slice(sigs, func(i, j int) bool { return sigs[i].Ref < sigs[j].Ref })
+
+	var buf bytes.Buffer
+	if _, err := scale.EncodeStructArray(scale.NewEncoder(&buf), sigs[:required]); err != nil {
+		return nil, fmt.Errorf("error encoding signatures: %w", err)
+	}
+	signed := make([]byte, 0, len(unsigned)+buf.Len())
+	signed = append(signed, unsigned...)
+	return append(signed, buf.Bytes()...), nil
+}
@@ -0,0 +1,174 @@
+package wallet
+
+import (
+	"testing"
+
+	"github.com/spacemeshos/go-spacemesh/common/types"
+	"github.com/spacemeshos/go-spacemesh/genvm/core"
+	sdkMultisig "github.com/spacemeshos/go-spacemesh/genvm/sdk/multisig"
+	"github.com/spacemeshos/go-spacemesh/genvm/templates/multisig"
+	"github.com/stretchr/testify/require"
+
+	"github.com/spacemeshos/smcli/common"
+)
+
+func TestMultiSigSigningRound(t *testing.T) {
+	// three signers, any two of which can spend
+	master, err := NewMasterKeyPair(goodSeed)
+	require.NoError(t, err)
+	signers, err := accountsFromMaster(master, goodSeed, 4)
+	require.NoError(t, err)
+	outsider := signers[3]
+	signers = signers[:3]
+	pubkeys := testPubkeys(t, 3)
+	principal, _, err := SpawnMultiSig(2, pubkeys)
+	require.NoError(t, err)
+	genesisID := types.Hash20{1, 2, 3}
+
+	data := TxnData{
+		Principal: principal,
+		Recipient: testPrincipal(t, outsider),
+		Amount:    1000,
+		Nonce:     1,
+		GasPrice:  1,
+	}
+	unsigned, err := GenerateTxnData(data)
+	require.NoError(t, err)
+
+	// each signer produces their part independently, referring to their key in canonical order
+	sorted := SortPublicKeys(pubkeys)
+	parts := make([]multisig.Part, len(signers))
+	for i, signer := range signers {
+		parts[i], err = SignMultiSigPart(signer, pubkeys, genesisID, unsigned)
+		require.NoError(t, err)
+		require.Equal(t, signer.Public, PublicKey(sorted[parts[i].Ref][:]))
+	}
+	_, err = SignMultiSigPart(outsider, pubkeys, genesisID, unsigned)
+	require.Error(t, err)
+
+	// parts may be collected in any order
+	signed, err := AggregateMultiSig(2, pubkeys, genesisID, unsigned, []multisig.Part{parts[2], parts[0]})
+	require.NoError(t, err)
+
+	// must match the go-spacemesh SDK aggregator byte for byte
+	agg := sdkMultisig.NewAggregator(unsigned)
+	agg.Add(parts[2], parts[0])
+	require.Equal(t, agg.Raw(), signed)
+
+	// extra parts are dropped
+	all, err := AggregateMultiSig(2, pubkeys, genesisID, unsigned, parts)
+	require.NoError(t, err)
+	var lowest []multisig.Part
+	for _, part := range parts {
+		if part.Ref < 2 {
+			lowest = append(lowest, part)
+		}
+	}
+	expected, err := AggregateMultiSig(2, pubkeys, genesisID, unsigned, lowest)
+	require.NoError(t, err)
+	require.Equal(t, expected, all)
+
+	// too few signatures
+	_, err = AggregateMultiSig(2, pubkeys, genesisID, unsigned, parts[:1])
+	require.ErrorContains(t, err, "not enough signatures")
+
+	// duplicate signer
+	_, err = AggregateMultiSig(2, pubkeys, genesisID, unsigned, []multisig.Part{parts[1], parts[1]})
+	require.ErrorContains(t, err, "duplicate signature")
+
+	// signature over a different network
+	wrongNet, err := SignMultiSigPart(signers[1], pubkeys, types.Hash20{}, unsigned)
+	require.NoError(t, err)
+	_, err = AggregateMultiSig(2, pubkeys, genesisID, unsigned, []multisig.Part{parts[0], wrongNet})
+	require.ErrorContains(t, err, "invalid signature")
+
+	// unknown signer
+	_, err = AggregateMultiSig(2, pubkeys, genesisID, unsigned, []multisig.Part{parts[0], {Ref: 3}})
+	require.Error(t, err)
+
+	// the template decodes exactly `required` (ref, signature) pairs without a length prefix
+	require.Len(t, signed, len(unsigned)+2*(1+len(core.Signature{})))
+}
+
+func TestMultiSigRawKeyOrder(t *testing.T) {
+	master, err := NewMasterKeyPair(goodSeed)
+	require.NoError(t, err)
+	signers, err := accountsFromMaster(master, goodSeed, 2)
+	require.NoError(t, err)
+	pubkeys := testPubkeys(t, 2)
+	reversed := []core.PublicKey{pubkeys[1], pubkeys[0]}
+	genesisID := types.Hash20{1, 2, 3}
+
+	// the keys are sorted unless told otherwise, so the order they're given in doesn't matter
+	principal, _, err := SpawnMultiSig(1, pubkeys)
+	require.NoError(t, err)
+	other, _, err := SpawnMultiSig(1, reversed)
+	require.NoError(t, err)
+	require.Equal(t, principal, other)
+	sorted, _, err := SpawnMultiSig(1, SortPublicKeys(pubkeys), WithRawKeyOrder())
+	require.NoError(t, err)
+	require.Equal(t, principal, sorted)
+
+	// in raw order, each order is a different account
+	raw, _, err := SpawnMultiSig(1, pubkeys, WithRawKeyOrder())
+	require.NoError(t, err)
+	rawReversed, _, err := SpawnMultiSig(1, reversed, WithRawKeyOrder())
+	require.NoError(t, err)
+	require.NotEqual(t, raw, rawReversed)
+	require.Contains(t, []core.Address{raw, rawReversed}, principal)
+
+	// and signers refer to their key in the order given
+	unsigned, err := GenerateTxnData(TxnData{Principal: raw, Recipient: raw, Amount: 1, Nonce: 1, GasPrice: 1})
+	require.NoError(t, err)
+	for i, signer := range signers {
+		part, err := SignMultiSigPart(signer, pubkeys, genesisID, unsigned, WithRawKeyOrder())
+		require.NoError(t, err)
+		require.EqualValues(t, i, part.Ref)
+		part, err = SignMultiSigPart(signer, reversed, genesisID, unsigned, WithRawKeyOrder())
+		require.NoError(t, err)
+		require.EqualValues(t, 1-i, part.Ref)
+
+		_, err = AggregateMultiSig(1, pubkeys, genesisID, unsigned, []multisig.Part{part}, WithRawKeyOrder())
+		require.ErrorContains(t, err, "invalid signature")
+		_, err = AggregateMultiSig(1, reversed, genesisID, unsigned, []multisig.Part{part}, WithRawKeyOrder())
+		require.NoError(t, err)
+	}
+}
+
+func TestMultiSigSigner(t *testing.T) {
+	w, err := NewMultiWalletRandomMnemonic(3)
+	require.NoError(t, err)
+	var own core.PublicKey
+	copy(own[:], w.Secrets.Accounts[1].Public)
+	pubkeys := append(testPubkeys(t, 2), own)
+	genesisID := types.Hash20{1}
+	unsigned := []byte("unsigned transaction")
+
+	// the wallet controls one of the participants
+	for _, opts := range [][]WalletOpt{nil, {WithRawKeyOrder()}} {
+		signer, ref, err := w.MultiSigSigner(pubkeys, opts...)
+		require.NoError(t, err)
+		require.Same(t, w.Secrets.Accounts[1], signer)
+		require.Equal(t, own, newWalletOpts(opts).multiSigKeys(pubkeys)[ref])
+		part, err := SignMultiSigPart(signer, pubkeys, genesisID, unsigned, opts...)
+		require.NoError(t, err)
+		require.Equal(t, uint8(ref), part.Ref)
+	}
+	_, ref, err := w.MultiSigSigner(pubkeys, WithRawKeyOrder())
+	require.NoError(t, err)
+	require.Equal(t, 2, ref)
+
+	// and of a stored multisig account
+	m, err := w.AddMultiSig("joint", 2, pubkeys)
+	require.NoError(t, err)
+	signer, _, err := w.MultiSigSigner(m.Keys(), WithRawKeyOrder())
+	require.NoError(t, err)
+	require.Same(t, w.Secrets.Accounts[1], signer)
+
+	// another wallet controls none of them
+	other, err := NewMultiWalletRandomMnemonic(3)
+	require.NoError(t, err)
+	_, _, err = other.MultiSigSigner(pubkeys)
+	require.ErrorIs(t, err, ErrNotParticipant)
+	require.ErrorIs(t, err, common.ErrBadInput)
+}
@@ -0,0 +1,216 @@
+package wallet
+
+import (
+	"encoding/hex"
+	"fmt"
+	"strings"
+
+	"github.com/spacemeshos/go-spacemesh/genvm/core"
+	"github.com/spacemeshos/go-spacemesh/genvm/templates/multisig"
+
+	"github.com/spacemeshos/smcli/common"
+)
+
+// A wallet can record the multisig accounts that it takes part in, so that their threshold and participants don't
+// have to be given again every time the account spawns or signs. They're stored in the wallet file along with the
+// accounts. Since the principal of a multisig account depends on the exact order of its participants, they're
+// stored in spawn order along with the principal computed when the account was added, and the principal is
+// computed again and checked whenever the wallet is read.
+
+var (
+	// ErrMultiSigNotFound is returned by Wallet.MultiSig if the wallet has no multisig account with the label.
+	ErrMultiSigNotFound = common.NewError(common.ErrBadInput, "no such multisig account in wallet")
+	// ErrMultiSigExists is returned by Wallet.AddMultiSig if the label or the account is already in the wallet.
+	ErrMultiSigExists = common.NewError(common.ErrBadInput, "multisig account already in wallet")
+	// ErrMultiSigPrincipal is returned if the stored principal of a multisig account doesn't match its
+	// participants, e.g., because the wallet file was edited.
+	ErrMultiSigPrincipal = common.NewError(common.ErrBadInput, "multisig principal does not match its participants")
+)
+
+// MultiSigAccount is a multisig account stored in a wallet, see Wallet.AddMultiSig.
+type MultiSigAccount struct {
+	// Label names the account in the wallet, e.g., "treasury".
+	Label    string `json:"label"`
+	Required uint8  `json:"required"`
+	// PublicKeys are the participants in spawn order.
+	PublicKeys []PublicKey `json:"publicKeys"`
+	// Principal is the hex-encoded address of the account, as computed when it was added.
+	Principal string `json:"principal"`
+}
+
+// Keys returns the participants of the account in spawn order.
+func (m *MultiSigAccount) Keys() []core.PublicKey {
+	keys := make([]core.PublicKey, len(m.PublicKeys))
+	for i, pubkey := range m.PublicKeys {
+		copy(keys[i][:], pubkey)
+	}
+	return keys
+}
+
+// Spawn computes the spawn arguments of the account again from its threshold and participants, and checks that
+// they produce the stored principal. The participants are already in spawn order, so pass WithRawKeyOrder along
+// with the returned public keys to SignMultiSigPart and AggregateMultiSig.
+func (m *MultiSigAccount) Spawn() (*MultiSigSpawn, error) {
+	for i, pubkey := range m.PublicKeys {
+		if len(pubkey) != len(core.PublicKey{}) {
+			return nil, fmt.Errorf("multisig %q: invalid length %d of public key %d", m.Label, len(pubkey), i)
+		}
+	}
+	spawn, err := DescribeMultiSigSpawn(m.Required, m.Keys(), WithRawKeyOrder())
+	if err != nil {
+		return nil, fmt.Errorf("multisig %q: %w", m.Label, err)
+	}
+	if hex.EncodeToString(spawn.Principal[:]) != strings.ToLower(m.Principal) {
+		return nil, fmt.Errorf("%w: %q is stored as %s but computes to %x", ErrMultiSigPrincipal, m.Label,
+			m.Principal, spawn.Principal[:])
+	}
+	return spawn, nil
+}
+
+// AddMultiSig stores a multisig account that requires `required` signatures from pubkeys in the wallet under
+// label. The keys are put in canonical order unless WithRawKeyOrder is given, as for SpawnMultiSig, and stored in
+// that order. The label must be unique, and so must the account: the same account can't be stored twice.
+func (w *Wallet) AddMultiSig(
+	label string,
+	required uint8,
+	pubkeys []core.PublicKey,
+	opts ...WalletOpt,
+) (*MultiSigAccount, error) {
+	if label == "" {
+		return nil, fmt.Errorf("multisig label must not be empty")
+	}
+	if err := checkName("multisig label", label); err != nil {
+		return nil, err
+	}
+	spawn, err := DescribeMultiSigSpawn(required, pubkeys, opts...)
+	if err != nil {
+		return nil, err
+	}
+	principal := hex.EncodeToString(spawn.Principal[:])
+	for _, m := range w.Secrets.MultiSigs {
+		if m.Label == label {
+			return nil, fmt.Errorf("%w: label %q is taken", ErrMultiSigExists, label)
+		}
+		if strings.ToLower(m.Principal) == principal {
+			return nil, fmt.Errorf("%w: stored as %q", ErrMultiSigExists, m.Label)
+		}
+	}
+	m := &MultiSigAccount{Label: label, Required: required, Principal: principal}
+	for _, pubkey := range spawn.PublicKeys {
+		m.PublicKeys = append(m.PublicKeys, append(PublicKey(nil), pubkey[:]...))
+	}
+	w.Secrets.MultiSigs = append(w.Secrets.MultiSigs, m)
+	return m, nil
+}
+
+// MultiSig returns the multisig account stored in the wallet under label, checking that it still produces its
+// stored principal. The returned error wraps ErrMultiSigNotFound if there's none.
+func (w *Wallet) MultiSig(label string) (*MultiSigAccount, *MultiSigSpawn, error) {
+	for _, m := range w.Secrets.MultiSigs {
+		if m.Label == label {
+			spawn, err := m.Spawn()
+			if err != nil {
+				return nil, nil, err
+			}
+			return m, spawn, nil
+		}
+	}
+	return nil, nil, fmt.Errorf("%w: %q", ErrMultiSigNotFound, label)
+}
+
+// SignMultiSigTxn produces the partial signature of the wallet over an offline transaction sent by the multisig
+// account stored under label, see SignMultiSigPart. The account of the wallet that signs is the one that takes part
+// in the multisig account, see MultiSigSigner: it's returned along with the part, whose ref is the position of its
+// key among the participants. The caller is expected to check the genesis ID against the wallet's, see
+// CheckGenesisID.
+func (w *Wallet) SignMultiSigTxn(label string, t *OfflineTxn) (multisig.Part, *EDKeyPair, error) {
+	spawn, err := w.multiSigTxn(label, t)
+	if err != nil {
+		return multisig.Part{}, nil, err
+	}
+	kp, _, err := w.MultiSigSigner(spawn.PublicKeys, WithRawKeyOrder())
+	if err != nil {
+		return multisig.Part{}, nil, err
+	}
+	part, err := SignMultiSigPart(kp, spawn.PublicKeys, t.GenesisID, t.Unsigned, WithRawKeyOrder())
+	if err != nil {
+		return multisig.Part{}, nil, err
+	}
+	return part, kp, nil
+}
+
+// AggregateMultiSigTxn combines the partial signatures collected over an offline transaction sent by the multisig
+// account stored under label, using its threshold and participants, see AggregateMultiSig.
+func (w *Wallet) AggregateMultiSigTxn(label string, t *OfflineTxn, parts []multisig.Part) ([]byte, error) {
+	spawn, err := w.multiSigTxn(label, t)
+	if err != nil {
+		return nil, err
+	}
+	return AggregateMultiSig(spawn.Required, spawn.PublicKeys, t.GenesisID, t.Unsigned, parts, WithRawKeyOrder())
+}
+
+// multiSigTxn returns the spawn arguments of the multisig account stored under label, checking that it sends t.
+func (w *Wallet) multiSigTxn(label string, t *OfflineTxn) (*MultiSigSpawn, error) {
+	_, spawn, err := w.MultiSig(label)
+	if err != nil {
+		return nil, err
+	}
+	d, err := t.Decode()
+	if err != nil {
+		return nil, err
+	}
+	if d.Method != core.MethodSpend || d.Principal != spawn.Principal {
+		return nil, common.WithCategory(common.ErrBadInput, fmt.Errorf("transaction is not a spend of multisig %q "+
+			"(%s), its principal is %s", label, spawn.Principal.String(), d.Principal.String()))
+	}
+	return spawn, nil
+}
+
+// EncodeMultiSigPart encodes a partial signature as hex, the ref of the participant followed by its signature, to
+// be passed on to whoever aggregates the signatures, see ParseMultiSigPart.
+func EncodeMultiSigPart(part multisig.Part) string {
+	return hex.EncodeToString(append([]byte{part.Ref}, part.Sig[:]...))
+}
+
+// ParseMultiSigPart parses a partial signature encoded by EncodeMultiSigPart.
+func ParseMultiSigPart(s string) (multisig.Part, error) {
+	var part multisig.Part
+	data, err := hex.DecodeString(strings.TrimPrefix(strings.TrimSpace(s), "0x"))
+	if err != nil || len(data) != 1+len(part.Sig) {
+		return part, common.WithCategory(common.ErrBadInput, fmt.Errorf("invalid partial signature, must be %d "+
+			"hex-encoded bytes", 1+len(part.Sig)))
+	}
+	part.Ref = data[0]
+	copy(part.Sig[:], data[1:])
+	return part, nil
+}
+
+// checkMultiSigs checks the multisig accounts read from a wallet file, see MultiSigAccount.Spawn.
+func checkMultiSigs(multisigs []*MultiSigAccount) error {
+	for i, m := range multisigs {
+		if m == nil {
+			return fmt.Errorf("multisig %d is missing", i)
+		}
+		if _, err := m.Spawn(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// copyMultiSigs returns a deep copy of multisigs.
+func copyMultiSigs(multisigs []*MultiSigAccount) []*MultiSigAccount {
+	if multisigs == nil {
+		return nil
+	}
+	copies := make([]*MultiSigAccount, len(multisigs))
+	for i, m := range multisigs {
+		c := *m
+		c.PublicKeys = make([]PublicKey, len(m.PublicKeys))
+		for j, pubkey := range m.PublicKeys {
+			c.PublicKeys[j] = append(PublicKey(nil), pubkey...)
+		}
+		copies[i] = &c
+	}
+	return copies
+}
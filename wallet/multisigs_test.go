@@ -0,0 +1,166 @@
+package wallet
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+
+	"github.com/spacemeshos/go-spacemesh/common/types"
+	"github.com/spacemeshos/go-spacemesh/genvm/templates/multisig"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMultiSigAccountRoundTrip(t *testing.T) {
+	w, err := NewMultiWalletRandomMnemonic(1)
+	require.NoError(t, err)
+	pubkeys := testPubkeys(t, 3)
+	want, _, err := SpawnMultiSig(2, pubkeys)
+	require.NoError(t, err)
+
+	m, err := w.AddMultiSig("treasury", 2, pubkeys)
+	require.NoError(t, err)
+	spawn, err := m.Spawn()
+	require.NoError(t, err)
+	require.Equal(t, want, spawn.Principal)
+	require.Equal(t, SortPublicKeys(pubkeys), m.Keys())
+	raw, err := w.AddMultiSig("raw", 2, pubkeys, WithRawKeyOrder())
+	require.NoError(t, err)
+	require.Equal(t, pubkeys, raw.Keys())
+
+	_, err = w.AddMultiSig("treasury", 1, pubkeys)
+	require.ErrorIs(t, err, ErrMultiSigExists)
+	// the same account under another label
+	_, err = w.AddMultiSig("again", 2, pubkeys[:])
+	require.ErrorIs(t, err, ErrMultiSigExists)
+	_, err = w.AddMultiSig("", 2, pubkeys)
+	require.Error(t, err)
+	_, err = w.AddMultiSig("too many", 4, pubkeys)
+	require.ErrorIs(t, err, ErrInvalidThreshold)
+
+	// persisted in the encrypted wallet file, and recomputes the same principal when it's opened
+	wk := NewKey(WithRandomSalt(), WithIterations(1000), WithPbkdf2Password([]byte("password")))
+	var file bytes.Buffer
+	require.NoError(t, wk.Export(&file, w))
+	opened, err := wk.Open(bytes.NewReader(file.Bytes()))
+	require.NoError(t, err)
+	require.Equal(t, w.Secrets.MultiSigs, opened.Secrets.MultiSigs)
+	m, spawn, err = opened.MultiSig("treasury")
+	require.NoError(t, err)
+	require.Equal(t, "treasury", m.Label)
+	require.Equal(t, uint8(2), spawn.Required)
+	require.Equal(t, want, spawn.Principal)
+	_, spawn, err = opened.MultiSig("raw")
+	require.NoError(t, err)
+	require.Equal(t, pubkeys, spawn.PublicKeys)
+	_, _, err = opened.MultiSig("savings")
+	require.ErrorIs(t, err, ErrMultiSigNotFound)
+
+	// a wallet whose stored principal doesn't match the participants doesn't open
+	opened.Secrets.MultiSigs[0].Required = 1
+	file.Reset()
+	require.NoError(t, wk.Export(&file, opened))
+	_, err = wk.Open(bytes.NewReader(file.Bytes()))
+	require.ErrorIs(t, err, ErrMultiSigPrincipal)
+}
+
+func TestMultiSigAccountHardwareWallet(t *testing.T) {
+	m := newMockLedger(LedgerDevice{ID: "usb://ledger?id=1", Product: "Nano S"})
+	useMockLedger(t, m)
+	w, err := NewMultiWalletFromLedger(1)
+	require.NoError(t, err)
+	pubkeys := testPubkeys(t, 2)
+	_, err = w.AddMultiSig("joint", 1, pubkeys)
+	require.NoError(t, err)
+
+	hw, err := w.Hardware()
+	require.NoError(t, err)
+	var file bytes.Buffer
+	require.NoError(t, hw.Export(&file))
+	read, err := ReadHardwareWallet(bytes.NewReader(file.Bytes()))
+	require.NoError(t, err)
+	_, spawn, err := read.Wallet().MultiSig("joint")
+	require.NoError(t, err)
+	want, _, err := SpawnMultiSig(1, pubkeys)
+	require.NoError(t, err)
+	require.Equal(t, want, spawn.Principal)
+
+	// swapping the participants changes the principal
+	keys := hw.MultiSigs[0].PublicKeys
+	keys[0], keys[1] = keys[1], keys[0]
+	data, err := json.Marshal(hw)
+	require.NoError(t, err)
+	_, err = ReadHardwareWallet(bytes.NewReader(data))
+	require.ErrorIs(t, err, ErrMultiSigPrincipal)
+
+	// files of older versions, which can't store multisig accounts, are upgraded when they're written back
+	hw.Meta.Version = 1
+	hw.MultiSigs = nil
+	upgraded := hw.Wallet()
+	_, err = upgraded.AddMultiSig("joint", 1, pubkeys)
+	require.NoError(t, err)
+	hw, err = upgraded.Hardware()
+	require.NoError(t, err)
+	require.Equal(t, WalletVersion, hw.Meta.Version)
+}
+
+func TestMultiSigTxnByLabel(t *testing.T) {
+	// two wallets that each control one participant of a 2-of-3 account, stored under the same label
+	pubkeys := testPubkeys(t, 3)
+	genesisID := types.Hash20{1, 2, 3}
+	w1, err := NewMultiWalletFromSeed(goodSeed, 1)
+	require.NoError(t, err)
+	w2, err := NewMultiWalletFromSeed(goodSeed, 3)
+	require.NoError(t, err)
+	w2.Secrets.Accounts = w2.Secrets.Accounts[2:]
+	outsider, err := NewMultiWalletRandomMnemonic(1)
+	require.NoError(t, err)
+	for _, w := range []*Wallet{w1, w2, outsider} {
+		_, err := w.AddMultiSig("treasury", 2, pubkeys)
+		require.NoError(t, err)
+	}
+	_, spawn, err := w1.MultiSig("treasury")
+	require.NoError(t, err)
+	txn, err := NewOfflineTxn(TxnData{
+		Principal: spawn.Principal,
+		Recipient: types.Address{1},
+		Amount:    1000,
+		Nonce:     1,
+		GasPrice:  1,
+	}, genesisID)
+	require.NoError(t, err)
+
+	// each wallet signs with the account that takes part, found without picking it
+	part1, kp1, err := w1.SignMultiSigTxn("treasury", txn)
+	require.NoError(t, err)
+	require.Equal(t, w1.Secrets.Accounts[0], kp1)
+	part2, kp2, err := w2.SignMultiSigTxn("treasury", txn)
+	require.NoError(t, err)
+	require.Equal(t, w2.Secrets.Accounts[0], kp2)
+	require.NotEqual(t, part1.Ref, part2.Ref)
+	_, _, err = outsider.SignMultiSigTxn("treasury", txn)
+	require.ErrorIs(t, err, ErrNotParticipant)
+	_, _, err = w1.SignMultiSigTxn("savings", txn)
+	require.ErrorIs(t, err, ErrMultiSigNotFound)
+
+	// the parts survive being passed around, and any wallet storing the account can aggregate them using its
+	// threshold
+	parsed, err := ParseMultiSigPart(EncodeMultiSigPart(part2) + "\n")
+	require.NoError(t, err)
+	require.Equal(t, part2, parsed)
+	_, err = ParseMultiSigPart("00")
+	require.Error(t, err)
+	_, err = outsider.AggregateMultiSigTxn("treasury", txn, []multisig.Part{part1})
+	require.ErrorContains(t, err, "not enough signatures")
+	signed, err := outsider.AggregateMultiSigTxn("treasury", txn, []multisig.Part{parsed, part1})
+	require.NoError(t, err)
+	want, err := AggregateMultiSig(2, pubkeys, genesisID, txn.Unsigned, []multisig.Part{part1, part2})
+	require.NoError(t, err)
+	require.Equal(t, want, signed)
+
+	// the transaction must be sent by the stored account
+	other, err := w1.AddMultiSig("other", 1, pubkeys)
+	require.NoError(t, err)
+	require.NotEqual(t, spawn.Principal.String(), other.Principal)
+	_, _, err = w1.SignMultiSigTxn("other", txn)
+	require.ErrorContains(t, err, "not a spend of multisig")
+}
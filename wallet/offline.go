@@ -0,0 +1,142 @@
+package wallet
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+
+	"github.com/spacemeshos/go-spacemesh/common/types"
+	"github.com/spacemeshos/go-spacemesh/genvm/core"
+
+	"github.com/spacemeshos/smcli/common"
+)
+
+// offlineTxnVersion is the version of the offline transaction file format.
+const offlineTxnVersion = 1
+
+// OfflineTxn is an unsigned transaction together with the genesis ID of the network it's for: everything needed
+// to review and sign it on a machine without network access. The transaction itself includes the principal,
+// recipient, amount, nonce, and gas price, see DecodeTransaction.
+type OfflineTxn struct {
+	GenesisID types.Hash20
+	Unsigned  []byte
+}
+
+// offlineTxnFile is the serialized form of an OfflineTxn.
+type offlineTxnFile struct {
+	Version     int    `json:"version"`
+	GenesisID   string `json:"genesisId"`
+	Transaction string `json:"transaction"`
+}
+
+// NewOfflineTxn builds an unsigned spend transaction using GenerateTxnData, for the network with the given
+// genesis ID.
+func NewOfflineTxn(data TxnData, genesisID types.Hash20) (*OfflineTxn, error) {
+	if genesisID == (types.Hash20{}) {
+		return nil, fmt.Errorf("genesis ID must be set")
+	}
+	unsigned, err := GenerateTxnData(data)
+	if err != nil {
+		return nil, err
+	}
+	return &OfflineTxn{GenesisID: genesisID, Unsigned: unsigned}, nil
+}
+
+// ReadOfflineTxn reads an offline transaction written by Export, and checks that it can be signed, see Decode.
+func ReadOfflineTxn(file io.Reader) (*OfflineTxn, error) {
+	f := &offlineTxnFile{}
+	if err := json.NewDecoder(file).Decode(f); err != nil {
+		return nil, common.WithCategory(common.ErrBadInput, fmt.Errorf("error reading offline transaction file: %w", err))
+	}
+	if f.Version != offlineTxnVersion {
+		return nil, common.WithCategory(common.ErrBadInput,
+			fmt.Errorf("unsupported offline transaction file version %d", f.Version))
+	}
+	genesisID, err := ParseGenesisID(f.GenesisID)
+	if err != nil {
+		return nil, err
+	}
+	unsigned, err := hex.DecodeString(f.Transaction)
+	if err != nil {
+		return nil, common.WithCategory(common.ErrBadInput, fmt.Errorf("invalid transaction: %w", err))
+	}
+	t := &OfflineTxn{GenesisID: genesisID, Unsigned: unsigned}
+	if _, err := t.Decode(); err != nil {
+		return nil, err
+	}
+	return t, nil
+}
+
+// Export writes the offline transaction to file as JSON.
+func (t *OfflineTxn) Export(file io.Writer) error {
+	enc := json.NewEncoder(file)
+	enc.SetIndent("", "  ")
+	return enc.Encode(&offlineTxnFile{
+		Version:     offlineTxnVersion,
+		GenesisID:   hex.EncodeToString(t.GenesisID[:]),
+		Transaction: hex.EncodeToString(t.Unsigned),
+	})
+}
+
+// Decode returns what the transaction does, for review before signing. Only unsigned spends, and the spawns of
+// single-sig wallet accounts by themselves (see TransferTxns), can be signed offline.
+func (t *OfflineTxn) Decode() (*DecodedTxn, error) {
+	d, err := DecodeTransaction(t.Unsigned)
+	if err != nil {
+		return nil, err
+	}
+	selfSpawn := d.Method == core.MethodSpawn && d.TemplateName == TemplateNameWallet && d.SelfSpawn
+	if d.Method != core.MethodSpend && !selfSpawn {
+		return nil, common.WithCategory(common.ErrBadInput, fmt.Errorf("unsupported %s transaction, only spends and "+
+			"single-sig self spawns can be signed offline", d.MethodName))
+	}
+	if len(d.Signature) > 0 || len(d.Parts) > 0 {
+		return nil, common.WithCategory(common.ErrBadInput, fmt.Errorf("transaction is already signed"))
+	}
+	return d, nil
+}
+
+// SignOfflineTxn signs an offline transaction using the account of the wallet that's its principal, and returns
+// the signed transaction, ready to be submitted to a node, along with the HD account index of the account. A
+// Ledger wallet asks the device to sign, which requires the user's approval; use WithLedgerDevice to select the
+// device. That returns ErrLedgerSigningUnsupported until the Ledger transport can sign, see CheckLedgerSigning.
+// The caller is expected to check the genesis ID against the wallet's, see CheckGenesisID.
+func (w *Wallet) SignOfflineTxn(t *OfflineTxn, opts ...WalletOpt) ([]byte, uint32, error) {
+	d, err := t.Decode()
+	if err != nil {
+		return nil, 0, err
+	}
+	principals, err := w.Principals()
+	if err != nil {
+		return nil, 0, err
+	}
+	for i, principal := range principals {
+		if principal != d.Principal {
+			continue
+		}
+		acct := w.Secrets.Accounts[i]
+		index := accountIndex(acct, i)
+		if master := w.Secrets.MasterKeypair; master != nil && master.KeyType == typeLedger {
+			body := core.SigningBody(t.GenesisID[:], t.Unsigned)
+			sig, err := SignWithLedger(acct.Path, body, opts...)
+			if err != nil {
+				return nil, 0, err
+			}
+			if !acct.Verify(body, sig) {
+				return nil, 0, fmt.Errorf("account %d on the Ledger device does not match the wallet: %w",
+					index, errLedgerMismatch)
+			}
+			signed := make([]byte, 0, len(t.Unsigned)+len(sig))
+			signed = append(signed, t.Unsigned...)
+			return append(signed, sig...), index, nil
+		}
+		signed, err := SignTxn(acct, t.GenesisID, t.Unsigned)
+		if err != nil {
+			return nil, 0, fmt.Errorf("account %d: %w", index, err)
+		}
+		return signed, index, nil
+	}
+	return nil, 0, fmt.Errorf("%w: no account of the wallet is the principal %s", ErrAccountNotFound,
+		d.Principal.String())
+}
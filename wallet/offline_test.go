@@ -0,0 +1,135 @@
+package wallet
+
+import (
+	"bytes"
+	"crypto/ed25519"
+	"strings"
+	"testing"
+
+	"github.com/spacemeshos/go-spacemesh/common/types"
+	"github.com/spacemeshos/go-spacemesh/genvm/core"
+	"github.com/stretchr/testify/require"
+)
+
+func TestOfflineTxnCycle(t *testing.T) {
+	mnemonic := "film theme cheese broken kingdom destroy inch ready wear inspire shove pudding"
+	w, err := NewMultiWalletFromMnemonic(mnemonic, 3)
+	require.NoError(t, err)
+	genesisID := types.Hash20{1, 2, 3}
+	principals, err := w.Principals()
+	require.NoError(t, err)
+	data := TxnData{
+		Principal: principals[2],
+		Recipient: principals[0],
+		Amount:    1000,
+		Nonce:     4,
+		GasPrice:  2,
+	}
+
+	// build on the online machine
+	built, err := NewOfflineTxn(data, genesisID)
+	require.NoError(t, err)
+	var file bytes.Buffer
+	require.NoError(t, built.Export(&file))
+
+	// review and sign on the offline machine
+	offline, err := ReadOfflineTxn(&file)
+	require.NoError(t, err)
+	require.Equal(t, built, offline)
+	d, err := offline.Decode()
+	require.NoError(t, err)
+	require.Equal(t, data.Principal, d.Principal)
+	require.Equal(t, data.Recipient, d.Recipient)
+	require.Equal(t, data.Amount, d.Amount)
+	require.Equal(t, data.Nonce, d.Nonce)
+	require.Equal(t, data.GasPrice, d.GasPrice)
+
+	signed, index, err := w.SignOfflineTxn(offline)
+	require.NoError(t, err)
+	require.EqualValues(t, 2, index)
+
+	// the signed transaction is what broadcast submits
+	d, err = DecodeTransaction(signed)
+	require.NoError(t, err)
+	require.Equal(t, data.Principal, d.Principal)
+	require.Equal(t, data.Amount, d.Amount)
+	require.True(t, ed25519.Verify(ed25519.PublicKey(w.Secrets.Accounts[2].Public),
+		core.SigningBody(genesisID[:], offline.Unsigned), d.Signature))
+
+	// signed transactions can't be signed again
+	_, err = (&OfflineTxn{GenesisID: genesisID, Unsigned: signed}).Decode()
+	require.Error(t, err)
+
+	// the spawn of the account can be signed too
+	var pubkey core.PublicKey
+	copy(pubkey[:], w.Secrets.Accounts[1].Public)
+	spawn, err := GenerateSpawnTxnData(pubkey, 1)
+	require.NoError(t, err)
+	signed, index, err = w.SignOfflineTxn(&OfflineTxn{GenesisID: genesisID, Unsigned: spawn})
+	require.NoError(t, err)
+	require.EqualValues(t, 1, index)
+	d, err = DecodeTransaction(signed)
+	require.NoError(t, err)
+	require.True(t, d.SelfSpawn)
+	require.True(t, w.Secrets.Accounts[1].Verify(core.SigningBody(genesisID[:], spawn), d.Signature))
+}
+
+func TestOfflineTxnInvalid(t *testing.T) {
+	mnemonic := "film theme cheese broken kingdom destroy inch ready wear inspire shove pudding"
+	w, err := NewMultiWalletFromMnemonic(mnemonic, 1)
+	require.NoError(t, err)
+	data := TxnData{Principal: types.Address{1}, Recipient: types.Address{2}, Amount: 1, GasPrice: 1}
+
+	_, err = NewOfflineTxn(data, types.Hash20{})
+	require.Error(t, err)
+
+	// no account of the wallet is the principal
+	txn, err := NewOfflineTxn(data, types.Hash20{1})
+	require.NoError(t, err)
+	_, _, err = w.SignOfflineTxn(txn)
+	require.ErrorIs(t, err, ErrAccountNotFound)
+
+	empty, err := NewMultiWalletFromMnemonic(mnemonic, 0, WithAccountsLater(true))
+	require.NoError(t, err)
+	_, _, err = empty.SignOfflineTxn(txn)
+	require.ErrorIs(t, err, ErrNoAccounts)
+
+	for name, file := range map[string]string{
+		"not json":          "0102",
+		"version":           `{"version": 2, "genesisId": "0100000000000000000000000000000000000000", "transaction": ""}`,
+		"genesis ID":        `{"version": 1, "genesisId": "01", "transaction": ""}`,
+		"transaction":       `{"version": 1, "genesisId": "0100000000000000000000000000000000000000", "transaction": "zz"}`,
+		"empty transaction": `{"version": 1, "genesisId": "0100000000000000000000000000000000000000", "transaction": ""}`,
+	} {
+		_, err := ReadOfflineTxn(strings.NewReader(file))
+		require.Error(t, err, name)
+	}
+}
+
+func TestOfflineTxnLedger(t *testing.T) {
+	m := newMockLedger(LedgerDevice{ID: "usb://ledger?id=1", Product: "Nano S"})
+	useMockLedger(t, m)
+	w, err := NewMultiWalletFromLedger(2)
+	require.NoError(t, err)
+	principals, err := w.Principals()
+	require.NoError(t, err)
+	genesisID := types.Hash20{1}
+	txn, err := NewOfflineTxn(TxnData{
+		Principal: principals[1],
+		Recipient: principals[0],
+		Amount:    1,
+		GasPrice:  1,
+	}, genesisID)
+	require.NoError(t, err)
+
+	signed, index, err := w.SignOfflineTxn(txn)
+	require.NoError(t, err)
+	require.EqualValues(t, 1, index)
+	d, err := DecodeTransaction(signed)
+	require.NoError(t, err)
+	require.True(t, w.Secrets.Accounts[1].Verify(core.SigningBody(genesisID[:], txn.Unsigned), d.Signature))
+
+	m.reject = true
+	_, _, err = w.SignOfflineTxn(txn)
+	require.ErrorIs(t, err, ErrLedgerRejected)
+}
@@ -0,0 +1,49 @@
+package wallet
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/spacemeshos/smcli/common"
+)
+
+// maxPassphraseLength bounds the BIP39 passphrase read from a file by ReadPassphrase.
+const maxPassphraseLength = 1024
+
+// ErrPassphraseSources is returned by ReadPassphrase if the passphrase is given both in a file and in the
+// environment.
+var ErrPassphraseSources = common.NewError(common.ErrBadInput,
+	"BIP39 passphrase given both in a file and in the environment, use only one")
+
+// ReadPassphrase returns the BIP39 passphrase to pass to WithPassphrase, from exactly one of three sources: the
+// file fn if it's not empty, env, the value of an environment variable, if it's set (even if it's empty, for a
+// wallet without a passphrase), or else prompt, which normally asks the user. Automated restores can so supply
+// the passphrase without putting it on the command line, where other users could see it in the process list.
+// The file may end with a newline, which is removed, but other whitespace is kept since it's significant in a
+// passphrase.
+func ReadPassphrase(fn string, env *string, prompt func() (string, error)) (string, error) {
+	switch {
+	case fn != "" && env != nil:
+		return "", ErrPassphraseSources
+	case env != nil:
+		return *env, nil
+	case fn == "":
+		return prompt()
+	}
+	f, err := os.Open(fn)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+	data, err := io.ReadAll(io.LimitReader(f, maxPassphraseLength+1))
+	if err != nil {
+		return "", fmt.Errorf("error reading passphrase file: %w", err)
+	}
+	if len(data) > maxPassphraseLength {
+		return "", common.WithCategory(common.ErrBadInput, fmt.Errorf("passphrase file too long"))
+	}
+	p := strings.TrimSuffix(string(data), "\n")
+	return strings.TrimSuffix(p, "\r"), nil
+}
@@ -0,0 +1,55 @@
+package wallet
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"github.com/tyler-smith/go-bip39"
+)
+
+func TestReadPassphrase(t *testing.T) {
+	// whitespace is significant in a passphrase, so only the line ending of the file is removed
+	const passphrase = " correct horse battery staple "
+	fn := filepath.Join(t.TempDir(), "passphrase")
+	require.NoError(t, os.WriteFile(fn, []byte(passphrase+"\r\n"), 0o600))
+	env := passphrase
+	noPrompt := func() (string, error) { return "", fmt.Errorf("unexpected prompt") }
+	prompt := func() (string, error) { return passphrase, nil }
+
+	sources := map[string]func() (string, error){
+		"file":        func() (string, error) { return ReadPassphrase(fn, nil, noPrompt) },
+		"env":         func() (string, error) { return ReadPassphrase("", &env, noPrompt) },
+		"interactive": func() (string, error) { return ReadPassphrase("", nil, prompt) },
+	}
+	want := bip39.NewSeed(testMnemonic, passphrase)
+	for name, source := range sources {
+		t.Run(name, func(t *testing.T) {
+			p, err := source()
+			require.NoError(t, err)
+			require.Equal(t, passphrase, p)
+			w, err := NewMultiWalletFromMnemonic(testMnemonic, 1, WithPassphrase(p))
+			require.NoError(t, err)
+			seed, err := w.Seed()
+			require.NoError(t, err)
+			require.Equal(t, want, seed)
+		})
+	}
+
+	// an empty variable is a source too, for wallets without a passphrase
+	empty := ""
+	p, err := ReadPassphrase("", &empty, noPrompt)
+	require.NoError(t, err)
+	require.Empty(t, p)
+
+	_, err = ReadPassphrase(fn, &env, noPrompt)
+	require.ErrorIs(t, err, ErrPassphraseSources)
+	_, err = ReadPassphrase(filepath.Join(t.TempDir(), "missing"), nil, noPrompt)
+	require.ErrorIs(t, err, os.ErrNotExist)
+	require.NoError(t, os.WriteFile(fn, []byte(strings.Repeat("a", maxPassphraseLength+1)), 0o600))
+	_, err = ReadPassphrase(fn, nil, noPrompt)
+	require.Error(t, err)
+}
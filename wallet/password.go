@@ -0,0 +1,108 @@
+package wallet
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/spacemeshos/smcli/common"
+)
+
+// ErrWeakPassword is returned when a password doesn't satisfy the password policy.
+var ErrWeakPassword = common.NewError(common.ErrBadInput, "password is too weak")
+
+// ReadPassword returns the password of a wallet file from env, the value of an environment variable, if it's set,
+// or else from prompt, which normally asks the user and is the default. The environment is meant for automated
+// pipelines only: other processes of the same user can read it, so it's less safe than a prompt. The password is
+// returned as is, even if it's empty, and is never logged.
+func ReadPassword(env *string, prompt func() (string, error)) (string, error) {
+	if env != nil {
+		return *env, nil
+	}
+	return prompt()
+}
+
+// PasswordPolicy describes the minimum requirements for a password used to encrypt a wallet file.
+type PasswordPolicy struct {
+	// MinLength is the minimum number of characters.
+	MinLength int
+	// MinClasses is the minimum number of character classes (lowercase, uppercase, digits, and symbols).
+	MinClasses int
+	// PassphraseLength is the length from which a password counts as a passphrase and MinClasses doesn't apply.
+	PassphraseLength int
+	// AllowWeak disables the policy, e.g., for automated tests.
+	AllowWeak bool
+}
+
+// DefaultPasswordPolicy is applied to new wallet passwords unless the user explicitly allows a weak one.
+var DefaultPasswordPolicy = PasswordPolicy{
+	MinLength:        12,
+	MinClasses:       3,
+	PassphraseLength: 20,
+}
+
+// commonPasswords is a small denylist of passwords that are among the first to be tried by any attacker.
+var commonPasswords = map[string]struct{}{
+	"123456789012":  {},
+	"1q2w3e4r5t6y":  {},
+	"abc123abc123":  {},
+	"administrator": {},
+	"letmein12345":  {},
+	"password":      {},
+	"password1":     {},
+	"password123":   {},
+	"password1234":  {},
+	"password123!":  {},
+	"p@ssw0rd1234":  {},
+	"qwerty123456":  {},
+	"qwertyuiop12":  {},
+	"spacemesh":     {},
+	"spacemesh123":  {},
+	"spacemesh2023": {},
+	"welcome12345":  {},
+}
+
+// Check returns an error wrapping ErrWeakPassword that describes everything the password is missing.
+func (p PasswordPolicy) Check(password []byte) error {
+	if p.AllowWeak {
+		return nil
+	}
+	var problems []string
+	if _, ok := commonPasswords[strings.ToLower(string(password))]; ok {
+		problems = append(problems, "it is a commonly used password")
+	}
+	length := utf8.RuneCount(password)
+	if length < p.MinLength {
+		problems = append(problems, fmt.Sprintf("use at least %d characters (got %d)", p.MinLength, length))
+	}
+	if length < p.PassphraseLength {
+		if classes := characterClasses(string(password)); classes < p.MinClasses {
+			problems = append(problems, fmt.Sprintf(
+				"mix at least %d of lowercase letters, uppercase letters, digits, and symbols (got %d), "+
+					"or use a passphrase of at least %d characters",
+				p.MinClasses, classes, p.PassphraseLength))
+		}
+	}
+	if len(problems) > 0 {
+		return fmt.Errorf("%w: %s", ErrWeakPassword, strings.Join(problems, "; "))
+	}
+	return nil
+}
+
+func characterClasses(password string) int {
+	var lower, upper, digit, symbol int
+	for _, r := range password {
+		switch {
+		case unicode.IsLower(r):
+			lower = 1
+		case unicode.IsUpper(r):
+			upper = 1
+		case unicode.IsDigit(r):
+			digit = 1
+		default:
+			symbol = 1
+		}
+	}
+	return lower + upper + digit + symbol
+}
@@ -0,0 +1,91 @@
+package wallet
+
+import (
+	"bytes"
+	"io"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestPasswordPolicy(t *testing.T) {
+	accepted := []string{
+		"Tr0ub4dor&3xyz",
+		"correct-horse-battery",
+		"correct horse battery staple",
+		"sp4cemesh-W4llet",
+		"Ünïcödé-Pässwörd1",
+	}
+	for _, pw := range accepted {
+		require.NoError(t, DefaultPasswordPolicy.Check([]byte(pw)), pw)
+	}
+
+	rejected := map[string]string{
+		"":                    "at least 12 characters",
+		"Sh0rt!":              "at least 12 characters",
+		"alllowercaseletters": "mix at least 3",
+		"ALLUPPER1234":        "mix at least 3",
+		"Password123!":        "commonly used",
+		"spacemesh":           "commonly used",
+		"qwerty123456":        "commonly used",
+	}
+	for pw, reason := range rejected {
+		err := DefaultPasswordPolicy.Check([]byte(pw))
+		require.ErrorIs(t, err, ErrWeakPassword, pw)
+		require.ErrorContains(t, err, reason, pw)
+	}
+
+	// every problem is reported at once
+	err := DefaultPasswordPolicy.Check([]byte("password"))
+	require.ErrorContains(t, err, "commonly used")
+	require.ErrorContains(t, err, "at least 12 characters")
+	require.ErrorContains(t, err, "mix at least 3")
+
+	// the policy can be explicitly overridden
+	weak := DefaultPasswordPolicy
+	weak.AllowWeak = true
+	for pw := range rejected {
+		require.NoError(t, weak.Check([]byte(pw)))
+	}
+	// without changing the default
+	require.Error(t, DefaultPasswordPolicy.Check([]byte("password")))
+}
+
+func TestReadPassword(t *testing.T) {
+	const password = "correct horse battery staple"
+	w, err := NewMultiWalletFromMnemonic(testMnemonic, 1)
+	require.NoError(t, err)
+	buf := &bytes.Buffer{}
+	wKey := NewKey(WithRandomSalt(), WithIterations(1000), WithPbkdf2Password([]byte(password)))
+	require.NoError(t, wKey.Export(buf, w))
+	data := buf.Bytes()
+	open := func(p string) error {
+		wKey := NewKey(WithPasswordOnly([]byte(p)))
+		_, err := wKey.Open(bytes.NewReader(data))
+		return err
+	}
+
+	// the environment wins over the prompt, and its password decrypts the wallet
+	env := password
+	prompted := false
+	prompt := func() (string, error) {
+		prompted = true
+		return password, nil
+	}
+	p, err := ReadPassword(&env, prompt)
+	require.NoError(t, err)
+	require.False(t, prompted)
+	require.NoError(t, open(p))
+	wrong := "wrong password"
+	p, err = ReadPassword(&wrong, prompt)
+	require.NoError(t, err)
+	require.ErrorIs(t, open(p), ErrWrongPassword)
+
+	// without it, the user is prompted
+	p, err = ReadPassword(nil, prompt)
+	require.NoError(t, err)
+	require.True(t, prompted)
+	require.NoError(t, open(p))
+	_, err = ReadPassword(nil, func() (string, error) { return "", io.ErrUnexpectedEOF })
+	require.ErrorIs(t, err, io.ErrUnexpectedEOF)
+}
@@ -0,0 +1,176 @@
+package wallet
+
+import (
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/spacemeshos/smcli/common"
+)
+
+// An AccountPicker lets the user choose an account of a wallet, e.g., the one that signs, rather than having to
+// know its index. On a terminal it shows a menu that's navigated using the arrow keys (or j and k), see Pick;
+// otherwise it lists the accounts and reads the index or label of one of them, see Prompt. Neither needs anything
+// beyond the terminal's standard escape sequences.
+
+// ErrNoAccountPicked is returned by AccountPicker if the user cancels or doesn't choose one of the accounts.
+var ErrNoAccountPicked = common.NewError(common.ErrBadInput, "no account picked")
+
+// Keys read by AccountPicker.Pick. A terminal in raw mode sends Enter as a carriage return.
+const (
+	keyCtrlC  = 0x03
+	keyEscape = 0x1b
+)
+
+// AccountPicker picks one of the accounts of a wallet, see NewAccountPicker.
+type AccountPicker struct {
+	accounts []AccountPublicKey
+	cursor   int
+}
+
+// NewAccountPicker returns a picker of one of accounts, as returned by PublicKeys.
+func NewAccountPicker(accounts []AccountPublicKey) *AccountPicker {
+	return &AccountPicker{accounts: accounts}
+}
+
+// Pick shows a menu of the accounts on out and lets the user move between them using the arrow keys, or j and k,
+// until one is chosen using Enter. q, Escape, and Ctrl-C cancel. The terminal must be in raw mode so that every
+// key is read from in as soon as it's pressed. The menu is drawn again in place after every move.
+func (p *AccountPicker) Pick(in io.Reader, out io.Writer) (AccountPublicKey, error) {
+	if len(p.accounts) == 0 {
+		return AccountPublicKey{}, ErrNoAccounts
+	}
+	fmt.Fprint(out, "Choose an account (arrow keys to move, Enter to select, q to cancel):\r\n")
+	p.render(out)
+	for {
+		key, err := readByte(in)
+		if err != nil {
+			return AccountPublicKey{}, err
+		}
+		switch key {
+		case '\r', '\n':
+			return p.accounts[p.cursor], nil
+		case 'q', keyCtrlC:
+			return AccountPublicKey{}, ErrNoAccountPicked
+		case 'k':
+			p.move(-1)
+		case 'j':
+			p.move(1)
+		case keyEscape:
+			// arrow keys are sent as ESC [ A (up) and ESC [ B (down), or ESC O A and ESC O B
+			next, err := readByte(in)
+			if err != nil {
+				return AccountPublicKey{}, err
+			}
+			if next != '[' && next != 'O' {
+				return AccountPublicKey{}, ErrNoAccountPicked
+			}
+			arrow, err := readByte(in)
+			if err != nil {
+				return AccountPublicKey{}, err
+			}
+			switch arrow {
+			case 'A':
+				p.move(-1)
+			case 'B':
+				p.move(1)
+			default:
+				continue
+			}
+		default:
+			continue
+		}
+		// move back up to the first account and draw the menu again
+		fmt.Fprintf(out, "\033[%dA\r\033[J", len(p.accounts))
+		p.render(out)
+	}
+}
+
+// Prompt lists the accounts on out and reads a line from in containing the index or the label of one of them.
+// It's used instead of Pick if in isn't a terminal, e.g., when input is scripted. in is read one byte at a time so
+// that later prompts read the following lines.
+func (p *AccountPicker) Prompt(in io.Reader, out io.Writer) (AccountPublicKey, error) {
+	if len(p.accounts) == 0 {
+		return AccountPublicKey{}, ErrNoAccounts
+	}
+	for i := range p.accounts {
+		fmt.Fprintf(out, "%s\n", p.row(i))
+	}
+	fmt.Fprint(out, "Enter the index or label of the account: ")
+	var line []byte
+	for {
+		b, err := readByte(in)
+		if err == io.EOF && len(line) > 0 {
+			break
+		}
+		if err != nil {
+			return AccountPublicKey{}, err
+		}
+		if b == '\n' {
+			break
+		}
+		line = append(line, b)
+	}
+	fmt.Fprintln(out)
+	choice := strings.TrimSpace(string(line))
+	if index, err := strconv.ParseUint(choice, 10, 32); err == nil {
+		for _, acct := range p.accounts {
+			if acct.Index == uint32(index) {
+				return acct, nil
+			}
+		}
+	}
+	for _, acct := range p.accounts {
+		if acct.Label != "" && acct.Label == choice {
+			return acct, nil
+		}
+	}
+	return AccountPublicKey{}, fmt.Errorf("%w: no account with index or label %q", ErrNoAccountPicked, choice)
+}
+
+// move moves the cursor by delta accounts, stopping at the first and the last one.
+func (p *AccountPicker) move(delta int) {
+	p.cursor += delta
+	if p.cursor < 0 {
+		p.cursor = 0
+	}
+	if p.cursor >= len(p.accounts) {
+		p.cursor = len(p.accounts) - 1
+	}
+}
+
+// render draws one line per account, marking the one under the cursor.
+func (p *AccountPicker) render(out io.Writer) {
+	for i := range p.accounts {
+		marker := "  "
+		if i == p.cursor {
+			marker = "> "
+		}
+		fmt.Fprintf(out, "%s%s\r\n", marker, p.row(i))
+	}
+}
+
+// row describes the account at position i.
+func (p *AccountPicker) row(i int) string {
+	acct := p.accounts[i]
+	row := fmt.Sprintf("%3d  %s", acct.Index, acct.Address)
+	if acct.Label != "" {
+		row += "  " + acct.Label
+	}
+	return row
+}
+
+// readByte reads a single byte from r.
+func readByte(r io.Reader) (byte, error) {
+	var buf [1]byte
+	for {
+		n, err := r.Read(buf[:])
+		if n == 1 {
+			return buf[0], nil
+		}
+		if err != nil {
+			return 0, err
+		}
+	}
+}
@@ -0,0 +1,97 @@
+package wallet
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/spacemeshos/smcli/common"
+)
+
+func pickerAccounts() []AccountPublicKey {
+	return []AccountPublicKey{
+		{Index: 0, Address: "sm1first"},
+		{Index: 1, Address: "sm1second", Label: "savings"},
+		{Index: 5, Address: "sm1third", Label: "7"},
+	}
+}
+
+func TestAccountPickerPick(t *testing.T) {
+	for _, tc := range []struct {
+		name  string
+		keys  string
+		index uint32
+	}{
+		{"enter", "\r", 0},
+		{"down", "\x1b[B\r", 1},
+		{"down twice", "\x1b[B\x1b[B\n", 5},
+		{"stops at the last account", "\x1b[B\x1b[B\x1b[B\x1b[B\r", 5},
+		{"stops at the first account", "\x1b[A\x1b[A\r", 0},
+		{"down and up", "\x1b[B\x1b[B\x1b[A\r", 1},
+		{"application mode arrows", "\x1bOB\r", 1},
+		{"j and k", "jjk\r", 1},
+		{"other keys are ignored", "x\x1b[C5j\r", 1},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			var out bytes.Buffer
+			acct, err := NewAccountPicker(pickerAccounts()).Pick(strings.NewReader(tc.keys), &out)
+			require.NoError(t, err)
+			require.Equal(t, tc.index, acct.Index)
+		})
+	}
+
+	for _, keys := range []string{"q", "j\x03", "\x1bx"} {
+		_, err := NewAccountPicker(pickerAccounts()).Pick(strings.NewReader(keys), &bytes.Buffer{})
+		require.ErrorIs(t, err, ErrNoAccountPicked, keys)
+		require.ErrorIs(t, err, common.ErrBadInput, keys)
+	}
+	// input ends before a choice is made
+	_, err := NewAccountPicker(pickerAccounts()).Pick(strings.NewReader("j"), &bytes.Buffer{})
+	require.Error(t, err)
+	_, err = NewAccountPicker(nil).Pick(strings.NewReader("\r"), &bytes.Buffer{})
+	require.ErrorIs(t, err, ErrNoAccounts)
+
+	// the menu is drawn again in place, with the cursor on the current account
+	var out bytes.Buffer
+	_, err = NewAccountPicker(pickerAccounts()).Pick(strings.NewReader("j\r"), &out)
+	require.NoError(t, err)
+	require.Contains(t, out.String(), ">   0  sm1first\r\n    1  sm1second  savings\r\n")
+	require.True(t, strings.HasSuffix(out.String(),
+		"\033[3A\r\033[J    0  sm1first\r\n>   1  sm1second  savings\r\n    5  sm1third  7\r\n"))
+}
+
+func TestAccountPickerPrompt(t *testing.T) {
+	for _, tc := range []struct {
+		input string
+		index uint32
+	}{
+		{"0\n", 0},
+		{"5\n", 5},
+		{" 1 \r\n", 1},
+		{"savings\n", 1},
+		// a label that's a number is only matched if no account has that index
+		{"7\n", 5},
+		{"5", 5},
+	} {
+		var out bytes.Buffer
+		acct, err := NewAccountPicker(pickerAccounts()).Prompt(strings.NewReader(tc.input), &out)
+		require.NoError(t, err, tc.input)
+		require.Equal(t, tc.index, acct.Index, tc.input)
+		require.Contains(t, out.String(), "  1  sm1second  savings\n")
+	}
+
+	for _, input := range []string{"2\n", "Savings\n", "\n", "-1\n"} {
+		_, err := NewAccountPicker(pickerAccounts()).Prompt(strings.NewReader(input), &bytes.Buffer{})
+		require.ErrorIs(t, err, ErrNoAccountPicked, input)
+	}
+	_, err := NewAccountPicker(pickerAccounts()).Prompt(strings.NewReader(""), &bytes.Buffer{})
+	require.Error(t, err)
+
+	// only the first line is read, leaving the rest for the next prompt
+	in := strings.NewReader("1\ny\n")
+	_, err = NewAccountPicker(pickerAccounts()).Prompt(in, &bytes.Buffer{})
+	require.NoError(t, err)
+	require.Equal(t, 2, in.Len())
+}
@@ -0,0 +1,90 @@
+package wallet
+
+import (
+	"bytes"
+	"crypto/ed25519"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"strings"
+
+	"github.com/spacemeshos/smcli/common"
+)
+
+var (
+	// ErrInvalidPrivateKey is returned by ParsePrivateKey for keys that can't be decoded or aren't valid ed25519
+	// private keys.
+	ErrInvalidPrivateKey = common.NewError(common.ErrBadInput, "invalid private key")
+	// ErrNotDerivable is returned when deriving accounts or the seed of a wallet that was imported from a single
+	// private key, see NewWalletFromPrivateKey.
+	ErrNotDerivable = common.NewError(common.ErrBadInput,
+		"wallet was imported from a private key and has no mnemonic to derive accounts from")
+)
+
+// ParsePrivateKey decodes a raw ed25519 private key written as hex, with or without a 0x prefix, or as base64.
+// The key is either the 32-byte seed of the key or the 64-byte private key made of the seed followed by the
+// public key, which must match the seed.
+func ParsePrivateKey(s string) (PrivateKey, error) {
+	s = strings.TrimSpace(s)
+	key, err := hex.DecodeString(strings.TrimPrefix(s, "0x"))
+	if err != nil {
+		if key, err = base64.StdEncoding.DecodeString(s); err != nil {
+			return nil, fmt.Errorf("%w: neither hex nor base64", ErrInvalidPrivateKey)
+		}
+	}
+	defer wipe(key)
+	switch len(key) {
+	case ed25519.SeedSize:
+		return PrivateKey(ed25519.NewKeyFromSeed(key)), nil
+	case ed25519.PrivateKeySize:
+		priv := ed25519.NewKeyFromSeed(key[:ed25519.SeedSize])
+		if !bytes.Equal(priv[ed25519.SeedSize:], key[ed25519.SeedSize:]) {
+			wipe(priv)
+			return nil, fmt.Errorf("%w: public key doesn't match the seed", ErrInvalidPrivateKey)
+		}
+		return PrivateKey(priv), nil
+	default:
+		return nil, fmt.Errorf("%w: expected %d or %d bytes, got %d", ErrInvalidPrivateKey, ed25519.SeedSize,
+			ed25519.PrivateKeySize, len(key))
+	}
+}
+
+// NewWalletFromPrivateKey creates a wallet with a single account that uses the private key key, see
+// ParsePrivateKey, e.g., one generated by another tool. The wallet has no mnemonic or master keypair, so no more
+// accounts can be derived and it has no seed: AddAccounts, Seed, Verify, and RepairAccounts return ErrNotDerivable.
+// Only WithDisplayName and WithGenesisID apply. The wallet can be saved like any other, and the key must be
+// backed up separately since nothing else can restore the account.
+func NewWalletFromPrivateKey(key string, opts ...WalletOpt) (*Wallet, error) {
+	o := newWalletOpts(opts)
+	priv, err := ParsePrivateKey(key)
+	if err != nil {
+		return nil, err
+	}
+	acct := &EDKeyPair{
+		DisplayName: "Imported Key",
+		Created:     common.NowTimeString(),
+		Public:      PublicKey(ed25519.PrivateKey(priv).Public().(ed25519.PublicKey)),
+		Private:     priv,
+		KeyType:     typeImported,
+	}
+	w := &Wallet{
+		Meta: walletMetadata{
+			Version:     WalletVersion,
+			DisplayName: DefaultDisplayName,
+			Created:     common.NowTimeString(),
+			GenesisID:   o.genesisIDString(),
+		},
+		Secrets: walletSecrets{Accounts: []*EDKeyPair{acct}},
+	}
+	if err := w.SetDisplayName(o.displayName); err != nil {
+		return nil, err
+	}
+	return w, nil
+}
+
+// IsImported reports whether the wallet was imported from a single private key, see NewWalletFromPrivateKey, so
+// that no accounts can be derived from it.
+func (w *Wallet) IsImported() bool {
+	return w.Secrets.MasterKeypair == nil && len(w.Secrets.Accounts) == 1 && w.Secrets.Accounts[0] != nil &&
+		w.Secrets.Accounts[0].KeyType == typeImported
+}
@@ -0,0 +1,102 @@
+package wallet
+
+import (
+	"bytes"
+	"crypto/ed25519"
+	"encoding/base64"
+	"encoding/hex"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestParsePrivateKey(t *testing.T) {
+	// RFC 8032, section 7.1, test 1
+	seed, err := hex.DecodeString("9d61b19deffd5a60ba844af492ec2cc44449c5697b326919703bac031cae7f60")
+	require.NoError(t, err)
+	public, err := hex.DecodeString("d75a980182b10ab7d54bfed3c964073a0ee172f3daa62325af021a68f707511a")
+	require.NoError(t, err)
+	full := append(append([]byte{}, seed...), public...)
+
+	for _, s := range []string{
+		hex.EncodeToString(seed),
+		"0x" + hex.EncodeToString(seed),
+		hex.EncodeToString(full),
+		base64.StdEncoding.EncodeToString(seed),
+		" " + base64.StdEncoding.EncodeToString(full) + "\n",
+	} {
+		key, err := ParsePrivateKey(s)
+		require.NoError(t, err, s)
+		require.Equal(t, full, []byte(key), s)
+	}
+
+	bad := append([]byte{}, full...)
+	bad[len(bad)-1] ^= 1
+	for _, s := range []string{
+		"",
+		"not a key",
+		hex.EncodeToString(seed[:31]),
+		hex.EncodeToString(bad),
+	} {
+		_, err := ParsePrivateKey(s)
+		require.ErrorIs(t, err, ErrInvalidPrivateKey, s)
+	}
+}
+
+func TestNewWalletFromPrivateKey(t *testing.T) {
+	// the key of an account of another wallet imports to the same address
+	other, err := NewMultiWalletFromMnemonic(testMnemonic, 2)
+	require.NoError(t, err)
+	acct := other.Secrets.Accounts[1]
+	w, err := NewWalletFromPrivateKey(hex.EncodeToString(acct.Private), WithDisplayName("imported"))
+	require.NoError(t, err)
+	require.True(t, w.IsImported())
+	require.False(t, other.IsImported())
+	require.Empty(t, w.Mnemonic())
+	require.Nil(t, w.Secrets.MasterKeypair)
+	require.Equal(t, "imported", w.Meta.DisplayName)
+	require.Equal(t, []string{other.Addresses("sm")[1]}, w.Addresses("sm"))
+	require.Equal(t, acct.Public, w.Secrets.Accounts[0].Public)
+
+	// a 32-byte seed works too
+	seeded, err := NewWalletFromPrivateKey(base64.StdEncoding.EncodeToString(acct.Private[:ed25519.SeedSize]))
+	require.NoError(t, err)
+	require.Equal(t, w.Addresses("sm"), seeded.Addresses("sm"))
+
+	// no accounts can be derived
+	require.ErrorIs(t, w.AddAccounts(1), ErrNotDerivable)
+	_, err = w.Seed()
+	require.ErrorIs(t, err, ErrNotDerivable)
+	_, err = w.Verify()
+	require.ErrorIs(t, err, ErrNotDerivable)
+	require.Len(t, w.Secrets.Accounts, 1)
+
+	_, err = NewWalletFromPrivateKey("00")
+	require.ErrorIs(t, err, ErrInvalidPrivateKey)
+}
+
+func TestPrivateKeyWalletRoundTrip(t *testing.T) {
+	_, priv, err := ed25519.GenerateKey(nil)
+	require.NoError(t, err)
+	w, err := NewWalletFromPrivateKey(hex.EncodeToString(priv))
+	require.NoError(t, err)
+
+	password := []byte("password")
+	wk := NewKey(WithRandomSalt(), WithIterations(1000), WithPbkdf2Password(password))
+	var file bytes.Buffer
+	require.NoError(t, wk.Export(&file, w))
+	require.NotContains(t, file.String(), hex.EncodeToString(priv))
+
+	rk := NewKey(WithPasswordOnly(password))
+	opened, err := rk.Open(bytes.NewReader(file.Bytes()))
+	require.NoError(t, err)
+	require.True(t, opened.IsImported())
+	require.Equal(t, []byte(priv), []byte(opened.Secrets.Accounts[0].Private))
+	require.Equal(t, w.Addresses("sm"), opened.Addresses("sm"))
+	require.ErrorIs(t, opened.AddAccounts(1), ErrNotDerivable)
+
+	// it still signs
+	sig, err := opened.SignMessage(0, []byte("hello"))
+	require.NoError(t, err)
+	require.NotEmpty(t, sig)
+}
@@ -0,0 +1,40 @@
+package wallet
+
+import (
+	"fmt"
+
+	"github.com/spacemeshos/smcli/common"
+)
+
+// RepairAccounts replaces the accounts of the wallet with the first n accounts derived from its mnemonic, or read
+// from the Ledger device backing it, e.g., when Verify reports that the stored accounts are corrupted but the
+// mnemonic is intact. n is normally the number of accounts the wallet had: accounts following them can then be
+// restored using ScanAccounts. The label of a stored account is kept if its account index can still be told, see
+// PublicKeys, and is below n. Deriving the accounts needs the same passphrase or Ledger device as AddAccounts. The
+// wallet is left unchanged if they can't be derived.
+func (w *Wallet) RepairAccounts(n int, opts ...WalletOpt) error {
+	if n < 0 || n > common.MaxAccountsPerWallet {
+		return fmt.Errorf("%w %d, must be between 0 and %d", ErrInvalidAccountCount, n, common.MaxAccountsPerWallet)
+	}
+	seed, err := w.derivationSeed(opts)
+	if err != nil {
+		return err
+	}
+	defer wipe(seed)
+	accounts, err := accountsFromMaster(w.Secrets.MasterKeypair, seed, n)
+	if err != nil {
+		return err
+	}
+
+	for i, acct := range w.Secrets.Accounts {
+		if acct == nil {
+			continue
+		}
+		if index := int(accountIndex(acct, i)); index < n && acct.Label != "" && accounts[index].Label == "" {
+			accounts[index].Label = acct.Label
+		}
+		wipe(acct.Private)
+	}
+	w.Secrets.Accounts = accounts
+	return nil
+}
@@ -0,0 +1,60 @@
+package wallet
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestRepairAccounts(t *testing.T) {
+	want, err := NewMultiWalletFromMnemonic(testMnemonic, 3)
+	require.NoError(t, err)
+
+	w, err := NewMultiWalletFromMnemonic(testMnemonic, 3)
+	require.NoError(t, err)
+	require.NoError(t, w.SetAccountLabel(1, "savings"))
+	require.NoError(t, w.SetAccountLabel(2, "hot"))
+	// the first account is zeroed, the second one's key is corrupted but its path still tells its index, and the
+	// third one is lost along with its label
+	w.Secrets.Accounts[0] = &EDKeyPair{}
+	w.Secrets.Accounts[1].Public = make(PublicKey, len(w.Secrets.Accounts[1].Public))
+	w.Secrets.Accounts[2] = nil
+	mismatches, err := w.Verify()
+	require.NoError(t, err)
+	require.Len(t, mismatches, 3)
+
+	require.NoError(t, w.RepairAccounts(len(w.Secrets.Accounts)))
+	require.Equal(t, want.Addresses("sm"), w.Addresses("sm"))
+	for i, acct := range w.Secrets.Accounts {
+		require.Equal(t, want.Secrets.Accounts[i].Private, acct.Private)
+		require.Equal(t, want.Secrets.Accounts[i].Path, acct.Path)
+	}
+	require.Empty(t, w.Secrets.Accounts[0].Label)
+	require.Equal(t, "savings", w.Secrets.Accounts[1].Label)
+	require.Empty(t, w.Secrets.Accounts[2].Label)
+	mismatches, err = w.Verify()
+	require.NoError(t, err)
+	require.Empty(t, mismatches)
+
+	// a wallet whose accounts are all zeroed is repaired too, and can be given more accounts than it had
+	for i := range w.Secrets.Accounts {
+		w.Secrets.Accounts[i] = &EDKeyPair{}
+	}
+	require.NoError(t, w.RepairAccounts(4))
+	require.Len(t, w.Secrets.Accounts, 4)
+	require.Equal(t, want.Addresses("sm"), w.Addresses("sm")[:3])
+
+	// the accounts can't be repaired without the passphrase, and are left as they were
+	w, err = NewMultiWalletFromMnemonic(testMnemonic, 2, WithPassphrase("secret"))
+	require.NoError(t, err)
+	w.Secrets.passphrase = ""
+	w.Secrets.Accounts[0] = &EDKeyPair{}
+	require.ErrorIs(t, w.RepairAccounts(2), errPassphraseMismatch)
+	require.Equal(t, &EDKeyPair{}, w.Secrets.Accounts[0])
+	require.NoError(t, w.RepairAccounts(2, WithPassphrase("secret")))
+	mismatches, err = w.Verify()
+	require.NoError(t, err)
+	require.Empty(t, mismatches)
+
+	require.ErrorIs(t, w.RepairAccounts(-1), ErrInvalidAccountCount)
+}
@@ -0,0 +1,57 @@
+package wallet
+
+import (
+	"fmt"
+
+	"github.com/spacemeshos/go-spacemesh/genvm/core"
+
+	"github.com/spacemeshos/smcli/common"
+)
+
+// AccountChecker reports whether the account with a given address has ever been used, e.g., by querying a node.
+type AccountChecker interface {
+	AccountUsed(addr core.Address) (bool, error)
+}
+
+// ScanAccounts looks for used accounts following the ones stored in the wallet, e.g., after restoring a wallet
+// from a mnemonic without knowing how many accounts it had. Accounts are derived in order and passed to checker
+// until the gap limit (set using WithGapLimit, DefaultGapLimit by default) of consecutive unused accounts is
+// reached, or common.MaxAccountsPerWallet accounts have been checked. The accounts up to the last used one are
+// then appended to the wallet, and their number returned. Deriving accounts needs the same passphrase or Ledger
+// device as AddAccounts.
+func (w *Wallet) ScanAccounts(checker AccountChecker, opts ...WalletOpt) (int, error) {
+	o := &walletOpts{gapLimit: DefaultGapLimit}
+	for _, opt := range opts {
+		opt(o)
+	}
+	if o.gapLimit <= 0 {
+		return 0, fmt.Errorf("invalid gap limit %d", o.gapLimit)
+	}
+	seed, err := w.derivationSeed(opts)
+	if err != nil {
+		return 0, err
+	}
+	defer wipe(seed)
+
+	var derived []*EDKeyPair
+	used := 0
+	for i := len(w.Secrets.Accounts); i < common.MaxAccountsPerWallet && len(derived)-used < o.gapLimit; i++ {
+		acct, err := accountFromMaster(w.Secrets.MasterKeypair, seed, i)
+		if err != nil {
+			return 0, err
+		}
+		derived = append(derived, acct)
+		ok, err := checker.AccountUsed(PubkeyToPrincipal(acct.Public))
+		if err != nil {
+			return 0, fmt.Errorf("account %d: %w", i, err)
+		}
+		if ok {
+			used = len(derived)
+		}
+	}
+	for _, acct := range derived[used:] {
+		wipe(acct.Private)
+	}
+	w.Secrets.Accounts = append(w.Secrets.Accounts, derived[:used]...)
+	return used, nil
+}
@@ -0,0 +1,109 @@
+package wallet
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/spacemeshos/go-spacemesh/genvm/core"
+	"github.com/stretchr/testify/require"
+
+	"github.com/spacemeshos/smcli/common"
+)
+
+// mockChecker reports activity on the accounts at the given indices of a reference wallet.
+type mockChecker struct {
+	used    map[core.Address]bool
+	checked int
+	err     error
+}
+
+func newMockChecker(t *testing.T, ref *Wallet, indices ...int) *mockChecker {
+	t.Helper()
+	m := &mockChecker{used: make(map[core.Address]bool)}
+	for _, i := range indices {
+		m.used[PubkeyToPrincipal(ref.Secrets.Accounts[i].Public)] = true
+	}
+	return m
+}
+
+func (m *mockChecker) AccountUsed(addr core.Address) (bool, error) {
+	m.checked++
+	return m.used[addr], m.err
+}
+
+func TestScanAccounts(t *testing.T) {
+	mnemonic := "film theme cheese broken kingdom destroy inch ready wear inspire shove pudding"
+	ref, err := NewMultiWalletFromMnemonic(mnemonic, common.MaxAccountsPerWallet)
+	require.NoError(t, err)
+
+	newWallet := func() *Wallet {
+		w, err := NewMultiWalletFromMnemonic(mnemonic, 0, WithAccountsLater(true))
+		require.NoError(t, err)
+		return w
+	}
+
+	t.Run("used range", func(t *testing.T) {
+		w := newWallet()
+		checker := newMockChecker(t, ref, 0, 3, 22)
+		n, err := w.ScanAccounts(checker)
+		require.NoError(t, err)
+		// 22 is within the default gap of 20 after 3, and 20 more are checked after it
+		require.Equal(t, 23, n)
+		require.Equal(t, 23+DefaultGapLimit, checker.checked)
+		require.Len(t, w.Secrets.Accounts, 23)
+		for i, acct := range w.Secrets.Accounts {
+			require.Equal(t, ref.Secrets.Accounts[i].Private, acct.Private)
+			require.Equal(t, ref.Secrets.Accounts[i].Path, acct.Path)
+		}
+	})
+
+	t.Run("gap limit", func(t *testing.T) {
+		w := newWallet()
+		n, err := w.ScanAccounts(newMockChecker(t, ref, 1, 5), WithGapLimit(3))
+		require.NoError(t, err)
+		require.Equal(t, 2, n)
+		require.Len(t, w.Secrets.Accounts, 2)
+
+		_, err = w.ScanAccounts(newMockChecker(t, ref), WithGapLimit(0))
+		require.Error(t, err)
+	})
+
+	t.Run("nothing used", func(t *testing.T) {
+		w := newWallet()
+		checker := newMockChecker(t, ref)
+		n, err := w.ScanAccounts(checker)
+		require.NoError(t, err)
+		require.Zero(t, n)
+		require.Empty(t, w.Secrets.Accounts)
+		require.Equal(t, DefaultGapLimit, checker.checked)
+	})
+
+	t.Run("continues from stored accounts", func(t *testing.T) {
+		w, err := NewMultiWalletFromMnemonic(mnemonic, 2)
+		require.NoError(t, err)
+		checker := newMockChecker(t, ref, 0, 4)
+		n, err := w.ScanAccounts(checker)
+		require.NoError(t, err)
+		require.Equal(t, 3, n)
+		require.Len(t, w.Secrets.Accounts, 5)
+		require.Equal(t, ref.Secrets.Accounts[4].Public, w.Secrets.Accounts[4].Public)
+	})
+
+	t.Run("hard cap", func(t *testing.T) {
+		w := newWallet()
+		checker := newMockChecker(t, ref, common.MaxAccountsPerWallet-1)
+		n, err := w.ScanAccounts(checker, WithGapLimit(common.MaxAccountsPerWallet))
+		require.NoError(t, err)
+		require.Equal(t, common.MaxAccountsPerWallet, n)
+		require.Equal(t, common.MaxAccountsPerWallet, checker.checked)
+	})
+
+	t.Run("checker error", func(t *testing.T) {
+		w := newWallet()
+		checker := newMockChecker(t, ref, 0)
+		checker.err = errors.New("node unavailable")
+		_, err := w.ScanAccounts(checker)
+		require.ErrorIs(t, err, checker.err)
+		require.Empty(t, w.Secrets.Accounts)
+	})
+}
@@ -0,0 +1,267 @@
+package wallet
+
+import (
+	"bytes"
+	"crypto/rand"
+	"crypto/sha256"
+	"fmt"
+	"strings"
+
+	"github.com/tyler-smith/go-bip39"
+	"github.com/tyler-smith/go-bip39/wordlists"
+	"golang.org/x/text/unicode/norm"
+
+	"github.com/spacemeshos/smcli/common"
+)
+
+// Shamir backups split the entropy of a mnemonic into N shares, any M of which (the threshold) can be combined
+// to rebuild the mnemonic. Fewer than M shares reveal nothing about it.
+//
+// Each byte of the entropy is shared separately using a random polynomial of degree M-1 over GF(2^8), with the
+// AES field polynomial x^8+x^4+x^3+x+1. Share i (1-255) holds the value of every polynomial at x=i.
+//
+// A share is encoded as the bytes
+//
+//	id (2 bytes) || threshold (1 byte) || index (1 byte) || values (len(entropy) bytes) || checksum (4 bytes)
+//
+// where id is random and identical for all shares of a split, and the checksum is the first four bytes of the
+// SHA-256 of everything before it. The bytes are then written as words from the English BIP39 wordlist, 11 bits
+// per word, with the unused bits of the last word set to zero. A 24-word mnemonic produces 30-word shares and a
+// 12-word mnemonic produces 18-word shares.
+const (
+	shareHeaderSize   = 4
+	shareChecksumSize = 4
+	shareBitsPerWord  = 11
+
+	// minEntropySize is the entropy length of a 12-word mnemonic.
+	minEntropySize = 16
+)
+
+var (
+	// ErrShareCorrupt is returned if a share contains an unknown word or a bad checksum.
+	ErrShareCorrupt = common.NewError(common.ErrBadInput, "corrupt share")
+	// ErrShareMismatch is returned if shares don't belong to the same split.
+	ErrShareMismatch = common.NewError(common.ErrBadInput, "shares do not belong to the same backup")
+	// ErrNotEnoughShares is returned if fewer shares than the threshold are combined.
+	ErrNotEnoughShares = common.NewError(common.ErrBadInput, "not enough shares")
+)
+
+// gfExp and gfLog are exponent and logarithm tables for GF(2^8) with generator 3.
+var gfExp, gfLog = func() (exp [510]byte, log [256]byte) {
+	x := byte(1)
+	for i := 0; i < 255; i++ {
+		exp[i] = x
+		exp[i+255] = x
+		log[x] = byte(i)
+		// multiply by the generator 3, i.e., x*2 + x
+		hi := x & 0x80
+		x2 := x << 1
+		if hi != 0 {
+			x2 ^= 0x1b
+		}
+		x ^= x2
+	}
+	return
+}()
+
+func gfMul(a, b byte) byte {
+	if a == 0 || b == 0 {
+		return 0
+	}
+	return gfExp[int(gfLog[a])+int(gfLog[b])]
+}
+
+func gfDiv(a, b byte) byte {
+	if a == 0 {
+		return 0
+	}
+	return gfExp[int(gfLog[a])+255-int(gfLog[b])]
+}
+
+// shareWordIndex maps the words of the English wordlist to their index.
+var shareWordIndex = func() map[string]int {
+	index := make(map[string]int, len(wordlists.English))
+	for i, word := range wordlists.English {
+		index[word] = i
+	}
+	return index
+}()
+
+type share struct {
+	id        [2]byte
+	threshold byte
+	index     byte
+	values    []byte
+}
+
+func (s *share) encode() string {
+	data := make([]byte, 0, shareHeaderSize+len(s.values)+shareChecksumSize)
+	data = append(data, s.id[0], s.id[1], s.threshold, s.index)
+	data = append(data, s.values...)
+	sum := sha256.Sum256(data)
+	data = append(data, sum[:shareChecksumSize]...)
+
+	nWords := (len(data)*8 + shareBitsPerWord - 1) / shareBitsPerWord
+	words := make([]string, nWords)
+	for i := range words {
+		idx := 0
+		for bit := i * shareBitsPerWord; bit < (i+1)*shareBitsPerWord; bit++ {
+			idx <<= 1
+			if bit < len(data)*8 && data[bit/8]&(0x80>>(bit%8)) != 0 {
+				idx |= 1
+			}
+		}
+		words[i] = wordlists.English[idx]
+	}
+	return strings.Join(words, " ")
+}
+
+func decodeShare(s string) (*share, error) {
+	words := strings.Fields(s)
+	nBits := len(words) * shareBitsPerWord
+	// the data length is always a multiple of four bytes and there's less than one word of padding
+	nBytes := nBits / 8 / 4 * 4
+	if nBytes < shareHeaderSize+shareChecksumSize+minEntropySize || nBits-nBytes*8 >= shareBitsPerWord {
+		return nil, fmt.Errorf("%w: wrong number of words (%d)", ErrShareCorrupt, len(words))
+	}
+	data := make([]byte, nBytes)
+	for i, word := range words {
+		idx, ok := shareWordIndex[strings.ToLower(word)]
+		if !ok {
+			return nil, fmt.Errorf("%w: unknown word %q", ErrShareCorrupt, word)
+		}
+		for j := 0; j < shareBitsPerWord; j++ {
+			bit := i*shareBitsPerWord + j
+			set := idx&(1<<(shareBitsPerWord-1-j)) != 0
+			switch {
+			case bit < nBytes*8 && set:
+				data[bit/8] |= 0x80 >> (bit % 8)
+			case bit >= nBytes*8 && set:
+				return nil, fmt.Errorf("%w: bad padding", ErrShareCorrupt)
+			}
+		}
+	}
+	body, checksum := data[:nBytes-shareChecksumSize], data[nBytes-shareChecksumSize:]
+	sum := sha256.Sum256(body)
+	if !bytes.Equal(checksum, sum[:shareChecksumSize]) {
+		return nil, fmt.Errorf("%w: bad checksum", ErrShareCorrupt)
+	}
+	sh := &share{
+		threshold: body[2],
+		index:     body[3],
+		values:    body[shareHeaderSize:],
+	}
+	copy(sh.id[:], body[:2])
+	if sh.index == 0 || sh.threshold < 2 {
+		return nil, fmt.Errorf("%w: bad header", ErrShareCorrupt)
+	}
+	return sh, nil
+}
+
+// SplitMnemonic splits the entropy of a mnemonic (in any supported language) into the given number of shares,
+// any threshold of which can be combined using CombineShares to rebuild it. The threshold must be at least 2
+// and at most the number of shares, which can be at most 255. The shares are written using the English wordlist.
+// Note that a BIP39 passphrase, if any, is not part of the shares.
+func SplitMnemonic(m string, threshold, shares int) ([]string, error) {
+	if threshold < 2 || threshold > shares || shares > 255 {
+		return nil, fmt.Errorf("invalid threshold %d of %d shares, need 2 <= threshold <= shares <= 255",
+			threshold, shares)
+	}
+	lang, err := mnemonicLanguage(m)
+	if err != nil {
+		return nil, err
+	}
+	var entropy []byte
+	err = withWordList(lang, func() (err error) {
+		entropy, err = bip39.EntropyFromMnemonic(norm.NFKD.String(m))
+		return
+	})
+	if err != nil {
+		return nil, err
+	}
+	defer wipe(entropy)
+
+	var id [2]byte
+	if _, err := rand.Read(id[:]); err != nil {
+		return nil, err
+	}
+	// coeffs[i] holds the coefficients of the polynomial for byte i, coeffs[i][0] being the secret itself
+	coeffs := make([][]byte, len(entropy))
+	defer func() {
+		for _, c := range coeffs {
+			wipe(c)
+		}
+	}()
+	for i, b := range entropy {
+		coeffs[i] = make([]byte, threshold)
+		coeffs[i][0] = b
+		if _, err := rand.Read(coeffs[i][1:]); err != nil {
+			return nil, err
+		}
+	}
+	result := make([]string, shares)
+	for x := 1; x <= shares; x++ {
+		sh := share{id: id, threshold: byte(threshold), index: byte(x), values: make([]byte, len(entropy))}
+		for i := range entropy {
+			// Horner's method
+			var y byte
+			for j := threshold - 1; j >= 0; j-- {
+				y = gfMul(y, byte(x)) ^ coeffs[i][j]
+			}
+			sh.values[i] = y
+		}
+		result[x-1] = sh.encode()
+	}
+	return result, nil
+}
+
+// CombineShares rebuilds a mnemonic from shares produced by SplitMnemonic. At least threshold shares are
+// required, and all of them must belong to the same split. The mnemonic is written in English unless another
+// language is set using WithLanguage.
+func CombineShares(shares []string, opts ...WalletOpt) (string, error) {
+	o := newWalletOpts(opts)
+	if len(shares) == 0 {
+		return "", ErrNotEnoughShares
+	}
+	decoded := make([]*share, 0, len(shares))
+	seen := make(map[byte]bool, len(shares))
+	for i, s := range shares {
+		sh, err := decodeShare(s)
+		if err != nil {
+			return "", fmt.Errorf("share %d: %w", i+1, err)
+		}
+		if len(decoded) > 0 {
+			first := decoded[0]
+			if sh.id != first.id || sh.threshold != first.threshold || len(sh.values) != len(first.values) {
+				return "", fmt.Errorf("share %d: %w", i+1, ErrShareMismatch)
+			}
+		}
+		if seen[sh.index] {
+			return "", fmt.Errorf("share %d: duplicate share number %d", i+1, sh.index)
+		}
+		seen[sh.index] = true
+		decoded = append(decoded, sh)
+	}
+	threshold := int(decoded[0].threshold)
+	if len(decoded) < threshold {
+		return "", fmt.Errorf("%w: got %d, need %d", ErrNotEnoughShares, len(decoded), threshold)
+	}
+	decoded = decoded[:threshold]
+
+	// Lagrange interpolation at x=0
+	entropy := make([]byte, len(decoded[0].values))
+	defer wipe(entropy)
+	for j, sj := range decoded {
+		basis := byte(1)
+		for k, sk := range decoded {
+			if j != k {
+				// subtraction is XOR in GF(2^8)
+				basis = gfMul(basis, gfDiv(sk.index, sk.index^sj.index))
+			}
+		}
+		for i := range entropy {
+			entropy[i] ^= gfMul(basis, sj.values[i])
+		}
+	}
+	return newMnemonic(o.language, entropy)
+}
@@ -0,0 +1,112 @@
+package wallet
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"github.com/tyler-smith/go-bip39/wordlists"
+)
+
+func TestGF256(t *testing.T) {
+	// every nonzero element has an inverse
+	for a := 1; a < 256; a++ {
+		require.Equal(t, byte(1), gfMul(byte(a), gfDiv(1, byte(a))), a)
+	}
+	// AES test vector: {57} * {83} = {c1}
+	require.Equal(t, byte(0xc1), gfMul(0x57, 0x83))
+}
+
+func TestSplitMnemonic(t *testing.T) {
+	shares, err := SplitMnemonic(testMnemonic, 2, 3)
+	require.NoError(t, err)
+	require.Len(t, shares, 3)
+	for _, s := range shares {
+		require.Len(t, strings.Fields(s), 18)
+	}
+
+	// any two shares, in any order, rebuild the mnemonic
+	for _, pair := range [][]int{{0, 1}, {1, 2}, {0, 2}, {2, 0}} {
+		m, err := CombineShares([]string{shares[pair[0]], shares[pair[1]]})
+		require.NoError(t, err)
+		require.Equal(t, testMnemonic, m)
+	}
+	m, err := CombineShares(shares)
+	require.NoError(t, err)
+	require.Equal(t, testMnemonic, m)
+
+	// one share is not enough
+	_, err = CombineShares(shares[:1])
+	require.ErrorIs(t, err, ErrNotEnoughShares)
+	_, err = CombineShares(nil)
+	require.ErrorIs(t, err, ErrNotEnoughShares)
+
+	// the same share twice doesn't count
+	_, err = CombineShares([]string{shares[0], shares[0]})
+	require.Error(t, err)
+
+	// shares of different splits can't be mixed
+	other, err := SplitMnemonic(testMnemonic, 2, 3)
+	require.NoError(t, err)
+	_, err = CombineShares([]string{shares[0], other[1]})
+	require.ErrorIs(t, err, ErrShareMismatch)
+}
+
+func TestSplitMnemonic24Words(t *testing.T) {
+	w, err := NewMultiWalletRandomMnemonic(1)
+	require.NoError(t, err)
+	shares, err := SplitMnemonic(w.Mnemonic(), 3, 5)
+	require.NoError(t, err)
+	for _, s := range shares {
+		require.Len(t, strings.Fields(s), 30)
+	}
+	m, err := CombineShares([]string{shares[4], shares[1], shares[2]})
+	require.NoError(t, err)
+	require.Equal(t, w.Mnemonic(), m)
+	_, err = CombineShares(shares[:2])
+	require.ErrorIs(t, err, ErrNotEnoughShares)
+}
+
+func TestSplitMnemonicLanguage(t *testing.T) {
+	w, err := NewMultiWalletRandomMnemonic(1, WithLanguage(LanguageSpanish))
+	require.NoError(t, err)
+	shares, err := SplitMnemonic(w.Mnemonic(), 2, 2)
+	require.NoError(t, err)
+	m, err := CombineShares(shares, WithLanguage(LanguageSpanish))
+	require.NoError(t, err)
+	require.Equal(t, w.Mnemonic(), m)
+}
+
+func TestCorruptShare(t *testing.T) {
+	shares, err := SplitMnemonic(testMnemonic, 2, 3)
+	require.NoError(t, err)
+	words := strings.Fields(shares[1])
+
+	// replace each word with a different valid word: the checksum catches it
+	for i := range words {
+		corrupt := make([]string, len(words))
+		copy(corrupt, words)
+		idx := shareWordIndex[words[i]]
+		corrupt[i] = wordlists.English[(idx+1)%len(wordlists.English)]
+		_, err = CombineShares([]string{shares[0], strings.Join(corrupt, " ")})
+		require.ErrorIs(t, err, ErrShareCorrupt, i)
+	}
+
+	// unknown word
+	corrupt := append([]string{"notaword"}, words[1:]...)
+	_, err = CombineShares([]string{shares[0], strings.Join(corrupt, " ")})
+	require.ErrorIs(t, err, ErrShareCorrupt)
+
+	// missing word
+	_, err = CombineShares([]string{shares[0], strings.Join(words[1:], " ")})
+	require.ErrorIs(t, err, ErrShareCorrupt)
+}
+
+func TestSplitMnemonicInvalid(t *testing.T) {
+	for _, tc := range []struct{ threshold, shares int }{{1, 3}, {4, 3}, {2, 256}, {0, 0}} {
+		_, err := SplitMnemonic(testMnemonic, tc.threshold, tc.shares)
+		require.Error(t, err, tc)
+	}
+	_, err := SplitMnemonic("film theme cheese broken", 2, 3)
+	require.Error(t, err)
+}
@@ -0,0 +1,302 @@
+package wallet
+
+import (
+	"bytes"
+	"crypto/ed25519"
+	"encoding/json"
+	"fmt"
+	"io"
+
+	smbip32 "github.com/spacemeshos/smkeys/bip32"
+
+	"github.com/spacemeshos/smcli/common"
+)
+
+// Smapp, the Spacemesh desktop app, stores wallets in a format that smcli's is derived from. The secrets are
+// encrypted the same way, using AES-GCM with a key derived using PBKDF2-HMAC-SHA512, but the metadata and the
+// layout of the secrets differ: Smapp records the kind of wallet and the node it talks to, keeps an address
+// book, has no master keypair, and doesn't version its files.
+
+// SmappPbkdf2Iterations is the PBKDF2 iteration count used by Smapp, and by ExportSmapp.
+const SmappPbkdf2Iterations = 120000
+
+// smappTimeFormat is the format of Smapp timestamps, which use dashes rather than colons.
+const smappTimeFormat = "2006-01-02T15-04-05.000Z"
+
+// Values of the type field of Smapp wallet metadata.
+const (
+	SmappWalletTypeLocalNode = "local-node"
+	SmappWalletTypeRemoteAPI = "remote-api"
+)
+
+// Values of the type field of Smapp keypairs.
+const (
+	smappKeyTypeSoftware = "software"
+	smappKeyTypeHardware = "hardware"
+)
+
+// smappWalletFile is an encrypted Smapp wallet file.
+type smappWalletFile struct {
+	Meta   smappWalletMeta        `json:"meta"`
+	Crypto walletSecretsEncrypted `json:"crypto"`
+}
+
+type smappWalletMeta struct {
+	DisplayName string `json:"displayName"`
+	Created     string `json:"created"`
+	GenesisID   string `json:"genesisID"`
+	RemoteAPI   string `json:"remoteApi"`
+	Type        string `json:"type"`
+}
+
+// smappWalletSecrets is the decrypted part of a Smapp wallet file.
+type smappWalletSecrets struct {
+	Mnemonic string            `json:"mnemonic"`
+	Accounts []*smappKeyPair   `json:"accounts"`
+	Contacts []json.RawMessage `json:"contacts"`
+}
+
+type smappKeyPair struct {
+	DisplayName string     `json:"displayName"`
+	Created     string     `json:"created"`
+	Path        HDPath     `json:"path"`
+	PublicKey   PublicKey  `json:"publicKey"`
+	SecretKey   PrivateKey `json:"secretKey"`
+	Type        string     `json:"type,omitempty"`
+}
+
+// IsSmappWallet reports whether data looks like a Smapp wallet file rather than an smcli one: Smapp records the
+// wallet type and remote API in the metadata, which smcli doesn't.
+func IsSmappWallet(data []byte) bool {
+	var file struct {
+		Meta   map[string]json.RawMessage `json:"meta"`
+		Crypto map[string]json.RawMessage `json:"crypto"`
+	}
+	if err := json.Unmarshal(data, &file); err != nil || file.Crypto == nil {
+		return false
+	}
+	_, hasType := file.Meta["type"]
+	_, hasRemoteAPI := file.Meta["remoteApi"]
+	_, hasVersion := file.Meta["version"]
+	return (hasType || hasRemoteAPI) && !hasVersion
+}
+
+// ImportSmapp decrypts a Smapp wallet file using its password and converts it into a wallet. The accounts are
+// rederived from the mnemonic and checked against the public keys in the file, keeping their names and creation
+// times. Anything that smcli has no place for, such as the address book, is dropped; the returned warnings
+// describe what was lost so that they can be shown to the user. Smapp wallets backed by a hardware device have
+// no mnemonic and can't be imported: create a new wallet using the same device instead.
+func ImportSmapp(file io.Reader, password []byte) (*Wallet, []string, error) {
+	sf := &smappWalletFile{}
+	if err := json.NewDecoder(file).Decode(sf); err != nil {
+		return nil, nil, fmt.Errorf("error reading Smapp wallet file: %w", err)
+	}
+	if sf.Crypto.KDF != KDFPbkdf2 {
+		return nil, nil, fmt.Errorf("unsupported Smapp key derivation function %q", sf.Crypto.KDF)
+	}
+	k := NewKey(WithPasswordOnly(password))
+	plaintext, err := k.decryptSecrets(&EncryptedWalletFile{Secrets: sf.Crypto})
+	if err != nil {
+		return nil, nil, err
+	}
+	defer wipe(plaintext)
+	secrets := &smappWalletSecrets{}
+	if err := json.Unmarshal(plaintext, secrets); err != nil {
+		return nil, nil, fmt.Errorf("error reading Smapp wallet secrets: %w", err)
+	}
+	for _, acct := range secrets.Accounts {
+		wipe(acct.SecretKey)
+	}
+
+	for _, acct := range secrets.Accounts {
+		if acct.Type == smappKeyTypeHardware {
+			return nil, nil, fmt.Errorf("Smapp hardware wallets can't be imported, create a new wallet " +
+				"using the same device instead")
+		}
+	}
+	if _, err := mnemonicLanguage(secrets.Mnemonic); err != nil {
+		return nil, nil, fmt.Errorf("Smapp wallet does not contain a valid mnemonic")
+	}
+	seed := mnemonicToSeed(secrets.Mnemonic, "")
+	defer wipe(seed)
+	master, err := NewMasterKeyPair(seed)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var warnings []string
+	accounts := make([]*EDKeyPair, 0, len(secrets.Accounts))
+	for i, sa := range secrets.Accounts {
+		acct, warning, err := importSmappAccount(master, seed, sa)
+		if err != nil {
+			return nil, nil, fmt.Errorf("Smapp account %d: %w", i, err)
+		}
+		if warning != "" {
+			warnings = append(warnings, fmt.Sprintf("account %d: %s", i, warning))
+		}
+		accounts = append(accounts, acct)
+	}
+	if len(accounts) == 0 {
+		warnings = append(warnings, "the wallet has no accounts, add them using add-accounts")
+	}
+
+	w, err := walletFromMnemonicAndAccounts(secrets.Mnemonic, master, accounts)
+	if err != nil {
+		return nil, nil, err
+	}
+	if sf.Meta.Created != "" {
+		w.Meta.Created = sf.Meta.Created
+	}
+	w.normalizeTimestamps()
+	if err := w.SetDisplayName(sf.Meta.DisplayName); err != nil {
+		warnings = append(warnings, fmt.Sprintf("invalid wallet name %q replaced by %q: %v",
+			sf.Meta.DisplayName, DefaultDisplayName, err))
+	}
+	if sf.Meta.GenesisID != "" {
+		if _, err := ParseGenesisID(sf.Meta.GenesisID); err != nil {
+			warnings = append(warnings, fmt.Sprintf("genesis ID dropped: %v", err))
+		} else {
+			w.Meta.GenesisID = sf.Meta.GenesisID
+		}
+	}
+	if sf.Meta.RemoteAPI != "" {
+		warnings = append(warnings, fmt.Sprintf("remote API %s not imported, use --endpoint to select a node",
+			sf.Meta.RemoteAPI))
+	}
+	if len(secrets.Contacts) > 0 {
+		warnings = append(warnings, fmt.Sprintf("%d address book contact(s) not imported", len(secrets.Contacts)))
+	}
+	return w, warnings, nil
+}
+
+// importSmappAccount rederives a Smapp account from the seed and checks it against the file. Smapp uses the same
+// paths as smcli, but any fully hardened path can be derived; others produce a warning since AddAccounts continues
+// from the standard path.
+func importSmappAccount(master *EDKeyPair, seed []byte, sa *smappKeyPair) (*EDKeyPair, string, error) {
+	if !IsPathCompletelyHardened(sa.Path) || len(sa.Path) == 0 {
+		return nil, "", fmt.Errorf("unsupported HD path %s", sa.Path.String())
+	}
+	key, err := smbip32.Derive(HDPathToString(sa.Path), seed)
+	if err != nil {
+		return nil, "", err
+	}
+	private := PrivateKey(key[:])
+	public := PublicKey(ed25519.PrivateKey(private).Public().(ed25519.PublicKey))
+	if !bytes.Equal(public, sa.PublicKey) {
+		common.Logger().Debug("Smapp public key does not match the mnemonic", "path", HDPathToString(sa.Path),
+			"derived", public, "file", sa.PublicKey)
+		return nil, "", fmt.Errorf("public key does not match the mnemonic")
+	}
+	acct := &EDKeyPair{
+		DisplayName: sa.DisplayName,
+		Created:     sa.Created,
+		Path:        sa.Path,
+		Public:      public,
+		Private:     private,
+	}
+	standard := len(sa.Path) == len(master.Path)+1
+	for i := 0; standard && i < len(master.Path); i++ {
+		standard = sa.Path[i] == master.Path[i]
+	}
+	if !standard {
+		return acct, fmt.Sprintf("non-standard HD path %s", sa.Path.String()), nil
+	}
+	return acct, "", nil
+}
+
+// ErrSmappPassphrase is returned by CheckSmappExport for wallets created with a BIP39 passphrase.
+var ErrSmappPassphrase = common.NewError(common.ErrBadInput,
+	"wallet was created with a BIP-39 passphrase, which Smapp doesn't support")
+
+// CheckSmappExport checks that the wallet can be exported by ExportSmapp, so that commands can fail before asking
+// for a password. Wallets backed by a Ledger device or saved without their mnemonic can't be, and neither can
+// wallets created with a BIP39 passphrase: Smapp derives the keys from the mnemonic alone, so it would reject
+// every account of the file. The passphrase isn't stored, so it's detected by deriving the master key from the
+// mnemonic without one.
+func CheckSmappExport(w *Wallet) error {
+	master := w.Secrets.MasterKeypair
+	if master == nil || master.KeyType == typeLedger {
+		return fmt.Errorf("only wallets created from a mnemonic can be exported to Smapp")
+	}
+	if len(w.Secrets.Mnemonic) == 0 {
+		return fmt.Errorf("%w, which Smapp needs", ErrNoMnemonic)
+	}
+	if _, err := mnemonicLanguage(w.Mnemonic()); err != nil {
+		return fmt.Errorf("wallet does not contain a valid mnemonic")
+	}
+	seed := mnemonicToSeed(w.Mnemonic(), "")
+	defer wipe(seed)
+	withoutPassphrase, err := newMasterKeyPairAt(seed, master.Path)
+	if err != nil {
+		return err
+	}
+	defer wipe(withoutPassphrase.Private)
+	if !bytes.Equal(withoutPassphrase.Public, master.Public) {
+		return ErrSmappPassphrase
+	}
+	return nil
+}
+
+// ExportSmapp encrypts the wallet using password and writes it to file in the Smapp wallet file format, so that it
+// can be opened in Smapp. The mnemonic and the accounts are kept, with account labels used as the account names
+// if set. The file is encrypted with the KDF parameters used by Smapp. Wallets that Smapp can't open aren't
+// exported, see CheckSmappExport: in particular, for a wallet backed by a Ledger device, add the device to Smapp
+// instead.
+func ExportSmapp(file io.Writer, w *Wallet, password []byte) error {
+	if err := CheckSmappExport(w); err != nil {
+		return err
+	}
+	secrets := &smappWalletSecrets{
+		Mnemonic: w.Mnemonic(),
+		Accounts: make([]*smappKeyPair, 0, len(w.Secrets.Accounts)),
+		Contacts: []json.RawMessage{},
+	}
+	for i, acct := range w.Secrets.Accounts {
+		if len(acct.Private) != ed25519.PrivateKeySize {
+			return fmt.Errorf("account %d does not contain a private key", i)
+		}
+		name := acct.DisplayName
+		if acct.Label != "" {
+			name = acct.Label
+		}
+		secrets.Accounts = append(secrets.Accounts, &smappKeyPair{
+			DisplayName: name,
+			Created:     smappTimeString(acct.Created),
+			Path:        acct.Path,
+			PublicKey:   acct.Public,
+			SecretKey:   acct.Private,
+			Type:        smappKeyTypeSoftware,
+		})
+	}
+	plaintext, err := json.Marshal(secrets)
+	if err != nil {
+		return err
+	}
+	defer wipe(plaintext)
+
+	k := NewKey(WithRandomSalt(), WithIterations(SmappPbkdf2Iterations), WithPbkdf2Password(password))
+	ew := &EncryptedWalletFile{}
+	if err := k.encryptSecrets(ew, plaintext); err != nil {
+		return err
+	}
+	ew.Secrets.KDFParams.Hash = Pbkdf2HashName
+	sf := &smappWalletFile{
+		Meta: smappWalletMeta{
+			DisplayName: w.Meta.DisplayName,
+			Created:     smappTimeString(w.Meta.Created),
+			GenesisID:   w.Meta.GenesisID,
+			Type:        SmappWalletTypeLocalNode,
+		},
+		Crypto: ew.Secrets,
+	}
+	return json.NewEncoder(file).Encode(sf)
+}
+
+// smappTimeString rewrites a timestamp in smappTimeFormat, if it can be parsed, for ExportSmapp.
+func smappTimeString(s string) string {
+	t, err := common.ParseTimeString(s)
+	if err != nil {
+		return s
+	}
+	return t.UTC().Format(smappTimeFormat)
+}
@@ -0,0 +1,211 @@
+package wallet
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"testing"
+
+	"github.com/spacemeshos/go-spacemesh/common/types"
+	"github.com/stretchr/testify/require"
+)
+
+// testdata/smapp_wallet.json is a Smapp wallet file holding the first two accounts of the mnemonic in
+// TestOpenFixture and an address book entry, encrypted with the password "password".
+func TestImportSmapp(t *testing.T) {
+	data, err := os.ReadFile("testdata/smapp_wallet.json")
+	require.NoError(t, err)
+	require.True(t, IsSmappWallet(data))
+
+	w, warnings, err := ImportSmapp(bytes.NewReader(data), []byte("password"))
+	require.NoError(t, err)
+	require.Equal(t, "film theme cheese broken kingdom destroy inch ready wear inspire shove pudding", w.Mnemonic())
+	require.Equal(t, []string{
+		"sm1qqqqqqz9rf583slhn38g6q6a562ctltv9fv5w8q2gdz9k",
+		"sm1qqqqqqygmz2nnr7ush67yx7g4mmksm979m3a0xcphk3pt",
+	}, w.Addresses("sm"))
+	require.Equal(t, "My Smapp Wallet", w.Meta.DisplayName)
+	// Smapp timestamps use dashes rather than colons
+	require.Equal(t, "2023-03-01T12:00:00.000Z", w.Meta.Created)
+	require.Equal(t, "9eebff023abb17ccb775c602daade8ed708f0a50", w.Meta.GenesisID)
+	require.Equal(t, WalletVersion, w.Meta.Version)
+	require.Equal(t, "Savings", w.Secrets.Accounts[1].DisplayName)
+	require.Equal(t, "m/44'/540'/0'/0'/1'", w.Secrets.Accounts[1].Path.String())
+	require.Equal(t, []string{"1 address book contact(s) not imported"}, warnings)
+
+	// the imported wallet is a regular smcli wallet
+	require.NoError(t, w.AddAccounts(1))
+	require.Len(t, w.Secrets.Accounts, 3)
+	buf := &bytes.Buffer{}
+	wKey := NewKey(WithRandomSalt(), WithPbkdf2Password([]byte("new password")))
+	require.NoError(t, wKey.Export(buf, w))
+	require.False(t, IsSmappWallet(buf.Bytes()))
+
+	_, _, err = ImportSmapp(bytes.NewReader(data), []byte("wrong password"))
+	require.ErrorIs(t, err, ErrWrongPassword)
+}
+
+func TestIsSmappWallet(t *testing.T) {
+	data, err := os.ReadFile("testdata/wallet.json")
+	require.NoError(t, err)
+	require.False(t, IsSmappWallet(data))
+	require.False(t, IsSmappWallet([]byte("not json")))
+	require.False(t, IsSmappWallet([]byte(`{"meta": {"type": "local-node"}}`)))
+}
+
+// smappFixture decrypts the Smapp fixture so that tests can tamper with it, and returns a function that
+// reencrypts the modified secrets.
+func smappFixture(t *testing.T) (*smappWalletSecrets, *smappWalletFile, func() []byte) {
+	data, err := os.ReadFile("testdata/smapp_wallet.json")
+	require.NoError(t, err)
+	sf := &smappWalletFile{}
+	require.NoError(t, json.Unmarshal(data, sf))
+	k := NewKey(WithPasswordOnly([]byte("password")))
+	plaintext, err := k.decryptSecrets(&EncryptedWalletFile{Secrets: sf.Crypto})
+	require.NoError(t, err)
+	secrets := &smappWalletSecrets{}
+	require.NoError(t, json.Unmarshal(plaintext, secrets))
+	return secrets, sf, func() []byte {
+		plaintext, err := json.Marshal(secrets)
+		require.NoError(t, err)
+		ew := &EncryptedWalletFile{}
+		require.NoError(t, k.encryptSecrets(ew, plaintext))
+		sf.Crypto = ew.Secrets
+		data, err := json.Marshal(sf)
+		require.NoError(t, err)
+		return data
+	}
+}
+
+func TestImportSmappInvalid(t *testing.T) {
+	t.Run("key mismatch", func(t *testing.T) {
+		secrets, _, encode := smappFixture(t)
+		secrets.Accounts[1].PublicKey = secrets.Accounts[0].PublicKey
+		_, _, err := ImportSmapp(bytes.NewReader(encode()), []byte("password"))
+		require.ErrorContains(t, err, "does not match")
+	})
+	t.Run("hardware", func(t *testing.T) {
+		secrets, _, encode := smappFixture(t)
+		secrets.Mnemonic = ""
+		secrets.Accounts[0].Type = smappKeyTypeHardware
+		_, _, err := ImportSmapp(bytes.NewReader(encode()), []byte("password"))
+		require.ErrorContains(t, err, "hardware")
+	})
+	t.Run("bad mnemonic", func(t *testing.T) {
+		secrets, _, encode := smappFixture(t)
+		secrets.Mnemonic = "film theme cheese"
+		_, _, err := ImportSmapp(bytes.NewReader(encode()), []byte("password"))
+		require.ErrorContains(t, err, "mnemonic")
+	})
+	t.Run("metadata", func(t *testing.T) {
+		secrets, sf, encode := smappFixture(t)
+		secrets.Contacts = nil
+		sf.Meta.DisplayName = ""
+		sf.Meta.GenesisID = "beef"
+		sf.Meta.RemoteAPI = "https://mainnet-api.spacemesh.network"
+		sf.Meta.Type = SmappWalletTypeRemoteAPI
+		w, warnings, err := ImportSmapp(bytes.NewReader(encode()), []byte("password"))
+		require.NoError(t, err)
+		require.Equal(t, DefaultDisplayName, w.Meta.DisplayName)
+		require.Empty(t, w.Meta.GenesisID)
+		require.Len(t, warnings, 3)
+		require.Contains(t, warnings[0], "wallet name")
+		require.Contains(t, warnings[1], "genesis ID")
+		require.Contains(t, warnings[2], "mainnet-api.spacemesh.network")
+	})
+	t.Run("non-standard path", func(t *testing.T) {
+		secrets, _, encode := smappFixture(t)
+		seed := mnemonicToSeed(secrets.Mnemonic, "")
+		master, err := NewMasterKeyPair(seed)
+		require.NoError(t, err)
+		other := HDPath{BIP44Purpose(), BIP44SpacemeshCoinType(), BIP44HardenedAccountIndex(1)}
+		acct, warning, err := importSmappAccount(master, seed, &smappKeyPair{Path: other})
+		require.ErrorContains(t, err, "does not match")
+		require.Nil(t, acct)
+		require.Empty(t, warning)
+
+		kp, err := (&EDKeyPair{Path: other[:2]}).NewChildKeyPair(seed, 1)
+		require.NoError(t, err)
+		secrets.Accounts[1].Path, secrets.Accounts[1].PublicKey = kp.Path, kp.Public
+		w, warnings, err := ImportSmapp(bytes.NewReader(encode()), []byte("password"))
+		require.NoError(t, err)
+		require.Equal(t, kp.Public, w.Secrets.Accounts[1].Public)
+		require.Equal(t, []string{
+			"account 1: non-standard HD path m/44'/540'/1'",
+			"1 address book contact(s) not imported",
+		}, warnings)
+
+		secrets.Accounts[1].Path = HDPath{BIP44Purpose(), 1}
+		_, _, err = ImportSmapp(bytes.NewReader(encode()), []byte("password"))
+		require.ErrorContains(t, err, "unsupported HD path")
+	})
+}
+
+func TestExportSmapp(t *testing.T) {
+	const mnemonic = "film theme cheese broken kingdom destroy inch ready wear inspire shove pudding"
+	w, err := NewMultiWalletFromMnemonic(mnemonic, 3, WithDisplayName("Exported"), WithGenesisID(types.Hash20{1}))
+	require.NoError(t, err)
+	require.NoError(t, w.SetAccountLabel(1, "savings"))
+
+	buf := &bytes.Buffer{}
+	require.NoError(t, ExportSmapp(buf, w, []byte("smapp password")))
+	require.True(t, IsSmappWallet(buf.Bytes()))
+	require.NotContains(t, buf.String(), "film")
+
+	// the identifiers Smapp expects
+	var raw struct {
+		Meta   map[string]interface{} `json:"meta"`
+		Crypto map[string]interface{} `json:"crypto"`
+	}
+	require.NoError(t, json.Unmarshal(buf.Bytes(), &raw))
+	require.Equal(t, "AES-GCM", raw.Crypto["cipher"])
+	require.Equal(t, "PBKDF2", raw.Crypto["kdf"])
+	require.Equal(t, map[string]interface{}{
+		"dklen":      float64(256),
+		"hash":       "SHA-512",
+		"salt":       raw.Crypto["kdfparams"].(map[string]interface{})["salt"],
+		"iterations": float64(SmappPbkdf2Iterations),
+	}, raw.Crypto["kdfparams"])
+	require.Equal(t, SmappWalletTypeLocalNode, raw.Meta["type"])
+	require.NotContains(t, raw.Meta["created"], ":")
+	require.Equal(t, "", raw.Meta["remoteApi"])
+
+	// and back again
+	w2, warnings, err := ImportSmapp(bytes.NewReader(buf.Bytes()), []byte("smapp password"))
+	require.NoError(t, err)
+	require.Empty(t, warnings)
+	require.Equal(t, w.Addresses("sm"), w2.Addresses("sm"))
+	require.Equal(t, w.Mnemonic(), w2.Mnemonic())
+	require.Equal(t, w.Meta.DisplayName, w2.Meta.DisplayName)
+	require.Equal(t, w.Meta.Created, w2.Meta.Created)
+	require.Equal(t, w.Meta.GenesisID, w2.Meta.GenesisID)
+	require.Equal(t, w.Secrets.MasterKeypair.Public, w2.Secrets.MasterKeypair.Public)
+	require.Equal(t, "savings", w2.Secrets.Accounts[1].DisplayName)
+	for i, acct := range w2.Secrets.Accounts {
+		require.Equal(t, w.Secrets.Accounts[i].Private, acct.Private)
+		require.Equal(t, w.Secrets.Accounts[i].Path, acct.Path)
+	}
+}
+
+func TestExportSmappLedger(t *testing.T) {
+	useMockLedger(t, newMockLedger(LedgerDevice{ID: "usb://ledger?id=1", Product: "Nano S"}))
+	w, err := NewMultiWalletFromLedger(1)
+	require.NoError(t, err)
+	require.Error(t, ExportSmapp(&bytes.Buffer{}, w, []byte("password")))
+}
+
+func TestExportSmappPassphrase(t *testing.T) {
+	// Smapp ignores the passphrase, so the accounts it would derive aren't the ones in the file
+	const mnemonic = "film theme cheese broken kingdom destroy inch ready wear inspire shove pudding"
+	w, err := NewMultiWalletFromMnemonic(mnemonic, 2, WithPassphrase("25th word"))
+	require.NoError(t, err)
+	require.ErrorIs(t, CheckSmappExport(w), ErrSmappPassphrase)
+	buf := &bytes.Buffer{}
+	require.ErrorIs(t, ExportSmapp(buf, w, []byte("smapp password")), ErrSmappPassphrase)
+	require.Empty(t, buf.Bytes())
+
+	// an empty passphrase is no passphrase
+	w, err = NewMultiWalletFromMnemonic(mnemonic, 2, WithPassphrase(""))
+	require.NoError(t, err)
+	require.NoError(t, CheckSmappExport(w))
+}
@@ -0,0 +1,227 @@
+package wallet
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/spacemeshos/go-scale"
+	"github.com/spacemeshos/go-spacemesh/genvm/core"
+	"github.com/spacemeshos/go-spacemesh/genvm/templates/multisig"
+	"github.com/spacemeshos/go-spacemesh/genvm/templates/vault"
+	"github.com/spacemeshos/go-spacemesh/genvm/templates/vesting"
+	walletTemplate "github.com/spacemeshos/go-spacemesh/genvm/templates/wallet"
+
+	"github.com/spacemeshos/smcli/common"
+)
+
+// MaxMultiSigKeys is the maximum number of public keys of a multisig or vesting account, a limit of the
+// multisig template's spawn arguments (their PublicKeys are encoded with scale:"max=10"). Since thresholds and
+// participant refs are uint8s, it also keeps them from overflowing.
+const MaxMultiSigKeys = 10
+
+var (
+	// ErrInvalidThreshold is returned if a multisig account requires no signatures, or more than it has keys.
+	ErrInvalidThreshold = common.NewError(common.ErrBadInput, "invalid threshold")
+	// ErrTooManyPublicKeys is returned if a multisig account has more than MaxMultiSigKeys public keys.
+	ErrTooManyPublicKeys = common.NewError(common.ErrBadInput, "too many public keys")
+	// ErrDuplicatePublicKey is returned if a participant of a multisig account is given more than once.
+	ErrDuplicatePublicKey = common.NewError(common.ErrBadInput, "duplicate public key")
+	// ErrZeroPublicKey is returned if a participant of a multisig account has an all-zero public key.
+	ErrZeroPublicKey = common.NewError(common.ErrBadInput, "all-zero public key")
+)
+
+// encodeSpawnArgs serializes template spawn arguments the way they're included in a spawn transaction.
+func encodeSpawnArgs(args scale.Encodable) ([]byte, error) {
+	var buf bytes.Buffer
+	if _, err := args.EncodeScale(scale.NewEncoder(&buf)); err != nil {
+		return nil, fmt.Errorf("error encoding spawn arguments: %w", err)
+	}
+	return buf.Bytes(), nil
+}
+
+// SpawnSingleSig computes the principal address of a single-sig wallet account owned by pubkey. It also returns
+// the encoded spawn arguments, which are needed to build the spawn transaction for the account.
+func SpawnSingleSig(pubkey core.PublicKey) (core.Address, []byte, error) {
+	if pubkey == (core.PublicKey{}) {
+		return core.Address{}, nil, fmt.Errorf("public key must be set")
+	}
+	args := &walletTemplate.SpawnArguments{PublicKey: pubkey}
+	encoded, err := encodeSpawnArgs(args)
+	if err != nil {
+		return core.Address{}, nil, err
+	}
+	return core.ComputePrincipal(walletTemplate.TemplateAddress, args), encoded, nil
+}
+
+// MultiSigThreshold checks that a multisig or vesting account with the given number of public keys can require
+// `required` signatures, and returns the threshold as the template expects it. Counts are checked before they're
+// narrowed to a uint8, so that large values, e.g., read from user input, can't wrap around to a valid threshold.
+func MultiSigThreshold(required, keys int) (uint8, error) {
+	switch {
+	case keys <= 0:
+		return 0, fmt.Errorf("must provide at least one public key")
+	case keys > MaxMultiSigKeys:
+		return 0, fmt.Errorf("%w: got %d, a multisig account can have at most %d",
+			ErrTooManyPublicKeys, keys, MaxMultiSigKeys)
+	case required <= 0 || required > keys:
+		return 0, fmt.Errorf("%w %d, must be between 1 and %d (the number of public keys)",
+			ErrInvalidThreshold, required, keys)
+	}
+	return uint8(required), nil
+}
+
+// newMultiSigArgs validates a k-of-n multisig configuration and returns the spawn arguments for it. The
+// participants must be distinct: a key given twice would count twice toward the threshold.
+func newMultiSigArgs(required uint8, pubkeys []core.PublicKey) (*multisig.SpawnArguments, error) {
+	if _, err := MultiSigThreshold(int(required), len(pubkeys)); err != nil {
+		return nil, err
+	}
+	seen := make(map[core.PublicKey]int, len(pubkeys))
+	for i, pubkey := range pubkeys {
+		if pubkey == (core.PublicKey{}) {
+			return nil, fmt.Errorf("%w at index %d", ErrZeroPublicKey, i)
+		}
+		if first, ok := seen[pubkey]; ok {
+			return nil, fmt.Errorf("%w at index %d, same as index %d", ErrDuplicatePublicKey, i, first)
+		}
+		seen[pubkey] = i
+	}
+	return &multisig.SpawnArguments{
+		Required:   required,
+		PublicKeys: pubkeys,
+	}, nil
+}
+
+// The multisig template is sensitive to the order of the public keys: the same keys listed in a different order
+// spawn a different account, and signatures refer to participants by their position. To avoid surprises, smcli
+// sorts the public keys of multisig accounts in canonical order, ascending byte order (see SortPublicKeys),
+// before spawning or signing, so that participants who list the same keys differently agree on the account. Use
+// WithRawKeyOrder to keep the keys in the order given, e.g., for an account spawned elsewhere without sorting
+// them. Participants can compare the MultiSigSpawn of an account to check that they agree on it before anyone
+// funds it.
+
+// MultiSigSpawn describes the spawn arguments of a multisig account, for its participants to verify.
+type MultiSigSpawn struct {
+	Principal core.Address
+	Required  uint8
+	// PublicKeys are the participants in spawn order.
+	PublicKeys []core.PublicKey
+	// Fingerprint is a short hash of the template and the spawn arguments that's easy to compare out loud.
+	Fingerprint string
+}
+
+// SortPublicKeys returns a copy of pubkeys in canonical order, ascending byte order.
+func SortPublicKeys(pubkeys []core.PublicKey) []core.PublicKey {
+	sorted := make([]core.PublicKey, len(pubkeys))
+	copy(sorted, pubkeys)
+	sort.Slice(sorted, func(i, j int) bool {
+		return bytes.Compare(sorted[i][:], sorted[j][:]) < 0
+	})
+	return sorted
+}
+
+// WithRawKeyOrder keeps the public keys of a multisig account in the order given instead of sorting them in
+// canonical order. The same order must then be used consistently to spawn the account and to sign for it.
+func WithRawKeyOrder() WalletOpt {
+	return func(o *walletOpts) {
+		o.rawKeyOrder = true
+	}
+}
+
+// multiSigKeys returns the public keys of a multisig account in spawn order.
+func (o *walletOpts) multiSigKeys(pubkeys []core.PublicKey) []core.PublicKey {
+	if o.rawKeyOrder {
+		return pubkeys
+	}
+	return SortPublicKeys(pubkeys)
+}
+
+// SpawnMultiSig computes the principal address of a multisig account that requires `required` signatures from
+// the given public keys, in canonical order unless WithRawKeyOrder is given. It also returns the encoded spawn
+// arguments, which are needed to build the spawn transaction for the account.
+func SpawnMultiSig(required uint8, pubkeys []core.PublicKey, opts ...WalletOpt) (core.Address, []byte, error) {
+	// validate first, so that errors refer to the keys in the order they were given
+	args, err := newMultiSigArgs(required, pubkeys)
+	if err != nil {
+		return core.Address{}, nil, err
+	}
+	args.PublicKeys = newWalletOpts(opts).multiSigKeys(pubkeys)
+	encoded, err := encodeSpawnArgs(args)
+	if err != nil {
+		return core.Address{}, nil, err
+	}
+	return core.ComputePrincipal(multisig.TemplateAddress, args), encoded, nil
+}
+
+// DescribeMultiSigSpawn returns the principal address, participants, and fingerprint of a multisig account, see
+// SpawnMultiSig. Unless WithRawKeyOrder is given, every participant gets the same result from the same keys in
+// any order.
+func DescribeMultiSigSpawn(required uint8, pubkeys []core.PublicKey, opts ...WalletOpt) (*MultiSigSpawn, error) {
+	principal, encoded, err := SpawnMultiSig(required, pubkeys, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return &MultiSigSpawn{
+		Principal:   principal,
+		Required:    required,
+		PublicKeys:  newWalletOpts(opts).multiSigKeys(pubkeys),
+		Fingerprint: spawnFingerprint(multisig.TemplateAddress, encoded),
+	}, nil
+}
+
+// spawnFingerprint hashes a template address and encoded spawn arguments, and formats the first 16 bytes of the
+// hash as groups of four hex digits.
+func spawnFingerprint(template core.Address, encoded []byte) string {
+	h := sha256.New()
+	h.Write(template[:])
+	h.Write(encoded)
+	digits := hex.EncodeToString(h.Sum(nil)[:16])
+	groups := make([]string, 0, len(digits)/4)
+	for i := 0; i < len(digits); i += 4 {
+		groups = append(groups, digits[i:i+4])
+	}
+	return strings.Join(groups, "-")
+}
+
+// SpawnVesting computes the principal address of a vesting account, which is a multisig account that can also
+// drain a vault. It takes the same arguments as SpawnMultiSig, but always keeps the public keys in the order
+// given since vesting accounts are defined in the genesis ledger that way: sign for them using WithRawKeyOrder.
+func SpawnVesting(required uint8, pubkeys []core.PublicKey) (core.Address, []byte, error) {
+	args, err := newMultiSigArgs(required, pubkeys)
+	if err != nil {
+		return core.Address{}, nil, err
+	}
+	encoded, err := encodeSpawnArgs(args)
+	if err != nil {
+		return core.Address{}, nil, err
+	}
+	return core.ComputePrincipal(vesting.TemplateAddress, args), encoded, nil
+}
+
+// SpawnVault computes the principal address of a vault account holding vested funds for its owner (normally
+// a vesting account). It also returns the encoded spawn arguments.
+func SpawnVault(args *vault.SpawnArguments) (core.Address, []byte, error) {
+	switch {
+	case args.Owner == (core.Address{}):
+		return core.Address{}, nil, fmt.Errorf("vault owner must be set")
+	case args.TotalAmount == 0:
+		return core.Address{}, nil, fmt.Errorf("vault total amount must be greater than zero")
+	case args.InitialUnlockAmount == 0:
+		return core.Address{}, nil, fmt.Errorf("vault initial unlock amount must be greater than zero")
+	case args.InitialUnlockAmount > args.TotalAmount:
+		return core.Address{}, nil, fmt.Errorf("vault initial unlock amount (%d) exceeds total amount (%d)",
+			args.InitialUnlockAmount, args.TotalAmount)
+	case !args.VestingStart.Before(args.VestingEnd):
+		return core.Address{}, nil, fmt.Errorf("vesting start (%d) must precede vesting end (%d)",
+			args.VestingStart, args.VestingEnd)
+	}
+	encoded, err := encodeSpawnArgs(args)
+	if err != nil {
+		return core.Address{}, nil, err
+	}
+	return core.ComputePrincipal(vault.TemplateAddress, args), encoded, nil
+}
@@ -0,0 +1,248 @@
+package wallet
+
+import (
+	"bytes"
+	"crypto/ed25519"
+	"fmt"
+	"testing"
+
+	"github.com/spacemeshos/economics/constants"
+	"github.com/spacemeshos/go-scale"
+	"github.com/spacemeshos/go-spacemesh/common/types"
+	"github.com/spacemeshos/go-spacemesh/genvm/core"
+	"github.com/spacemeshos/go-spacemesh/genvm/templates/multisig"
+	"github.com/spacemeshos/go-spacemesh/genvm/templates/vault"
+	walletTemplate "github.com/spacemeshos/go-spacemesh/genvm/templates/wallet"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSpawnSingleSig(t *testing.T) {
+	master, err := NewMasterKeyPair(goodSeed)
+	require.NoError(t, err)
+	accts, err := accountsFromMaster(master, goodSeed, 1)
+	require.NoError(t, err)
+
+	var pubkey core.PublicKey
+	copy(pubkey[:], accts[0].Public)
+	principal, encoded, err := SpawnSingleSig(pubkey)
+	require.NoError(t, err)
+	require.Equal(t, PubkeyToAddress(accts[0].Public, types.NetworkHRP()), principal.String())
+
+	var args walletTemplate.SpawnArguments
+	_, err = args.DecodeScale(scale.NewDecoder(bytes.NewReader(encoded)))
+	require.NoError(t, err)
+	require.Equal(t, pubkey, args.PublicKey)
+
+	_, _, err = SpawnSingleSig(core.PublicKey{})
+	require.Error(t, err)
+}
+
+func testPubkeys(t *testing.T, n int) []core.PublicKey {
+	master, err := NewMasterKeyPair(goodSeed)
+	require.NoError(t, err)
+	accts, err := accountsFromMaster(master, goodSeed, n)
+	require.NoError(t, err)
+	keys := make([]core.PublicKey, n)
+	for i, acct := range accts {
+		copy(keys[i][:], acct.Public)
+	}
+	return keys
+}
+
+func TestSpawnMultiSig(t *testing.T) {
+	keys := testPubkeys(t, 3)
+	principal, encoded, err := SpawnMultiSig(2, keys)
+	require.NoError(t, err)
+
+	var args multisig.SpawnArguments
+	_, err = args.DecodeScale(scale.NewDecoder(bytes.NewReader(encoded)))
+	require.NoError(t, err)
+	require.EqualValues(t, 2, args.Required)
+	require.Equal(t, SortPublicKeys(keys), args.PublicKeys)
+	require.Equal(t, core.ComputePrincipal(multisig.TemplateAddress, &args), principal)
+
+	// the threshold is part of the principal
+	other, _, err := SpawnMultiSig(3, keys)
+	require.NoError(t, err)
+	require.NotEqual(t, principal, other)
+
+	_, _, err = SpawnMultiSig(1, nil)
+	require.Error(t, err)
+}
+
+func TestSpawnMultiSigThreshold(t *testing.T) {
+	keys := testPubkeys(t, MaxMultiSigKeys+1)
+
+	_, _, err := SpawnMultiSig(0, keys[:3])
+	require.ErrorIs(t, err, ErrInvalidThreshold)
+	require.ErrorContains(t, err, "threshold 0, must be between 1 and 3")
+	_, _, err = SpawnMultiSig(4, keys[:3])
+	require.ErrorIs(t, err, ErrInvalidThreshold)
+	require.ErrorContains(t, err, "threshold 4, must be between 1 and 3")
+	_, _, err = SpawnVesting(4, keys[:3])
+	require.ErrorIs(t, err, ErrInvalidThreshold)
+
+	_, _, err = SpawnMultiSig(MaxMultiSigKeys, keys[:MaxMultiSigKeys])
+	require.NoError(t, err)
+	_, _, err = SpawnMultiSig(2, keys)
+	require.ErrorIs(t, err, ErrTooManyPublicKeys)
+	require.ErrorContains(t, err, fmt.Sprintf("got %d, a multisig account can have at most %d",
+		MaxMultiSigKeys+1, MaxMultiSigKeys))
+}
+
+func TestMultiSigThresholdBounds(t *testing.T) {
+	for _, c := range []struct{ required, keys int }{
+		{1, 1},
+		{1, MaxMultiSigKeys},
+		{MaxMultiSigKeys, MaxMultiSigKeys},
+	} {
+		m, err := MultiSigThreshold(c.required, c.keys)
+		require.NoError(t, err, c)
+		require.EqualValues(t, c.required, m, c)
+	}
+
+	for _, c := range []struct{ required, keys int }{
+		{0, 1},
+		{-1, 3},
+		{2, 1},
+		{MaxMultiSigKeys + 1, MaxMultiSigKeys},
+		// would wrap around to a valid threshold as a uint8
+		{256, MaxMultiSigKeys},
+		{257, MaxMultiSigKeys},
+	} {
+		_, err := MultiSigThreshold(c.required, c.keys)
+		require.ErrorIs(t, err, ErrInvalidThreshold, c)
+	}
+
+	for _, keys := range []int{MaxMultiSigKeys + 1, 255, 256, 257} {
+		_, err := MultiSigThreshold(1, keys)
+		require.ErrorIs(t, err, ErrTooManyPublicKeys, keys)
+		require.ErrorContains(t, err, fmt.Sprintf("at most %d", MaxMultiSigKeys))
+	}
+	_, err := MultiSigThreshold(1, 0)
+	require.Error(t, err)
+
+	// a participant past the template's maximum can't sign with a ref that wraps around
+	pub, priv, err := ed25519.GenerateKey(nil)
+	require.NoError(t, err)
+	kp := &EDKeyPair{Public: PublicKey(pub), Private: PrivateKey(priv)}
+	keys := make([]core.PublicKey, 257)
+	for i := range keys[:256] {
+		keys[i][0], keys[i][1] = 1, byte(i)
+	}
+	copy(keys[256][:], pub)
+	_, err = SignMultiSigPart(kp, keys, types.Hash20{}, []byte("tx"), WithRawKeyOrder())
+	require.ErrorIs(t, err, ErrTooManyPublicKeys)
+}
+
+func TestSpawnMultiSigInvalidKeys(t *testing.T) {
+	keys := testPubkeys(t, 3)
+
+	_, _, err := SpawnMultiSig(2, []core.PublicKey{keys[0], keys[1], keys[0]})
+	require.ErrorIs(t, err, ErrDuplicatePublicKey)
+	require.ErrorContains(t, err, "index 2, same as index 0")
+	_, _, err = SpawnVesting(2, []core.PublicKey{keys[0], keys[1], keys[2], keys[1]})
+	require.ErrorIs(t, err, ErrDuplicatePublicKey)
+	require.ErrorContains(t, err, "index 3, same as index 1")
+
+	_, _, err = SpawnMultiSig(1, []core.PublicKey{keys[0], {}})
+	require.ErrorIs(t, err, ErrZeroPublicKey)
+	require.ErrorContains(t, err, "index 1")
+	_, _, err = SpawnMultiSig(1, []core.PublicKey{{}, {}})
+	require.ErrorIs(t, err, ErrZeroPublicKey)
+	require.ErrorContains(t, err, "index 0")
+}
+
+func TestDescribeMultiSigSpawn(t *testing.T) {
+	keys := testPubkeys(t, 3)
+	original := append([]core.PublicKey(nil), keys...)
+	sorted := SortPublicKeys(keys)
+	require.NotEqual(t, keys, sorted, "test keys should not already be sorted")
+	for i := 1; i < len(sorted); i++ {
+		require.Negative(t, bytes.Compare(sorted[i-1][:], sorted[i][:]))
+	}
+
+	spawn, err := DescribeMultiSigSpawn(2, keys)
+	require.NoError(t, err)
+	require.EqualValues(t, 2, spawn.Required)
+	require.Equal(t, sorted, spawn.PublicKeys)
+	principal, _, err := SpawnMultiSig(2, sorted, WithRawKeyOrder())
+	require.NoError(t, err)
+	require.Equal(t, principal, spawn.Principal)
+	require.Regexp(t, `^[0-9a-f]{4}(-[0-9a-f]{4}){7}$`, spawn.Fingerprint)
+
+	// any order of the same keys gives the same result
+	for _, order := range [][]int{{0, 1, 2}, {2, 1, 0}, {1, 0, 2}, {1, 2, 0}} {
+		reordered := make([]core.PublicKey, 0, len(keys))
+		for _, i := range order {
+			reordered = append(reordered, keys[i])
+		}
+		other, err := DescribeMultiSigSpawn(2, reordered)
+		require.NoError(t, err)
+		require.Equal(t, spawn, other)
+	}
+	require.Equal(t, original, keys, "input must not be modified")
+
+	// but not a different threshold or set of keys
+	other, err := DescribeMultiSigSpawn(3, keys)
+	require.NoError(t, err)
+	require.NotEqual(t, spawn.Principal, other.Principal)
+	require.NotEqual(t, spawn.Fingerprint, other.Fingerprint)
+	other, err = DescribeMultiSigSpawn(2, keys[:2])
+	require.NoError(t, err)
+	require.NotEqual(t, spawn.Fingerprint, other.Fingerprint)
+
+	// unless the keys are kept in the order given
+	raw, err := DescribeMultiSigSpawn(2, keys, WithRawKeyOrder())
+	require.NoError(t, err)
+	require.Equal(t, keys, raw.PublicKeys)
+	require.NotEqual(t, spawn.Principal, raw.Principal)
+	require.NotEqual(t, spawn.Fingerprint, raw.Fingerprint)
+
+	_, err = DescribeMultiSigSpawn(2, []core.PublicKey{keys[2], keys[0], keys[2]})
+	require.ErrorIs(t, err, ErrDuplicatePublicKey)
+	require.ErrorContains(t, err, "index 2, same as index 0")
+}
+
+func TestSpawnVault(t *testing.T) {
+	// 2-of-3 vesting account over the first three goodSeed accounts, owning a vault with the genesis schedule.
+	// The addresses are pinned so that any change to the encoding of the spawn arguments is caught.
+	owner, _, err := SpawnVesting(2, testPubkeys(t, 3))
+	require.NoError(t, err)
+	require.Equal(t, "sm1qqqqqqq8nv02h47d0uyczw3ruaa62grjtepl9gsclq4t4", owner.String())
+
+	valid := vault.SpawnArguments{
+		Owner:               owner,
+		TotalAmount:         1000 * constants.OneSmesh,
+		InitialUnlockAmount: 250 * constants.OneSmesh,
+		VestingStart:        types.LayerID(constants.VestStart),
+		VestingEnd:          types.LayerID(constants.VestEnd),
+	}
+	args := valid
+	principal, encoded, err := SpawnVault(&args)
+	require.NoError(t, err)
+	require.Equal(t, "sm1qqqqqq8rxcgq7gxzwg5mujcxyuvkl2mmtxkk34spr34y8", principal.String())
+
+	var decoded vault.SpawnArguments
+	_, err = decoded.DecodeScale(scale.NewDecoder(bytes.NewReader(encoded)))
+	require.NoError(t, err)
+	require.Equal(t, valid, decoded)
+
+	testCases := map[string]func(a *vault.SpawnArguments){
+		"no owner":        func(a *vault.SpawnArguments) { a.Owner = core.Address{} },
+		"zero total":      func(a *vault.SpawnArguments) { a.TotalAmount = 0 },
+		"zero initial":    func(a *vault.SpawnArguments) { a.InitialUnlockAmount = 0 },
+		"initial > total": func(a *vault.SpawnArguments) { a.InitialUnlockAmount = a.TotalAmount + 1 },
+		"start == end":    func(a *vault.SpawnArguments) { a.VestingEnd = a.VestingStart },
+		"start after end": func(a *vault.SpawnArguments) { a.VestingStart, a.VestingEnd = a.VestingEnd, a.VestingStart },
+	}
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			args := valid
+			tc(&args)
+			_, encoded, err := SpawnVault(&args)
+			require.Error(t, err)
+			require.Nil(t, encoded)
+		})
+	}
+}
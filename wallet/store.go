@@ -4,20 +4,31 @@ import (
 	"bytes"
 	"crypto/aes"
 	"crypto/cipher"
-	"crypto/hmac"
 	"crypto/rand"
 	"crypto/sha512"
 	"encoding/json"
 	"fmt"
 	"io"
 	"log"
+	"sort"
 
 	"github.com/spf13/cobra"
 	"github.com/xdg-go/pbkdf2"
+	"golang.org/x/crypto/argon2"
+	"golang.org/x/crypto/scrypt"
+
+	"github.com/spacemeshos/smcli/common"
 )
 
 const EncKeyLen = 32
 
+// Supported key derivation functions, as they appear in the wallet file.
+const (
+	KDFPbkdf2 = "PBKDF2"
+	KDFScrypt = "scrypt"
+	KDFArgon2 = "argon2id"
+)
+
 // https://cheatsheetseries.owasp.org/cheatsheets/Password_Storage_Cheat_Sheet.html#pbkdf2
 const (
 	Pbkdf2Iterations   = 210000
@@ -25,18 +36,76 @@ const (
 	Pbkdf2SaltBytesLen = 16
 )
 
+// https://cheatsheetseries.owasp.org/cheatsheets/Password_Storage_Cheat_Sheet.html#scrypt
+const (
+	ScryptN = 1 << 17
+	ScryptR = 8
+	ScryptP = 1
+)
+
+// Argon2id defaults take about a second on a modern laptop. Memory is in KiB.
+// https://cheatsheetseries.owasp.org/cheatsheets/Password_Storage_Cheat_Sheet.html#argon2id
+const (
+	Argon2Time    = 3
+	Argon2Memory  = 256 * 1024
+	Argon2Threads = 4
+)
+
 var Pbkdf2HashFunc = sha512.New
 
+// Pbkdf2HashName names Pbkdf2HashFunc in the KDF params of wallet files. Files written by older versions of smcli
+// label it "SHA-256" even though they were derived using SHA-512 too: the label isn't used when decrypting, so
+// they open all the same.
+const Pbkdf2HashName = "SHA-512"
+
+// ErrWrongPassword is returned when a wallet file can't be decrypted. It's an authentication error (common.ErrAuth).
+var ErrWrongPassword = common.NewError(common.ErrAuth, "error decrypting wallet file: wrong password or corrupted file")
+
+// ErrEncryptionCheck is returned if newly encrypted wallet secrets don't decrypt back to the originals. The
+// wallet file would be unusable, so it must not be written.
+var ErrEncryptionCheck = fmt.Errorf("encryption self-check failed: the encrypted wallet can't be decrypted")
+
 type (
 	WalletKeyOpt func(*WalletKey)
 	WalletKey    struct {
 		key        []byte
 		pw         []byte
 		salt       []byte
+		kdf        string
 		iterations int
+		scryptN    int
+		scryptR    int
+		scryptP    int
+
+		argon2Time    uint32
+		argon2Memory  uint32
+		argon2Threads uint8
+
+		// derivedWith is what key was derived with, so that it's only derived again if that changes.
+		derivedWith keyDerivation
 	}
 )
 
+// keyDerivation is the KDF, salt, and KDF params that a wallet key is derived with. Deriving the key is by far
+// the slowest part of opening and saving a wallet, on purpose, so a WalletKey keeps the key for as long as these
+// don't change: opening a wallet and saving it again using the same WalletKey, as most commands do, runs the KDF
+// only once.
+type keyDerivation struct {
+	kdf        string
+	salt       string
+	iterations int
+	scryptN    int
+	scryptR    int
+	scryptP    int
+
+	argon2Time    uint32
+	argon2Memory  uint32
+	argon2Threads uint8
+}
+
+// pbkdf2Key derives PBKDF2 keys, see deriveKey.
+var pbkdf2Key = pbkdf2.Key
+
 func NewKey(opts ...WalletKeyOpt) WalletKey {
 	w := &WalletKey{}
 	for _, opt := range opts {
@@ -69,7 +138,7 @@ func WithSalt(salt [Pbkdf2SaltBytesLen]byte) WalletKeyOpt {
 
 		// if password is set, set the key as well
 		if k.pw != nil {
-			WithPbkdf2Password(k.pw)(k)
+			cobra.CheckErr(k.deriveKey())
 		}
 	}
 }
@@ -79,8 +148,30 @@ func WithIterations(iterations int) WalletKeyOpt {
 		k.iterations = iterations
 		if k.key != nil {
 			// regenerate
-			k.key = nil
-			WithPbkdf2Password(k.pw)(k)
+			cobra.CheckErr(k.deriveKey())
+		}
+	}
+}
+
+// WithScryptParams sets the scrypt cost parameters. The defaults are ScryptN, ScryptR, and ScryptP.
+func WithScryptParams(n, r, p int) WalletKeyOpt {
+	return func(k *WalletKey) {
+		k.scryptN, k.scryptR, k.scryptP = n, r, p
+		if k.key != nil {
+			// regenerate
+			cobra.CheckErr(k.deriveKey())
+		}
+	}
+}
+
+// WithArgon2Params sets the argon2id cost parameters: the number of passes, the memory in KiB, and the
+// parallelism. The defaults are Argon2Time, Argon2Memory, and Argon2Threads.
+func WithArgon2Params(time, memory uint32, threads uint8) WalletKeyOpt {
+	return func(k *WalletKey) {
+		k.argon2Time, k.argon2Memory, k.argon2Threads = time, memory, threads
+		if k.key != nil {
+			// regenerate
+			cobra.CheckErr(k.deriveKey())
 		}
 	}
 }
@@ -103,6 +194,20 @@ func WithPasswordOnly(password []byte) WalletKeyOpt {
 }
 
 func WithPbkdf2Password(password []byte) WalletKeyOpt {
+	return withKDFPassword(KDFPbkdf2, password)
+}
+
+// WithScryptPassword derives the key using scrypt, which is more memory-hard than PBKDF2.
+func WithScryptPassword(password []byte) WalletKeyOpt {
+	return withKDFPassword(KDFScrypt, password)
+}
+
+// WithArgon2Password derives the key using argon2id, which is memory-hard and resistant to GPU cracking.
+func WithArgon2Password(password []byte) WalletKeyOpt {
+	return withKDFPassword(KDFArgon2, password)
+}
+
+func withKDFPassword(kdf string, password []byte) WalletKeyOpt {
 	return func(k *WalletKey) {
 		if k.salt == nil {
 			log.Fatalf("Salt must be set.")
@@ -110,19 +215,93 @@ func WithPbkdf2Password(password []byte) WalletKeyOpt {
 		if k.key != nil {
 			log.Fatalf("Can only generate key once.")
 		}
-		iterations := k.iterations
-		if iterations == 0 {
-			iterations = Pbkdf2Iterations
-		}
-		k.key = pbkdf2.Key(
-			password,
+		k.kdf = kdf
+		k.pw = password
+		cobra.CheckErr(k.deriveKey())
+	}
+}
+
+func (k *WalletKey) pbkdf2Iterations() int {
+	if k.iterations == 0 {
+		return Pbkdf2Iterations
+	}
+	return k.iterations
+}
+
+func (k *WalletKey) scryptParams() (n, r, p int) {
+	n, r, p = ScryptN, ScryptR, ScryptP
+	if k.scryptN != 0 {
+		n = k.scryptN
+	}
+	if k.scryptR != 0 {
+		r = k.scryptR
+	}
+	if k.scryptP != 0 {
+		p = k.scryptP
+	}
+	return
+}
+
+func (k *WalletKey) argon2Params() (time, memory uint32, threads uint8) {
+	time, memory, threads = Argon2Time, Argon2Memory, Argon2Threads
+	if k.argon2Time != 0 {
+		time = k.argon2Time
+	}
+	if k.argon2Memory != 0 {
+		memory = k.argon2Memory
+	}
+	if k.argon2Threads != 0 {
+		threads = k.argon2Threads
+	}
+	return
+}
+
+// derivation returns what the key is derived with given the current KDF and params.
+func (k *WalletKey) derivation() keyDerivation {
+	d := keyDerivation{kdf: k.kdf, salt: string(k.salt)}
+	switch k.kdf {
+	case KDFPbkdf2, "":
+		d.kdf = KDFPbkdf2
+		d.iterations = k.pbkdf2Iterations()
+	case KDFScrypt:
+		d.scryptN, d.scryptR, d.scryptP = k.scryptParams()
+	case KDFArgon2:
+		d.argon2Time, d.argon2Memory, d.argon2Threads = k.argon2Params()
+	}
+	return d
+}
+
+// deriveKey (re)generates the encryption key from the password, salt, and KDF params, unless it was already
+// derived with the same ones.
+func (k *WalletKey) deriveKey() (err error) {
+	d := k.derivation()
+	if k.key != nil && k.derivedWith == d {
+		return nil
+	}
+	task := common.Progress().Start("Deriving the wallet key", 0)
+	defer task.Done()
+	switch k.kdf {
+	case KDFPbkdf2, "":
+		k.key = pbkdf2Key(
+			k.pw,
 			k.salt,
-			iterations,
+			k.pbkdf2Iterations(),
 			EncKeyLen,
 			Pbkdf2HashFunc,
 		)
-		k.pw = password
+	case KDFScrypt:
+		n, r, p := k.scryptParams()
+		k.key, err = scrypt.Key(k.pw, k.salt, n, r, p, EncKeyLen)
+	case KDFArgon2:
+		time, memory, threads := k.argon2Params()
+		k.key = argon2.IDKey(k.pw, k.salt, time, memory, threads, EncKeyLen)
+	default:
+		err = fmt.Errorf("unsupported key derivation function %q", k.kdf)
 	}
+	if err == nil {
+		k.derivedWith = d
+	}
+	return
 }
 
 // https://cheatsheetseries.owasp.org/cheatsheets/Secrets_Management_Cheat_Sheet.html#71-encryption-types-to-use
@@ -139,8 +318,10 @@ func (k *WalletKey) encrypt(plaintext []byte) (ciphertext []byte, nonce []byte,
 	if err != nil {
 		return
 	}
-	hash := hmac.New(sha512.New, k.key)
-	nonce = hash.Sum(plaintext)[:aesgcm.NonceSize()]
+	nonce = make([]byte, aesgcm.NonceSize())
+	if _, err = rand.Read(nonce); err != nil {
+		return
+	}
 
 	ciphertext = aesgcm.Seal(nil, nonce, plaintext, nil)
 	return
@@ -160,85 +341,263 @@ func (k *WalletKey) decrypt(ciphertext []byte, nonce []byte) (plaintext []byte,
 	return
 }
 
-func (k *WalletKey) Open(file io.Reader, debugMode bool) (*Wallet, error) {
+// Open reads and decrypts a wallet file. The plaintext secrets are never logged. The key is only derived if k
+// doesn't already hold one derived with the salt and KDF params of the file, see keyDerivation.
+func (k *WalletKey) Open(file io.Reader) (*Wallet, error) {
 	ew := &EncryptedWalletFile{}
 	if err := json.NewDecoder(file).Decode(ew); err != nil {
 		return nil, err
 	}
 
-	// set the salt, and warn if it's different
-	if k.salt == nil {
-		var salt [Pbkdf2SaltBytesLen]byte
-		copy(salt[:], ew.Secrets.KDFParams.Salt)
-		if !bytes.Equal(salt[:], ew.Secrets.KDFParams.Salt) {
-			return nil, fmt.Errorf("error reading encrypted wallet file salt, check salt length")
-		}
-		WithSalt(salt)(k)
-	} else if !bytes.Equal(ew.Secrets.KDFParams.Salt, k.salt) {
-		log.Printf("wallet key salt does not match wallet file salt")
-	}
-	WithIterations(ew.Secrets.KDFParams.Iterations)(k)
-	if ew.Secrets.KDFParams.Iterations < Pbkdf2Iterations {
-		log.Println("Warning: wallet file iterations count lower than recommended")
-	}
-
-	nonce := ew.Secrets.CipherParams.IV
-	encWallet := ew.Secrets.CipherText
-
 	// TODO: before decrypting, check that other meta params match
-	plaintext, err := k.decrypt(encWallet, nonce)
+	plaintext, err := k.decryptSecrets(ew)
 	if err != nil {
 		return nil, err
 	}
-	if debugMode {
-		log.Println("Decrypted JSON data:", string(plaintext))
+	defer wipe(plaintext)
+	if ew.Secrets.KDF == KDFPbkdf2 && ew.Secrets.KDFParams.Iterations < Pbkdf2Iterations {
+		common.Logger().Warn("wallet file iterations count lower than recommended",
+			"iterations", ew.Secrets.KDFParams.Iterations, "recommended", Pbkdf2Iterations)
 	}
 	secrets := &walletSecrets{}
 	if err := json.Unmarshal(plaintext, secrets); err != nil {
 		return nil, err
 	}
+	if err := checkMultiSigs(secrets.MultiSigs); err != nil {
+		return nil, err
+	}
 
 	// we have everything we need, construct and return the wallet.
 	w := &Wallet{
 		Meta:    ew.Meta,
 		Secrets: *secrets,
 	}
+	w.normalizeTimestamps()
+	common.Logger().Debug("opened wallet", "wallet", w)
 	return w, nil
 }
 
+// decryptSecrets derives the key using the KDF and params stored in the wallet file, then decrypts and returns
+// the plaintext secrets.
+func (k *WalletKey) decryptSecrets(ew *EncryptedWalletFile) ([]byte, error) {
+	if err := ew.migrate(); err != nil {
+		return nil, err
+	}
+
+	// set the salt, and warn if it's different
+	if k.salt == nil {
+		if len(ew.Secrets.KDFParams.Salt) != Pbkdf2SaltBytesLen {
+			return nil, fmt.Errorf("error reading encrypted wallet file salt, check salt length")
+		}
+		k.salt = ew.Secrets.KDFParams.Salt
+	} else if !bytes.Equal(ew.Secrets.KDFParams.Salt, k.salt) {
+		common.Logger().Warn("wallet key salt does not match wallet file salt")
+	}
+
+	// use the KDF and params from the file
+	params := ew.Secrets.KDFParams
+	switch ew.Secrets.KDF {
+	case KDFPbkdf2:
+		k.iterations = params.Iterations
+	case KDFScrypt:
+		k.scryptN, k.scryptR, k.scryptP = params.N, params.R, params.P
+	case KDFArgon2:
+		if params.Iterations <= 0 || params.Memory <= 0 || params.Parallelism <= 0 || params.Parallelism > 255 {
+			return nil, fmt.Errorf("invalid argon2id params in wallet file")
+		}
+		k.argon2Time, k.argon2Memory = uint32(params.Iterations), uint32(params.Memory)
+		k.argon2Threads = uint8(params.Parallelism)
+	default:
+		return nil, fmt.Errorf("unsupported key derivation function %q", ew.Secrets.KDF)
+	}
+	k.kdf = ew.Secrets.KDF
+	common.Logger().Debug("deriving wallet key", "version", ew.Meta.Version, "kdf", k.kdf,
+		"iterations", params.Iterations, "n", params.N, "r", params.R, "p", params.P, "memory", params.Memory)
+	if err := k.deriveKey(); err != nil {
+		return nil, err
+	}
+	plaintext, err := k.decrypt(ew.Secrets.CipherText, ew.Secrets.CipherParams.IV)
+	if err != nil {
+		// AES-GCM doesn't distinguish between a wrong key and a tampered ciphertext
+		common.Logger().Debug("error decrypting wallet secrets, the password is wrong or the file was modified",
+			"cipher", ew.Secrets.Cipher, "error", err)
+		return nil, ErrWrongPassword
+	}
+	return plaintext, nil
+}
+
+// Export encrypts the wallet using k and writes it to file as JSON. The output only depends on the wallet, apart
+// from the salt, IV, and ciphertext that are random for every export: fields are always written in the same order
+// and accounts in order of account index, so that exporting an unchanged wallet again yields the same file.
 func (k *WalletKey) Export(file io.Writer, w *Wallet) (err error) {
 	// encrypt the secrets
-	plaintext, err := json.Marshal(w.Secrets)
+	secrets := w.Secrets
+	secrets.Accounts = sortedAccounts(secrets.Accounts)
+	plaintext, err := json.Marshal(secrets)
 	if err != nil {
 		return
 	}
+	defer wipe(plaintext)
+	ew := &EncryptedWalletFile{Meta: w.Meta}
+	if err = k.encryptSecrets(ew, plaintext); err != nil {
+		return
+	}
+	return json.NewEncoder(file).Encode(ew)
+}
+
+// sortedAccounts returns a copy of accounts sorted by account index, see accountIndex. A missing account is
+// sorted by its position, and accounts with the same index, which Verify reports, keep their order.
+func sortedAccounts(accounts []*EDKeyPair) []*EDKeyPair {
+	if accounts == nil {
+		return nil
+	}
+	type indexed struct {
+		index uint32
+		acct  *EDKeyPair
+	}
+	order := make([]indexed, len(accounts))
+	for i, acct := range accounts {
+		order[i] = indexed{uint32(i), acct}
+		if acct != nil {
+			order[i].index = accountIndex(acct, i)
+		}
+	}
+	sort.SliceStable(order, func(i, j int) bool { return order[i].index < order[j].index })
+	sorted := make([]*EDKeyPair, len(order))
+	for i, o := range order {
+		sorted[i] = o.acct
+	}
+	return sorted
+}
+
+// encryptSecrets encrypts the plaintext secrets and stores them in the wallet file along with the cipher
+// and KDF params, once it's checked that they can be decrypted again.
+func (k *WalletKey) encryptSecrets(ew *EncryptedWalletFile, plaintext []byte) error {
 	ciphertext, nonce, err := k.encrypt(plaintext)
 	if err != nil {
-		return
+		return err
 	}
-	ew := &EncryptedWalletFile{
-		Meta: w.Meta,
-		Secrets: walletSecretsEncrypted{
-			Cipher:     "AES-GCM",
-			CipherText: ciphertext,
-			CipherParams: struct {
-				IV hexEncodedCiphertext `json:"iv"`
-			}{
-				IV: nonce,
-			},
-			KDF: "PBKDF2",
-			KDFParams: struct {
-				DKLen      int                  `json:"dklen"`
-				Hash       string               `json:"hash"`
-				Salt       hexEncodedCiphertext `json:"salt"`
-				Iterations int                  `json:"iterations"`
-			}{
-				DKLen:      Pbkdf2Dklen,
-				Hash:       "SHA-256",
-				Salt:       k.salt,
-				Iterations: Pbkdf2Iterations,
-			},
-		},
+	secrets := walletSecretsEncrypted{
+		Cipher:       "AES-GCM",
+		CipherText:   ciphertext,
+		CipherParams: cipherParams{IV: nonce},
+		KDFParams:    kdfParams{Salt: k.salt},
 	}
-	return json.NewEncoder(file).Encode(ew)
+	switch k.kdf {
+	case KDFScrypt:
+		secrets.KDF = KDFScrypt
+		secrets.KDFParams.DKLen = EncKeyLen
+		secrets.KDFParams.N, secrets.KDFParams.R, secrets.KDFParams.P = k.scryptParams()
+	case KDFArgon2:
+		time, memory, threads := k.argon2Params()
+		secrets.KDF = KDFArgon2
+		secrets.KDFParams.DKLen = EncKeyLen
+		secrets.KDFParams.Iterations = int(time)
+		secrets.KDFParams.Memory = int(memory)
+		secrets.KDFParams.Parallelism = int(threads)
+	default:
+		secrets.KDF = KDFPbkdf2
+		secrets.KDFParams.DKLen = Pbkdf2Dklen
+		secrets.KDFParams.Hash = Pbkdf2HashName
+		secrets.KDFParams.Iterations = k.pbkdf2Iterations()
+	}
+	if err := checkEncryptedSecrets(secrets, k.pw, plaintext, k); err != nil {
+		return err
+	}
+	ew.Secrets = secrets
+	return nil
+}
+
+// checkEncryptedSecrets decrypts newly encrypted secrets the way Open would, using only the password and the
+// params stored alongside them, and checks that they match the plaintext: a bug that produced a file that can't
+// be decrypted would otherwise only show once the keys are needed. If derived, the key that encrypted them, is
+// given, its key is reused rather than derived again as long as the stored params are the ones it was derived
+// with.
+func checkEncryptedSecrets(secrets walletSecretsEncrypted, password, plaintext []byte, derived *WalletKey) error {
+	k := NewKey(WithPasswordOnly(password))
+	if derived != nil && derived.key != nil && bytes.Equal(derived.pw, password) {
+		k.key = append([]byte(nil), derived.key...)
+		k.derivedWith = derived.derivedWith
+	}
+	ew := &EncryptedWalletFile{Meta: walletMetadata{Version: WalletVersion}, Secrets: secrets}
+	decrypted, err := k.decryptSecrets(ew)
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrEncryptionCheck, err)
+	}
+	defer wipe(decrypted)
+	wipe(k.key)
+	if !bytes.Equal(decrypted, plaintext) {
+		return fmt.Errorf("%w: the decrypted secrets don't match", ErrEncryptionCheck)
+	}
+	return nil
+}
+
+// ChangePassword re-encrypts the wallet secrets under newPassword using a freshly generated salt and IV.
+// The metadata, the KDF and its params, and the secrets themselves are preserved as-is.
+func (ew *EncryptedWalletFile) ChangePassword(oldPassword, newPassword []byte) error {
+	oldKey := NewKey(WithPasswordOnly(oldPassword))
+	plaintext, err := oldKey.decryptSecrets(ew)
+	if err != nil {
+		return err
+	}
+	defer wipe(plaintext)
+
+	newKey := &WalletKey{
+		kdf:        oldKey.kdf,
+		iterations: oldKey.iterations,
+		scryptN:    oldKey.scryptN,
+		scryptR:    oldKey.scryptR,
+		scryptP:    oldKey.scryptP,
+
+		argon2Time:    oldKey.argon2Time,
+		argon2Memory:  oldKey.argon2Memory,
+		argon2Threads: oldKey.argon2Threads,
+	}
+	WithRandomSalt()(newKey)
+	withKDFPassword(oldKey.kdf, newPassword)(newKey)
+	return newKey.encryptSecrets(ew, plaintext)
+}
+
+// NeedsRekey reports whether the wallet file is encrypted using PBKDF2 with fewer than the given number of
+// iterations, i.e., whether Rekey would strengthen it. Files encrypted using scrypt or argon2id never need it.
+func (ew *EncryptedWalletFile) NeedsRekey(iterations int) bool {
+	return ew.Secrets.KDF == KDFPbkdf2 && ew.Secrets.KDFParams.Iterations < iterations
+}
+
+// Rekey re-encrypts the wallet secrets of a PBKDF2-encrypted wallet file using the given number of iterations,
+// with the same password and a freshly generated salt and IV. The metadata and the secrets are preserved as-is,
+// except that the file is upgraded to WalletVersion.
+// Use it to keep up with the recommended iteration count, see Pbkdf2Iterations.
+func (ew *EncryptedWalletFile) Rekey(password []byte, iterations int) error {
+	if ew.Secrets.KDF != KDFPbkdf2 {
+		return fmt.Errorf("can only rekey PBKDF2-encrypted wallet files, this one uses %s", ew.Secrets.KDF)
+	}
+	if iterations <= 0 {
+		return fmt.Errorf("invalid number of iterations %d", iterations)
+	}
+	// decrypt a copy, which gets migrated, so the file is left untouched on error
+	migrated := *ew
+	oldKey := NewKey(WithPasswordOnly(password))
+	plaintext, err := oldKey.decryptSecrets(&migrated)
+	if err != nil {
+		return err
+	}
+	defer wipe(plaintext)
+
+	ew.Meta = migrated.Meta
+	newKey := NewKey(WithRandomSalt(), WithIterations(iterations), WithPbkdf2Password(password))
+	return newKey.encryptSecrets(ew, plaintext)
+}
+
+// Rename sets the display name of the wallet file, see SetDisplayName. The display name is part of the
+// unencrypted metadata so no password is needed, and everything else in the file is left untouched.
+func (ew *EncryptedWalletFile) Rename(name string) error {
+	if err := checkDisplayName(name); err != nil {
+		return err
+	}
+	if err := ew.migrate(); err != nil {
+		return err
+	}
+	ew.Meta.DisplayName = name
+	return nil
 }
@@ -1,14 +1,19 @@
 package wallet
 
 import (
+	"bytes"
 	"encoding/hex"
+	"encoding/json"
 	"fmt"
+	"hash"
 	"io"
 	"log"
 	"os"
 	"testing"
 
+	"github.com/spacemeshos/go-spacemesh/common/types"
 	"github.com/stretchr/testify/require"
+	"github.com/xdg-go/pbkdf2"
 )
 
 func TestStoreAndRetrieveWalletToFromFile(t *testing.T) {
@@ -36,7 +41,7 @@ func TestStoreAndRetrieveWalletToFromFile(t *testing.T) {
 	require.NoError(t, err)
 
 	file.Seek(0, io.SeekStart)
-	w2, err := wKey.Open(file, false)
+	w2, err := wKey.Open(file)
 	require.NoError(t, err)
 	require.Equal(t, w.Secrets.Accounts, w2.Secrets.Accounts)
 	require.Equal(t, w.Secrets.Mnemonic, w2.Secrets.Mnemonic)
@@ -47,7 +52,7 @@ func TestStoreAndRetrieveWalletToFromFile(t *testing.T) {
 		WithPbkdf2Password(password),
 	)
 	file.Seek(0, io.SeekStart)
-	w2, err = wKey.Open(file, false)
+	w2, err = wKey.Open(file)
 	require.NoError(t, err)
 	require.Equal(t, w.Secrets.Accounts, w2.Secrets.Accounts)
 	require.Equal(t, w.Secrets.Mnemonic, w2.Secrets.Mnemonic)
@@ -62,7 +67,7 @@ func TestStoreAndRetrieveWalletToFromFile(t *testing.T) {
 		WithPbkdf2Password(password2),
 	)
 	file.Seek(0, io.SeekStart)
-	_, err = wKey.Open(file, false)
+	_, err = wKey.Open(file)
 	require.Error(t, err)
 
 	// right password, wrong salt
@@ -73,7 +78,7 @@ func TestStoreAndRetrieveWalletToFromFile(t *testing.T) {
 		WithPbkdf2Password(password),
 	)
 	file.Seek(0, io.SeekStart)
-	_, err = wKey.Open(file, false)
+	_, err = wKey.Open(file)
 	require.Error(t, err)
 
 	// both wrong
@@ -82,6 +87,548 @@ func TestStoreAndRetrieveWalletToFromFile(t *testing.T) {
 		WithPbkdf2Password(password2),
 	)
 	file.Seek(0, io.SeekStart)
-	_, err = wKey.Open(file, false)
+	_, err = wKey.Open(file)
 	require.Error(t, err)
 }
+
+func TestStoreAndRetrieveWithKDF(t *testing.T) {
+	password := []byte("password")
+	w, err := NewMultiWalletRandomMnemonic(2)
+	require.NoError(t, err)
+
+	testCases := []struct {
+		kdf  string
+		opts []WalletKeyOpt
+	}{
+		{KDFPbkdf2, []WalletKeyOpt{WithRandomSalt(), WithPbkdf2Password(password)}},
+		{KDFScrypt, []WalletKeyOpt{WithRandomSalt(), WithScryptParams(1<<10, 8, 1), WithScryptPassword(password)}},
+		{KDFArgon2, []WalletKeyOpt{WithRandomSalt(), WithArgon2Params(1, 1024, 2), WithArgon2Password(password)}},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.kdf, func(t *testing.T) {
+			buf := &bytes.Buffer{}
+			wKey := NewKey(tc.opts...)
+			require.NoError(t, wKey.Export(buf, w))
+
+			// the KDF and its params are recorded in the file
+			ew := &EncryptedWalletFile{}
+			require.NoError(t, json.Unmarshal(buf.Bytes(), ew))
+			require.Equal(t, tc.kdf, ew.Secrets.KDF)
+			switch tc.kdf {
+			case KDFScrypt:
+				require.Equal(t, 1<<10, ew.Secrets.KDFParams.N)
+				require.Equal(t, 8, ew.Secrets.KDFParams.R)
+				require.Equal(t, 1, ew.Secrets.KDFParams.P)
+				require.Zero(t, ew.Secrets.KDFParams.Iterations)
+			case KDFArgon2:
+				require.Equal(t, 1, ew.Secrets.KDFParams.Iterations)
+				require.Equal(t, 1024, ew.Secrets.KDFParams.Memory)
+				require.Equal(t, 2, ew.Secrets.KDFParams.Parallelism)
+				require.Zero(t, ew.Secrets.KDFParams.N)
+			default:
+				require.Equal(t, Pbkdf2Iterations, ew.Secrets.KDFParams.Iterations)
+				require.Zero(t, ew.Secrets.KDFParams.N)
+			}
+
+			// decryption dispatches on the KDF in the file
+			wKey = NewKey(WithPasswordOnly(password))
+			w2, err := wKey.Open(bytes.NewReader(buf.Bytes()))
+			require.NoError(t, err)
+			require.Equal(t, w.Secrets.Accounts, w2.Secrets.Accounts)
+			require.Equal(t, w.Secrets.Mnemonic, w2.Secrets.Mnemonic)
+
+			// wrong password
+			wKey = NewKey(WithPasswordOnly([]byte("wrong")))
+			_, err = wKey.Open(bytes.NewReader(buf.Bytes()))
+			require.Error(t, err)
+		})
+	}
+}
+
+func TestArgon2WrongParams(t *testing.T) {
+	password := []byte("password")
+	w, err := NewMultiWalletRandomMnemonic(1)
+	require.NoError(t, err)
+	buf := &bytes.Buffer{}
+	wKey := NewKey(WithRandomSalt(), WithArgon2Params(1, 1024, 1), WithArgon2Password(password))
+	require.NoError(t, wKey.Export(buf, w))
+
+	testCases := map[string]func(p *kdfParams){
+		"time":        func(p *kdfParams) { p.Iterations++ },
+		"memory":      func(p *kdfParams) { p.Memory *= 2 },
+		"parallelism": func(p *kdfParams) { p.Parallelism++ },
+	}
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			ew := &EncryptedWalletFile{}
+			require.NoError(t, json.Unmarshal(buf.Bytes(), ew))
+			tc(&ew.Secrets.KDFParams)
+			_, err := decryptWithPassword(password, ew)
+			require.ErrorIs(t, err, ErrWrongPassword)
+		})
+	}
+
+	// missing params are rejected outright
+	ew := &EncryptedWalletFile{}
+	require.NoError(t, json.Unmarshal(buf.Bytes(), ew))
+	ew.Secrets.KDFParams.Memory = 0
+	_, err = decryptWithPassword(password, ew)
+	require.ErrorContains(t, err, "invalid argon2id params")
+}
+
+func TestOpenUnknownKDF(t *testing.T) {
+	password := []byte("password")
+	w, err := NewMultiWalletRandomMnemonic(1)
+	require.NoError(t, err)
+	buf := &bytes.Buffer{}
+	wKey := NewKey(WithRandomSalt(), WithPbkdf2Password(password))
+	require.NoError(t, wKey.Export(buf, w))
+
+	ew := &EncryptedWalletFile{}
+	require.NoError(t, json.Unmarshal(buf.Bytes(), ew))
+	ew.Secrets.KDF = "bcrypt"
+	data, err := json.Marshal(ew)
+	require.NoError(t, err)
+
+	wKey = NewKey(WithPasswordOnly(password))
+	_, err = wKey.Open(bytes.NewReader(data))
+	require.EqualError(t, err, `unsupported key derivation function "bcrypt"`)
+}
+
+func decryptWithPassword(password []byte, ew *EncryptedWalletFile) ([]byte, error) {
+	wKey := NewKey(WithPasswordOnly(password))
+	return wKey.decryptSecrets(ew)
+}
+
+func TestChangePassword(t *testing.T) {
+	oldPassword := []byte("old password")
+	newPassword := []byte("new password")
+	w, err := NewMultiWalletRandomMnemonic(3)
+	require.NoError(t, err)
+	w.Meta.DisplayName = "Savings"
+	w.Meta.GenesisID = "genesis"
+
+	for _, kdf := range []string{KDFPbkdf2, KDFScrypt, KDFArgon2} {
+		t.Run(kdf, func(t *testing.T) {
+			buf := &bytes.Buffer{}
+			wKey := NewKey(WithRandomSalt(), WithScryptParams(1<<10, 8, 1), WithArgon2Params(1, 1024, 1),
+				withKDFPassword(kdf, oldPassword))
+			require.NoError(t, wKey.Export(buf, w))
+			ew := &EncryptedWalletFile{}
+			require.NoError(t, json.Unmarshal(buf.Bytes(), ew))
+			before := *ew
+
+			plaintextBefore, err := decryptWithPassword(oldPassword, ew)
+			require.NoError(t, err)
+
+			// wrong old password
+			require.Error(t, ew.ChangePassword([]byte("wrong"), newPassword))
+
+			require.NoError(t, ew.ChangePassword(oldPassword, newPassword))
+			require.Equal(t, before.Meta, ew.Meta)
+			require.Equal(t, before.Secrets.KDF, ew.Secrets.KDF)
+			require.Equal(t, before.Secrets.KDFParams.Iterations, ew.Secrets.KDFParams.Iterations)
+			require.Equal(t, before.Secrets.KDFParams.N, ew.Secrets.KDFParams.N)
+			require.NotEqual(t, before.Secrets.KDFParams.Salt, ew.Secrets.KDFParams.Salt)
+			require.NotEqual(t, before.Secrets.CipherParams.IV, ew.Secrets.CipherParams.IV)
+
+			// old password no longer works
+			_, err = decryptWithPassword(oldPassword, ew)
+			require.Error(t, err)
+
+			// new one does, and the secrets are byte-identical
+			plaintextAfter, err := decryptWithPassword(newPassword, ew)
+			require.NoError(t, err)
+			require.Equal(t, plaintextBefore, plaintextAfter)
+
+			data, err := json.Marshal(ew)
+			require.NoError(t, err)
+			wKey = NewKey(WithPasswordOnly(newPassword))
+			w2, err := wKey.Open(bytes.NewReader(data))
+			require.NoError(t, err)
+			require.Equal(t, w.Meta, w2.Meta)
+			require.Equal(t, w.Secrets.Mnemonic, w2.Secrets.Mnemonic)
+			require.Equal(t, w.Secrets.Accounts, w2.Secrets.Accounts)
+		})
+	}
+}
+
+func TestPathsSurviveRoundTrip(t *testing.T) {
+	password := []byte("password")
+	n := 5
+	w, err := NewMultiWalletRandomMnemonic(n)
+	require.NoError(t, err)
+
+	buf := &bytes.Buffer{}
+	wKey := NewKey(WithRandomSalt(), WithPbkdf2Password(password))
+	require.NoError(t, wKey.Export(buf, w))
+	wKey = NewKey(WithPasswordOnly(password))
+	w2, err := wKey.Open(buf)
+	require.NoError(t, err)
+
+	require.Equal(t, "m/44'/540'/0'/0'", w2.Secrets.MasterKeypair.Path.String())
+	for i, acct := range w2.Secrets.Accounts {
+		require.Equal(t, fmt.Sprintf("m/44'/540'/0'/0'/%d'", i), acct.Path.String())
+		require.Equal(t, w.Secrets.Accounts[i].Path, acct.Path)
+	}
+}
+
+func TestOpenFixture(t *testing.T) {
+	// testdata/wallet.json contains two accounts derived from the mnemonic in TestWalletFromGivenMnemonic,
+	// encrypted with the password "password".
+	f, err := os.Open("testdata/wallet.json")
+	require.NoError(t, err)
+	defer f.Close()
+
+	wKey := NewKey(WithPasswordOnly([]byte("password")))
+	w, err := wKey.Open(f)
+	require.NoError(t, err)
+	require.Equal(t, "Main Wallet", w.Meta.DisplayName)
+	// the fixture predates versioning and is migrated on load, along with its timestamp, which used dashes
+	// rather than colons
+	require.Equal(t, WalletVersion, w.Meta.Version)
+	require.Equal(t, "2023-07-12T10:00:00.000Z", w.Meta.Created)
+	require.Equal(t, "film theme cheese broken kingdom destroy inch ready wear inspire shove pudding", w.Mnemonic())
+	require.Equal(t, []string{
+		"sm1qqqqqqz9rf583slhn38g6q6a562ctltv9fv5w8q2gdz9k",
+		"sm1qqqqqqygmz2nnr7ush67yx7g4mmksm979m3a0xcphk3pt",
+	}, w.Addresses("sm"))
+
+	// wrong password
+	f.Seek(0, io.SeekStart)
+	wKey = NewKey(WithPasswordOnly([]byte("wrong password")))
+	_, err = wKey.Open(f)
+	require.ErrorIs(t, err, ErrWrongPassword)
+}
+
+func TestAddressesSurviveRoundTrip(t *testing.T) {
+	// the addresses previewed by a dry run must be the ones in the wallet file
+	const mnemonic = "film theme cheese broken kingdom destroy inch ready wear inspire shove pudding"
+	preview, err := NewMultiWalletFromMnemonic(mnemonic, 4, WithPassphrase("passphrase"))
+	require.NoError(t, err)
+	expected := preview.Addresses("stest")
+
+	w, err := NewMultiWalletFromMnemonic(mnemonic, 4, WithPassphrase("passphrase"))
+	require.NoError(t, err)
+	password := []byte("password")
+	buf := &bytes.Buffer{}
+	wKey := NewKey(WithRandomSalt(), WithPbkdf2Password(password))
+	require.NoError(t, wKey.Export(buf, w))
+	wKey = NewKey(WithPasswordOnly(password))
+	w2, err := wKey.Open(buf)
+	require.NoError(t, err)
+	require.Equal(t, expected, w2.Addresses("stest"))
+	require.Len(t, expected, 4)
+}
+
+func TestWalletVersion(t *testing.T) {
+	password := []byte("password")
+	w, err := NewMultiWalletRandomMnemonic(1)
+	require.NoError(t, err)
+	require.Equal(t, WalletVersion, w.Meta.Version)
+	buf := &bytes.Buffer{}
+	wKey := NewKey(WithRandomSalt(), WithPbkdf2Password(password))
+	require.NoError(t, wKey.Export(buf, w))
+
+	ew := &EncryptedWalletFile{}
+	require.NoError(t, json.Unmarshal(buf.Bytes(), ew))
+	require.Equal(t, WalletVersion, ew.Meta.Version)
+
+	// files from a newer smcli are rejected before decrypting anything
+	ew.Meta.Version = WalletVersion + 1
+	data, err := json.Marshal(ew)
+	require.NoError(t, err)
+	wKey = NewKey(WithPasswordOnly(password))
+	_, err = wKey.Open(bytes.NewReader(data))
+	require.ErrorIs(t, err, ErrUnsupportedWalletVersion)
+	require.ErrorIs(t, ew.ChangePassword(password, []byte("new password")), ErrUnsupportedWalletVersion)
+
+	// files from before multisig accounts could be stored are upgraded
+	ew.Meta.Version = 1
+	data, err = json.Marshal(ew)
+	require.NoError(t, err)
+	w, err = wKey.Open(bytes.NewReader(data))
+	require.NoError(t, err)
+	require.Equal(t, WalletVersion, w.Meta.Version)
+}
+
+func TestMigrateUnversionedFixture(t *testing.T) {
+	data, err := os.ReadFile("testdata/wallet.json")
+	require.NoError(t, err)
+	require.NotContains(t, string(data), `"version"`)
+	ew := &EncryptedWalletFile{}
+	require.NoError(t, json.Unmarshal(data, ew))
+	before := *ew
+
+	// the migrated file keeps its metadata and secrets, and opens with the same password
+	require.NoError(t, ew.migrate())
+	require.Equal(t, WalletVersion, ew.Meta.Version)
+	require.Equal(t, before.Meta.DisplayName, ew.Meta.DisplayName)
+	require.Equal(t, before.Meta.Created, ew.Meta.Created)
+	require.Equal(t, before.Secrets, ew.Secrets)
+	migrated, err := json.Marshal(ew)
+	require.NoError(t, err)
+	wKey := NewKey(WithPasswordOnly([]byte("password")))
+	w, err := wKey.Open(bytes.NewReader(migrated))
+	require.NoError(t, err)
+	require.Equal(t, WalletVersion, w.Meta.Version)
+	require.Len(t, w.Secrets.Accounts, 2)
+}
+
+func TestRename(t *testing.T) {
+	password := []byte("password")
+	w, err := NewMultiWalletRandomMnemonic(2, WithGenesisID(types.Hash20{1}))
+	require.NoError(t, err)
+	buf := &bytes.Buffer{}
+	wKey := NewKey(WithRandomSalt(), WithPbkdf2Password(password))
+	require.NoError(t, wKey.Export(buf, w))
+	ew := &EncryptedWalletFile{}
+	require.NoError(t, json.Unmarshal(buf.Bytes(), ew))
+	before := *ew
+
+	require.Error(t, ew.Rename(""))
+	require.Equal(t, before, *ew)
+	require.NoError(t, ew.Rename("Donations"))
+
+	// only the name changed
+	expected := before
+	expected.Meta.DisplayName = "Donations"
+	require.Equal(t, expected, *ew)
+
+	// and it persists through re-encryption
+	require.NoError(t, ew.ChangePassword(password, []byte("new password")))
+	data, err := json.Marshal(ew)
+	require.NoError(t, err)
+	wKey = NewKey(WithPasswordOnly([]byte("new password")))
+	w2, err := wKey.Open(bytes.NewReader(data))
+	require.NoError(t, err)
+	require.Equal(t, "Donations", w2.Meta.DisplayName)
+	require.Equal(t, w.Meta.Created, w2.Meta.Created)
+	require.Equal(t, w.Meta.GenesisID, w2.Meta.GenesisID)
+	require.Equal(t, w.Secrets.Accounts, w2.Secrets.Accounts)
+
+	buf.Reset()
+	require.NoError(t, wKey.Export(buf, w2))
+	wKey = NewKey(WithPasswordOnly([]byte("new password")))
+	w3, err := wKey.Open(buf)
+	require.NoError(t, err)
+	require.Equal(t, w2.Meta, w3.Meta)
+}
+
+func TestRekey(t *testing.T) {
+	// testdata/wallet_low_iterations.json holds the same accounts as testdata/wallet.json, encrypted with the
+	// password "password" using only 1000 PBKDF2 iterations.
+	data, err := os.ReadFile("testdata/wallet_low_iterations.json")
+	require.NoError(t, err)
+	ew := &EncryptedWalletFile{}
+	require.NoError(t, json.Unmarshal(data, ew))
+	require.Equal(t, 1000, ew.Secrets.KDFParams.Iterations)
+	before := *ew
+
+	const target = 5000
+	require.True(t, ew.NeedsRekey(target))
+	require.False(t, ew.NeedsRekey(1000))
+	require.ErrorIs(t, ew.Rekey([]byte("wrong password"), target), ErrWrongPassword)
+	require.Error(t, ew.Rekey([]byte("password"), 0))
+	require.Equal(t, before, *ew)
+
+	require.NoError(t, ew.Rekey([]byte("password"), target))
+	require.False(t, ew.NeedsRekey(target))
+	// the file is upgraded to the current version, the rest of the metadata is kept
+	meta := before.Meta
+	meta.Version = WalletVersion
+	require.Equal(t, meta, ew.Meta)
+	require.Equal(t, KDFPbkdf2, ew.Secrets.KDF)
+	require.Equal(t, target, ew.Secrets.KDFParams.Iterations)
+	require.NotEqual(t, before.Secrets.KDFParams.Salt, ew.Secrets.KDFParams.Salt)
+	require.NotEqual(t, before.Secrets.CipherText, ew.Secrets.CipherText)
+
+	// the upgraded file still opens with the same password and holds the same wallet
+	rekeyed, err := json.Marshal(ew)
+	require.NoError(t, err)
+	wKey := NewKey(WithPasswordOnly([]byte("password")))
+	w, err := wKey.Open(bytes.NewReader(rekeyed))
+	require.NoError(t, err)
+	require.Equal(t, "film theme cheese broken kingdom destroy inch ready wear inspire shove pudding", w.Mnemonic())
+	require.Equal(t, []string{
+		"sm1qqqqqqz9rf583slhn38g6q6a562ctltv9fv5w8q2gdz9k",
+		"sm1qqqqqqygmz2nnr7ush67yx7g4mmksm979m3a0xcphk3pt",
+	}, w.Addresses("sm"))
+}
+
+func TestRekeyOtherKDF(t *testing.T) {
+	password := []byte("password")
+	w, err := NewMultiWalletRandomMnemonic(1)
+	require.NoError(t, err)
+	buf := &bytes.Buffer{}
+	wKey := NewKey(WithRandomSalt(), WithScryptParams(1<<10, 8, 1), WithScryptPassword(password))
+	require.NoError(t, wKey.Export(buf, w))
+	ew := &EncryptedWalletFile{}
+	require.NoError(t, json.Unmarshal(buf.Bytes(), ew))
+
+	require.False(t, ew.NeedsRekey(Pbkdf2Iterations))
+	require.Error(t, ew.Rekey(password, Pbkdf2Iterations))
+}
+
+func TestCreateWithIterations(t *testing.T) {
+	password := []byte("password")
+	w, err := NewMultiWalletRandomMnemonic(1)
+	require.NoError(t, err)
+	buf := &bytes.Buffer{}
+	wKey := NewKey(WithRandomSalt(), WithIterations(1234), WithPbkdf2Password(password))
+	require.NoError(t, wKey.Export(buf, w))
+	ew := &EncryptedWalletFile{}
+	require.NoError(t, json.Unmarshal(buf.Bytes(), ew))
+	require.Equal(t, 1234, ew.Secrets.KDFParams.Iterations)
+
+	wKey = NewKey(WithPasswordOnly(password))
+	w2, err := wKey.Open(buf)
+	require.NoError(t, err)
+	require.Equal(t, w.Secrets.Accounts, w2.Secrets.Accounts)
+}
+
+func TestEncryptionSelfCheck(t *testing.T) {
+	password := []byte("password")
+	w, err := NewMultiWalletRandomMnemonic(1)
+	require.NoError(t, err)
+	plaintext, err := json.Marshal(w.Secrets)
+	require.NoError(t, err)
+
+	wKey := NewKey(WithRandomSalt(), WithIterations(1000), WithPbkdf2Password(password))
+	ew := &EncryptedWalletFile{Meta: w.Meta}
+	require.NoError(t, wKey.encryptSecrets(ew, plaintext))
+
+	// whatever doesn't decrypt back to the same secrets is caught
+	require.ErrorIs(t, checkEncryptedSecrets(ew.Secrets, password, append([]byte{' '}, plaintext...), nil),
+		ErrEncryptionCheck)
+	require.ErrorIs(t, checkEncryptedSecrets(ew.Secrets, []byte("passworD"), plaintext, nil), ErrEncryptionCheck)
+	secrets := ew.Secrets
+	secrets.CipherText = append([]byte{}, ew.Secrets.CipherText...)
+	secrets.CipherText[0] ^= 1
+	require.ErrorIs(t, checkEncryptedSecrets(secrets, password, plaintext, nil), ErrEncryptionCheck)
+	secrets = ew.Secrets
+	secrets.KDFParams.Iterations++
+	require.ErrorIs(t, checkEncryptedSecrets(secrets, password, plaintext, nil), ErrEncryptionCheck)
+
+	// a key whose stored params don't match the ones it was derived with is never written out
+	wKey = NewKey(WithRandomSalt(), WithIterations(1000), WithPbkdf2Password(password))
+	wKey.iterations = 1001
+	buf := &bytes.Buffer{}
+	require.ErrorIs(t, wKey.Export(buf, w), ErrEncryptionCheck)
+	require.Zero(t, buf.Len())
+}
+
+func TestExportDeterministic(t *testing.T) {
+	password := []byte("password")
+	w, err := NewMultiWalletRandomMnemonic(3)
+	require.NoError(t, err)
+	require.NoError(t, w.SetAccountLabel(1, "savings"))
+	// accounts that are out of order in memory are written in order of index
+	accts := w.Secrets.Accounts
+	accts[0], accts[2] = accts[2], accts[0]
+
+	export := func(w *Wallet) ([]byte, []byte) {
+		var buf bytes.Buffer
+		wk := NewKey(WithRandomSalt(), WithIterations(1000), WithPbkdf2Password(password))
+		require.NoError(t, wk.Export(&buf, w))
+		ew := &EncryptedWalletFile{}
+		require.NoError(t, json.Unmarshal(buf.Bytes(), ew))
+		rk := NewKey(WithPasswordOnly(password))
+		plaintext, err := rk.decryptSecrets(ew)
+		require.NoError(t, err)
+		return buf.Bytes(), plaintext
+	}
+	// blankRandom clears the fields that are random for every export
+	blankRandom := func(data []byte) []byte {
+		ew := &EncryptedWalletFile{}
+		require.NoError(t, json.Unmarshal(data, ew))
+		ew.Secrets.CipherText = nil
+		ew.Secrets.CipherParams.IV = nil
+		ew.Secrets.KDFParams.Salt = nil
+		blank, err := json.Marshal(ew)
+		require.NoError(t, err)
+		return blank
+	}
+
+	first, firstPlaintext := export(w)
+	second, secondPlaintext := export(w)
+	require.NotEqual(t, first, second)
+	require.Equal(t, blankRandom(first), blankRandom(second))
+	require.Equal(t, firstPlaintext, secondPlaintext)
+
+	// and so does reopening the wallet and exporting it again
+	wk := NewKey(WithPasswordOnly(password))
+	opened, err := wk.Open(bytes.NewReader(first))
+	require.NoError(t, err)
+	for i, acct := range opened.Secrets.Accounts {
+		require.Equal(t, uint32(i), accountIndex(acct, i))
+	}
+	third, thirdPlaintext := export(opened)
+	require.Equal(t, blankRandom(first), blankRandom(third))
+	require.Equal(t, firstPlaintext, thirdPlaintext)
+	// the wallet itself is left as it was
+	require.Equal(t, "m/44'/540'/0'/0'/2'", w.Secrets.Accounts[0].Path.String())
+}
+
+func TestKeyDerivedOncePerOpen(t *testing.T) {
+	derivations := 0
+	t.Cleanup(func() { pbkdf2Key = pbkdf2.Key })
+	pbkdf2Key = func(password, salt []byte, iter, keyLen int, h func() hash.Hash) []byte {
+		derivations++
+		return pbkdf2.Key(password, salt, iter, keyLen, h)
+	}
+
+	password := []byte("password")
+	w, err := NewMultiWalletRandomMnemonic(1)
+	require.NoError(t, err)
+	wKey := NewKey(WithRandomSalt(), WithIterations(1000), WithPbkdf2Password(password))
+	buf := &bytes.Buffer{}
+	require.NoError(t, wKey.Export(buf, w))
+	// the self-check reuses the key
+	require.Equal(t, 1, derivations)
+	data := buf.Bytes()
+
+	derivations = 0
+	wKey = NewKey(WithPasswordOnly(password))
+	w2, err := wKey.Open(bytes.NewReader(data))
+	require.NoError(t, err)
+	require.Equal(t, 1, derivations)
+
+	// saving the wallet again using the same key, and opening the result, doesn't derive it again
+	buf = &bytes.Buffer{}
+	require.NoError(t, wKey.Export(buf, w2))
+	_, err = wKey.Open(buf)
+	require.NoError(t, err)
+	require.Equal(t, 1, derivations)
+
+	// but it is derived again if the params change
+	wKey.iterations = 1001
+	buf = &bytes.Buffer{}
+	require.ErrorIs(t, wKey.Export(buf, w2), ErrEncryptionCheck)
+	require.Equal(t, 2, derivations)
+
+	derivations = 0
+	wKey = NewKey(WithPasswordOnly([]byte("passworD")))
+	_, err = wKey.Open(bytes.NewReader(data))
+	require.ErrorIs(t, err, ErrWrongPassword)
+	require.Equal(t, 1, derivations)
+}
+
+func BenchmarkOpen(b *testing.B) {
+	password := []byte("password")
+	w, err := NewMultiWalletRandomMnemonic(1)
+	require.NoError(b, err)
+	for _, kdf := range []string{KDFPbkdf2, KDFScrypt, KDFArgon2} {
+		wKey := NewKey(WithRandomSalt(), withKDFPassword(kdf, password))
+		buf := &bytes.Buffer{}
+		require.NoError(b, wKey.Export(buf, w))
+		data := buf.Bytes()
+		b.Run(kdf, func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				wKey := NewKey(WithPasswordOnly(password))
+				_, err := wKey.Open(bytes.NewReader(data))
+				require.NoError(b, err)
+			}
+		})
+	}
+}
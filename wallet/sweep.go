@@ -0,0 +1,151 @@
+package wallet
+
+import (
+	"fmt"
+	"math/bits"
+
+	"github.com/spacemeshos/go-spacemesh/genvm/core"
+)
+
+// Sweeping moves the funds of every account of a wallet to the account with the same index in another wallet,
+// e.g., to rotate to a new mnemonic after the old one may have been compromised. Each funded account sends its
+// whole balance, less the maximum fees of doing so, in a single spend, preceded by its spawn if it hasn't been
+// spawned yet. Since the maximum fee is charged up front and the actual fee can be lower, a little dust may be
+// left behind in the old account.
+
+// SweepChecker reports the balance of an account, counting pending transactions, as well as whether it has been
+// spawned and the nonce of its next transaction, e.g., by querying a node.
+type SweepChecker interface {
+	SpawnChecker
+	AccountBalance(addr core.Address) (uint64, error)
+}
+
+// SweepTransfer moves the balance of an account of one wallet to the account with the same index in another, see
+// PlanSweep.
+type SweepTransfer struct {
+	// Index is the HD account index of both accounts.
+	Index uint32
+	From  core.Address
+	To    core.Address
+	// Balance is the balance of From, counting pending transactions. Amount is what's sent to To, and Fee the
+	// maximum fee of Txns, which is paid from the rest.
+	Balance uint64
+	Amount  uint64
+	Fee     uint64
+	// Txns are the unsigned transactions to sign using the account and submit in order: the spawn of From if it
+	// hasn't been spawned yet, then the spend.
+	Txns [][]byte
+}
+
+// SweepPlan is the transfers that sweep the funds of a wallet, see PlanSweep.
+type SweepPlan struct {
+	Transfers []*SweepTransfer
+	// Dust are the funded accounts whose balance doesn't cover the fees of sending it, which are left behind.
+	// They have no Txns.
+	Dust []*SweepTransfer
+}
+
+// Total returns the total amount sent by the transfers and their total maximum fee.
+func (p *SweepPlan) Total() (amount, fee uint64) {
+	for _, t := range p.Transfers {
+		amount += t.Amount
+		fee += t.Fee
+	}
+	return amount, fee
+}
+
+// PlanSweep builds the transfers that move the funds of every account of from to the account with the same HD
+// account index in to, at the given gas price. checker is asked for the balance of each account of from, and for
+// its next nonce if it has any funds; accounts without funds are skipped. Every funded account must have a
+// counterpart in to, which must be a different account. Nothing is signed: sign each transaction using the
+// account of from that sends it, e.g., using SignOfflineTxn.
+func PlanSweep(checker SweepChecker, from, to *Wallet, gasPrice uint64) (*SweepPlan, error) {
+	if gasPrice == 0 {
+		return nil, fmt.Errorf("gas price must be greater than zero")
+	}
+	plan := &SweepPlan{}
+	for i, acct := range sortedAccounts(from.Secrets.Accounts) {
+		if acct == nil {
+			return nil, fmt.Errorf("account %d is missing", i)
+		}
+		t := &SweepTransfer{Index: accountIndex(acct, i), From: PubkeyToPrincipal(acct.Public)}
+		balance, err := checker.AccountBalance(t.From)
+		if err != nil {
+			return nil, fmt.Errorf("error querying the balance of %s: %w", t.From.String(), err)
+		}
+		if balance == 0 {
+			continue
+		}
+		t.Balance = balance
+		recipient, err := to.Account(t.Index)
+		if err != nil {
+			return nil, fmt.Errorf("account %d of the new wallet is needed to receive the funds of %s: %w",
+				t.Index, t.From.String(), err)
+		}
+		t.To = PubkeyToPrincipal(recipient.Public)
+		if err := CheckRecipient(t.From, t.To); err != nil {
+			return nil, fmt.Errorf("account %d: %w", t.Index, err)
+		}
+		if err := t.build(checker, acct, gasPrice); err != nil {
+			return nil, fmt.Errorf("account %d: %w", t.Index, err)
+		}
+		if t.Txns == nil {
+			plan.Dust = append(plan.Dust, t)
+		} else {
+			plan.Transfers = append(plan.Transfers, t)
+		}
+	}
+	return plan, nil
+}
+
+// build sets the fee, amount, and transactions of a transfer whose balance is set, leaving Txns unset if the
+// balance doesn't cover the fee.
+func (t *SweepTransfer) build(checker SpawnChecker, acct *EDKeyPair, gasPrice uint64) error {
+	spawned, nonce, err := checker.AccountSpawned(t.From)
+	if err != nil {
+		return fmt.Errorf("error checking whether %s has been spawned: %w", t.From.String(), err)
+	}
+	var txns [][]byte
+	if !spawned {
+		var pubkey core.PublicKey
+		copy(pubkey[:], acct.Public)
+		spawn, err := GenerateSpawnTxnData(pubkey, gasPrice)
+		if err != nil {
+			return err
+		}
+		est, err := EstimateTxnFee(spawn, gasPrice)
+		if err != nil {
+			return err
+		}
+		txns = append(txns, spawn)
+		t.Fee = est.Fee
+		nonce = 1
+	}
+
+	// the size of the spend, and so its fee, depends on the amount: sending the whole balance is the largest
+	data := TxnData{Principal: t.From, Recipient: t.To, Amount: t.Balance, Nonce: nonce, GasPrice: gasPrice}
+	largest, err := GenerateTxnData(data)
+	if err != nil {
+		return err
+	}
+	est, err := EstimateTxnFee(largest, gasPrice)
+	if err != nil {
+		return err
+	}
+	fee, carry := bits.Add64(t.Fee, est.Fee, 0)
+	if carry != 0 {
+		return fmt.Errorf("fee overflows at gas price %d", gasPrice)
+	}
+	t.Fee = fee
+	if t.Balance <= t.Fee {
+		return nil
+	}
+	t.Amount = t.Balance - t.Fee
+	data.Amount = t.Amount
+	spend, err := GenerateTxnData(data)
+	if err != nil {
+		return err
+	}
+	t.Txns = append(txns, spend)
+	return nil
+}
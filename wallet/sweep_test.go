@@ -0,0 +1,102 @@
+package wallet
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/spacemeshos/go-spacemesh/genvm/core"
+	"github.com/stretchr/testify/require"
+)
+
+// mockSweepChecker reports the balances in balances, and the accounts in nonces as spawned, with their next nonce.
+type mockSweepChecker struct {
+	mockSpawnChecker
+	balances map[core.Address]uint64
+}
+
+func (m *mockSweepChecker) AccountBalance(addr core.Address) (uint64, error) {
+	return m.balances[addr], m.err
+}
+
+func TestPlanSweep(t *testing.T) {
+	from, err := NewMultiWalletFromMnemonic(testMnemonic, 4)
+	require.NoError(t, err)
+	to, err := NewMultiWalletRandomMnemonic(4)
+	require.NoError(t, err)
+	fromAddrs, err := from.Principals()
+	require.NoError(t, err)
+	toAddrs, err := to.Principals()
+	require.NoError(t, err)
+
+	// account 0 is spawned, 1 isn't, 2 is empty, and 3 can't pay the fee
+	checker := &mockSweepChecker{
+		mockSpawnChecker: mockSpawnChecker{nonces: map[core.Address]uint64{fromAddrs[0]: 5, fromAddrs[3]: 1}},
+		balances:         map[core.Address]uint64{fromAddrs[0]: 1_000_000, fromAddrs[1]: 2_000_000, fromAddrs[3]: 10},
+	}
+	plan, err := PlanSweep(checker, from, to, 1)
+	require.NoError(t, err)
+	require.Len(t, plan.Transfers, 2)
+	for i, want := range []struct {
+		balance uint64
+		methods []string
+		nonces  []core.Nonce
+	}{
+		{1_000_000, []string{MethodNameSpend}, []core.Nonce{5}},
+		{2_000_000, []string{MethodNameSpawn, MethodNameSpend}, []core.Nonce{0, 1}},
+	} {
+		tr := plan.Transfers[i]
+		require.EqualValues(t, i, tr.Index)
+		require.Equal(t, fromAddrs[i], tr.From)
+		require.Equal(t, toAddrs[i], tr.To)
+		require.Equal(t, want.balance, tr.Balance)
+		require.Equal(t, tr.Balance, tr.Amount+tr.Fee)
+
+		var fee uint64
+		var methods []string
+		var nonces []core.Nonce
+		for _, tx := range tr.Txns {
+			d, err := DecodeTransaction(tx)
+			require.NoError(t, err)
+			require.Equal(t, tr.From, d.Principal)
+			methods = append(methods, d.MethodName)
+			nonces = append(nonces, d.Nonce)
+			if d.Method == core.MethodSpend {
+				require.Equal(t, tr.To, d.Recipient)
+				require.Equal(t, tr.Amount, d.Amount)
+			}
+			est, err := EstimateTxnFee(tx, 1)
+			require.NoError(t, err)
+			fee += est.Fee
+		}
+		require.Equal(t, want.methods, methods)
+		require.Equal(t, want.nonces, nonces)
+		// the fee of the planned spend is at most the one it was planned with
+		require.LessOrEqual(t, fee, tr.Fee)
+	}
+	require.Len(t, plan.Dust, 1)
+	require.EqualValues(t, 3, plan.Dust[0].Index)
+	require.Nil(t, plan.Dust[0].Txns)
+	amount, fee := plan.Total()
+	require.Equal(t, plan.Transfers[0].Amount+plan.Transfers[1].Amount, amount)
+	require.Equal(t, uint64(3_000_000), amount+fee)
+
+	// the funds must have somewhere to go
+	short, err := NewMultiWalletRandomMnemonic(1)
+	require.NoError(t, err)
+	_, err = PlanSweep(checker, from, short, 1)
+	require.ErrorIs(t, err, ErrAccountNotFound)
+	_, err = PlanSweep(checker, from, from, 1)
+	require.ErrorIs(t, err, ErrSelfSend)
+
+	// nothing to sweep
+	plan, err = PlanSweep(&mockSweepChecker{}, from, to, 1)
+	require.NoError(t, err)
+	require.Empty(t, plan.Transfers)
+	require.Empty(t, plan.Dust)
+
+	checker.err = errors.New("node unavailable")
+	_, err = PlanSweep(checker, from, to, 1)
+	require.ErrorIs(t, err, checker.err)
+	_, err = PlanSweep(checker, from, to, 0)
+	require.Error(t, err)
+}
@@ -0,0 +1,89 @@
+package wallet
+
+import (
+	"fmt"
+
+	"github.com/spacemeshos/go-spacemesh/genvm/core"
+
+	"github.com/spacemeshos/smcli/common"
+)
+
+// Spawn modes of TransferTxns.
+const (
+	// SpawnAuto spawns the sending account before the spend if it hasn't been spawned yet.
+	SpawnAuto = "auto"
+	// SpawnOnly only spawns the sending account.
+	SpawnOnly = "only"
+	// SpawnNever only spends, e.g., if the account is known to have been spawned.
+	SpawnNever = "never"
+)
+
+// ErrPublicKeyNeeded is returned by TransferTxns if the sending account has to be spawned but its public key wasn't
+// given.
+var ErrPublicKeyNeeded = common.NewError(common.ErrBadInput, "public key needed to spawn the sending account")
+
+// SpawnChecker reports whether the account with a given address has been spawned, and the nonce of its next
+// transaction, e.g., by querying a node.
+type SpawnChecker interface {
+	AccountSpawned(addr core.Address) (bool, uint64, error)
+}
+
+// TransferTxns builds the unsigned transactions needed to send funds from a single-sig wallet account, to be
+// signed and submitted in order. A fresh account must spawn itself before it can spend: in SpawnAuto mode, checker
+// is asked whether data.Principal has been spawned and for its next nonce, and if it hasn't been spawned a spawn
+// transaction using nonce 0 is prepended to the spend, which then uses nonce 1. The spawn needs the public key of
+// the account, which is ignored otherwise. SpawnOnly and SpawnNever force the spawn or the spend alone without
+// using checker, which can be nil; the spend uses data.Nonce.
+func TransferTxns(checker SpawnChecker, data TxnData, pubkey core.PublicKey, mode string) ([][]byte, error) {
+	spawn := func() ([]byte, error) {
+		if pubkey == (core.PublicKey{}) {
+			return nil, fmt.Errorf("%w %s", ErrPublicKeyNeeded, data.Principal.String())
+		}
+		principal, _, err := SpawnSingleSig(pubkey)
+		if err != nil {
+			return nil, err
+		}
+		if principal != data.Principal {
+			return nil, fmt.Errorf("public key does not match the sending account %s", data.Principal.String())
+		}
+		return GenerateSpawnTxnData(pubkey, data.GasPrice)
+	}
+	switch mode {
+	case SpawnOnly:
+		tx, err := spawn()
+		if err != nil {
+			return nil, err
+		}
+		return [][]byte{tx}, nil
+	case SpawnNever:
+		tx, err := GenerateTxnData(data)
+		if err != nil {
+			return nil, err
+		}
+		return [][]byte{tx}, nil
+	case SpawnAuto:
+	default:
+		return nil, fmt.Errorf("unsupported spawn mode %q, must be %s, %s, or %s",
+			mode, SpawnAuto, SpawnOnly, SpawnNever)
+	}
+
+	spawned, nonce, err := checker.AccountSpawned(data.Principal)
+	if err != nil {
+		return nil, fmt.Errorf("error checking whether %s has been spawned: %w", data.Principal.String(), err)
+	}
+	var txs [][]byte
+	if !spawned {
+		tx, err := spawn()
+		if err != nil {
+			return nil, err
+		}
+		txs = append(txs, tx)
+		nonce = 1
+	}
+	data.Nonce = nonce
+	tx, err := GenerateTxnData(data)
+	if err != nil {
+		return nil, err
+	}
+	return append(txs, tx), nil
+}
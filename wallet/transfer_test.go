@@ -0,0 +1,101 @@
+package wallet
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/spacemeshos/go-spacemesh/genvm/core"
+	"github.com/stretchr/testify/require"
+)
+
+// mockSpawnChecker reports the accounts in nonces as spawned, with their next nonce.
+type mockSpawnChecker struct {
+	nonces  map[core.Address]uint64
+	checked int
+	err     error
+}
+
+func (m *mockSpawnChecker) AccountSpawned(addr core.Address) (bool, uint64, error) {
+	m.checked++
+	nonce, ok := m.nonces[addr]
+	return ok, nonce, m.err
+}
+
+func TestTransferTxns(t *testing.T) {
+	mnemonic := "film theme cheese broken kingdom destroy inch ready wear inspire shove pudding"
+	w, err := NewMultiWalletFromMnemonic(mnemonic, 2)
+	require.NoError(t, err)
+	var pubkey core.PublicKey
+	copy(pubkey[:], w.Secrets.Accounts[0].Public)
+	data := TxnData{
+		Principal: PubkeyToPrincipal(pubkey[:]),
+		Recipient: PubkeyToPrincipal(w.Secrets.Accounts[1].Public),
+		Amount:    100,
+		Nonce:     9,
+		GasPrice:  2,
+	}
+	decode := func(txs [][]byte) []*DecodedTxn {
+		decoded := make([]*DecodedTxn, len(txs))
+		for i, tx := range txs {
+			decoded[i], err = DecodeTransaction(tx)
+			require.NoError(t, err)
+			require.Equal(t, data.Principal, decoded[i].Principal)
+			require.Equal(t, data.GasPrice, decoded[i].GasPrice)
+		}
+		return decoded
+	}
+
+	// spawned: the spend alone, using the next nonce
+	checker := &mockSpawnChecker{nonces: map[core.Address]uint64{data.Principal: 4}}
+	txs, err := TransferTxns(checker, data, core.PublicKey{}, SpawnAuto)
+	require.NoError(t, err)
+	d := decode(txs)
+	require.Len(t, d, 1)
+	require.Equal(t, MethodNameSpend, d[0].MethodName)
+	require.EqualValues(t, 4, d[0].Nonce)
+	require.Equal(t, data.Amount, d[0].Amount)
+
+	// not spawned: the spawn first
+	checker = &mockSpawnChecker{}
+	txs, err = TransferTxns(checker, data, pubkey, SpawnAuto)
+	require.NoError(t, err)
+	d = decode(txs)
+	require.Len(t, d, 2)
+	require.Equal(t, MethodNameSpawn, d[0].MethodName)
+	require.True(t, d[0].SelfSpawn)
+	require.EqualValues(t, 0, d[0].Nonce)
+	require.Equal(t, MethodNameSpend, d[1].MethodName)
+	require.EqualValues(t, 1, d[1].Nonce)
+
+	// the spawn needs the right public key
+	_, err = TransferTxns(checker, data, core.PublicKey{}, SpawnAuto)
+	require.ErrorIs(t, err, ErrPublicKeyNeeded)
+	var other core.PublicKey
+	copy(other[:], w.Secrets.Accounts[1].Public)
+	_, err = TransferTxns(checker, data, other, SpawnAuto)
+	require.Error(t, err)
+
+	checker.err = errors.New("unreachable")
+	_, err = TransferTxns(checker, data, pubkey, SpawnAuto)
+	require.ErrorIs(t, err, checker.err)
+
+	// forced modes don't check
+	checker = &mockSpawnChecker{}
+	txs, err = TransferTxns(checker, data, pubkey, SpawnOnly)
+	require.NoError(t, err)
+	d = decode(txs)
+	require.Len(t, d, 1)
+	require.Equal(t, MethodNameSpawn, d[0].MethodName)
+	txs, err = TransferTxns(checker, data, core.PublicKey{}, SpawnNever)
+	require.NoError(t, err)
+	d = decode(txs)
+	require.Len(t, d, 1)
+	require.Equal(t, MethodNameSpend, d[0].MethodName)
+	require.Equal(t, data.Nonce, d[0].Nonce)
+	require.Zero(t, checker.checked)
+	_, err = TransferTxns(nil, data, pubkey, SpawnOnly)
+	require.NoError(t, err)
+
+	_, err = TransferTxns(checker, data, pubkey, "sometimes")
+	require.Error(t, err)
+}
@@ -0,0 +1,92 @@
+package wallet
+
+import (
+	"crypto/ed25519"
+	"fmt"
+	"math"
+
+	"github.com/spacemeshos/go-spacemesh/common/types"
+	"github.com/spacemeshos/go-spacemesh/genvm/core"
+	"github.com/spacemeshos/go-spacemesh/genvm/sdk"
+	walletTemplate "github.com/spacemeshos/go-spacemesh/genvm/templates/wallet"
+)
+
+// TxnData contains everything required to build a simple coin transfer (spend) from a single-sig wallet account.
+type TxnData struct {
+	// Principal is the address of the sending account.
+	Principal core.Address
+	// Recipient is the address of the receiving account.
+	Recipient core.Address
+	// Amount is denominated in smidge.
+	Amount uint64
+	// Nonce must match the next nonce of the sending account.
+	Nonce core.Nonce
+	// GasPrice is denominated in smidge per unit of gas.
+	GasPrice uint64
+}
+
+func (d *TxnData) validate() error {
+	var empty core.Address
+	switch {
+	case d.Principal == empty:
+		return fmt.Errorf("principal address must be set")
+	case d.Recipient == empty:
+		return fmt.Errorf("recipient address must be set")
+	case d.Amount == 0:
+		return fmt.Errorf("amount must be greater than zero")
+	case d.GasPrice == 0:
+		return fmt.Errorf("gas price must be greater than zero")
+	case d.Nonce == math.MaxUint64:
+		// the account nonce is incremented by every transaction so this one could never be applied
+		return fmt.Errorf("invalid nonce %d", d.Nonce)
+	}
+	return nil
+}
+
+// GenerateTxnData encodes an unsigned spend transaction for the single-sig wallet template in the format
+// expected by go-spacemesh. The signature over these bytes (and the genesis ID) must be appended to them
+// before the transaction can be submitted to a node.
+func GenerateTxnData(data TxnData) ([]byte, error) {
+	if err := data.validate(); err != nil {
+		return nil, err
+	}
+	payload := core.Payload{
+		Nonce:    data.Nonce,
+		GasPrice: data.GasPrice,
+	}
+	args := walletTemplate.SpendArguments{
+		Destination: data.Recipient,
+		Amount:      data.Amount,
+	}
+	return sdk.Encode(&sdk.TxVersion, &data.Principal, &sdk.MethodSpend, &payload, &args), nil
+}
+
+// SignTxn signs an unsigned transaction (e.g., one produced by GenerateTxnData) and returns the complete,
+// signed transaction ready to be submitted to a node. The signature commits to the genesis ID of the network
+// so that the transaction can't be replayed on a different network.
+func SignTxn(kp *EDKeyPair, genesisID types.Hash20, unsigned []byte) ([]byte, error) {
+	if len(kp.Private) != ed25519.PrivateKeySize {
+		return nil, fmt.Errorf("keypair does not contain a private key")
+	}
+	sig := kp.Sign(core.SigningBody(genesisID[:], unsigned))
+	signed := make([]byte, 0, len(unsigned)+len(sig))
+	signed = append(signed, unsigned...)
+	return append(signed, sig...), nil
+}
+
+// GenerateSpawnTxnData encodes an unsigned transaction that spawns the single-sig wallet account owned by pubkey,
+// see SpawnSingleSig. Spawning is the first transaction of every account, so it uses nonce 0, and the account must
+// have been sent enough funds to pay its fee. The transaction is signed using SignTxn like a spend.
+func GenerateSpawnTxnData(pubkey core.PublicKey, gasPrice uint64) ([]byte, error) {
+	if gasPrice == 0 {
+		return nil, fmt.Errorf("gas price must be greater than zero")
+	}
+	principal, _, err := SpawnSingleSig(pubkey)
+	if err != nil {
+		return nil, err
+	}
+	payload := core.Payload{GasPrice: gasPrice}
+	args := walletTemplate.SpawnArguments{PublicKey: pubkey}
+	return sdk.Encode(&sdk.TxVersion, &principal, &sdk.MethodSpawn, &walletTemplate.TemplateAddress, &payload,
+		&args), nil
+}
@@ -0,0 +1,179 @@
+package wallet
+
+import (
+	"bytes"
+	"crypto/ed25519"
+	"math"
+	"testing"
+
+	"github.com/spacemeshos/go-scale"
+	"github.com/spacemeshos/go-spacemesh/common/types"
+	"github.com/spacemeshos/go-spacemesh/genvm/core"
+	"github.com/spacemeshos/go-spacemesh/genvm/sdk"
+	sdkWallet "github.com/spacemeshos/go-spacemesh/genvm/sdk/wallet"
+	walletTemplate "github.com/spacemeshos/go-spacemesh/genvm/templates/wallet"
+	"github.com/stretchr/testify/require"
+)
+
+func testPrincipal(t *testing.T, kp *EDKeyPair) core.Address {
+	args := &walletTemplate.SpawnArguments{}
+	copy(args.PublicKey[:], kp.Public)
+	return core.ComputePrincipal(walletTemplate.TemplateAddress, args)
+}
+
+func TestGenerateTxnData(t *testing.T) {
+	master, err := NewMasterKeyPair(goodSeed)
+	require.NoError(t, err)
+	accts, err := accountsFromMaster(master, goodSeed, 2)
+	require.NoError(t, err)
+
+	data := TxnData{
+		Principal: testPrincipal(t, accts[0]),
+		Recipient: testPrincipal(t, accts[1]),
+		Amount:    123456789,
+		Nonce:     7,
+		GasPrice:  2,
+	}
+	tx, err := GenerateTxnData(data)
+	require.NoError(t, err)
+
+	// decode the fields back
+	dec := scale.NewDecoder(bytes.NewReader(tx))
+	version, _, err := scale.DecodeCompact8(dec)
+	require.NoError(t, err)
+	require.EqualValues(t, sdk.TxVersion, version)
+	var principal core.Address
+	_, err = principal.DecodeScale(dec)
+	require.NoError(t, err)
+	require.Equal(t, data.Principal, principal)
+	method, _, err := scale.DecodeCompact8(dec)
+	require.NoError(t, err)
+	require.EqualValues(t, core.MethodSpend, method)
+	var payload core.Payload
+	_, err = payload.DecodeScale(dec)
+	require.NoError(t, err)
+	require.Equal(t, data.Nonce, payload.Nonce)
+	require.Equal(t, data.GasPrice, payload.GasPrice)
+	var args walletTemplate.SpendArguments
+	_, err = args.DecodeScale(dec)
+	require.NoError(t, err)
+	require.Equal(t, data.Recipient, args.Destination)
+	require.Equal(t, data.Amount, args.Amount)
+
+	// nothing left over
+	_, _, err = scale.DecodeByte(dec)
+	require.Error(t, err)
+
+	// the encoding must match the go-spacemesh SDK, which produces the same bytes followed by the signature
+	signed := sdkWallet.Spend(
+		ed25519.PrivateKey(accts[0].Private),
+		data.Recipient,
+		data.Amount,
+		data.Nonce,
+		sdk.WithGasPrice(data.GasPrice),
+	)
+	require.Equal(t, signed[:len(signed)-ed25519.SignatureSize], tx)
+}
+
+func TestGenerateTxnDataValidation(t *testing.T) {
+	valid := TxnData{
+		Principal: types.Address{1},
+		Recipient: types.Address{2},
+		Amount:    1,
+		Nonce:     1,
+		GasPrice:  1,
+	}
+	_, err := GenerateTxnData(valid)
+	require.NoError(t, err)
+
+	testCases := map[string]func(d *TxnData){
+		"no principal": func(d *TxnData) { d.Principal = types.Address{} },
+		"no recipient": func(d *TxnData) { d.Recipient = types.Address{} },
+		"zero amount":  func(d *TxnData) { d.Amount = 0 },
+		"zero gas":     func(d *TxnData) { d.GasPrice = 0 },
+		"bad nonce":    func(d *TxnData) { d.Nonce = math.MaxUint64 },
+	}
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			d := valid
+			tc(&d)
+			tx, err := GenerateTxnData(d)
+			require.Error(t, err)
+			require.Nil(t, tx)
+		})
+	}
+}
+
+func TestSignTxn(t *testing.T) {
+	master, err := NewMasterKeyPair(goodSeed)
+	require.NoError(t, err)
+	accts, err := accountsFromMaster(master, goodSeed, 2)
+	require.NoError(t, err)
+	genesisID := types.Hash20{1, 2, 3}
+
+	data := TxnData{
+		Principal: testPrincipal(t, accts[0]),
+		Recipient: testPrincipal(t, accts[1]),
+		Amount:    100,
+		Nonce:     1,
+		GasPrice:  1,
+	}
+	unsigned, err := GenerateTxnData(data)
+	require.NoError(t, err)
+	signed, err := SignTxn(accts[0], genesisID, unsigned)
+	require.NoError(t, err)
+
+	// must match the go-spacemesh SDK byte for byte
+	expected := sdkWallet.Spend(
+		ed25519.PrivateKey(accts[0].Private),
+		data.Recipient,
+		data.Amount,
+		data.Nonce,
+		sdk.WithGasPrice(data.GasPrice),
+		sdk.WithGenesisID(genesisID),
+	)
+	require.Equal(t, expected, signed)
+
+	// the signature only verifies over the correct genesis ID
+	sig := signed[len(unsigned):]
+	require.True(t, accts[0].Verify(core.SigningBody(genesisID[:], unsigned), sig))
+	require.False(t, accts[0].Verify(core.SigningBody(types.Hash20{}.Bytes(), unsigned), sig))
+
+	// can't sign without a private key
+	_, err = SignTxn(&EDKeyPair{Public: accts[0].Public}, genesisID, unsigned)
+	require.Error(t, err)
+}
+
+func TestGenerateSpawnTxnData(t *testing.T) {
+	master, err := NewMasterKeyPair(goodSeed)
+	require.NoError(t, err)
+	accts, err := accountsFromMaster(master, goodSeed, 1)
+	require.NoError(t, err)
+	genesisID := types.Hash20{1, 2, 3}
+	var pubkey core.PublicKey
+	copy(pubkey[:], accts[0].Public)
+
+	unsigned, err := GenerateSpawnTxnData(pubkey, 2)
+	require.NoError(t, err)
+	signed, err := SignTxn(accts[0], genesisID, unsigned)
+	require.NoError(t, err)
+
+	// must match the go-spacemesh SDK byte for byte
+	expected := sdkWallet.SelfSpawn(
+		ed25519.PrivateKey(accts[0].Private),
+		0,
+		sdk.WithGasPrice(2),
+		sdk.WithGenesisID(genesisID),
+	)
+	require.Equal(t, expected, signed)
+
+	d, err := DecodeTransaction(unsigned)
+	require.NoError(t, err)
+	require.True(t, d.SelfSpawn)
+	require.Equal(t, testPrincipal(t, accts[0]), d.Principal)
+
+	_, err = GenerateSpawnTxnData(pubkey, 0)
+	require.Error(t, err)
+	_, err = GenerateSpawnTxnData(core.PublicKey{}, 1)
+	require.Error(t, err)
+}
@@ -0,0 +1,112 @@
+package wallet
+
+import (
+	"encoding/hex"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/spacemeshos/smcli/common"
+)
+
+// hdVectors pin the keys and addresses derived from a few mnemonics. The algorithm is:
+//
+//  1. seed = BIP39 seed of the NFKD-normalized mnemonic and passphrase (PBKDF2-HMAC-SHA512, 2048 iterations)
+//  2. master key = SLIP-10 ed25519 key at m/44'/540'/0'/0'
+//  3. account i = SLIP-10 ed25519 key at m/44'/540'/0'/0'/i', derived from the seed (all levels hardened)
+//  4. address = principal of the single-sig wallet template spawned with the account public key, bech32 with HRP
+//
+// These must never change: a change here means existing wallets would derive different addresses.
+var hdVectors = []struct {
+	mnemonic   string
+	passphrase string
+	master     string
+	accounts   map[int][2]string // index -> (public key, mainnet address)
+}{
+	{
+		mnemonic: testMnemonic,
+		master:   "0b88a7066458f62878b7bc8ae5e61de9e3d626dd00a556b9207441e62a9b6b7b",
+		accounts: map[int][2]string{
+			0: {
+				"de30fc9b812248583da6259433626fcdd2cb5ce589b00047b81e127950b9bca6",
+				"sm1qqqqqqz9rf583slhn38g6q6a562ctltv9fv5w8q2gdz9k",
+			},
+			1: {
+				"3e53ca89fae03e6e9477f0fdc3abf994d1885007a73d136f4122a260a70348cd",
+				"sm1qqqqqqygmz2nnr7ush67yx7g4mmksm979m3a0xcphk3pt",
+			},
+			common.MaxAccountsPerWallet - 1: {
+				"673b8668e914389994050f3d2f85fcc8457ff54bdc0722571add2507d2d3a4e4",
+				"sm1qqqqqqqjqm5ukncvvf4h3ajfs5pcq7kt6w3d0vch796kf",
+			},
+		},
+	},
+	{
+		mnemonic: "abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon about",
+		master:   "dbe3388e29e9aec92cd8520c24fcb205a22aef5bed8a1368efbd29a8bbde7f3a",
+		accounts: map[int][2]string{
+			0: {
+				"f628d5733ba18640769ea46a24000be53a42fbfef0cd2c826044bbc640d119e2",
+				"sm1qqqqqqqlgfpwap59jlwmre4p9xrlk8fenfnlsrqva0uyp",
+			},
+			1: {
+				"c48f73df0ac70ecc015045e2df9d322e0b9fad5ca149a79fca173cfc65b70e2e",
+				"sm1qqqqqqxqxytaxnaf7lgen7erm8qmu53nyucdrncw492c8",
+			},
+			common.MaxAccountsPerWallet - 1: {
+				"c94f4f6fa99986502df831ca605aff5a66402946691e1bdd486f5815cb276916",
+				"sm1qqqqqq8gw6n7khv4flew8e5727lef2usj7hzrfshjk8qu",
+			},
+		},
+	},
+	{
+		mnemonic:   "abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon about",
+		passphrase: "TREZOR",
+		master:     "cbe3a1ed6f5e4916f2dbd283234b2fdc6d0c0ffec374ab02fad596b414556db8",
+		accounts: map[int][2]string{
+			0: {
+				"df22d207310f594b42e661bf1e151f9220a8619f35bb887515a4cba130761db3",
+				"sm1qqqqqqqhn34gfy4qn9sxtrnhv0gu33vdtll3edgqmd5sd",
+			},
+			1: {
+				"223616dc637d7aa7b08d3355452b97c79f19fcb172e0d92851d100fa276413d6",
+				"sm1qqqqqqqlsr5hrfjj5erqx3zukmwrm0ch0nyc44gh5jh7h",
+			},
+			common.MaxAccountsPerWallet - 1: {
+				"64808a48a11ab422c8fbb1693973e35743041a333d33474522e9d1ceafb8b8b6",
+				"sm1qqqqqq932hgemtrnyewkxsa8d7usqgnzr565waczz6823",
+			},
+		},
+	},
+}
+
+func TestHDVectors(t *testing.T) {
+	for _, v := range hdVectors {
+		seed := mnemonicToSeed(v.mnemonic, v.passphrase)
+		master, err := NewMasterKeyPair(seed)
+		require.NoError(t, err)
+		require.Equal(t, v.master, hex.EncodeToString(master.Public), v.mnemonic)
+
+		// derive each account individually
+		for idx, expected := range v.accounts {
+			acct, err := master.NewChildKeyPair(seed, idx)
+			require.NoError(t, err)
+			require.Equal(t, expected[0], hex.EncodeToString(acct.Public), idx)
+			require.Equal(t, expected[1], PubkeyToAddress(acct.Public, "sm"), idx)
+			require.Equal(t, BIP44HardenedAccountIndex(uint32(idx)), acct.Path.Index())
+		}
+
+		// and as part of a full wallet
+		accts, err := accountsFromMaster(master, seed, common.MaxAccountsPerWallet)
+		require.NoError(t, err)
+		for idx, expected := range v.accounts {
+			require.Equal(t, expected[0], hex.EncodeToString(accts[idx].Public), idx)
+		}
+		w, err := NewMultiWalletFromMnemonic(v.mnemonic, common.MaxAccountsPerWallet, WithPassphrase(v.passphrase))
+		require.NoError(t, err)
+		addresses := w.Addresses("sm")
+		for idx, expected := range v.accounts {
+			require.Equal(t, expected[1], addresses[idx], idx)
+		}
+	}
+}
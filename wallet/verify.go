@@ -0,0 +1,73 @@
+package wallet
+
+import (
+	"bytes"
+	"fmt"
+
+	"github.com/spacemeshos/smcli/common"
+)
+
+// AccountMismatch describes an account stored in a wallet that isn't the account its mnemonic derives, e.g.,
+// because the wallet file is corrupted or was tampered with.
+type AccountMismatch struct {
+	// Position is the position of the account in the wallet.
+	Position int
+	// Index is the HD account index of the account, as reported by PublicKeys.
+	Index uint32
+	// Problem describes what doesn't match.
+	Problem string
+}
+
+// Verify re-derives every account of the wallet from its mnemonic, or reads it from the Ledger device backing the
+// wallet, and checks that it matches the stored account: its path, public key, and for software wallets its
+// private key. A reopened wallet that was created with a BIP39 passphrase needs it to be passed in again using
+// WithPassphrase. Mismatches are returned in order of position, and none if the wallet is healthy. An error is
+// returned if the accounts can't be derived at all, e.g., because the mnemonic and passphrase don't match the
+// master keypair of the wallet.
+func (w *Wallet) Verify(opts ...WalletOpt) ([]AccountMismatch, error) {
+	seed, err := w.derivationSeed(opts)
+	if err != nil {
+		return nil, err
+	}
+	defer wipe(seed)
+
+	master := w.Secrets.MasterKeypair
+	var mismatches []AccountMismatch
+	seen := make(map[uint32]int, len(w.Secrets.Accounts))
+	for i, acct := range w.Secrets.Accounts {
+		if acct == nil {
+			mismatches = append(mismatches, AccountMismatch{Position: i, Index: uint32(i), Problem: "account is missing"})
+			continue
+		}
+		index := accountIndex(acct, i)
+		mismatch := func(format string, args ...interface{}) {
+			mismatches = append(mismatches,
+				AccountMismatch{Position: i, Index: index, Problem: fmt.Sprintf(format, args...)})
+		}
+		if first, ok := seen[index]; ok {
+			mismatch("same account index as the account at position %d", first)
+			continue
+		}
+		seen[index] = i
+		if index >= common.MaxAccountsPerWallet {
+			mismatch("invalid account index, must be less than %d", common.MaxAccountsPerWallet)
+			continue
+		}
+		derived, err := accountFromMaster(master, seed, int(index))
+		if err != nil {
+			return nil, fmt.Errorf("account %d: %w", i, err)
+		}
+		switch {
+		case HDPathToString(acct.Path) != HDPathToString(derived.Path):
+			mismatch("path %s, expected %s", HDPathToString(acct.Path), HDPathToString(derived.Path))
+		case acct.KeyType != master.KeyType:
+			mismatch("key type does not match the master keypair")
+		case !bytes.Equal(acct.Public, derived.Public):
+			mismatch("public key does not match the derived one")
+		case master.KeyType == typeSoftware && !bytes.Equal(acct.Private, derived.Private):
+			mismatch("private key does not match the derived one")
+		}
+		wipe(derived.Private)
+	}
+	return mismatches, nil
+}
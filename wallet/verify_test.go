@@ -0,0 +1,77 @@
+package wallet
+
+import (
+	"bytes"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+// openVerifyFixture opens testdata/wallet.json, see TestOpenFixture.
+func openVerifyFixture(t *testing.T) *Wallet {
+	data, err := os.ReadFile("testdata/wallet.json")
+	require.NoError(t, err)
+	wk := NewKey(WithPasswordOnly([]byte("password")))
+	w, err := wk.Open(bytes.NewReader(data))
+	require.NoError(t, err)
+	return w
+}
+
+func TestVerify(t *testing.T) {
+	w := openVerifyFixture(t)
+	mismatches, err := w.Verify()
+	require.NoError(t, err)
+	require.Empty(t, mismatches)
+
+	other, err := NewMultiWalletFromMnemonic(testMnemonic, 3)
+	require.NoError(t, err)
+	testCases := map[string]struct {
+		tamper  func(w *Wallet)
+		problem string
+	}{
+		"public key": {
+			func(w *Wallet) { w.Secrets.Accounts[1].Public = other.Secrets.Accounts[2].Public },
+			"public key does not match the derived one",
+		},
+		"private key": {
+			func(w *Wallet) { w.Secrets.Accounts[1].Private = other.Secrets.Accounts[2].Private },
+			"private key does not match the derived one",
+		},
+		"path": {
+			func(w *Wallet) {
+				w.Secrets.Accounts[1].Path = append(HDPath{}, w.Secrets.Accounts[1].Path...)
+				w.Secrets.Accounts[1].Path[HDChainSegment] = BIP44HardenedChain() + 1
+			},
+			"path m/44'/540'/0'/1'/1', expected m/44'/540'/0'/0'/1'",
+		},
+		"duplicate": {
+			func(w *Wallet) { w.Secrets.Accounts[1] = w.Secrets.Accounts[0] },
+			"same account index as the account at position 0",
+		},
+	}
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			w := openVerifyFixture(t)
+			tc.tamper(w)
+
+			// the tampered wallet is detected after a round trip through the wallet file
+			buf := &bytes.Buffer{}
+			wk := NewKey(WithRandomSalt(), WithPbkdf2Password([]byte("password")))
+			require.NoError(t, wk.Export(buf, w))
+			wk = NewKey(WithPasswordOnly([]byte("password")))
+			w, err := wk.Open(buf)
+			require.NoError(t, err)
+
+			mismatches, err := w.Verify()
+			require.NoError(t, err)
+			require.Len(t, mismatches, 1)
+			require.Equal(t, 1, mismatches[0].Position)
+			require.Equal(t, tc.problem, mismatches[0].Problem)
+		})
+	}
+
+	// a wrong passphrase can't verify anything
+	_, err = openVerifyFixture(t).Verify(WithPassphrase("wrong"))
+	require.ErrorIs(t, err, errPassphraseMismatch)
+}
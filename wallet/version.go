@@ -0,0 +1,55 @@
+package wallet
+
+import (
+	"fmt"
+
+	"github.com/spacemeshos/smcli/common"
+)
+
+// WalletVersion is the version of the wallet file format written by this version of smcli. It must be bumped,
+// and a migration added to migrations, whenever the format changes in a way that older files need upgrading.
+const WalletVersion = 2
+
+// ErrUnsupportedWalletVersion is returned for wallet files written by a newer version of smcli.
+var ErrUnsupportedWalletVersion = common.NewError(common.ErrBadInput, "unsupported wallet version")
+
+// migrations[v] upgrades a wallet file from version v to version v+1.
+var migrations = []func(ew *EncryptedWalletFile) error{
+	// 0 -> 1: the version field was introduced; nothing else changed.
+	func(ew *EncryptedWalletFile) error { return nil },
+	// 1 -> 2: the secrets can hold multisig accounts, see Wallet.AddMultiSig. Older files have none, so nothing
+	// changes, but older versions of smcli refuse to open the files that might, rather than dropping the accounts
+	// when they save the file again.
+	func(ew *EncryptedWalletFile) error { return nil },
+}
+
+// migrate upgrades a wallet file that was read from disk to the current version in place. Files without a
+// version predate versioning and are treated as version 0.
+func (ew *EncryptedWalletFile) migrate() error {
+	if ew.Meta.Version > WalletVersion || ew.Meta.Version < 0 {
+		return fmt.Errorf("%w %d, this version of smcli supports wallet files up to version %d",
+			ErrUnsupportedWalletVersion, ew.Meta.Version, WalletVersion)
+	}
+	for ew.Meta.Version < WalletVersion {
+		if err := migrations[ew.Meta.Version](ew); err != nil {
+			return fmt.Errorf("error migrating wallet file from version %d: %w", ew.Meta.Version, err)
+		}
+		ew.Meta.Version++
+	}
+	return nil
+}
+
+// normalizeTimestamps rewrites the creation timestamps of a wallet in common.TimeFormat. Files written by older
+// versions of smcli or by Smapp use another format, see common.ParseTimeString, and are updated the next time
+// they're saved. This is best-effort: timestamps that can't be parsed are kept as they are.
+func (w *Wallet) normalizeTimestamps() {
+	w.Meta.Created = common.NormalizeTimeString(w.Meta.Created)
+	if master := w.Secrets.MasterKeypair; master != nil {
+		master.Created = common.NormalizeTimeString(master.Created)
+	}
+	for _, acct := range w.Secrets.Accounts {
+		if acct != nil {
+			acct.Created = common.NormalizeTimeString(acct.Created)
+		}
+	}
+}
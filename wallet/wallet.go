@@ -1,11 +1,15 @@
 package wallet
 
 import (
+	"bytes"
 	"crypto/ed25519"
 	"encoding/hex"
 	"encoding/json"
 	"fmt"
+	"io"
+	"runtime"
 	"strings"
+	"sync"
 
 	"github.com/spacemeshos/go-spacemesh/common/types"
 	"github.com/spacemeshos/go-spacemesh/genvm/core"
@@ -15,7 +19,43 @@ import (
 	"github.com/spacemeshos/smcli/common"
 )
 
-var errWhitespace = fmt.Errorf("whitespace violation in mnemonic phrase")
+var (
+	errWhitespace         = common.NewError(common.ErrBadInput, "whitespace violation in mnemonic phrase")
+	errInvalidMnemonic    = common.NewError(common.ErrBadInput, "invalid mnemonic")
+	errPassphraseMismatch = common.NewError(common.ErrAuth,
+		"mnemonic and passphrase do not match the wallet master key, check passphrase")
+	errLedgerMismatch = common.NewError(common.ErrDevice,
+		"wallet master key not found on this Ledger device, check device")
+
+	// ErrNoAccounts is returned when a wallet without any accounts is created or used to access the network.
+	ErrNoAccounts = common.NewError(common.ErrBadInput, "wallet has no accounts")
+	// ErrInvalidAccountCount is returned when a wallet would contain fewer than zero or more than
+	// common.MaxAccountsPerWallet accounts. The error message reports the limit.
+	ErrInvalidAccountCount = common.NewError(common.ErrBadInput, "invalid number of accounts")
+	// ErrNoMnemonic is returned when deriving accounts or the seed of a wallet that was saved without its
+	// mnemonic, see ForgetMnemonic.
+	ErrNoMnemonic = common.NewError(common.ErrBadInput, "wallet does not contain its mnemonic")
+	// ErrInvalidSeed is returned for BIP39 seeds that can't be decoded or aren't SeedLen bytes long.
+	ErrInvalidSeed    = common.NewError(common.ErrBadInput, "invalid seed")
+	errSeedPassphrase = common.NewError(common.ErrBadInput,
+		"a wallet restored from its seed has no BIP39 passphrase, the seed already includes it")
+)
+
+// SeedLen is the length of a BIP39 seed in bytes.
+const SeedLen = 64
+
+// DefaultMnemonicWords is the length of a newly generated mnemonic unless otherwise specified.
+const DefaultMnemonicWords = 24
+
+// mnemonicEntropyBits maps each supported mnemonic length (in words) to its entropy size (in bits).
+// https://github.com/bitcoin/bips/blob/master/bip-0039.mediawiki#generating-the-mnemonic
+var mnemonicEntropyBits = map[int]int{
+	12: 128,
+	15: 160,
+	18: 192,
+	21: 224,
+	24: 256,
+}
 
 // Wallet is the basic data structure.
 type Wallet struct {
@@ -33,6 +73,8 @@ type EncryptedWalletFile struct {
 }
 
 type walletMetadata struct {
+	// Version is the wallet file format version, see WalletVersion.
+	Version     int    `json:"version"`
 	DisplayName string `json:"displayName"`
 	Created     string `json:"created"`
 	GenesisID   string `json:"genesisID"`
@@ -61,58 +103,257 @@ func (c *hexEncodedCiphertext) UnmarshalJSON(data []byte) (err error) {
 type walletSecretsEncrypted struct {
 	Cipher       string               `json:"cipher"`
 	CipherText   hexEncodedCiphertext `json:"cipherText"`
-	CipherParams struct {
-		IV hexEncodedCiphertext `json:"iv"`
-	} `json:"cipherParams"`
-	KDF       string `json:"kdf"`
-	KDFParams struct {
-		DKLen      int                  `json:"dklen"`
-		Hash       string               `json:"hash"`
-		Salt       hexEncodedCiphertext `json:"salt"`
-		Iterations int                  `json:"iterations"`
-	} `json:"kdfparams"`
+	CipherParams cipherParams         `json:"cipherParams"`
+	KDF          string               `json:"kdf"`
+	KDFParams    kdfParams            `json:"kdfparams"`
+}
+
+type cipherParams struct {
+	IV hexEncodedCiphertext `json:"iv"`
+}
+
+// kdfParams holds the params for all supported KDFs. Only those relevant to the KDF in use are set.
+type kdfParams struct {
+	DKLen int                  `json:"dklen"`
+	Hash  string               `json:"hash,omitempty"`
+	Salt  hexEncodedCiphertext `json:"salt"`
+	// the number of passes for argon2id
+	Iterations int `json:"iterations,omitempty"`
+
+	// scrypt
+	N int `json:"n,omitempty"`
+	R int `json:"r,omitempty"`
+	P int `json:"p,omitempty"`
+
+	// argon2id, memory is in KiB
+	Memory      int `json:"memory,omitempty"`
+	Parallelism int `json:"parallelism,omitempty"`
 }
 
 type walletSecrets struct {
-	Mnemonic      string `json:"mnemonic"`
+	Mnemonic secretBytes `json:"mnemonic"`
+	// Seed is the hex-encoded BIP39 seed of a wallet restored from its seed rather than from its mnemonic, see
+	// NewMultiWalletFromSeed. It's only set if the mnemonic isn't.
+	Seed          secretBytes `json:"seed,omitempty"`
 	MasterKeypair *EDKeyPair
 	Accounts      []*EDKeyPair `json:"accounts"`
+	// MultiSigs are the multisig accounts stored in the wallet, see AddMultiSig.
+	MultiSigs []*MultiSigAccount `json:"multisigs,omitempty"`
+
+	// passphrase is the optional BIP39 passphrase that was used to derive the seed. It's part of the
+	// derivation context but it's deliberately unexported so that it's never written to the wallet file.
+	passphrase string
+}
+
+// WalletOpt configures how a new wallet is derived.
+type WalletOpt func(*walletOpts)
+
+type walletOpts struct {
+	passphrase   string
+	words        int
+	ledgerDevice string
+	genesisID    types.Hash20
+	language     string
+	displayName  string
+	// accountsLater allows creating a wallet without any accounts.
+	accountsLater bool
+	// gapLimit is the number of accounts past the stored ones that FindAccount derives, or the number of
+	// consecutive unused accounts after which ScanAccounts stops.
+	gapLimit int
+	// rawKeyOrder keeps the public keys of multisig accounts in the order given, see WithRawKeyOrder.
+	rawKeyOrder bool
+	// pathTemplate is the path template of the accounts of a new wallet, see WithPathTemplate.
+	pathTemplate string
+}
+
+func newWalletOpts(opts []WalletOpt) *walletOpts {
+	o := &walletOpts{words: DefaultMnemonicWords, language: LanguageEnglish, displayName: DefaultDisplayName}
+	for _, opt := range opts {
+		opt(o)
+	}
+	return o
+}
+
+// checkAccountCount checks the number of accounts requested for a new wallet.
+func (o *walletOpts) checkAccountCount(n int) error {
+	switch {
+	case n < 0 || n > common.MaxAccountsPerWallet:
+		return fmt.Errorf("%w %d, must be between 0 and %d", ErrInvalidAccountCount, n, common.MaxAccountsPerWallet)
+	case n == 0 && !o.accountsLater:
+		return fmt.Errorf("%w: at least one account is required unless accounts are added later", ErrNoAccounts)
+	}
+	return nil
+}
+
+// genesisIDString returns the hex-encoded genesis ID, or an empty string if none was set.
+func (o *walletOpts) genesisIDString() string {
+	if o.genesisID == (types.Hash20{}) {
+		return ""
+	}
+	return hex.EncodeToString(o.genesisID[:])
+}
+
+// WithPathTemplate derives the accounts of a new wallet at the paths set by a path template, see
+// ParsePathTemplate, instead of DefaultPathTemplate, e.g., to reproduce the keys of another tool. The template is
+// recorded in the wallet as the path of its master key, so that accounts added later use it too. Wallets backed
+// by a Ledger device always use DefaultPathTemplate.
+func WithPathTemplate(template string) WalletOpt {
+	return func(o *walletOpts) {
+		o.pathTemplate = template
+	}
+}
+
+// masterPath returns the path of the master key of a new wallet, see WithPathTemplate.
+func (o *walletOpts) masterPath() (HDPath, error) {
+	if o.pathTemplate == "" {
+		return DefaultPath(), nil
+	}
+	return ParsePathTemplate(o.pathTemplate)
+}
+
+// WithPassphrase sets the optional BIP39 passphrase (sometimes called the "25th word"). The same mnemonic
+// with a different passphrase produces a completely different set of keys.
+// https://github.com/bitcoin/bips/blob/master/bip-0039.mediawiki#from-mnemonic-to-seed
+func WithPassphrase(passphrase string) WalletOpt {
+	return func(o *walletOpts) {
+		o.passphrase = passphrase
+	}
+}
+
+// WithWordCount sets the number of words in a newly generated mnemonic: one of 12, 15, 18, 21, or 24.
+// It has no effect when the mnemonic is provided by the user.
+func WithWordCount(words int) WalletOpt {
+	return func(o *walletOpts) {
+		o.words = words
+	}
+}
+
+// WithLanguage selects the BIP39 wordlist used to generate or validate a mnemonic, see Languages. The default
+// is English.
+func WithLanguage(lang string) WalletOpt {
+	return func(o *walletOpts) {
+		o.language = lang
+	}
+}
+
+// WithLedgerDevice selects the Ledger device to use, by ID (see EnumerateLedgerDevices), when more than one is
+// connected. It has no effect on wallets that aren't backed by a Ledger device.
+func WithLedgerDevice(id string) WalletOpt {
+	return func(o *walletOpts) {
+		o.ledgerDevice = id
+	}
+}
+
+// WithGenesisID records the genesis ID of the network the wallet is created for in its metadata, so that it
+// can't accidentally be used with another network (see CheckGenesisID).
+func WithGenesisID(genesisID types.Hash20) WalletOpt {
+	return func(o *walletOpts) {
+		o.genesisID = genesisID
+	}
 }
 
-func NewMultiWalletRandomMnemonic(n int) (*Wallet, error) {
+// WithDisplayName sets the display name of a new wallet, see SetDisplayName. The default is DefaultDisplayName.
+func WithDisplayName(name string) WalletOpt {
+	return func(o *walletOpts) {
+		o.displayName = name
+	}
+}
+
+// WithAccountsLater, if allow is true, allows creating a wallet with zero accounts, which can be added later
+// using AddAccounts. Otherwise asking for zero accounts is an error.
+func WithAccountsLater(allow bool) WalletOpt {
+	return func(o *walletOpts) {
+		o.accountsLater = allow
+	}
+}
+
+func NewMultiWalletRandomMnemonic(n int, opts ...WalletOpt) (*Wallet, error) {
+	o := newWalletOpts(opts)
+	bits, ok := mnemonicEntropyBits[o.words]
+	if !ok {
+		return nil, common.WithCategory(common.ErrBadInput,
+			fmt.Errorf("invalid mnemonic length %d, must be one of 12, 15, 18, 21, or 24 words", o.words))
+	}
+
 	// generate a new, random mnemonic
-	e, err := bip39.NewEntropy(ed25519.SeedSize * 8)
+	e, err := bip39.NewEntropy(bits)
 	if err != nil {
 		return nil, err
 	}
-	m, err := bip39.NewMnemonic(e)
+	m, err := newMnemonic(o.language, e)
 	if err != nil {
 		return nil, err
 	}
 
-	return NewMultiWalletFromMnemonic(m, n)
+	return NewMultiWalletFromMnemonic(m, n, opts...)
 }
 
-func NewMultiWalletFromMnemonic(m string, n int) (*Wallet, error) {
-	if n < 0 || n > common.MaxAccountsPerWallet {
-		return nil, fmt.Errorf("invalid number of accounts")
+func NewMultiWalletFromMnemonic(m string, n int, opts ...WalletOpt) (*Wallet, error) {
+	o := newWalletOpts(opts)
+	if err := o.checkAccountCount(n); err != nil {
+		return nil, err
 	}
-
-	// bip39 lib doesn't properly validate whitespace so we have to do that manually.
-	if expected := strings.Join(strings.Fields(m), " "); m != expected {
-		return nil, errWhitespace
+	m = normalizeMnemonic(m, opts)
+	if err := checkWhitespace(o.language, m); err != nil {
+		return nil, err
 	}
 
 	// this checks the number of words and the checksum.
-	if !bip39.IsMnemonicValid(m) {
-		return nil, fmt.Errorf("invalid mnemonic")
+	valid, err := isMnemonicValid(o.language, m)
+	if err != nil {
+		return nil, err
+	}
+	if !valid {
+		return nil, errInvalidMnemonic
 	}
 
-	// TODO: add option for user to provide passphrase
-	// https://github.com/spacemeshos/smcli/issues/18
+	masterPath, err := o.masterPath()
+	if err != nil {
+		return nil, err
+	}
+	seed := mnemonicToSeed(m, o.passphrase)
+	defer wipe(seed)
+	masterKeyPair, err := newMasterKeyPairAt(seed, masterPath)
+	if err != nil {
+		return nil, err
+	}
+	accounts, err := accountsFromMaster(masterKeyPair, seed, n)
+	if err != nil {
+		return nil, err
+	}
+	w, err := walletFromMnemonicAndAccounts(m, masterKeyPair, accounts)
+	if err != nil {
+		return nil, err
+	}
+	if err := w.SetDisplayName(o.displayName); err != nil {
+		return nil, err
+	}
+	w.Meta.GenesisID = o.genesisIDString()
+	w.Secrets.passphrase = o.passphrase
+	return w, nil
+}
 
-	seed := bip39.NewSeed(m, "")
-	masterKeyPair, err := NewMasterKeyPair(seed)
+// NewMultiWalletFromSeed restores a wallet from its 64-byte BIP39 seed, e.g., as printed by export-seed, when
+// the mnemonic it was derived from isn't available. The seed already includes the BIP39 passphrase, if any, so
+// WithPassphrase can't be used. The wallet has no mnemonic, which can't be recovered from the seed: the seed is
+// stored in its place so that accounts can still be added, and Seed returns it, but the wallet can't be exported
+// to Smapp or split into mnemonic shares.
+func NewMultiWalletFromSeed(seed []byte, n int, opts ...WalletOpt) (*Wallet, error) {
+	o := newWalletOpts(opts)
+	if err := o.checkAccountCount(n); err != nil {
+		return nil, err
+	}
+	if o.passphrase != "" {
+		return nil, errSeedPassphrase
+	}
+	if len(seed) != SeedLen {
+		return nil, fmt.Errorf("%w: expected %d bytes, got %d", ErrInvalidSeed, SeedLen, len(seed))
+	}
+	masterPath, err := o.masterPath()
+	if err != nil {
+		return nil, err
+	}
+	masterKeyPair, err := newMasterKeyPairAt(seed, masterPath)
 	if err != nil {
 		return nil, err
 	}
@@ -120,14 +361,49 @@ func NewMultiWalletFromMnemonic(m string, n int) (*Wallet, error) {
 	if err != nil {
 		return nil, err
 	}
-	return walletFromMnemonicAndAccounts(m, masterKeyPair, accounts)
+	w, err := walletFromMnemonicAndAccounts("", masterKeyPair, accounts)
+	if err != nil {
+		return nil, err
+	}
+	w.Secrets.Mnemonic = nil
+	w.Secrets.Seed = secretBytes(hex.EncodeToString(seed))
+	if err := w.SetDisplayName(o.displayName); err != nil {
+		return nil, err
+	}
+	w.Meta.GenesisID = o.genesisIDString()
+	return w, nil
+}
+
+// ParseSeed decodes a hex-encoded BIP39 seed, with or without a 0x prefix.
+func ParseSeed(s string) ([]byte, error) {
+	seed, err := hex.DecodeString(strings.TrimPrefix(strings.TrimSpace(s), "0x"))
+	if err != nil {
+		return nil, fmt.Errorf("%w: not hex", ErrInvalidSeed)
+	}
+	if len(seed) != SeedLen {
+		wipe(seed)
+		return nil, fmt.Errorf("%w: expected %d bytes, got %d", ErrInvalidSeed, SeedLen, len(seed))
+	}
+	return seed, nil
+}
+
+// IsSeedOnly reports whether the wallet was restored from its seed, see NewMultiWalletFromSeed, so that it has
+// no mnemonic.
+func (w *Wallet) IsSeedOnly() bool {
+	return len(w.Secrets.Mnemonic) == 0 && len(w.Secrets.Seed) > 0
 }
 
-func NewMultiWalletFromLedger(n int) (*Wallet, error) {
-	if n < 0 || n > common.MaxAccountsPerWallet {
-		return nil, fmt.Errorf("invalid number of accounts")
+func NewMultiWalletFromLedger(n int, opts ...WalletOpt) (*Wallet, error) {
+	o := newWalletOpts(opts)
+	if err := o.checkAccountCount(n); err != nil {
+		return nil, err
+	}
+	if masterPath, err := o.masterPath(); err != nil {
+		return nil, err
+	} else if HDPathToString(masterPath) != HDPathToString(DefaultPath()) {
+		return nil, fmt.Errorf("%w: Ledger wallets always use %s", ErrInvalidPathTemplate, DefaultPathTemplate)
 	}
-	masterKeyPair, err := NewMasterKeyPairFromLedger()
+	masterKeyPair, err := masterKeyPairFromLedger(o.ledgerDevice)
 	if err != nil {
 		return nil, err
 	}
@@ -136,19 +412,26 @@ func NewMultiWalletFromLedger(n int) (*Wallet, error) {
 	if err != nil {
 		return nil, err
 	}
-	return walletFromMnemonicAndAccounts("(none)", masterKeyPair, accounts)
+	w, err := walletFromMnemonicAndAccounts("(none)", masterKeyPair, accounts)
+	if err != nil {
+		return nil, err
+	}
+	if err := w.SetDisplayName(o.displayName); err != nil {
+		return nil, err
+	}
+	w.Meta.GenesisID = o.genesisIDString()
+	return w, nil
 }
 
 func walletFromMnemonicAndAccounts(m string, masterKp *EDKeyPair, kp []*EDKeyPair) (*Wallet, error) {
 	w := &Wallet{
 		Meta: walletMetadata{
-			DisplayName: "Main Wallet",
+			Version:     WalletVersion,
+			DisplayName: DefaultDisplayName,
 			Created:     common.NowTimeString(),
-			// TODO: set correctly
-			GenesisID: "",
 		},
 		Secrets: walletSecrets{
-			Mnemonic:      m,
+			Mnemonic:      secretBytes(m),
 			MasterKeypair: masterKp,
 			Accounts:      kp,
 		},
@@ -159,26 +442,285 @@ func walletFromMnemonicAndAccounts(m string, masterKp *EDKeyPair, kp []*EDKeyPai
 // accountsFromMaster generates one or more accounts from a master keypair and seed. Accounts use sequential HD paths.
 // The master keypair does not contain the seed that was used to generate it, so it needs to be passed in explicitly.
 func accountsFromMaster(masterKeypair *EDKeyPair, masterSeed []byte, n int) (accounts []*EDKeyPair, err error) {
-	accounts = make([]*EDKeyPair, 0, n)
-	for i := 0; i < n; i++ {
-		acct, err := masterKeypair.NewChildKeyPair(masterSeed, i)
+	indices := make([]int, n)
+	for i := range indices {
+		indices[i] = i
+	}
+	return deriveAccounts(masterKeypair, masterSeed, indices, derivationWorkers(masterKeypair))
+}
+
+// accountFromMaster derives a single account from a master keypair and seed at an arbitrary account index.
+func accountFromMaster(masterKeypair *EDKeyPair, masterSeed []byte, idx int) (*EDKeyPair, error) {
+	if idx < 0 || idx >= common.MaxAccountsPerWallet {
+		return nil, fmt.Errorf("invalid account index %d, must be between 0 and %d", idx, common.MaxAccountsPerWallet-1)
+	}
+	return masterKeypair.NewChildKeyPair(masterSeed, idx)
+}
+
+// accountsFromMasterAtIndices derives accounts from a master keypair and seed at an explicit, possibly
+// non-contiguous set of account indices. Accounts are returned in the order the indices were given.
+func accountsFromMasterAtIndices(masterKeypair *EDKeyPair, masterSeed []byte, indices []int) ([]*EDKeyPair, error) {
+	if len(indices) > common.MaxAccountsPerWallet {
+		return nil, fmt.Errorf("%w %d, must be at most %d",
+			ErrInvalidAccountCount, len(indices), common.MaxAccountsPerWallet)
+	}
+	seen := make(map[int]struct{}, len(indices))
+	for _, idx := range indices {
+		if _, ok := seen[idx]; ok {
+			return nil, fmt.Errorf("duplicate account index %d", idx)
+		}
+		seen[idx] = struct{}{}
+	}
+	return deriveAccounts(masterKeypair, masterSeed, indices, derivationWorkers(masterKeypair))
+}
+
+// derivationWorkers returns the number of accounts of a master keypair to derive concurrently: one per CPU for
+// software keys, and one for a Ledger device, which handles a single request at a time.
+func derivationWorkers(masterKeypair *EDKeyPair) int {
+	if masterKeypair.KeyType == typeLedger {
+		return 1
+	}
+	return runtime.GOMAXPROCS(0)
+}
+
+// deriveAccounts derives the accounts at the given indices using up to workers goroutines. accounts[i] is always
+// the account at indices[i], so the result doesn't depend on the number of workers.
+func deriveAccounts(masterKeypair *EDKeyPair, masterSeed []byte, indices []int, workers int) ([]*EDKeyPair, error) {
+	accounts := make([]*EDKeyPair, len(indices))
+	errs := make([]error, len(indices))
+	if workers > len(indices) {
+		workers = len(indices)
+	}
+	task := common.Progress().Start("Deriving accounts", len(indices))
+	defer task.Done()
+	jobs := make(chan int)
+	var wg sync.WaitGroup
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range jobs {
+				accounts[i], errs[i] = accountFromMaster(masterKeypair, masterSeed, indices[i])
+				task.Add(1)
+			}
+		}()
+	}
+	for i := range indices {
+		jobs <- i
+	}
+	close(jobs)
+	wg.Wait()
+
+	for _, err := range errs {
 		if err != nil {
+			for _, acct := range accounts {
+				if acct != nil {
+					wipe(acct.Private)
+				}
+			}
 			return nil, err
 		}
-		accounts = append(accounts, acct)
 	}
-	return
+	return accounts, nil
 }
 
+// Mnemonic returns the mnemonic of the wallet. The result is a copy that can't be wiped, see Wipe.
 func (w *Wallet) Mnemonic() string {
-	return w.Secrets.Mnemonic
+	return string(w.Secrets.Mnemonic)
 }
 
-func PubkeyToAddress(pubkey []byte, hrp string) string {
-	types.SetNetworkHRP(hrp)
+// Seed returns the BIP39 seed that the keys of the wallet are derived from, e.g., to reproduce the derivation
+// using another library. Like AddAccounts, a reopened wallet that was created with a BIP39 passphrase needs it
+// to be passed in again using WithPassphrase. Anyone who has the seed controls all of the accounts of the wallet:
+// wipe it once done. Wallets backed by a Ledger device have no seed that can be exported.
+func (w *Wallet) Seed(opts ...WalletOpt) ([]byte, error) {
+	if master := w.Secrets.MasterKeypair; master != nil && master.KeyType == typeLedger {
+		return nil, fmt.Errorf("the seed of a Ledger wallet never leaves the device")
+	}
+	return w.derivationSeed(opts)
+}
+
+// AddAccounts derives k more accounts, continuing the sequence from the current number of accounts, and
+// appends them to the wallet. Derivation is deterministic: the same mnemonic always yields the same new
+// accounts. Since the BIP39 passphrase is never stored in the wallet file, a wallet that was created with one
+// and then reopened needs it to be passed in again using WithPassphrase.
+func (w *Wallet) AddAccounts(k int, opts ...WalletOpt) error {
+	n := len(w.Secrets.Accounts)
+	switch {
+	case k < 0:
+		return fmt.Errorf("%w to add %d, must be at least 0", ErrInvalidAccountCount, k)
+	case n+k > common.MaxAccountsPerWallet:
+		return fmt.Errorf("%w to add %d, the wallet has %d and can contain at most %d",
+			ErrInvalidAccountCount, k, n, common.MaxAccountsPerWallet)
+	}
+	seed, err := w.derivationSeed(opts)
+	if err != nil {
+		return err
+	}
+	defer wipe(seed)
+
+	indices := make([]int, k)
+	for i := range indices {
+		indices[i] = n + i
+	}
+	accounts, err := accountsFromMasterAtIndices(w.Secrets.MasterKeypair, seed, indices)
+	if err != nil {
+		return err
+	}
+	w.Secrets.Accounts = append(w.Secrets.Accounts, accounts...)
+	return nil
+}
+
+// derivationSeed prepares the wallet for deriving more accounts and returns the seed to derive them from. For a
+// Ledger wallet the seed is empty and the device, selected using WithLedgerDevice, must be the one that created the
+// wallet. Otherwise it's derived from the mnemonic and the passphrase, set using WithPassphrase if the wallet was
+// reopened, or read from the wallet if it was restored from its seed, and checked against the master keypair.
+// Wallets without a master keypair, i.e., imported from a private key, return ErrNotDerivable. The caller should
+// wipe the seed when done.
+func (w *Wallet) derivationSeed(opts []WalletOpt) ([]byte, error) {
+	master := w.Secrets.MasterKeypair
+	if master == nil {
+		return nil, ErrNotDerivable
+	}
+
+	o := &walletOpts{passphrase: w.Secrets.passphrase, ledgerDevice: master.ledgerDevice}
+	for _, opt := range opts {
+		opt(o)
+	}
+
+	// seed is not used in case of ledger
+	if master.KeyType == typeLedger {
+		deviceMaster, err := masterKeyPairFromLedger(o.ledgerDevice)
+		if err != nil {
+			return nil, err
+		}
+		if !bytes.Equal(deviceMaster.Public, master.Public) {
+			return nil, errLedgerMismatch
+		}
+		master.ledgerDevice = deviceMaster.ledgerDevice
+		return []byte{}, nil
+	}
+	var seed []byte
+	switch {
+	case len(w.Secrets.Mnemonic) > 0:
+		m := w.Mnemonic()
+		if _, err := mnemonicLanguage(m); err != nil {
+			return nil, fmt.Errorf("wallet does not contain a valid mnemonic")
+		}
+		seed = mnemonicToSeed(m, o.passphrase)
+	case len(w.Secrets.Seed) > 0:
+		if o.passphrase != "" {
+			return nil, errSeedPassphrase
+		}
+		var err error
+		if seed, err = ParseSeed(string(w.Secrets.Seed)); err != nil {
+			return nil, fmt.Errorf("wallet does not contain a valid seed: %w", err)
+		}
+	default:
+		return nil, fmt.Errorf("%w, so no accounts can be derived from it: restore the wallet from its mnemonic "+
+			"to add accounts", ErrNoMnemonic)
+	}
+	// the master key is at the path set by the path template of the wallet
+	masterPath := master.Path
+	if len(masterPath) == 0 {
+		masterPath = DefaultPath()
+	}
+	derivedMaster, err := newMasterKeyPairAt(seed, masterPath)
+	if err != nil {
+		wipe(seed)
+		return nil, err
+	}
+	if !bytes.Equal(derivedMaster.Public, master.Public) {
+		wipe(seed)
+		return nil, errPassphraseMismatch
+	}
+	w.Secrets.passphrase = o.passphrase
+	return seed, nil
+}
+
+// Addresses returns the address of each account in the wallet, in order, using the given network HRP.
+func (w *Wallet) Addresses(hrp string) []string {
+	addresses := make([]string, 0, len(w.Secrets.Accounts))
+	for _, acct := range w.Secrets.Accounts {
+		addresses = append(addresses, PubkeyToAddress(acct.Public, hrp))
+	}
+	return addresses
+}
+
+// AccountPublicKey holds the public, shareable details of a wallet account. It never contains private keys.
+// The JSON encoding, written by WriteAccountsJSON, is part of the machine-readable CLI output and must remain
+// stable: fields may be added but not renamed or removed.
+type AccountPublicKey struct {
+	// Index is the account index in the HD path.
+	Index uint32 `json:"index"`
+	// Path is the full HD path of the account, e.g., m/44'/540'/0'/0'/0'.
+	Path   string `json:"path"`
+	Hex    string `json:"pubkeyHex"`
+	Base64 string `json:"-"`
+	// Address is bech32-encoded using the HRP passed to PublicKeys.
+	Address string `json:"address"`
+	// Label is the user-chosen label of the account, if any.
+	Label string `json:"label,omitempty"`
+}
+
+// PublicKeys returns the public key of each account in the wallet, in order, in several encodings along with
+// its address.
+func (w *Wallet) PublicKeys(hrp string) ([]AccountPublicKey, error) {
+	keys := make([]AccountPublicKey, 0, len(w.Secrets.Accounts))
+	for i, acct := range w.Secrets.Accounts {
+		hexKey, err := acct.ExportPublicKey(PubkeyEncodingHex)
+		if err != nil {
+			return nil, fmt.Errorf("account %d: %w", i, err)
+		}
+		base64Key, err := acct.ExportPublicKey(PubkeyEncodingBase64)
+		if err != nil {
+			return nil, fmt.Errorf("account %d: %w", i, err)
+		}
+		keys = append(keys, AccountPublicKey{
+			Index:   accountIndex(acct, i),
+			Path:    acct.Path.String(),
+			Hex:     hexKey,
+			Base64:  base64Key,
+			Address: PubkeyToAddress(acct.Public, hrp),
+			Label:   acct.Label,
+		})
+	}
+	return keys, nil
+}
+
+// WriteAccountsJSON writes the public keys of the accounts in the wallet, as returned by PublicKeys, to file as a
+// JSON array.
+func (w *Wallet) WriteAccountsJSON(file io.Writer, hrp string) error {
+	keys, err := w.PublicKeys(hrp)
+	if err != nil {
+		return err
+	}
+	enc := json.NewEncoder(file)
+	enc.SetIndent("", "  ")
+	return enc.Encode(keys)
+}
+
+// Principals returns the address of each account in the wallet, in order. It returns ErrNoAccounts if there
+// are none, so that network operations on a wallet without accounts fail rather than silently doing nothing.
+func (w *Wallet) Principals() ([]core.Address, error) {
+	if len(w.Secrets.Accounts) == 0 {
+		return nil, fmt.Errorf("%w, add accounts to it first", ErrNoAccounts)
+	}
+	principals := make([]core.Address, 0, len(w.Secrets.Accounts))
+	for _, acct := range w.Secrets.Accounts {
+		principals = append(principals, PubkeyToPrincipal(acct.Public))
+	}
+	return principals, nil
+}
+
+// PubkeyToPrincipal returns the address of the single-sig wallet account with the given public key.
+func PubkeyToPrincipal(pubkey []byte) core.Address {
 	key := [ed25519.PublicKeySize]byte{}
 	copy(key[:], pubkey)
 	walletArgs := &walletTemplate.SpawnArguments{PublicKey: key}
-	walletAddress := core.ComputePrincipal(walletTemplate.TemplateAddress, walletArgs)
-	return walletAddress.String()
+	return core.ComputePrincipal(walletTemplate.TemplateAddress, walletArgs)
+}
+
+func PubkeyToAddress(pubkey []byte, hrp string) string {
+	return AddressString(PubkeyToPrincipal(pubkey), hrp)
 }
@@ -2,13 +2,18 @@ package wallet
 
 import (
 	"crypto/ed25519"
+	"encoding/base64"
 	"encoding/hex"
+	"encoding/json"
 	"fmt"
+	"strings"
 	"testing"
 
 	"github.com/spacemeshos/go-spacemesh/common/types"
 	"github.com/stretchr/testify/require"
 	"github.com/tyler-smith/go-bip39"
+
+	"github.com/spacemeshos/smcli/common"
 )
 
 const Bip44Prefix = "m/44'/540'"
@@ -127,3 +132,478 @@ func TestMnemonicWhitespace(t *testing.T) {
 		require.Equal(t, errWhitespace, err, "expected whitespace error in mnemonic")
 	}
 }
+
+func TestMnemonicWithPassphrase(t *testing.T) {
+	mnemonic := "film theme cheese broken kingdom destroy inch ready wear inspire shove pudding"
+	n := 3
+
+	w1, err := NewMultiWalletFromMnemonic(mnemonic, n)
+	require.NoError(t, err)
+
+	// an empty passphrase is the same as no passphrase
+	w2, err := NewMultiWalletFromMnemonic(mnemonic, n, WithPassphrase(""))
+	require.NoError(t, err)
+	require.Equal(t, w1.Secrets.MasterKeypair.Public, w2.Secrets.MasterKeypair.Public)
+	for i := 0; i < n; i++ {
+		require.Equal(t, w1.Secrets.Accounts[i].Private, w2.Secrets.Accounts[i].Private)
+	}
+
+	// same mnemonic, different passphrases must produce completely different keys
+	w3, err := NewMultiWalletFromMnemonic(mnemonic, n, WithPassphrase("hidden wallet"))
+	require.NoError(t, err)
+	w4, err := NewMultiWalletFromMnemonic(mnemonic, n, WithPassphrase("hidden wallet 2"))
+	require.NoError(t, err)
+	require.Equal(t, mnemonic, w3.Mnemonic())
+	require.Equal(t, mnemonic, w4.Mnemonic())
+	for _, pair := range [][2]*Wallet{{w1, w3}, {w1, w4}, {w3, w4}} {
+		a, b := pair[0], pair[1]
+		require.NotEqual(t, a.Secrets.MasterKeypair.Public, b.Secrets.MasterKeypair.Public)
+		require.NotEqual(t, a.Secrets.MasterKeypair.Private, b.Secrets.MasterKeypair.Private)
+		for i := 0; i < n; i++ {
+			require.NotEqual(t, a.Secrets.Accounts[i].Private, b.Secrets.Accounts[i].Private)
+			require.NotEqual(t,
+				PubkeyToAddress(a.Secrets.Accounts[i].Public, types.NetworkHRP()),
+				PubkeyToAddress(b.Secrets.Accounts[i].Public, types.NetworkHRP()),
+			)
+		}
+	}
+
+	// the passphrase is deterministic
+	w5, err := NewMultiWalletFromMnemonic(mnemonic, n, WithPassphrase("hidden wallet"))
+	require.NoError(t, err)
+	for i := 0; i < n; i++ {
+		require.Equal(t, w3.Secrets.Accounts[i].Private, w5.Secrets.Accounts[i].Private)
+	}
+
+	// and it's never serialized
+	plaintext, err := json.Marshal(w3.Secrets)
+	require.NoError(t, err)
+	require.NotContains(t, string(plaintext), "hidden wallet")
+}
+
+func TestSeed(t *testing.T) {
+	mnemonic := "film theme cheese broken kingdom destroy inch ready wear inspire shove pudding"
+
+	w, err := NewMultiWalletFromMnemonic(mnemonic, 1)
+	require.NoError(t, err)
+	seed, err := w.Seed()
+	require.NoError(t, err)
+	require.Equal(t, bip39.NewSeed(mnemonic, ""), seed)
+
+	w, err = NewMultiWalletFromMnemonic(mnemonic, 1, WithPassphrase("hidden wallet"))
+	require.NoError(t, err)
+	seed, err = w.Seed()
+	require.NoError(t, err)
+	require.Equal(t, bip39.NewSeed(mnemonic, "hidden wallet"), seed)
+
+	// a reopened wallet needs the passphrase again, and it must be the right one
+	w, err = walletFromMnemonicAndAccounts(mnemonic, w.Secrets.MasterKeypair, w.Secrets.Accounts)
+	require.NoError(t, err)
+	_, err = w.Seed()
+	require.ErrorIs(t, err, errPassphraseMismatch)
+	_, err = w.Seed(WithPassphrase("wrong"))
+	require.ErrorIs(t, err, errPassphraseMismatch)
+	seed, err = w.Seed(WithPassphrase("hidden wallet"))
+	require.NoError(t, err)
+	require.Equal(t, bip39.NewSeed(mnemonic, "hidden wallet"), seed)
+}
+
+func TestMnemonicWordCount(t *testing.T) {
+	for words := range mnemonicEntropyBits {
+		t.Run(fmt.Sprintf("%d words", words), func(t *testing.T) {
+			w1, err := NewMultiWalletRandomMnemonic(1, WithWordCount(words))
+			require.NoError(t, err)
+			require.Len(t, strings.Fields(w1.Mnemonic()), words)
+
+			// importing the same mnemonic must produce the same master keypair
+			w2, err := NewMultiWalletFromMnemonic(w1.Mnemonic(), 1)
+			require.NoError(t, err)
+			require.Equal(t, w1.Secrets.MasterKeypair.Public, w2.Secrets.MasterKeypair.Public)
+			require.Equal(t, w1.Secrets.MasterKeypair.Private, w2.Secrets.MasterKeypair.Private)
+			require.Equal(t, w1.Secrets.Accounts[0].Private, w2.Secrets.Accounts[0].Private)
+		})
+	}
+
+	// default length
+	w, err := NewMultiWalletRandomMnemonic(1)
+	require.NoError(t, err)
+	require.Len(t, strings.Fields(w.Mnemonic()), DefaultMnemonicWords)
+
+	// unsupported lengths
+	for _, words := range []int{0, 11, 13, 25} {
+		_, err := NewMultiWalletRandomMnemonic(1, WithWordCount(words))
+		require.Error(t, err)
+	}
+}
+
+func TestAccountsAtIndices(t *testing.T) {
+	master, err := NewMasterKeyPair(goodSeed)
+	require.NoError(t, err)
+
+	// a single account at an arbitrary index
+	acct, err := accountFromMaster(master, goodSeed, 17)
+	require.NoError(t, err)
+	require.Equal(t, "m/44'/540'/0'/0'/17'", acct.Path.String())
+	require.Equal(t, "edb1f6c390193978230af0117b906d3f0d5c05c51f8abc1b5cb935c364889627", hex.EncodeToString(acct.Public))
+	require.Equal(t, "sm1qqqqqqzf6ax5gm03g6azj3uhtncjfdh5n9ss7wqkthphw", PubkeyToAddress(acct.Public, "sm"))
+
+	// a batch of non-contiguous indices, in the given order
+	accts, err := accountsFromMasterAtIndices(master, goodSeed, []int{5, 0, 127})
+	require.NoError(t, err)
+	require.Len(t, accts, 3)
+	require.Equal(t, "sm1qqqqqqx4wjy3csajsfpm3xetzzsg0vf88zzejnc7ur26f", PubkeyToAddress(accts[0].Public, "sm"))
+	require.Equal(t, "feae6977b42bf3441d04314d09c72c5d6f2d1cb4bf94834680785b819f8738dd", hex.EncodeToString(accts[1].Public))
+	require.Equal(t, "sm1qqqqqq9mfjys82xhj4ze8ppuw5yv3ju47klu9zgsmdz8x", PubkeyToAddress(accts[2].Public, "sm"))
+
+	// matches sequential derivation
+	seq, err := accountsFromMaster(master, goodSeed, 6)
+	require.NoError(t, err)
+	require.Equal(t, seq[5].Private, accts[0].Private)
+	require.Equal(t, seq[0].Private, accts[1].Private)
+
+	// invalid indices
+	_, err = accountFromMaster(master, goodSeed, -1)
+	require.Error(t, err)
+	_, err = accountFromMaster(master, goodSeed, common.MaxAccountsPerWallet)
+	require.Error(t, err)
+	_, err = accountsFromMasterAtIndices(master, goodSeed, []int{0, 5, 0})
+	require.EqualError(t, err, "duplicate account index 0")
+	_, err = accountsFromMasterAtIndices(master, goodSeed, []int{0, common.MaxAccountsPerWallet})
+	require.Error(t, err)
+}
+
+func TestAddAccounts(t *testing.T) {
+	mnemonic := "film theme cheese broken kingdom destroy inch ready wear inspire shove pudding"
+
+	for _, passphrase := range []string{"", "hidden"} {
+		w5, err := NewMultiWalletFromMnemonic(mnemonic, 5, WithPassphrase(passphrase))
+		require.NoError(t, err)
+		w, err := NewMultiWalletFromMnemonic(mnemonic, 2, WithPassphrase(passphrase))
+		require.NoError(t, err)
+
+		// grow a 2-account wallet to 5
+		require.NoError(t, w.AddAccounts(3))
+		require.Len(t, w.Secrets.Accounts, 5)
+		for i := range w5.Secrets.Accounts {
+			require.Equal(t, w5.Secrets.Accounts[i].Private, w.Secrets.Accounts[i].Private)
+			require.Equal(t, w5.Secrets.Accounts[i].Path, w.Secrets.Accounts[i].Path)
+		}
+	}
+
+	// the passphrase isn't stored, so a reopened wallet needs it again
+	w, err := NewMultiWalletFromMnemonic(mnemonic, 1, WithPassphrase("hidden"))
+	require.NoError(t, err)
+	w.Secrets.passphrase = ""
+	require.ErrorIs(t, w.AddAccounts(1), errPassphraseMismatch)
+	require.ErrorIs(t, w.AddAccounts(1, WithPassphrase("wrong")), errPassphraseMismatch)
+	require.Len(t, w.Secrets.Accounts, 1)
+	require.NoError(t, w.AddAccounts(1, WithPassphrase("hidden")))
+	require.Len(t, w.Secrets.Accounts, 2)
+
+	// respect the limit
+	require.Error(t, w.AddAccounts(common.MaxAccountsPerWallet-1))
+	require.Error(t, w.AddAccounts(-1))
+	require.NoError(t, w.AddAccounts(common.MaxAccountsPerWallet-2))
+	require.Len(t, w.Secrets.Accounts, common.MaxAccountsPerWallet)
+}
+
+func TestPubkeyToAddressNetworks(t *testing.T) {
+	pubkey, err := hex.DecodeString("de30fc9b812248583da6259433626fcdd2cb5ce589b00047b81e127950b9bca6")
+	require.NoError(t, err)
+
+	expected := map[string]string{
+		common.NetworkMainnet: "sm1qqqqqqz9rf583slhn38g6q6a562ctltv9fv5w8q2gdz9k",
+		common.NetworkTestnet: "stest1qqqqqqz9rf583slhn38g6q6a562ctltv9fv5w8qha56t0",
+	}
+	require.Len(t, expected, len(common.NetworkHRPs))
+	for network, hrp := range common.NetworkHRPs {
+		require.Equal(t, expected[network], PubkeyToAddress(pubkey, hrp))
+	}
+	require.NotEqual(t, expected[common.NetworkMainnet], expected[common.NetworkTestnet])
+}
+
+func TestPublicKeys(t *testing.T) {
+	master, err := NewMasterKeyPair(goodSeed)
+	require.NoError(t, err)
+	accts, err := accountsFromMasterAtIndices(master, goodSeed, []int{5, 17})
+	require.NoError(t, err)
+	w, err := walletFromMnemonicAndAccounts("", master, accts)
+	require.NoError(t, err)
+
+	keys, err := w.PublicKeys("sm")
+	require.NoError(t, err)
+	require.Equal(t, []AccountPublicKey{
+		{
+			Index:   5,
+			Path:    Bip44Prefix + "/0'/0'/5'",
+			Hex:     hex.EncodeToString(accts[0].Public),
+			Base64:  base64.StdEncoding.EncodeToString(accts[0].Public),
+			Address: "sm1qqqqqqx4wjy3csajsfpm3xetzzsg0vf88zzejnc7ur26f",
+		},
+		{
+			Index:   17,
+			Path:    Bip44Prefix + "/0'/0'/17'",
+			Hex:     "edb1f6c390193978230af0117b906d3f0d5c05c51f8abc1b5cb935c364889627",
+			Base64:  "7bH2w5AZOXgjCvARe5BtPw1cBcUfirwbXLk1w2SIlic=",
+			Address: "sm1qqqqqqzf6ax5gm03g6azj3uhtncjfdh5n9ss7wqkthphw",
+		},
+	}, keys)
+}
+
+func TestWriteAccountsJSON(t *testing.T) {
+	master, err := NewMasterKeyPair(goodSeed)
+	require.NoError(t, err)
+	accts, err := accountsFromMasterAtIndices(master, goodSeed, []int{0, 17})
+	require.NoError(t, err)
+	w, err := walletFromMnemonicAndAccounts("", master, accts)
+	require.NoError(t, err)
+
+	var buf strings.Builder
+	require.NoError(t, w.WriteAccountsJSON(&buf, "sm"))
+	require.NotContains(t, buf.String(), hex.EncodeToString(accts[1].Private))
+
+	// decode into a generic structure so that the field names are checked too
+	var decoded []map[string]interface{}
+	require.NoError(t, json.Unmarshal([]byte(buf.String()), &decoded))
+	require.Equal(t, []map[string]interface{}{
+		{
+			"index":     float64(0),
+			"path":      Bip44Prefix + "/0'/0'/0'",
+			"pubkeyHex": hex.EncodeToString(accts[0].Public),
+			"address":   PubkeyToAddress(accts[0].Public, "sm"),
+		},
+		{
+			"index":     float64(17),
+			"path":      Bip44Prefix + "/0'/0'/17'",
+			"pubkeyHex": "edb1f6c390193978230af0117b906d3f0d5c05c51f8abc1b5cb935c364889627",
+			"address":   "sm1qqqqqqzf6ax5gm03g6azj3uhtncjfdh5n9ss7wqkthphw",
+		},
+	}, decoded)
+}
+
+func TestZeroAccounts(t *testing.T) {
+	const goodMnemonic = "film theme cheese broken kingdom destroy inch ready wear inspire shove pudding"
+	_, err := NewMultiWalletRandomMnemonic(0)
+	require.ErrorIs(t, err, ErrNoAccounts)
+	_, err = NewMultiWalletFromMnemonic(goodMnemonic, 0)
+	require.ErrorIs(t, err, ErrNoAccounts)
+	useMockLedger(t, newMockLedger(LedgerDevice{ID: "usb://ledger?id=1", Product: "Nano S"}))
+	_, err = NewMultiWalletFromLedger(0)
+	require.ErrorIs(t, err, ErrNoAccounts)
+	_, err = NewMultiWalletFromMnemonic(goodMnemonic, 0, WithAccountsLater(false))
+	require.ErrorIs(t, err, ErrNoAccounts)
+
+	// unless explicitly allowed
+	w, err := NewMultiWalletFromMnemonic(goodMnemonic, 0, WithAccountsLater(true))
+	require.NoError(t, err)
+	require.Empty(t, w.Secrets.Accounts)
+	require.NotNil(t, w.Secrets.MasterKeypair)
+	w2, err := NewMultiWalletFromLedger(0, WithAccountsLater(true))
+	require.NoError(t, err)
+	require.Empty(t, w2.Secrets.Accounts)
+
+	// network operations refuse to use an empty wallet
+	_, err = w.Principals()
+	require.ErrorIs(t, err, ErrNoAccounts)
+
+	require.NoError(t, w.AddAccounts(2))
+	principals, err := w.Principals()
+	require.NoError(t, err)
+	require.Len(t, principals, 2)
+	for i, addr := range w.Addresses("sm") {
+		require.Equal(t, addr, AddressString(principals[i], "sm"))
+	}
+}
+
+func TestInvalidAccountCount(t *testing.T) {
+	mnemonic := "film theme cheese broken kingdom destroy inch ready wear inspire shove pudding"
+	useMockLedger(t, newMockLedger(LedgerDevice{ID: "usb://ledger?id=1", Product: "Nano S"}))
+	limit := fmt.Sprint(common.MaxAccountsPerWallet)
+
+	for _, n := range []int{-1, common.MaxAccountsPerWallet + 1, 40000} {
+		_, err := NewMultiWalletFromMnemonic(mnemonic, n)
+		require.ErrorIs(t, err, ErrInvalidAccountCount)
+		require.Contains(t, err.Error(), limit)
+		require.Contains(t, err.Error(), fmt.Sprint(n))
+
+		_, err = NewMultiWalletFromLedger(n)
+		require.ErrorIs(t, err, ErrInvalidAccountCount)
+		require.Contains(t, err.Error(), limit)
+		require.Contains(t, err.Error(), fmt.Sprint(n))
+	}
+	_, err := NewMultiWalletRandomMnemonic(common.MaxAccountsPerWallet + 1)
+	require.ErrorIs(t, err, ErrInvalidAccountCount)
+	require.EqualError(t, err, fmt.Sprintf("invalid number of accounts %d, must be between 0 and %d",
+		common.MaxAccountsPerWallet+1, common.MaxAccountsPerWallet))
+
+	// the limit applies to the wallet as a whole
+	w, err := NewMultiWalletFromMnemonic(mnemonic, 100)
+	require.NoError(t, err)
+	err = w.AddAccounts(common.MaxAccountsPerWallet - 99)
+	require.ErrorIs(t, err, ErrInvalidAccountCount)
+	require.EqualError(t, err, fmt.Sprintf("invalid number of accounts to add %d, the wallet has 100 and can "+
+		"contain at most %d", common.MaxAccountsPerWallet-99, common.MaxAccountsPerWallet))
+	err = w.AddAccounts(-2)
+	require.ErrorIs(t, err, ErrInvalidAccountCount)
+	require.Contains(t, err.Error(), "-2")
+}
+
+func TestDeriveAccountsParallel(t *testing.T) {
+	master, err := NewMasterKeyPair(goodSeed)
+	require.NoError(t, err)
+	indices := make([]int, common.MaxAccountsPerWallet)
+	for i := range indices {
+		// out of order, to check that results land at the position of their index
+		indices[i] = (i * 37) % common.MaxAccountsPerWallet
+	}
+
+	serial, err := deriveAccounts(master, goodSeed, indices, 1)
+	require.NoError(t, err)
+	for _, workers := range []int{2, 8, 1000} {
+		parallel, err := deriveAccounts(master, goodSeed, indices, workers)
+		require.NoError(t, err)
+		require.Len(t, parallel, len(serial))
+		for i, acct := range parallel {
+			require.Equal(t, serial[i].Private, acct.Private)
+			require.Equal(t, serial[i].Public, acct.Public)
+			require.Equal(t, serial[i].Path, acct.Path)
+			require.Equal(t, serial[i].DisplayName, acct.DisplayName)
+			require.Equal(t, BIP44HardenedAccountIndex(uint32(indices[i])), acct.Path.Index())
+		}
+	}
+
+	// the wallet constructor uses the same derivation
+	w, err := NewMultiWalletFromMnemonic(
+		"film theme cheese broken kingdom destroy inch ready wear inspire shove pudding", 100)
+	require.NoError(t, err)
+	seed := mnemonicToSeed(w.Mnemonic(), "")
+	for i, acct := range w.Secrets.Accounts {
+		want, err := w.Secrets.MasterKeypair.NewChildKeyPair(seed, i)
+		require.NoError(t, err)
+		require.Equal(t, want.Private, acct.Private)
+	}
+
+	// the first error wins, whatever the number of workers
+	_, err = deriveAccounts(master, goodSeed, []int{0, 1, common.MaxAccountsPerWallet, 2}, 4)
+	require.ErrorContains(t, err, fmt.Sprintf("invalid account index %d", common.MaxAccountsPerWallet))
+	accounts, err := deriveAccounts(master, goodSeed, nil, 4)
+	require.NoError(t, err)
+	require.Empty(t, accounts)
+}
+
+func BenchmarkDeriveAccounts(b *testing.B) {
+	master, err := NewMasterKeyPair(goodSeed)
+	require.NoError(b, err)
+	indices := make([]int, 100)
+	for i := range indices {
+		indices[i] = i
+	}
+	// the speedup depends on the number of CPUs
+	for _, workers := range []int{1, 2, 4, 8} {
+		b.Run(fmt.Sprintf("workers=%d", workers), func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				_, err := deriveAccounts(master, goodSeed, indices, workers)
+				require.NoError(b, err)
+			}
+		})
+	}
+}
+
+func TestErrorExitCodes(t *testing.T) {
+	mnemonic := "film theme cheese broken kingdom destroy inch ready wear inspire shove pudding"
+	w, err := NewMultiWalletFromMnemonic(mnemonic, 1, WithPassphrase("hidden wallet"))
+	require.NoError(t, err)
+
+	// a wrong password or passphrase is an authentication error
+	buf := &strings.Builder{}
+	wk := NewKey(WithRandomSalt(), WithPbkdf2Password([]byte("password")))
+	require.NoError(t, wk.Export(buf, w))
+	wk = NewKey(WithPasswordOnly([]byte("wrong")))
+	_, err = wk.Open(strings.NewReader(buf.String()))
+	require.Equal(t, common.ExitAuth, common.ExitCode(err))
+	w, err = walletFromMnemonicAndAccounts(mnemonic, w.Secrets.MasterKeypair, w.Secrets.Accounts)
+	require.NoError(t, err)
+	_, err = w.Seed(WithPassphrase("wrong"))
+	require.Equal(t, common.ExitAuth, common.ExitCode(err))
+
+	// invalid input
+	_, err = ValidateAddress("sm1invalid", "sm")
+	require.Equal(t, common.ExitBadInput, common.ExitCode(err))
+	_, err = NewMultiWalletFromMnemonic(mnemonic, -1)
+	require.Equal(t, common.ExitBadInput, common.ExitCode(err))
+	badChecksum := strings.TrimSpace(strings.Repeat("abandon ", 12))
+	_, err = NewMultiWalletFromMnemonic(badChecksum, 1)
+	require.Equal(t, common.ExitBadInput, common.ExitCode(err))
+	_, err = ReadMnemonic(strings.NewReader(badChecksum + "\n"))
+	require.Equal(t, common.ExitBadInput, common.ExitCode(err))
+	_, err = NewMultiWalletRandomMnemonic(1, WithWordCount(13))
+	require.Equal(t, common.ExitBadInput, common.ExitCode(err))
+	_, err = DecodeTransaction([]byte{0xff})
+	require.Equal(t, common.ExitBadInput, common.ExitCode(err))
+	_, err = ParseSignature("zz", PubkeyEncodingHex)
+	require.Equal(t, common.ExitBadInput, common.ExitCode(err))
+	_, err = ParseSignature("not base64!", PubkeyEncodingBase64)
+	require.Equal(t, common.ExitBadInput, common.ExitCode(err))
+	_, err = ParseSignature("00", "base58")
+	require.Equal(t, common.ExitBadInput, common.ExitCode(err))
+	_, err = ReadOfflineTxn(strings.NewReader(`{"version":1,"genesisID":"00","transaction":"00"}`))
+	require.Equal(t, common.ExitBadInput, common.ExitCode(err))
+
+	// device errors
+	_, err = selectLedgerDevice(newMockLedger(), "")
+	require.Equal(t, common.ExitDevice, common.ExitCode(err))
+}
+
+func TestNewMultiWalletFromSeed(t *testing.T) {
+	fromMnemonic, err := NewMultiWalletFromMnemonic(testMnemonic, 3, WithPassphrase("passphrase"))
+	require.NoError(t, err)
+	seed, err := fromMnemonic.Seed()
+	require.NoError(t, err)
+
+	// the seed of a mnemonic and passphrase restores the same accounts
+	w, err := NewMultiWalletFromSeed(seed, 3, WithDisplayName("restored"))
+	require.NoError(t, err)
+	require.Equal(t, fromMnemonic.Addresses("sm"), w.Addresses("sm"))
+	require.Equal(t, fromMnemonic.Secrets.MasterKeypair.Public, w.Secrets.MasterKeypair.Public)
+	require.True(t, w.IsSeedOnly())
+	require.False(t, fromMnemonic.IsSeedOnly())
+	require.Empty(t, w.Mnemonic())
+	require.Equal(t, "restored", w.Meta.DisplayName)
+
+	parsed, err := ParseSeed("0x" + hex.EncodeToString(seed) + "\n")
+	require.NoError(t, err)
+	require.Equal(t, seed, parsed)
+	for _, s := range []string{"", "not hex", hex.EncodeToString(seed[:32])} {
+		_, err := ParseSeed(s)
+		require.ErrorIs(t, err, ErrInvalidSeed, s)
+	}
+	_, err = NewMultiWalletFromSeed(seed[:32], 1)
+	require.ErrorIs(t, err, ErrInvalidSeed)
+	_, err = NewMultiWalletFromSeed(seed, 1, WithPassphrase("passphrase"))
+	require.Error(t, err)
+
+	// the seed is kept in the wallet file, so that accounts can still be added after reopening it
+	password := []byte("password")
+	wk := NewKey(WithRandomSalt(), WithIterations(1000), WithPbkdf2Password(password))
+	var file strings.Builder
+	require.NoError(t, wk.Export(&file, w))
+	rk := NewKey(WithPasswordOnly(password))
+	opened, err := rk.Open(strings.NewReader(file.String()))
+	require.NoError(t, err)
+	require.True(t, opened.IsSeedOnly())
+	require.NoError(t, opened.AddAccounts(2))
+	require.NoError(t, fromMnemonic.AddAccounts(2, WithPassphrase("passphrase")))
+	require.Equal(t, fromMnemonic.Addresses("sm"), opened.Addresses("sm"))
+	mismatches, err := opened.Verify()
+	require.NoError(t, err)
+	require.Empty(t, mismatches)
+	exported, err := opened.Seed()
+	require.NoError(t, err)
+	require.Equal(t, seed, exported)
+	require.Error(t, opened.AddAccounts(1, WithPassphrase("passphrase")))
+
+	// forgetting the mnemonic of a seed wallet forgets its seed
+	opened.ForgetMnemonic()
+	require.False(t, opened.IsSeedOnly())
+	require.ErrorIs(t, opened.AddAccounts(1), ErrNoMnemonic)
+}
@@ -0,0 +1,83 @@
+package wallet
+
+import (
+	"crypto/ed25519"
+	"encoding/json"
+	"fmt"
+	"io"
+)
+
+// WatchOnlyType identifies a watch-only export, to tell it apart from an encrypted wallet file.
+const WatchOnlyType = "watch-only"
+
+// WatchOnlyWallet contains only the public part of a wallet: enough to enumerate the addresses of its accounts
+// and monitor them, but not to derive any private key or sign anything. It's stored unencrypted.
+//
+// Spacemesh uses SLIP-10 ed25519 derivation, which only supports hardened children, so there's no extended
+// public key from which further accounts could be derived. A watch-only wallet contains exactly the accounts
+// that the wallet contained when it was exported.
+type WatchOnlyWallet struct {
+	// Type is always WatchOnlyType.
+	Type     string             `json:"type"`
+	Meta     walletMetadata     `json:"meta"`
+	Accounts []WatchOnlyAccount `json:"accounts"`
+}
+
+// WatchOnlyAccount is the public part of an account.
+type WatchOnlyAccount struct {
+	DisplayName string    `json:"displayName"`
+	Path        HDPath    `json:"path"`
+	PublicKey   PublicKey `json:"publicKey"`
+	Label       string    `json:"label,omitempty"`
+}
+
+// WatchOnly returns the watch-only part of the wallet. It does not share any memory with the wallet.
+func (w *Wallet) WatchOnly() *WatchOnlyWallet {
+	wo := &WatchOnlyWallet{
+		Type:     WatchOnlyType,
+		Meta:     w.Meta,
+		Accounts: make([]WatchOnlyAccount, 0, len(w.Secrets.Accounts)),
+	}
+	for _, acct := range w.Secrets.Accounts {
+		wo.Accounts = append(wo.Accounts, WatchOnlyAccount{
+			DisplayName: acct.DisplayName,
+			Path:        append(HDPath(nil), acct.Path...),
+			PublicKey:   append(PublicKey(nil), acct.Public...),
+			Label:       acct.Label,
+		})
+	}
+	return wo
+}
+
+// Addresses returns the addresses of the accounts of a watch-only wallet, in order, using the given HRP.
+func (wo *WatchOnlyWallet) Addresses(hrp string) []string {
+	addresses := make([]string, 0, len(wo.Accounts))
+	for _, acct := range wo.Accounts {
+		addresses = append(addresses, PubkeyToAddress(acct.PublicKey, hrp))
+	}
+	return addresses
+}
+
+// Export writes a watch-only wallet as JSON.
+func (wo *WatchOnlyWallet) Export(file io.Writer) error {
+	enc := json.NewEncoder(file)
+	enc.SetIndent("", "  ")
+	return enc.Encode(wo)
+}
+
+// ReadWatchOnly reads a watch-only wallet written by WatchOnlyWallet.Export.
+func ReadWatchOnly(file io.Reader) (*WatchOnlyWallet, error) {
+	wo := &WatchOnlyWallet{}
+	if err := json.NewDecoder(file).Decode(wo); err != nil {
+		return nil, fmt.Errorf("failed to read watch-only wallet: %w", err)
+	}
+	if wo.Type != WatchOnlyType {
+		return nil, fmt.Errorf("not a watch-only wallet: type %q", wo.Type)
+	}
+	for i, acct := range wo.Accounts {
+		if len(acct.PublicKey) != ed25519.PublicKeySize {
+			return nil, fmt.Errorf("account %d: invalid public key length %d", i, len(acct.PublicKey))
+		}
+	}
+	return wo, nil
+}
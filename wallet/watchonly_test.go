@@ -0,0 +1,54 @@
+package wallet
+
+import (
+	"bytes"
+	"crypto/ed25519"
+	"encoding/hex"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestWatchOnly(t *testing.T) {
+	w, err := NewMultiWalletFromMnemonic(testMnemonic, 3)
+	require.NoError(t, err)
+	wo := w.WatchOnly()
+	require.Equal(t, WatchOnlyType, wo.Type)
+	require.Equal(t, w.Meta, wo.Meta)
+	require.Len(t, wo.Accounts, 3)
+	require.Equal(t, w.Addresses("sm"), wo.Addresses("sm"))
+	require.Equal(t, w.Addresses("stest"), wo.Addresses("stest"))
+
+	buf := &bytes.Buffer{}
+	require.NoError(t, wo.Export(buf))
+
+	// no secrets are exported
+	exported := buf.String()
+	require.NotContains(t, exported, "secretKey")
+	require.NotContains(t, exported, "mnemonic")
+	require.NotContains(t, exported, strings.Fields(testMnemonic)[0])
+	for _, acct := range append(w.Secrets.Accounts, w.Secrets.MasterKeypair) {
+		require.NotContains(t, exported, hex.EncodeToString(acct.Private[:ed25519.SeedSize]))
+	}
+
+	// round trip
+	wo2, err := ReadWatchOnly(buf)
+	require.NoError(t, err)
+	require.Equal(t, wo, wo2)
+	require.Equal(t, w.Addresses("sm"), wo2.Addresses("sm"))
+	require.Equal(t, w.Secrets.Accounts[2].Path, wo2.Accounts[2].Path)
+
+	// the export doesn't alias the wallet
+	wo.Accounts[0].PublicKey[0] ^= 1
+	require.NotEqual(t, w.Addresses("sm")[0], wo.Addresses("sm")[0])
+}
+
+func TestReadWatchOnlyInvalid(t *testing.T) {
+	_, err := ReadWatchOnly(strings.NewReader(`{"meta":{},"crypto":{}}`))
+	require.Error(t, err)
+	_, err = ReadWatchOnly(strings.NewReader(`{"type":"watch-only","accounts":[{"publicKey":"abcd"}]}`))
+	require.Error(t, err)
+	_, err = ReadWatchOnly(strings.NewReader(`not json`))
+	require.Error(t, err)
+}
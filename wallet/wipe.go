@@ -0,0 +1,70 @@
+package wallet
+
+import "encoding/json"
+
+// Secrets are zeroed on a best-effort basis: Go strings are immutable and the garbage collector may move or copy
+// memory, so copies of a secret can outlive Wipe. To keep their number down the mnemonic is held as a byte
+// slice rather than a string, and the decrypted wallet file is zeroed as soon as it has been parsed. Copies that
+// can't be wiped are still made whenever a string is needed, e.g., by Mnemonic and while deriving the seed, since
+// the BIP39 library only accepts strings.
+
+// secretBytes holds a secret that's encoded as a JSON string, such as the mnemonic, in a byte slice so that it can
+// be wiped.
+type secretBytes []byte
+
+// MarshalJSON has a value receiver so that it's also used when the secrets are marshaled by value.
+func (s secretBytes) MarshalJSON() ([]byte, error) {
+	return json.Marshal(string(s))
+}
+
+func (s *secretBytes) UnmarshalJSON(data []byte) error {
+	var str string
+	if err := json.Unmarshal(data, &str); err != nil {
+		return err
+	}
+	*s = secretBytes(str)
+	return nil
+}
+
+// wipe overwrites b with zeros.
+func wipe(b []byte) {
+	for i := range b {
+		b[i] = 0
+	}
+}
+
+// Wipe overwrites the mnemonic and the private keys held by the wallet with zeros and removes them, along with
+// the BIP39 passphrase, once the wallet is no longer needed. Public keys and metadata are kept, but the wallet
+// can no longer sign, derive accounts, or be saved without losing its secrets. Callers that decrypt a wallet
+// should defer a call to Wipe.
+func (w *Wallet) Wipe() {
+	w.ForgetMnemonic()
+	for _, acct := range w.Secrets.Accounts {
+		if acct != nil {
+			acct.Wipe()
+		}
+	}
+}
+
+// Wipe overwrites the private key of the keypair with zeros and removes it, e.g., once a key read using
+// OpenKeyFile has been used to sign. Only the public key is kept, so the keypair can no longer sign.
+func (kp *EDKeyPair) Wipe() {
+	wipe(kp.Private)
+	kp.Private = nil
+}
+
+// ForgetMnemonic wipes and removes the mnemonic of the wallet, or its seed if it was restored from it, along with
+// the master private key derived from it and the BIP39 passphrase, so that a wallet file saved afterwards only
+// holds the keys of the accounts that were already derived. They can still sign, but no more accounts can be
+// derived and the seed can't be exported: AddAccounts, Seed, Verify, and RepairAccounts return ErrNoMnemonic.
+// Only the mnemonic can restore the wallet then, so it must be backed up.
+func (w *Wallet) ForgetMnemonic() {
+	wipe(w.Secrets.Mnemonic)
+	w.Secrets.Mnemonic = nil
+	wipe(w.Secrets.Seed)
+	w.Secrets.Seed = nil
+	w.Secrets.passphrase = ""
+	if master := w.Secrets.MasterKeypair; master != nil {
+		master.Wipe()
+	}
+}
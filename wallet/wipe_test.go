@@ -0,0 +1,134 @@
+package wallet
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/spacemeshos/go-spacemesh/common/types"
+	"github.com/stretchr/testify/require"
+)
+
+func requireZero(t *testing.T, b []byte) {
+	t.Helper()
+	require.NotEmpty(t, b)
+	require.Equal(t, make([]byte, len(b)), b)
+}
+
+func TestWipe(t *testing.T) {
+	w, err := NewMultiWalletRandomMnemonic(3, WithPassphrase("passphrase"))
+	require.NoError(t, err)
+	addresses := w.Addresses("sm")
+
+	// keep the underlying buffers around to check they've been overwritten
+	mnemonic := w.Secrets.Mnemonic
+	master := w.Secrets.MasterKeypair.Private
+	accounts := make([][]byte, len(w.Secrets.Accounts))
+	for i, acct := range w.Secrets.Accounts {
+		accounts[i] = acct.Private
+	}
+
+	w.Wipe()
+	requireZero(t, mnemonic)
+	requireZero(t, master)
+	for _, key := range accounts {
+		requireZero(t, key)
+	}
+	require.Empty(t, w.Mnemonic())
+	require.Empty(t, w.Secrets.passphrase)
+	require.Nil(t, w.Secrets.MasterKeypair.Private)
+
+	// public data remains, but the wallet can no longer sign or derive
+	require.Equal(t, addresses, w.Addresses("sm"))
+	_, err = SignTxn(w.Secrets.Accounts[0], types.Hash20{}, []byte("tx"))
+	require.Error(t, err)
+	require.Error(t, w.AddAccounts(1, WithPassphrase("passphrase")))
+
+	// wiping twice is harmless
+	w.Wipe()
+}
+
+func TestWipeMissingAccount(t *testing.T) {
+	w, err := NewMultiWalletRandomMnemonic(3)
+	require.NoError(t, err)
+	last := w.Secrets.Accounts[2].Private
+	w.Secrets.Accounts[1] = nil
+
+	require.NotPanics(t, w.Wipe)
+	requireZero(t, last)
+	require.Nil(t, w.Secrets.Accounts[0].Private)
+	require.Nil(t, w.Secrets.Accounts[1])
+	require.Nil(t, w.Secrets.Accounts[2].Private)
+}
+
+func TestWipeKeyPair(t *testing.T) {
+	w, err := NewMultiWalletRandomMnemonic(1)
+	require.NoError(t, err)
+	kp := w.Secrets.Accounts[0]
+	public := append(PublicKey{}, kp.Public...)
+	private := kp.Private
+
+	kp.Wipe()
+	requireZero(t, private)
+	require.Nil(t, kp.Private)
+	require.Equal(t, public, kp.Public)
+	_, err = kp.SignMessage([]byte("message"))
+	require.Error(t, err)
+}
+
+func TestSecretBytesJSON(t *testing.T) {
+	// the mnemonic is still written as a plain JSON string
+	const mnemonic = "film theme cheese broken kingdom destroy inch ready wear inspire shove pudding"
+	secrets := walletSecrets{Mnemonic: secretBytes(mnemonic)}
+	data, err := json.Marshal(secrets)
+	require.NoError(t, err)
+	var raw map[string]interface{}
+	require.NoError(t, json.Unmarshal(data, &raw))
+	require.Equal(t, mnemonic, raw["mnemonic"])
+
+	var decoded walletSecrets
+	require.NoError(t, json.Unmarshal(data, &decoded))
+	require.Equal(t, secrets.Mnemonic, decoded.Mnemonic)
+	require.Error(t, json.Unmarshal([]byte(`{"mnemonic": 1}`), &decoded))
+}
+
+func TestForgetMnemonic(t *testing.T) {
+	w, err := NewMultiWalletRandomMnemonic(2)
+	require.NoError(t, err)
+	m := w.Mnemonic()
+	addresses := w.Addresses("sm")
+	w.ForgetMnemonic()
+
+	password := []byte("password")
+	wk := NewKey(WithRandomSalt(), WithIterations(1000), WithPbkdf2Password(password))
+	var file bytes.Buffer
+	require.NoError(t, wk.Export(&file, w))
+	ew := &EncryptedWalletFile{}
+	require.NoError(t, json.Unmarshal(file.Bytes(), ew))
+	rk := NewKey(WithPasswordOnly(password))
+	plaintext, err := rk.decryptSecrets(ew)
+	require.NoError(t, err)
+	require.NotContains(t, string(plaintext), strings.Fields(m)[0]+" "+strings.Fields(m)[1])
+
+	// the mnemonic and the master private key are gone, the keys of the accounts aren't
+	opened, err := rk.Open(bytes.NewReader(file.Bytes()))
+	require.NoError(t, err)
+	require.Empty(t, opened.Mnemonic())
+	require.Empty(t, opened.Secrets.MasterKeypair.Private)
+	require.NotEmpty(t, opened.Secrets.MasterKeypair.Public)
+	require.Equal(t, addresses, opened.Addresses("sm"))
+	sig, err := opened.SignMessage(1, []byte("hello"))
+	require.NoError(t, err)
+	require.NotEmpty(t, sig)
+
+	// nothing can be derived from the mnemonic any longer
+	require.ErrorIs(t, opened.AddAccounts(1), ErrNoMnemonic)
+	require.Len(t, opened.Secrets.Accounts, 2)
+	_, err = opened.Seed()
+	require.ErrorIs(t, err, ErrNoMnemonic)
+	_, err = opened.Verify()
+	require.ErrorIs(t, err, ErrNoMnemonic)
+	require.ErrorIs(t, opened.RepairAccounts(2), ErrNoMnemonic)
+	require.ErrorIs(t, ExportSmapp(&bytes.Buffer{}, opened, password), ErrNoMnemonic)
+}